@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (a *AddCmd) Run(config engine.Config) error {
+	if a.Every == 0 && a.DailyAt == "" && a.Cron == "" {
+		return fmt.Errorf("give a schedule with --every, --daily-at, or --cron")
+	}
+
+	if a.Timezone != "" && a.DailyAt == "" {
+		return fmt.Errorf("--timezone requires --daily-at")
+	}
+
+	path, err := engine.AddJob(config, a.JobName, engine.NewJob{
+		Command:  a.Command,
+		Every:    a.Every,
+		DailyAt:  a.DailyAt,
+		Timezone: a.Timezone,
+		Cron:     a.Cron,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s\n", path)
+	fmt.Printf("Try it with: regular run --force %s\n", a.JobName)
+
+	return nil
+}
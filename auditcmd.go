@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+// How many hex digits of the content hash to show.
+const auditHashLength = 12
+
+func (a *AuditCmd) Run(config engine.Config) error {
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entries, err := db.AuditLog(a.JobName, a.Lines)
+	if err != nil {
+		return fmt.Errorf("error reading audit log: %w", err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No configuration changes recorded")
+		return nil
+	}
+
+	for _, entry := range entries {
+		hash := entry.Hash
+		if hash == "" {
+			hash = "-"
+		} else if len(hash) > auditHashLength {
+			hash = hash[:auditHashLength]
+		}
+
+		name := entry.JobName
+		if name == "" {
+			name = "-"
+		}
+
+		fmt.Printf("%s  %-6s  %-12s  %s  %s\n", entry.Time.Format(timestampFormat), entry.Action, hash, name, entry.Path)
+	}
+
+	return nil
+}
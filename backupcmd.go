@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (b *BackupCmd) Run(config engine.Config) error {
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Backup(b.Path); err != nil {
+		return err
+	}
+
+	fmt.Println("Backed up state database to", b.Path)
+
+	return nil
+}
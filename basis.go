@@ -2,7 +2,6 @@ package main
 
 import (
 	"path/filepath"
-	"regexp"
 	"time"
 
 	"github.com/adrg/xdg"
@@ -11,33 +10,8 @@ import (
 const (
 	version = "0.4.0"
 
-	appDBFileName         = "state.sqlite3"
-	appLockFileName       = "app.lock"
-	appLogFileName        = "app.log"
-	appSocketFileName     = "socket"
-	dirName               = "regular"
-
-	socketEnv = "REGULAR_SOCK"
-	globalEnvFileName     = "global.env"
-	jobConfigFileName     = "config.star"
-	jobEnvFileName        = "job.env"
-	jobExecutableFileName = "./run"
-	stderrFileName        = "stderr.log"
-	stdoutFileName        = "stdout.log"
-
-	jobDirEnvVar = "REGULAR_JOB_DIR"
-
-	enableVar     = "enable"
-	envVar        = "env"
-	logVar        = "log"
-	notifyModeVar = "notify"
-	oneDayVar     = "one_day"
-	oneHourVar    = "one_hour"
-	oneMinuteVar  = "one_minute"
-	shouldRunVar  = "should_run"
-
-	redactedValue = "[redacted]"
-	secretRegexp  = "(?i)(key|password|secret|token)"
+	appLogFileName = "app.log"
+	dirName        = "regular"
 
 	exitOK       = 0
 	exitError    = 1
@@ -48,13 +22,10 @@ const (
 
 	timestampFormat = "2006-01-02 15:04:05 -0700"
 
-	debounceInterval = 100 * time.Millisecond
-	maxMissedTime    = time.Hour
-	runInterval      = time.Second
-	scheduleInterval = time.Minute
+	defaultLogLines = 10
 
-	defaultLogLines  = 10
-	maxLogBufferSize = 256 * 1024
+	// How far ahead status looks for the next run of a job.
+	nextDueHorizon = 48 * time.Hour
 )
 
 var (
@@ -62,19 +33,6 @@ var (
 	defaultStateRoot  = filepath.Join(xdg.StateHome, dirName)
 )
 
-type Config struct {
-	ConfigRoot string
-	StateRoot  string
-}
-
-func jobDir(path string) string {
-	return filepath.Dir(path)
-}
-
-func jobNameFromPath(path string) string {
-	return filepath.Base(filepath.Dir(path))
-}
-
 func boolYesNo(b bool) string {
 	if b {
 		return "yes"
@@ -82,21 +40,3 @@ func boolYesNo(b bool) string {
 
 	return "no"
 }
-
-// Format a Duration without the trailing zero units.
-func formatDuration(d time.Duration) string {
-	d = d.Round(time.Millisecond)
-
-	if d > time.Second {
-		d = d.Round(100 * time.Millisecond)
-	}
-
-	zeroUnits := regexp.MustCompile("(^|[^0-9])(?:0h)?(?:0m)?(?:0s)?$")
-	s := zeroUnits.ReplaceAllString(d.String(), "$1")
-
-	if s == "" {
-		return "0"
-	}
-
-	return s
-}
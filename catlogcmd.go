@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (c *CatLogCmd) Run(config engine.Config) error {
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	run, err := db.Run(c.JobName, c.RunID)
+	if err != nil {
+		return fmt.Errorf("error getting run of job %q: %w", c.JobName, err)
+	}
+	if run == nil {
+		if c.RunID == 0 {
+			return fmt.Errorf("job %q has no recorded runs", c.JobName)
+		}
+
+		return fmt.Errorf("job %q has no run with ID %d", c.JobName, c.RunID)
+	}
+
+	logName := "stdout"
+	if c.Stderr {
+		logName = "stderr"
+	}
+
+	latest, err := db.LastRan(c.JobName)
+	if err != nil {
+		return fmt.Errorf("error getting last run of job %q: %w", c.JobName, err)
+	}
+
+	// The log file has the complete output of the latest run, while the database only has the beginning.
+	if latest != nil && latest.ID == run.ID {
+		ok, err := catLogFile(engine.LogFilePath(config.StateRoot, c.JobName, logName), run.Finished)
+		if ok || err != nil {
+			return err
+		}
+	}
+
+	lines, err := db.RunLog(run.ID, logName)
+	if err != nil {
+		return fmt.Errorf("error loading %s for job %q: %w", logName, c.JobName, err)
+	}
+
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// catLogFile copies the log file to stdout unless it is missing or was written after the run finished.
+// A newer log file belongs to a run in progress.
+func catLogFile(path string, finished time.Time) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("error opening log file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("error opening log file: %w", err)
+	}
+
+	if info.ModTime().After(finished.Add(time.Second)) {
+		return false, nil
+	}
+
+	if _, err := io.Copy(os.Stdout, f); err != nil {
+		return false, fmt.Errorf("error reading log file: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+const (
+	colorAlways = "always"
+	colorAuto   = "auto"
+	colorNever  = "never"
+)
+
+// Decide whether to color the output.
+// In auto mode, color stdout when it is a terminal unless NO_COLOR is set to a nonempty value.
+func useColor(mode string) bool {
+	switch mode {
+
+	case colorAlways:
+		return true
+
+	case colorNever:
+		return false
+
+	default:
+		if os.Getenv("NO_COLOR") != "" {
+			return false
+		}
+
+		return term.IsTerminal(int(os.Stdout.Fd()))
+	}
+}
+
+// Apply the color mode to all output that uses the color package.
+func setColorMode(mode string) {
+	color.NoColor = !useColor(mode)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	tests := []struct {
+		mode     string
+		expected bool
+	}{
+		{colorAlways, true},
+		{colorNever, false},
+		// NO_COLOR disables color in auto mode.
+		{colorAuto, false},
+	}
+
+	for _, tt := range tests {
+		if got := useColor(tt.mode); got != tt.expected {
+			t.Errorf("useColor(%q) = %v, want %v", tt.mode, got, tt.expected)
+		}
+	}
+}
@@ -4,24 +4,80 @@ complete -c regular -f
 
 # Global options.
 complete -c regular -s V -l version -d "Print version number and exit"
+complete -c regular -l color -d "When to color the output" -x -a "auto always never"
 complete -c regular -s c -l config-dir -d "Path to config directory" -r
 complete -c regular -s s -l state-dir -d "Path to state directory" -r
 
 # Commands.
-complete -c regular -n "not __fish_seen_subcommand_from list log run start status" -a list -d "List available jobs"
-complete -c regular -n "not __fish_seen_subcommand_from list log run start status" -a log -d "Show application log"
-complete -c regular -n "not __fish_seen_subcommand_from list log run start status" -a run -d "Run jobs once"
-complete -c regular -n "not __fish_seen_subcommand_from list log run start status" -a start -d "Start scheduler"
-complete -c regular -n "not __fish_seen_subcommand_from list log run start status" -a status -d "Show job status"
+set -l commands add audit backup cat-log disable enable export gc history init list log notify-test pause rename restore resume run start stats status validate why
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a add -d "Create a job from a command line"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a audit -d "Show configuration changes"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a backup -d "Back up the state database"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a cat-log -d "Show the complete output of a job run"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a disable -d "Stop scheduling jobs until a time or until enabled"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a enable -d "Resume scheduling disabled jobs"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a export -d "Export the predicted runs of jobs as a calendar"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a gc -d "Remove the state of deleted jobs"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a history -d "Show recent runs"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a init -d "Create an example job"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a list -d "List available jobs"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a log -d "Show application log"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a notify-test -d "Send a test notification"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a pause -d "Stop starting the jobs waiting in queues"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a rename -d "Rename a job and keep its history"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a restore -d "Restore the state database from a backup"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a resume -d "Start the jobs in paused queues again"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a run -d "Run jobs once"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a start -d "Start scheduler"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a stats -d "Show run statistics and queue load"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a status -d "Show job status"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a validate -d "Check job files for errors and unknown variables"
+complete -c regular -n "not __fish_seen_subcommand_from $commands" -a why -d "Explain why the scheduler queued a job or didn't"
 
 # Command-specific options.
+complete -c regular -n "__fish_seen_subcommand_from add" -l every -d "Run the job at this interval" -x
+complete -c regular -n "__fish_seen_subcommand_from add" -l daily-at -d "Run the job once a day at this time (HH:MM)" -x
+complete -c regular -n "__fish_seen_subcommand_from add" -l timezone -d "Time zone for --daily-at" -x
+complete -c regular -n "__fish_seen_subcommand_from add" -l cron -d "Run the job on this crontab schedule" -x
+complete -c regular -n "__fish_seen_subcommand_from list" -l names -d "Only print job names"
 complete -c regular -n "__fish_seen_subcommand_from log status" -s l -l log-lines -d "Number of log lines to show"
+complete -c regular -n "__fish_seen_subcommand_from log" -l job -d "Show the log lines about a job interleaved with the output of its runs" -xa "(__regular_list_jobs)"
+complete -c regular -n "__fish_seen_subcommand_from audit" -s n -l lines -d "Number of entries to show"
+complete -c regular -n "__fish_seen_subcommand_from history" -s n -l lines -d "Number of runs to show"
+complete -c regular -n "__fish_seen_subcommand_from history" -l dead -d "Only show runs that failed on their last retry"
+complete -c regular -n "__fish_seen_subcommand_from backup restore" -F -d "Backup file"
+complete -c regular -n "__fish_seen_subcommand_from cat-log" -l run -d "ID of the run to show" -r
+complete -c regular -n "__fish_seen_subcommand_from cat-log" -l stderr -d "Show stderr instead of stdout"
+complete -c regular -n "__fish_seen_subcommand_from export" -l format -d "Format of the export" -x -a ics
+complete -c regular -n "__fish_seen_subcommand_from export" -s w -l weeks -d "How many weeks of runs to predict" -x
+complete -c regular -n "__fish_seen_subcommand_from gc" -s n -l dry-run -d "Only print what would be removed"
+complete -c regular -n "__fish_seen_subcommand_from gc" -l grace -d "Keep the state of jobs active within this time" -x
+complete -c regular -n "__fish_seen_subcommand_from run" -s a -l all -d "Run all jobs"
+complete -c regular -n "__fish_seen_subcommand_from run" -s t -l tag -d "Run jobs with this tag" -x
+complete -c regular -n "__fish_seen_subcommand_from run" -s e -l env -d "Set an environment variable for the run" -x
+complete -c regular -n "__fish_seen_subcommand_from run" -l env-file -d "Load environment variables for the run" -r -F
 complete -c regular -n "__fish_seen_subcommand_from run" -s f -l force -d "Run jobs regardless of schedule"
+complete -c regular -n "__fish_seen_subcommand_from run" -s p -l parallel -d "Number of queues to run at the same time" -x
+complete -c regular -n "__fish_seen_subcommand_from run" -l no-jitter -d "Start jobs immediately without the random delay"
+complete -c regular -n "__fish_seen_subcommand_from stats" -l period -d "How far back to look" -x
+complete -c regular -n "__fish_seen_subcommand_from stats" -l recent -d "How many of the latest runs to compare with the earlier ones" -x
+complete -c regular -n "__fish_seen_subcommand_from stats" -l trend -d "Compare the latest run times with the earlier ones and flag slowdowns"
+complete -c regular -n "__fish_seen_subcommand_from stats" -l by-host -d "Show which hosts ran which jobs and when"
+complete -c regular -n "__fish_seen_subcommand_from status" -l disabled -d "Show disabled jobs"
+complete -c regular -n "__fish_seen_subcommand_from status" -l failed -d "Show jobs whose last run failed"
+complete -c regular -n "__fish_seen_subcommand_from status" -l running -d "Show running jobs"
+complete -c regular -n "__fish_seen_subcommand_from disable" -l until -d "Enable the jobs again at this time" -x
+complete -c regular -n "__fish_seen_subcommand_from disable" -l for -d "Enable the jobs again after this long" -x
+complete -c regular -n "__fish_seen_subcommand_from status" -l events -d "Number of scheduler events to show" -x
+complete -c regular -n "__fish_seen_subcommand_from start" -l foreground-logs -d "Log to stderr without timestamps"
+complete -c regular -n "__fish_seen_subcommand_from start" -l listen -d "Address for the HTTP API to listen on" -r
+complete -c regular -n "__fish_seen_subcommand_from start" -l debug-listen -d "Address for the debug endpoint to listen on" -r
+complete -c regular -n "__fish_seen_subcommand_from start" -l clock-offset -d "Shift the time the scheduler sees by this much" -x
 
 # A helper function for job name completion.
 function __regular_list_jobs
-    regular list
+    regular list --names
 end
 
 # Add job name completion for relevant commands.
-complete -c regular -n "__fish_seen_subcommand_from run status" -a "(__regular_list_jobs)" -d "Job name"
+complete -c regular -n "__fish_seen_subcommand_from audit cat-log disable enable export history notify-test rename run stats status validate why" -a "(__regular_list_jobs)" -d "Job name"
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"dbohdan.com/regular/engine"
+)
+
+// Layouts "disable --until" accepts in the local time zone.
+var untilLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+}
+
+// parseUntil parses the end of a snooze.
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range untilLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q: use YYYY-MM-DD, YYYY-MM-DD HH:MM, or RFC 3339", s)
+}
+
+// checkJobNames returns an error if a job doesn't exist.
+func checkJobNames(config engine.Config, names []string) error {
+	known, err := engine.ListJobNames(config.ConfigRoots()...)
+	if err != nil {
+		return err
+	}
+
+	jsc := engine.NewScheduler()
+	for _, name := range names {
+		if slices.Contains(known, name) {
+			continue
+		}
+
+		// Load the job file to look for the jobs defined with "job".
+		if _, err := jsc.LoadJob(config.ConfigRoots(), name); err != nil {
+			return fmt.Errorf("job %q not found", name)
+		}
+	}
+
+	return nil
+}
+
+func (d *DisableCmd) Run(config engine.Config) error {
+	now := time.Now()
+
+	var until time.Time
+	switch {
+	case d.Until != "":
+		var err error
+		until, err = parseUntil(d.Until)
+		if err != nil {
+			return err
+		}
+
+	case d.For < 0:
+		return errors.New("duration must not be negative")
+
+	case d.For > 0:
+		until = now.Add(d.For)
+	}
+
+	if !until.IsZero() && !until.After(now) {
+		return fmt.Errorf("time %s is in the past", until.Format(timestampFormat))
+	}
+
+	if err := checkJobNames(config, d.JobNames); err != nil {
+		return err
+	}
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, name := range d.JobNames {
+		if err := db.SnoozeJob(name, until); err != nil {
+			return fmt.Errorf("failed to disable job %q: %w", name, err)
+		}
+
+		if err := engine.TellDaemonDisabled(name, until); err != nil {
+			return fmt.Errorf("failed to tell the scheduler to disable job %q: %w", name, err)
+		}
+
+		fmt.Printf("Disabled %s %s\n", name, snoozeEnd(engine.Snooze{Until: until}))
+	}
+
+	return nil
+}
+
+func (e *EnableCmd) Run(config engine.Config) error {
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, name := range e.JobNames {
+		removed, err := db.UnsnoozeJob(name)
+		if err != nil {
+			return fmt.Errorf("failed to enable job %q: %w", name, err)
+		}
+
+		if err := engine.TellDaemonEnabled(name); err != nil {
+			return fmt.Errorf("failed to tell the scheduler to enable job %q: %w", name, err)
+		}
+
+		if removed {
+			fmt.Printf("Enabled %s\n", name)
+		} else {
+			fmt.Printf("%s wasn't disabled with \"regular disable\"\n", name)
+		}
+	}
+
+	return nil
+}
+
+// snoozeEnd describes when a snooze ends.
+func snoozeEnd(snooze engine.Snooze) string {
+	if snooze.Until.IsZero() {
+		return "until enabled"
+	}
+
+	return "until " + snooze.Until.Format(timestampFormat)
+}
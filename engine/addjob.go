@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.starlark.net/syntax"
+
+	"dbohdan.com/denv"
+)
+
+// NewJob describes a job that "regular add" creates from a command line.
+// Exactly one of the schedule fields is set.
+type NewJob struct {
+	Command []string
+
+	Every    time.Duration
+	DailyAt  string
+	Timezone string
+	Cron     string
+}
+
+// Source returns the job file for the job.
+func (j NewJob) Source() (string, error) {
+	if len(j.Command) == 0 {
+		return "", fmt.Errorf("no command")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Created by \"regular add\".\n# See the README for all options.\n\n")
+
+	switch {
+
+	case j.Every != 0:
+		if j.Every < time.Second || j.Every%time.Second != 0 {
+			return "", fmt.Errorf("interval must be a positive whole number of seconds: %v", j.Every)
+		}
+
+		fmt.Fprintf(&sb, "%s = %s(%s)\n", scheduleVar, everyVar, intervalSource(j.Every))
+
+	case j.DailyAt != "":
+		fmt.Fprintf(&sb, "%s = %s(%s", scheduleVar, dailyAtVar, syntax.Quote(j.DailyAt, false))
+		if j.Timezone != "" {
+			fmt.Fprintf(&sb, ", timezone=%s", syntax.Quote(j.Timezone, false))
+		}
+		sb.WriteString(")\n")
+
+	case j.Cron != "":
+		fmt.Fprintf(&sb, "%s = %s\n", scheduleVar, syntax.Quote(j.Cron, false))
+
+	default:
+		return "", fmt.Errorf("no schedule")
+	}
+
+	quoted := make([]string, len(j.Command))
+	for i, arg := range j.Command {
+		quoted[i] = syntax.Quote(arg, false)
+	}
+	fmt.Fprintf(&sb, "\ncommand = [%s]\n", strings.Join(quoted, ", "))
+
+	return sb.String(), nil
+}
+
+// intervalSource writes an interval with the largest time constant that divides it.
+func intervalSource(d time.Duration) string {
+	for _, unit := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{oneDayVar, 24 * time.Hour},
+		{oneHourVar, time.Hour},
+		{oneMinuteVar, time.Minute},
+	} {
+		if d%unit.duration != 0 {
+			continue
+		}
+
+		if n := d / unit.duration; n > 1 {
+			return fmt.Sprintf("%d * %s", n, unit.name)
+		}
+
+		return unit.name
+	}
+
+	return fmt.Sprint(int64(d / time.Second))
+}
+
+// AddJob creates a job directory with a job file generated from a command line and returns the path of the file.
+// It checks that the job loads before it writes the file and doesn't overwrite an existing job.
+func AddJob(config Config, name string, job NewJob) (string, error) {
+	if err := validateJobName(name); err != nil {
+		return "", err
+	}
+
+	src, err := job.Source()
+	if err != nil {
+		return "", err
+	}
+
+	path := jobFilePath(config, name)
+	program, err := compileJob(path, []byte(src))
+	if err == nil {
+		_, err = loadJobProgram(denv.OS(), config.ConfigRoot, path, program)
+	}
+	if err != nil {
+		return "", fmt.Errorf("generated job doesn't load: %w", err)
+	}
+
+	return writeNewJob(config, name, src)
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestNewJobSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      NewJob
+		schedule string
+		wantErr  bool
+	}{
+		{"every hour", NewJob{Every: time.Hour}, "schedule = every(one_hour)", false},
+		{"every 90 minutes", NewJob{Every: 90 * time.Minute}, "schedule = every(90 * one_minute)", false},
+		{"every 2 days", NewJob{Every: 48 * time.Hour}, "schedule = every(2 * one_day)", false},
+		{"every 45 seconds", NewJob{Every: 45 * time.Second}, "schedule = every(45)", false},
+		{"fractional seconds", NewJob{Every: 1500 * time.Millisecond}, "", true},
+		{"daily", NewJob{DailyAt: "03:30", Timezone: "Europe/Kyiv"}, `schedule = daily_at("03:30", timezone="Europe/Kyiv")`, false},
+		{"cron", NewJob{Cron: "*/5 * * * *"}, `schedule = "*/5 * * * *"`, false},
+		{"no schedule", NewJob{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.job.Command = []string{"true"}
+
+			src, err := tt.job.Source()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Source() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !strings.Contains(src, tt.schedule+"\n") {
+				t.Errorf("Expected %q in source, got:\n%s", tt.schedule, src)
+			}
+		})
+	}
+}
+
+func TestAddJob(t *testing.T) {
+	config := Config{ConfigRoot: t.TempDir(), StateRoot: t.TempDir()}
+	command := []string{"rsync", "-a", "my files/", `host:"backup"`, `back\slash`}
+
+	path, err := AddJob(config, "backup", NewJob{Command: command, Every: time.Hour})
+	if err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+
+	job, err := loadJob(denv.Env{}, path)
+	if err != nil {
+		t.Fatalf("Failed to load the generated job: %v", err)
+	}
+	if strings.Join(job.Command, "\x00") != strings.Join(command, "\x00") {
+		t.Errorf("Expected command %q, got %q", command, job.Command)
+	}
+	if job.ScheduleSummary() == "" {
+		t.Error("Expected the job to have a schedule")
+	}
+
+	if _, err := AddJob(config, "backup", NewJob{Command: command, Every: time.Hour}); err == nil {
+		t.Error("Expected an error for an existing job")
+	}
+
+	if _, err := AddJob(config, "bad", NewJob{Command: command, DailyAt: "25:00"}); err == nil {
+		t.Error("Expected an error for a bad time")
+	}
+	if _, err := os.Stat(jobFilePath(config, "bad")); !os.IsNotExist(err) {
+		t.Error("Expected no job file for a job that doesn't load")
+	}
+}
@@ -0,0 +1,629 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+type AppDB struct {
+	db *sql.DB
+}
+
+func OpenAppDB(stateRoot string) (*AppDB, error) {
+	if err := os.MkdirAll(stateRoot, dirPerms); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %v", err)
+	}
+
+	// Jobs in different queues finish at the same time.
+	// Wait for the lock instead of failing to save one of them.
+	dbPath := filepath.Join(stateRoot, appDBFileName)
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	if err := createSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &AppDB{db: db}, nil
+}
+
+func (c *AppDB) Close() error {
+	return c.db.Close()
+}
+
+func createSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+	    PRAGMA foreign_keys=ON;
+
+		CREATE TABLE IF NOT EXISTS completed_jobs (
+			id INTEGER PRIMARY KEY,
+			job_name TEXT NOT NULL,
+			error TEXT,
+			exit_status INTEGER NOT NULL,
+			started DATETIME NOT NULL,
+			finished DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			hostname TEXT NOT NULL DEFAULT '',
+			username TEXT NOT NULL DEFAULT '',
+			command TEXT NOT NULL DEFAULT '[]',
+			signal INTEGER NOT NULL DEFAULT 0,
+			success_exit_codes TEXT NOT NULL DEFAULT '[]',
+			attempt INTEGER NOT NULL DEFAULT 1,
+			dead INTEGER NOT NULL DEFAULT 0,
+			input_hash TEXT NOT NULL DEFAULT '',
+			skipped INTEGER NOT NULL DEFAULT 0,
+			exit_meaning TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_completed_jobs_job_name ON completed_jobs(job_name);
+
+		CREATE TABLE IF NOT EXISTS job_logs (
+			id INTEGER PRIMARY KEY,
+			completed_job_id INTEGER NOT NULL,
+			log_name TEXT NOT NULL,
+			line_number INTEGER NOT NULL,
+			line TEXT NOT NULL,
+			FOREIGN KEY(completed_job_id) REFERENCES completed_jobs(id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_job_logs_completed_job_id ON job_logs(completed_job_id);
+
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY,
+			time DATETIME NOT NULL,
+			action TEXT NOT NULL,
+			job_name TEXT NOT NULL,
+			path TEXT NOT NULL,
+			hash TEXT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_audit_log_job_name ON audit_log(job_name);
+
+		CREATE TABLE IF NOT EXISTS pending_notifications (
+			id INTEGER PRIMARY KEY,
+			notifier TEXT NOT NULL,
+			job_name TEXT NOT NULL,
+			run_id INTEGER NOT NULL,
+			error TEXT NOT NULL,
+			exit_status INTEGER NOT NULL,
+			started DATETIME NOT NULL,
+			finished DATETIME NOT NULL,
+			attempts INTEGER NOT NULL,
+			next_attempt DATETIME NOT NULL,
+			last_error TEXT NOT NULL,
+			notify_to TEXT NOT NULL DEFAULT '[]',
+			notify_via TEXT NOT NULL DEFAULT '[]',
+			success_exit_codes TEXT NOT NULL DEFAULT '[]',
+			notify_lines INTEGER,
+			notify_stdout INTEGER,
+			attachments TEXT NOT NULL DEFAULT '[]',
+			channel TEXT NOT NULL DEFAULT '',
+			completed TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE TABLE IF NOT EXISTS failure_streaks (
+			job_name TEXT PRIMARY KEY,
+			fingerprint TEXT NOT NULL,
+			count INTEGER NOT NULL,
+			since DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS job_events (
+			id INTEGER PRIMARY KEY,
+			time DATETIME NOT NULL,
+			job_name TEXT NOT NULL,
+			message TEXT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_job_events_job_name ON job_events(job_name);
+
+		CREATE TABLE IF NOT EXISTS queued_jobs (
+			id INTEGER PRIMARY KEY,
+			job_name TEXT NOT NULL,
+			start_at DATETIME NOT NULL,
+			retried INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS paused_queues (
+			queue TEXT PRIMARY KEY,
+			since DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS snoozed_jobs (
+			job_name TEXT PRIMARY KEY,
+			since DATETIME NOT NULL,
+			until DATETIME
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	err = addMissingColumns(db, "completed_jobs", []string{
+		"hostname TEXT NOT NULL DEFAULT ''",
+		"username TEXT NOT NULL DEFAULT ''",
+		"command TEXT NOT NULL DEFAULT '[]'",
+		"signal INTEGER NOT NULL DEFAULT 0",
+		"success_exit_codes TEXT NOT NULL DEFAULT '[]'",
+		"attempt INTEGER NOT NULL DEFAULT 1",
+		"dead INTEGER NOT NULL DEFAULT 0",
+		"input_hash TEXT NOT NULL DEFAULT ''",
+		"skipped INTEGER NOT NULL DEFAULT 0",
+		"exit_meaning TEXT NOT NULL DEFAULT ''",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = addMissingColumns(db, "queued_jobs", []string{
+		"retried INTEGER NOT NULL DEFAULT 0",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = addMissingColumns(db, "pending_notifications", []string{
+		"notify_to TEXT NOT NULL DEFAULT '[]'",
+		"notify_via TEXT NOT NULL DEFAULT '[]'",
+		"success_exit_codes TEXT NOT NULL DEFAULT '[]'",
+		"notify_lines INTEGER",
+		"notify_stdout INTEGER",
+		"attachments TEXT NOT NULL DEFAULT '[]'",
+		"channel TEXT NOT NULL DEFAULT ''",
+		"completed TEXT NOT NULL DEFAULT ''",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Older versions saved only part of the run, so a retry would describe it differently.
+	_, err = db.Exec(`DELETE FROM pending_notifications WHERE completed = ''`)
+	return err
+}
+
+// addMissingColumns adds columns to a table created by an older version.
+// Each column is a definition that starts with the column name.
+func addMissingColumns(db *sql.DB, table string, columns []string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]struct{})
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+
+		existing[name] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		name, _, _ := strings.Cut(column, " ")
+		if _, ok := existing[name]; ok {
+			continue
+		}
+
+		if _, err := db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column); err != nil {
+			return fmt.Errorf("failed to add column %q to table %q: %w", name, table, err)
+		}
+	}
+
+	return nil
+}
+
+// Save a completed job with its logs and return the ID of the run.
+func (c *AppDB) saveCompletedJob(jobName string, completed CompletedJob, logs []logFile) (int64, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	command, err := json.Marshal(completed.Command)
+	if err != nil {
+		return 0, err
+	}
+
+	successExitCodes, err := json.Marshal(completed.SuccessExitCodes)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO completed_jobs (
+			job_name,
+			error,
+			exit_status,
+			started,
+			finished,
+			hostname,
+			username,
+			command,
+			signal,
+			success_exit_codes,
+			attempt,
+			dead,
+			input_hash,
+			skipped,
+			exit_meaning
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		jobName,
+		completed.Error,
+		completed.ExitStatus,
+		completed.Started,
+		completed.Finished,
+		completed.Hostname,
+		completed.Username,
+		string(command),
+		completed.Signal,
+		string(successExitCodes),
+		max(completed.Attempt, 1),
+		completed.Dead,
+		completed.InputHash,
+		completed.Skipped,
+		completed.ExitMeaning,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	jobID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, logFile := range logs {
+		if err := c.saveLogFile(tx, jobID, logFile.name, logFile.path); err != nil {
+			return 0, err
+		}
+	}
+
+	return jobID, tx.Commit()
+}
+
+func (c *AppDB) saveLogFile(tx *sql.Tx, jobID int64, logName, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, maxLogBufferSize)
+	n, err := f.Read(buf)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	buf = buf[:n]
+
+	lineNum := 1
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	// Allow a single line to be as long as the entire log buffer; otherwise
+	// the default 64 KiB cap would fail the whole transaction on long lines.
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogBufferSize)
+	for scanner.Scan() {
+		_, err = tx.Exec(`
+			INSERT INTO job_logs (
+				completed_job_id,
+				log_name,
+				line_number,
+				line
+			) VALUES (?, ?, ?, ?)`,
+			jobID,
+			logName,
+			lineNum,
+			scanner.Text(),
+		)
+		if err != nil {
+			return err
+		}
+		lineNum++
+	}
+	return scanner.Err()
+}
+
+// The columns of completed_jobs in the order scanCompletedJob reads them.
+const completedJobColumns = `id, error, exit_status, started, finished, hostname, username, command, signal, success_exit_codes, attempt, dead, input_hash, skipped, exit_meaning`
+
+// scanCompletedJob reads a row of completedJobColumns.
+func scanCompletedJob(row interface{ Scan(dest ...any) error }) (CompletedJob, error) {
+	var completed CompletedJob
+	var command, successExitCodes string
+	err := row.Scan(
+		&completed.ID,
+		&completed.Error,
+		&completed.ExitStatus,
+		&completed.Started,
+		&completed.Finished,
+		&completed.Hostname,
+		&completed.Username,
+		&command,
+		&completed.Signal,
+		&successExitCodes,
+		&completed.Attempt,
+		&completed.Dead,
+		&completed.InputHash,
+		&completed.Skipped,
+		&completed.ExitMeaning,
+	)
+	if err != nil {
+		return completed, err
+	}
+
+	if err := json.Unmarshal([]byte(command), &completed.Command); err != nil {
+		return completed, fmt.Errorf("failed to decode command: %w", err)
+	}
+	if err := json.Unmarshal([]byte(successExitCodes), &completed.SuccessExitCodes); err != nil {
+		return completed, fmt.Errorf("failed to decode success exit codes: %w", err)
+	}
+
+	return completed, nil
+}
+
+// scanFunc adapts a function to the interface of scanCompletedJob.
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
+
+// JobRun is a run in the history of any job.
+type JobRun struct {
+	JobName string
+	CompletedJob
+}
+
+// Runs returns up to limit recent runs, newest first.
+// An empty job name means all jobs.
+// With dead set, only the runs that failed on their last attempt are returned.
+func (c *AppDB) Runs(jobName string, dead bool, limit int) ([]JobRun, error) {
+	rows, err := c.db.Query(`
+		SELECT job_name, `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE (? = '' OR job_name = ?) AND (NOT ? OR dead)
+		ORDER BY id DESC LIMIT ?`,
+		jobName,
+		jobName,
+		dead,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []JobRun{}
+	for rows.Next() {
+		var run JobRun
+		run.CompletedJob, err = scanCompletedJob(scanFunc(func(dest ...any) error {
+			return rows.Scan(append([]any{&run.JobName}, dest...)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+func (c *AppDB) LastCompleted(jobName string) (*CompletedJob, error) {
+	completed, err := scanCompletedJob(c.db.QueryRow(`
+		SELECT `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE job_name = ?
+		ORDER BY id DESC LIMIT 1`,
+		jobName,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &completed, nil
+}
+
+// LastRan returns the last run of a job that wasn't skipped or nil if there is none.
+// Skipped runs have no logs, so this is the run the logs are from.
+func (c *AppDB) LastRan(jobName string) (*CompletedJob, error) {
+	completed, err := scanCompletedJob(c.db.QueryRow(`
+		SELECT `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE job_name = ? AND skipped = 0
+		ORDER BY id DESC LIMIT 1`,
+		jobName,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &completed, nil
+}
+
+// History returns up to limit recent runs of a job, newest first.
+func (c *AppDB) History(jobName string, limit int) ([]CompletedJob, error) {
+	rows, err := c.db.Query(`
+		SELECT `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE job_name = ?
+		ORDER BY id DESC LIMIT ?`,
+		jobName,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []CompletedJob{}
+	for rows.Next() {
+		completed, err := scanCompletedJob(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, completed)
+	}
+
+	return history, rows.Err()
+}
+
+// JobLogs returns the last lines of a log of the last run of a job that wasn't skipped.
+func (c *AppDB) JobLogs(jobName string, logName string, limit int) ([]string, error) {
+	rows, err := c.db.Query(`
+		SELECT line
+		FROM (
+			SELECT l.line, l.line_number
+			FROM job_logs l
+			JOIN completed_jobs j ON j.id = l.completed_job_id
+			WHERE l.log_name = ?
+			AND j.id = (
+				SELECT id
+				FROM completed_jobs
+				WHERE job_name = ? AND skipped = 0
+				ORDER BY id DESC
+				LIMIT 1
+			)
+			ORDER BY l.line_number DESC
+			LIMIT ?
+		)
+		ORDER BY line_number ASC`,
+		logName,
+		jobName,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// Run returns the run of a job with the given ID or, if runID is 0, the latest run that wasn't skipped.
+// It returns nil if there is no such run.
+func (c *AppDB) Run(jobName string, runID int64) (*CompletedJob, error) {
+	if runID == 0 {
+		return c.LastRan(jobName)
+	}
+
+	completed, err := scanCompletedJob(c.db.QueryRow(`
+		SELECT `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE job_name = ? AND id = ?`,
+		jobName,
+		runID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &completed, nil
+}
+
+// runLogTail returns the last lines of a log of a run or, if runID is 0, of the latest run.
+func (c *AppDB) runLogTail(jobName string, runID int64, logName string, limit int) ([]string, error) {
+	if runID == 0 {
+		return c.JobLogs(jobName, logName, limit)
+	}
+
+	rows, err := c.db.Query(`
+		SELECT line
+		FROM (
+			SELECT line, line_number
+			FROM job_logs
+			WHERE completed_job_id = ? AND log_name = ?
+			ORDER BY line_number DESC
+			LIMIT ?
+		)
+		ORDER BY line_number ASC`,
+		runID,
+		logName,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// RunLog returns every stored line of a log of a run.
+func (c *AppDB) RunLog(runID int64, logName string) ([]string, error) {
+	rows, err := c.db.Query(`
+		SELECT line
+		FROM job_logs
+		WHERE completed_job_id = ? AND log_name = ?
+		ORDER BY line_number ASC`,
+		runID,
+		logName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJobRunnerDB(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "jobrunnerdb-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Open a test database.
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Test saving and retrieving a completed job.
+	jobName := "test-job"
+	now := time.Now()
+	completed := CompletedJob{
+		Error:      "test error",
+		ExitStatus: 1,
+		Started:    now.Add(-time.Minute),
+		Finished:   now,
+		Hostname:   "host",
+		Username:   "user",
+		Command:    []string{"echo", "hello world"},
+	}
+
+	// Create test log files.
+	logDir := filepath.Join(tmpDir, "logs")
+	if err := os.MkdirAll(logDir, dirPerms); err != nil {
+		t.Fatalf("Failed to create log directory: %v", err)
+	}
+
+	stdoutPath := filepath.Join(logDir, "stdout.log")
+	stderrPath := filepath.Join(logDir, "stderr.log")
+
+	if err := os.WriteFile(stdoutPath, []byte("test stdout\nline 2\n"), filePerms); err != nil {
+		t.Fatalf("Failed to write stdout file: %v", err)
+	}
+	if err := os.WriteFile(stderrPath, []byte("test stderr\n"), filePerms); err != nil {
+		t.Fatalf("Failed to write stderr file: %v", err)
+	}
+
+	logs := []logFile{
+		{name: "stdout", path: stdoutPath},
+		{name: "stderr", path: stderrPath},
+	}
+
+	// Test saveCompletedJob.
+	if _, err := db.saveCompletedJob(jobName, completed, logs); err != nil {
+		t.Errorf("Failed to save completed job: %v", err)
+	}
+
+	// Test getLastCompleted.
+	lastCompleted, err := db.LastCompleted(jobName)
+	if err != nil {
+		t.Errorf("Failed to get last completed job: %v", err)
+	}
+
+	if lastCompleted == nil {
+		t.Fatal("Expected last completed job, got nil")
+	}
+
+	if lastCompleted.Error != completed.Error {
+		t.Errorf("Expected error %q, got %q", completed.Error, lastCompleted.Error)
+	}
+
+	if lastCompleted.ExitStatus != completed.ExitStatus {
+		t.Errorf("Expected exit status %d, got %d", completed.ExitStatus, lastCompleted.ExitStatus)
+	}
+
+	if lastCompleted.Hostname != "host" || lastCompleted.Username != "user" {
+		t.Errorf("Expected origin user@host, got %s@%s", lastCompleted.Username, lastCompleted.Hostname)
+	}
+
+	if diff := cmp.Diff(completed.Command, lastCompleted.Command); diff != "" {
+		t.Errorf("Command mismatch (-want +got):\n%s", diff)
+	}
+
+	// Test getJobLogs.
+	stdoutLogs, err := db.JobLogs(jobName, "stdout", 10)
+	if err != nil {
+		t.Errorf("Failed to get stdout logs: %v", err)
+	}
+	if len(stdoutLogs) != 2 {
+		t.Errorf("Expected 2 stdout lines, got %d", len(stdoutLogs))
+	}
+	if stdoutLogs[0] != "test stdout" {
+		t.Errorf("Expected first line %q, got %q", "test stdout", stdoutLogs[0])
+	}
+
+	stderrLogs, err := db.JobLogs(jobName, "stderr", 10)
+	if err != nil {
+		t.Errorf("Failed to get stderr logs: %v", err)
+	}
+	if len(stderrLogs) != 1 {
+		t.Errorf("Expected 1 stderr line, got %d", len(stderrLogs))
+	}
+	if stderrLogs[0] != "test stderr" {
+		t.Errorf("Expected first line %q, got %q", "test stderr", stderrLogs[0])
+	}
+
+	if lastCompleted.ID == 0 {
+		t.Error("Expected last completed job to have an ID")
+	}
+
+	// Test Run and RunLog.
+	run, err := db.Run(jobName, lastCompleted.ID)
+	if err != nil {
+		t.Errorf("Failed to get run: %v", err)
+	}
+	if run == nil || run.ID != lastCompleted.ID {
+		t.Errorf("Expected run %d, got %+v", lastCompleted.ID, run)
+	}
+
+	missingRun, err := db.Run("other-job", lastCompleted.ID)
+	if err != nil {
+		t.Errorf("Failed to query run of other job: %v", err)
+	}
+	if missingRun != nil {
+		t.Error("Expected nil for run of other job")
+	}
+
+	runLog, err := db.RunLog(lastCompleted.ID, "stdout")
+	if err != nil {
+		t.Errorf("Failed to get run log: %v", err)
+	}
+	if len(runLog) != 2 || runLog[1] != "line 2" {
+		t.Errorf("Unexpected run log: %v", runLog)
+	}
+
+	// A skipped run has no logs, so the logs and the latest run are from the run before it.
+	if _, err := db.saveCompletedJob(jobName, CompletedJob{Started: now, Finished: now, Skipped: true}, nil); err != nil {
+		t.Fatalf("Failed to save skipped job: %v", err)
+	}
+
+	stdoutLogs, err = db.JobLogs(jobName, "stdout", 10)
+	if err != nil {
+		t.Errorf("Failed to get stdout logs: %v", err)
+	}
+	if len(stdoutLogs) != 2 {
+		t.Errorf("Expected 2 stdout lines after a skipped run, got %d", len(stdoutLogs))
+	}
+
+	run, err = db.Run(jobName, 0)
+	if err != nil {
+		t.Errorf("Failed to get run: %v", err)
+	}
+	if run == nil || run.ID != lastCompleted.ID {
+		t.Errorf("Expected run %d, got %+v", lastCompleted.ID, run)
+	}
+
+	// Test with a nonexistent job.
+	nonexistentJob, err := db.LastCompleted("nonexistent")
+	if err != nil {
+		t.Errorf("Failed to query nonexistent job: %v", err)
+	}
+
+	if nonexistentJob != nil {
+		t.Error("Expected nil for nonexistent job")
+	}
+}
+
+func TestOpenAppDBAddsColumns(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Create the table the way older versions did.
+	db, err := sql.Open("sqlite", filepath.Join(tmpDir, appDBFileName))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE completed_jobs (
+			id INTEGER PRIMARY KEY,
+			job_name TEXT NOT NULL,
+			error TEXT,
+			exit_status INTEGER NOT NULL,
+			started DATETIME NOT NULL,
+			finished DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		INSERT INTO completed_jobs (job_name, error, exit_status, started, finished)
+		VALUES ('old-job', '', 0, '2025-01-01 00:00:00', '2025-01-01 00:01:00');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+
+	appDB, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open old database: %v", err)
+	}
+	defer appDB.Close()
+
+	completed, err := appDB.LastCompleted("old-job")
+	if err != nil {
+		t.Fatalf("Failed to get last completed job: %v", err)
+	}
+
+	if completed == nil || completed.Hostname != "" || len(completed.Command) != 0 {
+		t.Errorf("Unexpected old run: %+v", completed)
+	}
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ArtifactsDir returns the directory with the artifacts of a run.
+func ArtifactsDir(stateRoot, jobName string, runID int64) string {
+	return filepath.Join(stateRoot, jobName, artifactsDirName, strconv.FormatInt(runID, 10))
+}
+
+// RunArtifacts lists the artifacts of a run relative to its artifacts directory in sorted order.
+// A run without artifacts has none.
+func RunArtifacts(stateRoot, jobName string, runID int64) ([]string, error) {
+	dir := ArtifactsDir(stateRoot, jobName, runID)
+	artifacts := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		artifacts = append(artifacts, rel)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	slices.Sort(artifacts)
+
+	return artifacts, nil
+}
+
+// collectArtifacts copies the files that match "artifacts" into the artifacts directory of a run.
+// A relative pattern is matched in the job directory,
+// and the files in it keep their path relative to it.
+// Other files are copied by name.
+// It returns the paths of the copies.
+func collectArtifacts(job JobConfig, stateRoot string, runID int64) ([]string, error) {
+	jobDir := job.Env[jobDirEnvVar]
+	dir := ArtifactsDir(stateRoot, job.Name, runID)
+
+	copied := []string{}
+	for _, pattern := range job.Artifacts {
+		pattern = expandHome(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(jobDir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return copied, fmt.Errorf("invalid %q pattern: %w", artifactsVar, err)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			name := filepath.Base(match)
+			if rel, err := filepath.Rel(jobDir, match); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				name = rel
+			}
+
+			dest := filepath.Join(dir, name)
+			if slices.Contains(copied, dest) {
+				continue
+			}
+
+			if err := copyFile(match, dest); err != nil {
+				return copied, err
+			}
+
+			copied = append(copied, dest)
+		}
+	}
+
+	return copied, nil
+}
+
+// copyFile copies a file and creates the directories for the copy.
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), dirPerms); err != nil {
+		return fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerms)
+	if err != nil {
+		return fmt.Errorf("failed to create artifact copy: %w", err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy artifact: %w", err)
+	}
+
+	return out.Close()
+}
+
+// pruneArtifacts removes the artifacts of all but the latest keep runs of a job.
+// Zero keeps the artifacts of every run.
+// It returns the removed directories.
+func pruneArtifacts(stateRoot, jobName string, keep int) ([]string, error) {
+	if keep == 0 {
+		return nil, nil
+	}
+
+	root := filepath.Join(stateRoot, jobName, artifactsDirName)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read artifacts directory: %w", err)
+	}
+
+	runIDs := []int64{}
+	for _, entry := range entries {
+		id, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		runIDs = append(runIDs, id)
+	}
+	slices.Sort(runIDs)
+
+	removed := []string{}
+	for _, id := range runIDs[:max(0, len(runIDs)-keep)] {
+		dir := ArtifactsDir(stateRoot, jobName, id)
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("failed to remove old artifacts: %w", err)
+		}
+
+		removed = append(removed, dir)
+	}
+
+	return removed, nil
+}
+
+// saveArtifacts collects the artifacts of a run and prunes the old ones.
+// Errors are logged because the run has finished.
+func (r Runner) saveArtifacts(job JobConfig, runID int64) {
+	copied, err := collectArtifacts(job, r.stateRoot, runID)
+	if err != nil {
+		LogJobPrintf(job.Name, "Failed to collect artifacts: %v", err)
+	}
+	if len(copied) > 0 {
+		LogJobPrintf(job.Name, "Collected %d artifacts in %v", len(copied), ArtifactsDir(r.stateRoot, job.Name, runID))
+	}
+
+	removed, err := pruneArtifacts(r.stateRoot, job.Name, job.ArtifactsKeep)
+	if err != nil {
+		LogJobPrintf(job.Name, "Failed to remove old artifacts: %v", err)
+	}
+	for _, dir := range removed {
+		LogJobPrintf(job.Name, "Removed old artifacts %v", dir)
+	}
+}
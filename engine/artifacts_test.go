@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestJobRunnerArtifacts(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	stateRoot := t.TempDir()
+	jobDir := t.TempDir()
+
+	db, err := OpenAppDB(stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	env := denv.OS()
+	env[jobDirEnvVar] = jobDir
+
+	runIDs := []int64{}
+	for i := range 3 {
+		runner.AddJob(JobConfig{
+			Name:          "build",
+			Command:       []string{"sh", "-c", "mkdir -p out && echo " + string(rune('a'+i)) + " > out/build.tar.gz && touch out/build.log"},
+			Env:           env,
+			Artifacts:     []string{"out/*.tar.gz", "missing/*"},
+			ArtifactsKeep: 2,
+		})
+		if err := runner.RunQueueHead("build"); err != nil {
+			t.Fatalf("RunQueueHead() error = %v", err)
+		}
+
+		completed, err := db.LastCompleted("build")
+		if err != nil || completed == nil {
+			t.Fatalf("Failed to get last run: %v", err)
+		}
+		runIDs = append(runIDs, completed.ID)
+	}
+
+	artifacts, err := RunArtifacts(stateRoot, "build", runIDs[2])
+	if err != nil {
+		t.Fatalf("RunArtifacts() error = %v", err)
+	}
+	if want := []string{filepath.Join("out", "build.tar.gz")}; !slices.Equal(artifacts, want) {
+		t.Errorf("Expected artifacts %v, got %v", want, artifacts)
+	}
+
+	data, err := os.ReadFile(filepath.Join(ArtifactsDir(stateRoot, "build", runIDs[2]), "out", "build.tar.gz"))
+	if err != nil || strings.TrimSpace(string(data)) != "c" {
+		t.Errorf("Expected the artifact of the last run, got %q (%v)", data, err)
+	}
+
+	// Only the last two runs keep their artifacts.
+	if artifacts, _ := RunArtifacts(stateRoot, "build", runIDs[0]); len(artifacts) != 0 {
+		t.Errorf("Expected the artifacts of the first run to be removed, got %v", artifacts)
+	}
+	if artifacts, _ := RunArtifacts(stateRoot, "build", runIDs[1]); len(artifacts) != 1 {
+		t.Errorf("Expected the artifacts of the second run to be kept, got %v", artifacts)
+	}
+}
+
+func TestArtifactsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "bad pattern",
+			src:     `artifacts = ["out/[.tar.gz"]`,
+			wantErr: `invalid "artifacts" pattern`,
+		},
+		{
+			name:    "negative keep",
+			src:     `artifacts_keep = -1`,
+			wantErr: `"artifacts_keep" must not be negative`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configRoot := t.TempDir()
+			path := writeTestJob(t, configRoot, "job", tt.src)
+
+			_, _, err := NewScheduler().Update(configRoot, path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
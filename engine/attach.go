@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// The length of the lines of base64 in an email.
+	base64LineLength = 76
+
+	// The largest file attached to an email.
+	// Attachments are read into memory, and mail servers reject big messages.
+	maxAttachmentSize = 10 * 1024 * 1024
+)
+
+// resolveAttachments finds the files in "attach" after a run.
+// A relative path is looked up in the job directory first and then in the job's state directory.
+// Missing files are logged and left out.
+func resolveAttachments(job JobConfig, jobStateDir string) []string {
+	attachments := []string{}
+
+	for _, path := range job.Attach {
+		path = expandHome(path)
+
+		candidates := []string{path}
+		if !filepath.IsAbs(path) {
+			candidates = []string{
+				filepath.Join(job.Env[jobDirEnvVar], path),
+				filepath.Join(jobStateDir, path),
+			}
+		}
+
+		found := false
+		for _, candidate := range candidates {
+			info, err := os.Stat(candidate)
+			if err == nil && info.Mode().IsRegular() {
+				attachments = append(attachments, candidate)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			LogJobPrintf(job.Name, "Not attaching %q to notifications because the file doesn't exist", path)
+		}
+	}
+
+	return attachments
+}
+
+// limitAttachments leaves out and logs the files too big to attach to an email.
+func limitAttachments(attachments []string) []string {
+	limited := []string{}
+
+	for _, path := range attachments {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("Not attaching %q to email: %v", path, err)
+			continue
+		}
+
+		if info.Size() > maxAttachmentSize {
+			log.Printf("Not attaching %q to email because it is %v bytes, more than the limit of %v", path, info.Size(), maxAttachmentSize)
+			continue
+		}
+
+		limited = append(limited, path)
+	}
+
+	return limited
+}
+
+// writeMultipart writes the body of an email with text and attached files.
+func writeMultipart(msg *bytes.Buffer, text string, attachments []string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	fmt.Fprintf(msg, "Content-Type: multipart/mixed; boundary=%s\n", writer.Boundary())
+	msg.WriteString("\n")
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write([]byte(text)); err != nil {
+		return err
+	}
+
+	for _, path := range attachments {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment: %w", err)
+		}
+
+		name := filepath.Base(path)
+
+		contentType := mime.TypeByExtension(filepath.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return err
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 0 {
+			n := min(len(encoded), base64LineLength)
+			if _, err := part.Write([]byte(encoded[:n] + "\r\n")); err != nil {
+				return err
+			}
+
+			encoded = encoded[n:]
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	msg.Write(body.Bytes())
+
+	return nil
+}
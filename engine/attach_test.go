@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestResolveAttachments(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	jobDir := t.TempDir()
+	stateDir := t.TempDir()
+	otherDir := t.TempDir()
+
+	for _, path := range []string{
+		filepath.Join(jobDir, "report.html"),
+		filepath.Join(jobDir, "both.txt"),
+		filepath.Join(stateDir, "both.txt"),
+		filepath.Join(stateDir, "out", "summary.csv"),
+		filepath.Join(otherDir, "abs.log"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := JobConfig{
+		Name:   "report",
+		Attach: []string{"report.html", "both.txt", "out/summary.csv", filepath.Join(otherDir, "abs.log"), "missing.txt", "out"},
+		Env:    denv.Env{jobDirEnvVar: jobDir},
+	}
+
+	got := resolveAttachments(job, stateDir)
+	want := []string{
+		filepath.Join(jobDir, "report.html"),
+		filepath.Join(jobDir, "both.txt"),
+		filepath.Join(stateDir, "out", "summary.csv"),
+		filepath.Join(otherDir, "abs.log"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected attachments %v, got %v", want, got)
+	}
+}
+
+func TestSendSendmailAttachments(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "message")
+
+	script := filepath.Join(dir, "sendmail")
+	content := "#! /bin/sh\ncat > " + output + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	report := filepath.Join(dir, "report.html")
+	reportData := strings.Repeat("<p>All good</p>\n", 20)
+	if err := os.WriteFile(report, []byte(reportData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sendSendmail(script, []string{"alice"}, `Job "report" succeeded`, "Started: now\n", []string{report}); err != nil {
+		t.Fatalf("sendSendmail() error = %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Expected a multipart message, got %q", msg.Header.Get("Content-Type"))
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	text, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	textData, _ := io.ReadAll(text)
+	if string(textData) != "Started: now\n" {
+		t.Errorf("Expected the text first, got %q", textData)
+	}
+
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName() != "report.html" || !strings.HasPrefix(attachment.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Unexpected attachment headers %v", attachment.Header)
+	}
+
+	encoded, _ := io.ReadAll(attachment)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || string(decoded) != reportData {
+		t.Errorf("Expected the attached file, got %q (%v)", decoded, err)
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("Expected two parts, got %v", err)
+	}
+}
+
+func TestLimitAttachments(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.txt")
+	big := filepath.Join(dir, "big.bin")
+
+	if err := os.WriteFile(small, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(big, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(big, maxAttachmentSize+1); err != nil {
+		t.Fatal(err)
+	}
+
+	got := limitAttachments([]string{small, big, filepath.Join(dir, "gone.txt")})
+	if want := []string{small}; !slices.Equal(got, want) {
+		t.Errorf("Expected attachments %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"time"
+)
+
+// Actions in the audit log.
+const (
+	AuditAdd    = "add"
+	AuditUpdate = "update"
+	AuditRemove = "remove"
+	AuditReload = "reload"
+)
+
+// AuditEntry records a configuration change detected by the scheduler.
+// Hash is the SHA-256 of the changed file or empty if the file is gone.
+type AuditEntry struct {
+	Time    time.Time
+	Action  string
+	JobName string
+	Path    string
+	Hash    string
+}
+
+// SetAuditLog makes the scheduler record the configuration changes it detects in the database.
+func (jsc *Scheduler) SetAuditLog(db *AppDB) {
+	jsc.mu.Lock()
+	defer jsc.mu.Unlock()
+
+	jsc.auditDB = db
+}
+
+func (jsc *Scheduler) audit(action, jobName, path string) {
+	jsc.mu.RLock()
+	db := jsc.auditDB
+	jsc.mu.RUnlock()
+
+	if db == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:    time.Now(),
+		Action:  action,
+		JobName: jobName,
+		Path:    path,
+		Hash:    hashFile(path),
+	}
+
+	if err := db.saveAuditEntry(entry); err != nil {
+		log.Printf("Failed to save audit log entry: %v", err)
+	}
+}
+
+// hashFile returns the hex SHA-256 of a file's contents or an empty string if the file can't be read.
+func hashFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *AppDB) saveAuditEntry(entry AuditEntry) error {
+	_, err := c.db.Exec(`
+		INSERT INTO audit_log (
+			time,
+			action,
+			job_name,
+			path,
+			hash
+		) VALUES (?, ?, ?, ?, ?)`,
+		entry.Time,
+		entry.Action,
+		entry.JobName,
+		entry.Path,
+		entry.Hash,
+	)
+
+	return err
+}
+
+// AuditLog returns the latest audit log entries in chronological order.
+// An empty jobName selects the entries for all jobs.
+func (c *AppDB) AuditLog(jobName string, limit int) ([]AuditEntry, error) {
+	rows, err := c.db.Query(`
+		SELECT time, action, job_name, path, hash
+		FROM (
+			SELECT id, time, action, job_name, path, hash
+			FROM audit_log
+			WHERE ? = '' OR job_name = ?
+			ORDER BY id DESC
+			LIMIT ?
+		)
+		ORDER BY id ASC`,
+		jobName,
+		jobName,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.Time, &entry.Action, &entry.JobName, &entry.Path, &entry.Hash); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	stateRoot := t.TempDir()
+	db, err := OpenAppDB(stateRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	configRoot := t.TempDir()
+	jobPath := writeTestJob(t, configRoot, "backup", "")
+	otherPath := writeTestJob(t, configRoot, "other", "")
+
+	jsc := NewScheduler()
+	jsc.audit(AuditAdd, "backup", jobPath) // Ignored without a database.
+	jsc.SetAuditLog(db)
+
+	jsc.audit(AuditAdd, "backup", jobPath)
+	jsc.audit(AuditAdd, "other", otherPath)
+
+	if err := os.Remove(jobPath); err != nil {
+		t.Fatal(err)
+	}
+	jsc.audit(AuditRemove, "backup", jobPath)
+
+	entries, err := db.AuditLog("", 10)
+	if err != nil {
+		t.Fatalf("AuditLog() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	entries, err = db.AuditLog("backup", 10)
+	if err != nil {
+		t.Fatalf("AuditLog() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries for job, want 2", len(entries))
+	}
+
+	if entries[0].Action != AuditAdd || len(entries[0].Hash) != 64 {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Action != AuditRemove || entries[1].Hash != "" {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+	if entries[1].Path != filepath.Join(configRoot, "backup", jobConfigFileName) {
+		t.Errorf("Path = %q", entries[1].Path)
+	}
+
+	entries, err = db.AuditLog("", 1)
+	if err != nil {
+		t.Fatalf("AuditLog() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != AuditRemove {
+		t.Errorf("expected only the latest entry, got %+v", entries)
+	}
+}
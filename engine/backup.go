@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteBackuper is implemented by the connections of the SQLite driver.
+type sqliteBackuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Backup copies the database to a new file while it remains in use.
+func (c *AppDB) Backup(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file already exists: %v", path)
+	}
+
+	err := c.withBackuper(func(conn sqliteBackuper) error {
+		backup, err := conn.NewBackup(path)
+		if err != nil {
+			return err
+		}
+
+		return runBackup(backup)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	return os.Chmod(path, filePerms)
+}
+
+// Restore replaces the contents of the database with a backup.
+func (c *AppDB) Restore(path string) error {
+	// Opening a nonexistent file would create an empty database and restore that.
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+
+	err := c.withBackuper(func(conn sqliteBackuper) error {
+		backup, err := conn.NewRestore(path)
+		if err != nil {
+			return err
+		}
+
+		return runBackup(backup)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	// A backup from an older version may lack some tables.
+	return createSchema(c.db)
+}
+
+func (c *AppDB) withBackuper(f func(conn sqliteBackuper) error) error {
+	conn, err := c.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		backuper, ok := driverConn.(sqliteBackuper)
+		if !ok {
+			return fmt.Errorf("database driver doesn't support backups")
+		}
+
+		return f(backuper)
+	})
+}
+
+func runBackup(backup *sqlite.Backup) error {
+	for {
+		more, err := backup.Step(-1)
+		if err != nil {
+			_ = backup.Finish()
+			return err
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return backup.Finish()
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupRestore(t *testing.T) {
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	completed := CompletedJob{ExitStatus: 7, Started: started, Finished: started.Add(time.Second)}
+	if _, err := db.saveCompletedJob("backup-test", completed, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "backup.sqlite3")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	if err := db.Backup(backupPath); err == nil {
+		t.Error("expected error backing up to an existing file")
+	}
+
+	other, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	if err := other.Restore(backupPath); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	restored, err := other.LastCompleted("backup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored == nil || restored.ExitStatus != 7 {
+		t.Errorf("restored = %+v, want exit status 7", restored)
+	}
+
+	if err := other.Restore(filepath.Join(t.TempDir(), "missing.sqlite3")); err == nil {
+		t.Error("expected error restoring a missing file")
+	}
+}
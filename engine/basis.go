@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	appDBFileName        = "state.sqlite3"
+	appLockFileName      = "app.lock"
+	appSocketFileName    = "socket"
+	crontabFileName      = "crontab"
+	daemonInfoFileName   = "daemon.json"
+	encryptedEnvFileName = "env.age"
+	settingsFileName     = "settings.star"
+	dirName              = "regular"
+
+	socketEnv             = "REGULAR_SOCK"
+	artifactsDirName      = "artifacts"
+	globalEnvFileName     = "global.env"
+	jobConfigFileName     = "config.star"
+	jobEnvFileName        = "job.env"
+	jobExecutableFileName = "./run"
+	jobLockFileName       = "job.lock"
+	notifiersDirName      = "notifiers"
+	resultFileName        = "last-result.json"
+	runNowFileName        = "run-now"
+	stderrFileName        = "stderr.log"
+	stdoutFileName        = "stdout.log"
+	templateFileName      = "template.star"
+
+	// Separates the name of a job directory from the name a "job" call gives a job in it.
+	generatedJobSep = ":"
+
+	fileEnvVarPrefix = "REGULAR_FILE_"
+	filesDirEnvVar   = "REGULAR_FILES_DIR"
+	jobDirEnvVar     = "REGULAR_JOB_DIR"
+	queueEnvVar      = "REGULAR_QUEUE"
+	queueWaitEnvVar  = "REGULAR_QUEUE_WAIT_SECONDS"
+
+	defaultMQTTTopic = "regular/{job}"
+
+	redactedValue = "[redacted]"
+
+	// How many runs of a job keep their artifacts by default.
+	defaultArtifactsKeep = 10
+
+	defaultSendmailPath    = "/usr/sbin/sendmail"
+	emailTransportSendmail = "sendmail"
+	emailTransportSMTP     = "smtp"
+
+	containerExecutorName = "container"
+	localExecutorName     = "local"
+	shellExecutorName     = "shell"
+	sshExecutorName       = "ssh"
+	systemdExecutorName   = "systemd-scope"
+
+	logToFile   = "file"
+	logToSyslog = "syslog"
+
+	notifyViaEmail   = "email"
+	notifyViaMatrix  = "matrix"
+	notifyViaNtfy    = "ntfy"
+	notifyViaPlugins = "plugins"
+	notifyViaXMPP    = "xmpp"
+
+	artifactsVar     = "artifacts"
+	artifactsKeepVar = "artifacts_keep"
+	dailyAtVar       = "daily_at"
+	deadlineVar      = "deadline"
+	enableVar        = "enable"
+	envDefaultsVar   = "env_defaults"
+	envVar           = "env"
+	escalateVar      = "escalate"
+	everyVar         = "every"
+	exitMeaningsVar  = "exit_meanings"
+	filesVar         = "files"
+	globVar          = "glob"
+	hostsVar         = "hosts"
+	inputsVar        = "inputs"
+	instanceVar      = "instance"
+	jobVar           = "job"
+	jsonVar          = "json"
+	logVar           = "log"
+	logPathVar       = "log_path"
+	logToVar         = "log_to"
+	maxOutputVar     = "max_output"
+	notifyLinesVar   = "notify_lines"
+	notifyModeVar    = "notify"
+	notifyViaVar     = "notify_via"
+	oneDayVar        = "one_day"
+	oneHourVar       = "one_hour"
+	oneMinuteVar     = "one_minute"
+	queueOverflowVar = "queue_overflow"
+	readFileVar      = "read_file"
+	retriesVar       = "retries"
+	retryDelayVar    = "retry_delay"
+	scheduleVar      = "schedule"
+	shouldRunVar     = "should_run"
+	stateQuotaVar    = "state_quota"
+	successCodesVar  = "success_exit_codes"
+	whichVar         = "which"
+
+	exitOK       = 0
+	exitError    = 1
+	exitBadUsage = 2
+
+	dirPerms  = 0700
+	filePerms = 0600
+
+	debounceInterval      = 100 * time.Millisecond
+	defaultGCGrace        = 30 * 24 * time.Hour
+	dstCheckWindow        = 3 * time.Hour
+	gcInterval            = 24 * time.Hour
+	maxMissedTime         = time.Hour
+	notifierPluginTimeout = time.Minute
+	notifierTimeout       = 30 * time.Second
+	notifyRetryBaseDelay  = time.Minute
+	notifyRetryInterval   = 30 * time.Second
+	notifyRetryMaxDelay   = time.Hour
+	runInterval           = time.Second
+	scheduleInterval      = time.Minute
+	schedulerLagWarning   = 30 * time.Second
+	socketDialTimeout     = time.Second
+
+	defaultLogLines   = 10
+	maxJobEvents      = 100
+	maxNotifyAttempts = 10
+	truncationMarker  = "\n[... %d bytes truncated ...]\n"
+	maxLogBufferSize  = 256 * 1024
+
+	// Integers that "should_run" returns from this value on are Unix timestamps; smaller ones are delays in seconds.
+	// It is about 31 years in seconds and a time in 2001.
+	minShouldRunTimestamp = 1_000_000_000
+
+	// Limits on Starlark evaluation, so a runaway job file can't hang the scheduler.
+	starlarkMaxSteps = 10_000_000
+	starlarkTimeout  = 5 * time.Second
+)
+
+// Config holds the directories an instance of Regular works with.
+type Config struct {
+	// The settings and new jobs are in ConfigRoot.
+	ConfigRoot string
+	// More directories with jobs.
+	// A job in a later directory overrides a job with the same name in an earlier one.
+	ExtraConfigRoots []string
+	StateRoot        string
+}
+
+// ConfigRoots returns every config directory in order of precedence, lowest first.
+func (c Config) ConfigRoots() []string {
+	return append([]string{c.ConfigRoot}, c.ExtraConfigRoots...)
+}
+
+func jobDir(path string) string {
+	return filepath.Dir(path)
+}
+
+func jobNameFromPath(path string) string {
+	return filepath.Base(filepath.Dir(path))
+}
+
+// generatedJobName returns the full name of a job that a "job" call in a job file defines.
+func generatedJobName(dir, name string) string {
+	return dir + generatedJobSep + name
+}
+
+// jobDirName returns the name of the job directory that defines a job.
+// It is the name of the job itself unless the job comes from a "job" call.
+func jobDirName(name string) string {
+	dir, _, _ := strings.Cut(name, generatedJobSep)
+	return dir
+}
+
+// validateJobName checks that a name can be the name of a job directory.
+func validateJobName(name string) error {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return fmt.Errorf("invalid job name: %q", name)
+	}
+
+	return nil
+}
+
+// FormatDuration formats a Duration without the trailing zero units.
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+
+	if d > time.Second {
+		d = d.Round(100 * time.Millisecond)
+	}
+
+	zeroUnits := regexp.MustCompile("(^|[^0-9])(?:0h)?(?:0m)?(?:0s)?$")
+	s := zeroUnits.ReplaceAllString(d.String(), "$1")
+
+	if s == "" {
+		return "0"
+	}
+
+	return s
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const icsTimeFormat = "20060102T150405Z"
+
+// PredictedRun is a run the scheduler should start according to "should_run".
+type PredictedRun struct {
+	JobName string
+	Start   time.Time
+	// The start plus the mean run time of the recent runs.
+	End time.Time
+}
+
+// PredictRuns calls "should_run" for every minute from from until until and lists the runs it would start.
+// Every predicted run is added to the history the next calls get, so a job that looks at its last run is predicted correctly.
+// A predicted run takes the mean run time of the recent runs or a minute without history.
+// Unless the job allows duplicates, it isn't due again until the predicted run finishes.
+func (j JobConfig) PredictRuns(from, until time.Time, history []CompletedJob) ([]PredictedRun, error) {
+	runs := []PredictedRun{}
+	if !j.Enable || !j.RunsOnThisHost() || j.ShouldRun == nil {
+		return runs, nil
+	}
+
+	runtime := max(meanRuntime(history), time.Minute)
+	history = append([]CompletedJob{}, history...)
+
+	probe := from
+	for probe.Before(until) {
+		start, due, err := j.shouldRun(probe, history)
+		if err != nil {
+			return runs, err
+		}
+
+		next := probe
+		if due {
+			run := PredictedRun{JobName: j.Name, Start: start, End: start.Add(runtime)}
+			runs = append(runs, run)
+
+			// The history is newest first.
+			predicted := CompletedJob{Started: run.Start, Finished: run.End, Attempt: 1}
+			history = append([]CompletedJob{predicted}, history...)[:min(len(history)+1, ShouldRunHistoryLength)]
+
+			if !j.Duplicate {
+				next = maxTime(next, run.End.Add(-time.Nanosecond))
+			}
+		}
+
+		probe = next.Truncate(time.Minute).Add(time.Minute)
+	}
+
+	return runs, nil
+}
+
+// WriteICS writes the predicted runs as an iCalendar file with one event per run.
+// The events are stamped with now.
+func WriteICS(w io.Writer, runs []PredictedRun, now time.Time) error {
+	bw := bufio.NewWriter(w)
+
+	line := func(s string) {
+		bw.WriteString(foldICSLine(s))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//dbohdan.com//regular//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:regular")
+
+	for _, run := range runs {
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%s-%d@regular", escapeICSText(run.JobName), run.Start.Unix()))
+		line("DTSTAMP:" + now.UTC().Format(icsTimeFormat))
+		line("DTSTART:" + run.Start.UTC().Format(icsTimeFormat))
+		line("DTEND:" + run.End.UTC().Format(icsTimeFormat))
+		line("SUMMARY:" + escapeICSText(run.JobName))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// escapeICSText escapes a value of the TEXT type.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// foldICSLine splits a content line longer than 75 octets into continuation lines.
+// It doesn't split UTF-8 sequences.
+func foldICSLine(s string) string {
+	const limit = 75
+
+	var sb strings.Builder
+	length := 0
+
+	for _, r := range s {
+		size := len(string(r))
+		if length+size > limit {
+			sb.WriteString("\r\n ")
+			length = 1
+		}
+
+		sb.WriteRune(r)
+		length += size
+	}
+
+	return sb.String()
+}
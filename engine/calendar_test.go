@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestPredictRuns(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	history := []CompletedJob{{
+		Started:  start.Add(-10 * time.Hour),
+		Finished: start.Add(-10*time.Hour + 30*time.Minute),
+	}}
+
+	tests := []struct {
+		name    string
+		content string
+		history []CompletedJob
+		want    []time.Time
+	}{
+		{
+			name:    "at a minute",
+			content: "def should_run(hour, minute, **_):\n    return hour == 3 and minute == 0",
+			want:    []time.Time{start.Add(17 * time.Hour), start.Add(41 * time.Hour)},
+		},
+		{
+			// Without the predicted runs in the history, the job would be due every minute after the first.
+			name:    "since finished",
+			content: "def should_run(timestamp, finished, **_):\n    return timestamp - finished >= one_day",
+			history: history,
+			want:    []time.Time{start.Add(14*time.Hour + 30*time.Minute), start.Add(39 * time.Hour)},
+		},
+		{
+			// The job takes 30 minutes, so it isn't due while it runs.
+			name:    "while running",
+			content: "def should_run(hour, minute, **_):\n    return hour == 12 and minute % 20 == 0",
+			history: history,
+			want:    []time.Time{start.Add(2 * time.Hour), start.Add(2*time.Hour + 40*time.Minute), start.Add(26 * time.Hour), start.Add(26*time.Hour + 40*time.Minute)},
+		},
+		{
+			name:    "duplicate",
+			content: "duplicate = True\ndef should_run(hour, minute, timestamp, **_):\n    return hour == 12 and minute % 20 == 0 and timestamp < 1714651200",
+			history: history,
+			want:    []time.Time{start.Add(2 * time.Hour), start.Add(2*time.Hour + 20*time.Minute), start.Add(2*time.Hour + 40*time.Minute)},
+		},
+		{
+			name:    "disabled",
+			content: "enable = False\ndef should_run(**_):\n    return True",
+			want:    []time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobPath := filepath.Join(t.TempDir(), "config.star")
+			if err := os.WriteFile(jobPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := loadJob(denv.Env{}, jobPath)
+			if err != nil {
+				t.Fatalf("loadJob() error = %v", err)
+			}
+
+			runs, err := job.PredictRuns(start, start.Add(48*time.Hour), tt.history)
+			if err != nil {
+				t.Fatalf("PredictRuns() error = %v", err)
+			}
+
+			got := []time.Time{}
+			for _, run := range runs {
+				got = append(got, run.Start)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("PredictRuns() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("PredictRuns() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	name := "backup, photos; " + strings.Repeat("long-", 20)
+
+	var sb strings.Builder
+	err := WriteICS(&sb, []PredictedRun{{JobName: name, Start: start, End: start.Add(time.Hour)}}, start)
+	if err != nil {
+		t.Fatalf("WriteICS() error = %v", err)
+	}
+	ics := sb.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20240501T080000Z\r\n",
+		"DTEND:20240501T090000Z\r\n",
+		"SUMMARY:backup\\, photos\\; long-",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("Expected %q in %q", want, ics)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected lines to be folded at 75 octets, got %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+escapeICSText(name)+"\r\n") {
+		t.Errorf("Expected the full summary after unfolding, got %q", unfolded)
+	}
+}
@@ -0,0 +1,56 @@
+package engine
+
+import "time"
+
+// Clock tells the scheduler and the runner what time it is and lets them wait.
+// A clock other than the system one lets you try out schedules without changing the system time
+// and lets tests simulate days of scheduling.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	Sleep(d time.Duration)
+}
+
+// Ticker delivers ticks at intervals like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the system time.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+func (SystemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// OffsetClock is the system time shifted by an offset.
+// It waits in real time.
+type OffsetClock struct {
+	SystemClock
+	Offset time.Duration
+}
+
+func (c OffsetClock) Now() time.Time {
+	return time.Now().Add(c.Offset)
+}
@@ -0,0 +1,411 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+	"github.com/google/go-cmp/cmp"
+	"go.starlark.net/starlark"
+)
+
+// fakeClock is a clock for tests that only moves when told to.
+// Sleeping moves it forward at once.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	slept   []time.Duration
+}
+
+type fakeTicker struct {
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+	stopped  bool
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ticker := &fakeTicker{c: make(chan time.Time, 1), interval: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, ticker)
+
+	return ticker
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	c.slept = append(c.slept, d)
+	c.mu.Unlock()
+
+	c.Advance(d)
+}
+
+// Advance moves the clock forward and fires the tickers that are due.
+// Like time.Ticker, a ticker fires once however many intervals have passed.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	for _, ticker := range c.tickers {
+		if ticker.stopped || ticker.next.After(c.now) {
+			continue
+		}
+
+		for !ticker.next.After(c.now) {
+			ticker.next = ticker.next.Add(ticker.interval)
+		}
+
+		select {
+		case ticker.c <- c.now:
+		default:
+		}
+	}
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.stopped = true
+}
+
+// waitForTicks waits until the scheduler has handled n ticks.
+func waitForTicks(t *testing.T, jsc *Scheduler, n int64) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for jsc.Stats().Ticks < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for tick %d", n)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOffsetClock(t *testing.T) {
+	clock := OffsetClock{Offset: 5 * time.Hour}
+
+	diff := clock.Now().Sub(time.Now())
+	if diff < 5*time.Hour-time.Second || diff > 5*time.Hour {
+		t.Errorf("Expected the clock to be 5h ahead, got %v", diff)
+	}
+}
+
+func TestJobRunnerClock(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+	runner.Clock = OffsetClock{Offset: 5 * time.Hour}
+
+	// A job due now by the offset clock starts without waiting for the offset.
+	runner.AddJob(JobConfig{
+		Name:    "offset",
+		Command: []string{"true"},
+		Env:     denv.OS(),
+		startAt: runner.Clock.Now(),
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runner.RunQueueHead("offset")
+	}()
+
+	select {
+
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunQueueHead() error = %v", err)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the job to start without waiting")
+	}
+}
+
+func TestJobRunnerClockMinInterval(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+	runner.Clock = OffsetClock{Offset: -5 * time.Hour}
+	runner.MinInterval = time.Hour
+
+	alwaysDue := starlark.NewBuiltin("should_run", func(_ *starlark.Thread, _ *starlark.Builtin, _ starlark.Tuple, _ []starlark.Tuple) (starlark.Value, error) {
+		return starlark.True, nil
+	})
+	job := JobConfig{
+		Name:      "offset",
+		Command:   []string{"true"},
+		Enable:    true,
+		Env:       denv.OS(),
+		ShouldRun: alwaysDue,
+	}
+
+	now := runner.Clock.Now()
+	if err := job.AddToQueueIfDue(runner, now); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if err := runner.RunQueueHead("offset"); err != nil {
+		t.Fatalf("RunQueueHead() error = %v", err)
+	}
+
+	// The run is recorded in the time of the offset clock.
+	completed, err := runner.LastCompleted("offset")
+	if err != nil || completed == nil {
+		t.Fatalf("LastCompleted() = %v, %v", completed, err)
+	}
+	if diff := completed.Started.Sub(now); diff < 0 || diff > time.Minute {
+		t.Errorf("Expected the run to start at about %v, got %v", now, completed.Started)
+	}
+
+	// Two hours later by the offset clock, the minimum interval has passed.
+	if err := job.AddToQueueIfDue(runner, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if n := runner.queueLen("offset"); n != 1 {
+		t.Errorf("Expected the job to be queued after the minimum interval, queue length is %d", n)
+	}
+}
+
+func TestScheduleCatchUp(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	configRoot := t.TempDir()
+	stateRoot := t.TempDir()
+
+	writeTestJob(t, configRoot, "catch-up", `
+duplicate = True
+
+def should_run(minute, **_):
+    return minute % 10 in [3, 7]
+`)
+
+	db, err := OpenAppDB(stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	jsc := NewScheduler()
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	clock := newFakeClock(time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local))
+	jsc.SetClock(clock)
+	go WithLog(func() error {
+		return jsc.Schedule(runner)
+	})
+
+	// Wait for the schedule loop to create its ticker.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		clock.mu.Lock()
+		started := len(clock.tickers) > 0
+		clock.mu.Unlock()
+
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the schedule loop")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	// A tick 10 minutes late checks every minute it missed.
+	clock.Advance(10 * time.Minute)
+	waitForTicks(t, jsc, 1)
+
+	if n := runner.queueLen("catch-up"); n != 2 {
+		t.Errorf("Expected the job to be queued for minutes 03 and 07, queue length is %d", n)
+	}
+	if lag := jsc.Stats().Lag; lag != 9*time.Minute {
+		t.Errorf("Expected a lag of 9m, got %v", lag)
+	}
+
+	// After more than maxMissedTime, the missed minutes are skipped.
+	clock.Advance(2 * time.Hour)
+	waitForTicks(t, jsc, 2)
+
+	if n := runner.queueLen("catch-up"); n != 2 {
+		t.Errorf("Expected no runs in the skipped time, queue length is %d", n)
+	}
+
+	// Checking resumes from 02:10 a minute at a time.
+	for i := range 10 {
+		clock.Advance(time.Minute)
+		waitForTicks(t, jsc, int64(3+i))
+	}
+
+	if n := runner.queueLen("catch-up"); n != 4 {
+		t.Errorf("Expected two more runs at 02:13 and 02:17, queue length is %d", n)
+	}
+}
+
+func TestRunQueueHeadSkipsFutureJobs(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local)
+	clock := newFakeClock(start)
+	runner.Clock = clock
+
+	ran := []string{}
+	for _, job := range []JobConfig{
+		{Name: "later", startAt: start.Add(time.Hour)},
+		{Name: "now"},
+	} {
+		job.Command = []string{"true"}
+		job.Env = denv.OS()
+		job.Queue = "shared"
+		job.OnComplete = func(CompletedJob) {
+			ran = append(ran, job.Name)
+		}
+
+		runner.AddJob(job)
+	}
+
+	// The job that is due runs before the one queued ahead of it.
+	if err := runner.RunQueueHead("shared"); err != nil {
+		t.Fatalf("RunQueueHead() error = %v", err)
+	}
+	if err := runner.RunQueueHead("shared"); err != nil {
+		t.Fatalf("RunQueueHead() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"now"}, ran); diff != "" {
+		t.Errorf("Runs mismatch (-want +got):\n%s", diff)
+	}
+
+	clock.Advance(time.Hour)
+	if err := runner.RunQueueHead("shared"); err != nil {
+		t.Fatalf("RunQueueHead() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"now", "later"}, ran); diff != "" {
+		t.Errorf("Runs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestJitterSimulated(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local)
+	clock := newFakeClock(start)
+	runner.Clock = clock
+
+	// A job that should start later isn't run early,
+	// and running the queue to completion waits for exactly that long.
+	runner.AddJob(JobConfig{
+		Name:    "delayed",
+		Command: []string{"true"},
+		Env:     denv.OS(),
+		startAt: start.Add(10 * time.Minute),
+	})
+	if err := runner.RunQueueHead("delayed"); err != nil {
+		t.Fatalf("RunQueueHead() error = %v", err)
+	}
+	if n := runner.queueLen("delayed"); n != 1 || len(clock.slept) != 0 {
+		t.Fatalf("Expected the job to stay queued without waiting, queue length is %d, waited %v", n, clock.slept)
+	}
+	if err := runner.RunQueued(); err != nil {
+		t.Fatalf("RunQueued() error = %v", err)
+	}
+	if len(clock.slept) != 1 || clock.slept[0] != 10*time.Minute {
+		t.Errorf("Expected to wait 10m, waited %v", clock.slept)
+	}
+
+	// The random delay stays within the jitter and is whole seconds.
+	for range 20 {
+		clock.slept = nil
+
+		runner.AddJob(JobConfig{
+			Name:    "jittery",
+			Command: []string{"true"},
+			Env:     denv.OS(),
+			Jitter:  time.Hour,
+		})
+		if err := runner.RunQueueHead("jittery"); err != nil {
+			t.Fatalf("RunQueueHead() error = %v", err)
+		}
+
+		if len(clock.slept) != 1 {
+			t.Fatalf("Expected one wait, got %v", clock.slept)
+		}
+		if wait := clock.slept[0]; wait < 0 || wait >= time.Hour || wait%time.Second != 0 {
+			t.Errorf("Expected a delay of whole seconds under 1h, got %v", wait)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type CompletedJob struct {
+	// The ID of the run in the database or 0 if it hasn't been saved.
+	ID         int64
+	Error      string
+	ExitStatus int
+	Started    time.Time
+	Finished   time.Time
+	// The signal that killed the job or 0.
+	Signal int
+	// What the exit status means according to "exit_meanings" or an empty string.
+	ExitMeaning string
+
+	// Where and as whom the job ran and the command it ran.
+	// They keep history from a shared state directory meaningful.
+	Hostname string
+	Username string
+	Command  []string
+
+	// The exit statuses that count as success.
+	// Empty means only 0.
+	SuccessExitCodes []int
+
+	// The email recipients and the channels for notifications about the run.
+	// Empty means the defaults.
+	// They aren't saved in the history.
+	NotifyTo  []string
+	NotifyVia []string
+
+	// The paths of the files to attach to notifications about the run.
+	// They aren't saved in the history.
+	Attachments []string
+
+	// How many log lines to include in notifications and whether to include stdout.
+	// Nil means the global settings.
+	NotifyLines  *int
+	NotifyStdout *bool
+
+	// How many times in a row the job has failed the same way and since when.
+	// They are only set for jobs with "dedupe_failures".
+	Repeats        int
+	RepeatingSince time.Time
+
+	// How many runs in a row have failed.
+	// It is only set for jobs with "escalate" and stops counting past the last step.
+	Failures int
+
+	// Which attempt the run was starting from 1.
+	// A run is dead when it failed on the last attempt "retries" allowed.
+	Attempt int
+	Dead    bool
+
+	// The hash of the command, the environment, and the inputs of a job with "skip_unchanged"
+	// and whether the run was skipped because they were the same as in the last successful run.
+	InputHash string
+	Skipped   bool
+
+	// The only channel to notify through when a notification that failed on it is retried.
+	// Empty means all channels.
+	retryChannel string
+}
+
+func (cj CompletedJob) IsSuccess() bool {
+	if cj.Error != "" {
+		return false
+	}
+
+	if len(cj.SuccessExitCodes) == 0 {
+		return cj.ExitStatus == 0
+	}
+
+	return slices.Contains(cj.SuccessExitCodes, cj.ExitStatus)
+}
+
+// ExitStatusText describes the exit status with its meaning like "24 (some files vanished)".
+func (cj CompletedJob) ExitStatusText() string {
+	if cj.ExitMeaning == "" {
+		return strconv.Itoa(cj.ExitStatus)
+	}
+
+	return fmt.Sprintf("%d (%s)", cj.ExitStatus, cj.ExitMeaning)
+}
+
+// SignalName returns the name and the number of the signal that killed the job like "SIGKILL (9)".
+// It returns an empty string if the job wasn't killed by a signal.
+func (cj CompletedJob) SignalName() string {
+	if cj.Signal == 0 {
+		return ""
+	}
+
+	name := unix.SignalName(syscall.Signal(cj.Signal))
+	if name == "" {
+		name = "signal"
+	}
+
+	return fmt.Sprintf("%s (%d)", name, cj.Signal)
+}
+
+// notifiesVia reports whether notifications about the run go through a channel.
+func (cj CompletedJob) notifiesVia(channel string) bool {
+	return len(cj.NotifyVia) == 0 || slices.Contains(cj.NotifyVia, channel)
+}
+
+// retriesOnly reports whether a retried notification goes through other channels than this one.
+func (cj CompletedJob) retriesOnly(channel string) bool {
+	return cj.retryChannel != "" && cj.retryChannel != channel
+}
+
+// CommandLine returns the command as a string for a POSIX shell.
+func (cj CompletedJob) CommandLine() string {
+	return quoteCommand(cj.Command)
+}
+
+// runOrigin returns the hostname and the username for a run.
+// They are empty if they can't be found.
+func runOrigin() (hostname, username string) {
+	hostname, _ = os.Hostname()
+
+	if currentUser, err := user.Current(); err == nil {
+		username = currentUser.Username
+	}
+
+	return hostname, username
+}
@@ -0,0 +1,356 @@
+package engine
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+
+	"dbohdan.com/denv"
+)
+
+const crontabJobPrefix = "crontab-"
+
+var (
+	crontabEnvRegexp  = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	crontabNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+	cronMonthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronDowNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+	cronShorthands = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// A parsed five-field cron schedule.
+// Each field is a bit set of the values it matches.
+type cronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// Cron matches either the day of month or the day of week when both are restricted.
+	domStar bool
+	dowStar bool
+}
+
+type crontabEntry struct {
+	Command  string
+	Env      denv.Env
+	Line     int
+	Name     string
+	Schedule cronSchedule
+	Spec     string
+}
+
+func parseCronSchedule(spec string) (cronSchedule, error) {
+	if expanded, ok := cronShorthands[spec]; ok {
+		spec = expanded
+	} else if strings.HasPrefix(spec, "@") {
+		return cronSchedule{}, fmt.Errorf("unsupported schedule: %v", spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("expected 5 schedule fields, got %d", len(fields))
+	}
+
+	var sched cronSchedule
+	var err error
+
+	if sched.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return sched, fmt.Errorf("bad minute field: %w", err)
+	}
+
+	if sched.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return sched, fmt.Errorf("bad hour field: %w", err)
+	}
+
+	if sched.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return sched, fmt.Errorf("bad day of month field: %w", err)
+	}
+
+	if sched.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return sched, fmt.Errorf("bad month field: %w", err)
+	}
+
+	if sched.dow, err = parseCronField(fields[4], 0, 7, cronDowNames); err != nil {
+		return sched, fmt.Errorf("bad day of week field: %w", err)
+	}
+
+	// Both 0 and 7 mean Sunday.
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1
+	}
+
+	sched.domStar = strings.HasPrefix(fields[2], "*")
+	sched.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return sched, nil
+}
+
+// Parse a comma-separated list of values, ranges, and steps into a bit set.
+// Names are matched case-insensitively and map to min + their index.
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+
+	parseValue := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return min + i, nil
+			}
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("bad value: %q", s)
+		}
+
+		if n < min || n > max {
+			return 0, fmt.Errorf("value out of range %d-%d: %d", min, max, n)
+		}
+
+		return n, nil
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("bad step: %q", stepPart)
+			}
+		}
+
+		var start, end int
+		if rangePart == "*" {
+			start, end = min, max
+		} else {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			start, err = parseValue(startPart)
+			if err != nil {
+				return 0, err
+			}
+
+			end = start
+			if isRange {
+				end, err = parseValue(endPart)
+				if err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "a/n" means "a-max/n".
+				end = max
+			}
+
+			if end < start {
+				return 0, fmt.Errorf("bad range: %q", rangePart)
+			}
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+	}
+
+	return bits, nil
+}
+
+func (s cronSchedule) matches(minute, hour, day, month, dow int) bool {
+	if s.minute&(1<<minute) == 0 || s.hour&(1<<hour) == 0 || s.month&(1<<month) == 0 {
+		return false
+	}
+
+	domMatch := s.dom&(1<<day) != 0
+	dowMatch := s.dow&(1<<dow) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// Parse a crontab in the classic user format.
+// A comment directly above an entry that consists of a single word names the job.
+// Other entries are named after a hash of their line.
+// Variable assignments apply to the entries that follow them.
+func parseCrontab(r io.Reader) ([]crontabEntry, error) {
+	entries := []crontabEntry{}
+	env := denv.Env{}
+	lastComment := ""
+	seen := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			lastComment = ""
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			lastComment = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			continue
+		}
+
+		comment := lastComment
+		lastComment = ""
+
+		if match := crontabEnvRegexp.FindStringSubmatch(line); match != nil {
+			env[match[1]] = unquoteCrontabValue(match[2])
+			continue
+		}
+
+		var spec, command string
+		if strings.HasPrefix(line, "@") {
+			var ok bool
+			spec, command, ok = strings.Cut(line, " ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: missing command", lineNum)
+			}
+		} else {
+			fields := strings.Fields(line)
+			if len(fields) < 6 {
+				return nil, fmt.Errorf("line %d: missing command", lineNum)
+			}
+
+			spec = strings.Join(fields[:5], " ")
+			command = strings.Join(fields[5:], " ")
+		}
+
+		sched, err := parseCronSchedule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		name := crontabJobName(comment, line)
+		if otherLine, ok := seen[name]; ok {
+			return nil, fmt.Errorf("line %d: job name %q already used on line %d", lineNum, name, otherLine)
+		}
+		seen[name] = lineNum
+
+		entryEnv := make(denv.Env, len(env))
+		for k, v := range env {
+			entryEnv[k] = v
+		}
+
+		entries = append(entries, crontabEntry{
+			Command:  strings.TrimSpace(command),
+			Env:      entryEnv,
+			Line:     lineNum,
+			Name:     name,
+			Schedule: sched,
+			Spec:     spec,
+		})
+	}
+
+	return entries, scanner.Err()
+}
+
+func crontabJobName(comment, line string) string {
+	if crontabNameRegexp.MatchString(comment) {
+		return comment
+	}
+
+	sum := sha256.Sum256([]byte(line))
+	return crontabJobPrefix + hex.EncodeToString(sum[:])[:8]
+}
+
+func unquoteCrontabValue(value string) string {
+	value = strings.TrimSpace(value)
+
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+// Convert the schedule into a "should_run" function with the same keyword arguments as a Starlark one.
+func (s cronSchedule) shouldRunBuiltin() *starlark.Builtin {
+	return starlark.NewBuiltin(shouldRunVar, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		values := map[string]int{}
+
+		for _, kv := range kwargs {
+			key, ok := kv[0].(starlark.String)
+			if !ok {
+				continue
+			}
+
+			n, err := starlark.AsInt32(kv[1])
+			if err != nil {
+				continue
+			}
+
+			values[key.GoString()] = n
+		}
+
+		for _, key := range []string{"minute", "hour", "day", "month", "dow"} {
+			if _, ok := values[key]; !ok {
+				return starlark.None, fmt.Errorf("%s: missing argument %q", b.Name(), key)
+			}
+		}
+
+		matches := s.matches(values["minute"], values["hour"], values["day"], values["month"], values["dow"])
+
+		return starlark.Bool(matches), nil
+	})
+}
+
+func (e crontabEntry) jobConfig(env denv.Env) JobConfig {
+	return JobConfig{
+		Command:      []string{"sh", "-c", e.Command},
+		Enable:       true,
+		Env:          denv.Merge(env, e.Env),
+		Log:          true,
+		Name:         e.Name,
+		Notify:       NotifyOnFailure,
+		ShouldRun:    e.Schedule.shouldRunBuiltin(),
+		scheduleSpec: e.Spec,
+	}
+}
+
+func loadCrontab(env denv.Env, path string) ([]JobConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := parseCrontab(f)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []JobConfig{}
+	for _, entry := range entries {
+		jobs = append(jobs, entry.jobConfig(env))
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		minute  int
+		hour    int
+		day     int
+		month   int
+		dow     int
+		matches bool
+	}{
+		{"* * * * *", 7, 13, 2, 5, 3, true},
+		{"0 3 * * *", 0, 3, 15, 6, 1, true},
+		{"0 3 * * *", 1, 3, 15, 6, 1, false},
+		{"*/15 * * * *", 45, 0, 1, 1, 0, true},
+		{"*/15 * * * *", 46, 0, 1, 1, 0, false},
+		{"0 9-17/4 * * mon-fri", 0, 13, 10, 1, 5, true},
+		{"0 9-17/4 * * mon-fri", 0, 13, 10, 1, 6, false},
+		{"0 0 * * 7", 0, 0, 10, 1, 0, true},
+		{"0 0 1 jan *", 0, 0, 1, 1, 4, true},
+		// Day of month and day of week are combined with "or" when both are restricted.
+		{"0 0 1 * 1", 0, 0, 20, 1, 1, true},
+		{"0 0 1 * 1", 0, 0, 1, 1, 3, true},
+		{"0 0 1 * 1", 0, 0, 2, 1, 3, false},
+		{"@daily", 0, 0, 2, 1, 3, true},
+		{"@hourly", 0, 5, 2, 1, 3, true},
+		{"@hourly", 5, 5, 2, 1, 3, false},
+	}
+
+	for _, tt := range tests {
+		sched, err := parseCronSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("parseCronSchedule(%q) error = %v", tt.spec, err)
+			continue
+		}
+
+		got := sched.matches(tt.minute, tt.hour, tt.day, tt.month, tt.dow)
+		if got != tt.matches {
+			t.Errorf("%q matches(%d, %d, %d, %d, %d) = %v, want %v", tt.spec, tt.minute, tt.hour, tt.day, tt.month, tt.dow, got, tt.matches)
+		}
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@reboot",
+	} {
+		if _, err := parseCronSchedule(spec); err == nil {
+			t.Errorf("parseCronSchedule(%q) expected error", spec)
+		}
+	}
+}
+
+func TestParseCrontab(t *testing.T) {
+	crontab := `
+SHELL=/bin/sh
+MAILTO="someone"
+
+# backup
+0 3 * * * backup.sh ~/docs
+
+# Clean up temporary files every hour.
+@hourly rm -rf ~/tmp/*
+`
+
+	entries, err := parseCrontab(strings.NewReader(crontab))
+	if err != nil {
+		t.Fatalf("parseCrontab() error = %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].Name != "backup" {
+		t.Errorf("first entry name = %q, want %q", entries[0].Name, "backup")
+	}
+
+	if entries[0].Command != "backup.sh ~/docs" {
+		t.Errorf("first entry command = %q", entries[0].Command)
+	}
+
+	if entries[0].Env["MAILTO"] != "someone" {
+		t.Errorf(`Env["MAILTO"] = %q, want "someone"`, entries[0].Env["MAILTO"])
+	}
+
+	if !strings.HasPrefix(entries[1].Name, crontabJobPrefix) {
+		t.Errorf("second entry name = %q, want hash-based name", entries[1].Name)
+	}
+
+	if entries[1].Command != "rm -rf ~/tmp/*" {
+		t.Errorf("second entry command = %q", entries[1].Command)
+	}
+}
+
+func TestParseCrontabDuplicateName(t *testing.T) {
+	crontab := `
+# same
+* * * * * true
+# same
+* * * * * false
+`
+
+	if _, err := parseCrontab(strings.NewReader(crontab)); err == nil {
+		t.Error("expected error for duplicate job name")
+	}
+}
+
+func TestCrontabShouldRun(t *testing.T) {
+	sched, err := parseCronSchedule("30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := crontabEntry{Command: "true", Name: "test", Schedule: sched}.jobConfig(nil)
+
+	kwargs := func(minute int) []starlark.Tuple {
+		return []starlark.Tuple{
+			{starlark.String("minute"), starlark.MakeInt(minute)},
+			{starlark.String("hour"), starlark.MakeInt(1)},
+			{starlark.String("day"), starlark.MakeInt(1)},
+			{starlark.String("month"), starlark.MakeInt(1)},
+			{starlark.String("dow"), starlark.MakeInt(1)},
+		}
+	}
+
+	thread := &starlark.Thread{Name: "test"}
+	for minute, want := range map[int]starlark.Value{30: starlark.True, 31: starlark.False} {
+		result, err := starlark.Call(thread, job.ShouldRun, nil, kwargs(minute))
+		if err != nil {
+			t.Fatalf(`"should_run" call failed: %v`, err)
+		}
+
+		if result != want {
+			t.Errorf(`"should_run" at minute %d returned %v, want %v`, minute, result, want)
+		}
+	}
+}
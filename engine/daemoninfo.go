@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DaemonInfo describes the daemon that uses a state directory.
+// The daemon writes it at start and removes it when it stops cleanly,
+// so a file left behind means the daemon crashed or is still running.
+type DaemonInfo struct {
+	PID      int       `json:"pid"`
+	Started  time.Time `json:"started"`
+	Hostname string    `json:"hostname"`
+}
+
+// WriteDaemonInfo records the current process as the daemon of a state directory.
+// Call remove when the daemon stops.
+func WriteDaemonInfo(stateRoot string) (remove func(), err error) {
+	hostname, _ := os.Hostname()
+	data, err := json.Marshal(DaemonInfo{
+		PID:      os.Getpid(),
+		Started:  time.Now(),
+		Hostname: hostname,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(stateRoot, daemonInfoFileName)
+	if err := os.WriteFile(path, data, filePerms); err != nil {
+		return nil, fmt.Errorf("failed to write daemon info: %w", err)
+	}
+
+	return func() {
+		_ = os.Remove(path)
+	}, nil
+}
+
+// ReadDaemonInfo returns the recorded daemon of a state directory or nil if there is none.
+func ReadDaemonInfo(stateRoot string) (*DaemonInfo, error) {
+	data, err := os.ReadFile(filepath.Join(stateRoot, daemonInfoFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read daemon info: %w", err)
+	}
+
+	var info DaemonInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode daemon info: %w", err)
+	}
+
+	return &info, nil
+}
+
+// Alive reports whether the daemon process exists.
+// A daemon on another host is assumed to be alive because its process can't be checked.
+func (d DaemonInfo) Alive() bool {
+	if hostname, _ := os.Hostname(); d.Hostname != hostname {
+		return true
+	}
+
+	err := unix.Kill(d.PID, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDaemonInfo(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	info, err := ReadDaemonInfo(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read missing daemon info: %v", err)
+	}
+	if info != nil {
+		t.Fatalf("Expected no daemon info, got %+v", info)
+	}
+
+	remove, err := WriteDaemonInfo(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to write daemon info: %v", err)
+	}
+
+	info, err = ReadDaemonInfo(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read daemon info: %v", err)
+	}
+	if info == nil || info.PID != os.Getpid() {
+		t.Fatalf("Expected daemon info for PID %d, got %+v", os.Getpid(), info)
+	}
+	if !info.Alive() {
+		t.Error("Expected the current process to be alive")
+	}
+
+	remove()
+
+	if _, err := os.Stat(filepath.Join(tmpDir, daemonInfoFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected daemon info to be removed, got %v", err)
+	}
+}
+
+func TestDaemonInfoAlive(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name string
+		info DaemonInfo
+		want bool
+	}{
+		{"current process", DaemonInfo{PID: os.Getpid(), Hostname: hostname}, true},
+		// PIDs don't go this high on Linux.
+		{"dead process", DaemonInfo{PID: 1 << 30, Hostname: hostname}, false},
+		{"other host", DaemonInfo{PID: 1 << 30, Hostname: hostname + "-other"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.Alive(); got != tt.want {
+				t.Errorf("Alive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseDeadline parses a "deadline" like "06:00" in the local time zone.
+func parseDeadline(deadline string) (dailyAtSchedule, error) {
+	parsed, err := time.Parse("15:04", deadline)
+	if err != nil {
+		return dailyAtSchedule{}, fmt.Errorf("%q must be in the format HH:MM: %q", deadlineVar, deadline)
+	}
+
+	return dailyAtSchedule{
+		hour:     parsed.Hour(),
+		minute:   parsed.Minute(),
+		location: time.Local,
+	}, nil
+}
+
+// nextDeadline returns the first time after a run starts at t when the clock shows the deadline.
+// A run that starts at the deadline has a day to finish.
+func nextDeadline(deadline string, t time.Time) (time.Time, error) {
+	s, err := parseDeadline(deadline)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := s.target(t)
+	if !next.After(t) {
+		next = s.target(t.AddDate(0, 0, 1))
+	}
+
+	return next, nil
+}
+
+// watchDeadline warns about a run that is still going at its deadline.
+// With "deadline_kill", the runner kills the run instead through the timeout of the execution.
+// Call the returned function when the run finishes.
+func (r Runner) watchDeadline(job JobConfig, deadlineAt time.Time) func() {
+	if deadlineAt.IsZero() || job.DeadlineKill {
+		return func() {}
+	}
+
+	timer := time.AfterFunc(deadlineAt.Sub(r.Clock.Now()), func() {
+		LogJobPrintf(job.Name, "Still running at deadline %s", job.Deadline)
+		r.alert(job, "still running at deadline "+job.Deadline)
+	})
+
+	return func() {
+		timer.Stop()
+	}
+}
+
+// deadlineTimeout shortens the timeout of a run so it is killed at the deadline.
+func deadlineTimeout(timeout time.Duration, deadlineAt, started time.Time) time.Duration {
+	untilDeadline := deadlineAt.Sub(started)
+	if timeout > 0 && timeout < untilDeadline {
+		return timeout
+	}
+
+	return untilDeadline
+}
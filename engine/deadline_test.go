@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline string
+		started  time.Time
+		want     time.Time
+	}{
+		{
+			"later today",
+			"06:00",
+			time.Date(2025, 1, 5, 2, 0, 0, 0, time.Local),
+			time.Date(2025, 1, 5, 6, 0, 0, 0, time.Local),
+		},
+		{
+			"tomorrow",
+			"06:00",
+			time.Date(2025, 1, 5, 22, 0, 0, 0, time.Local),
+			time.Date(2025, 1, 6, 6, 0, 0, 0, time.Local),
+		},
+		{
+			"at the deadline",
+			"06:00",
+			time.Date(2025, 1, 5, 6, 0, 0, 0, time.Local),
+			time.Date(2025, 1, 6, 6, 0, 0, 0, time.Local),
+		},
+		{
+			"next month",
+			"00:30",
+			time.Date(2025, 1, 31, 23, 0, 0, 0, time.Local),
+			time.Date(2025, 2, 1, 0, 30, 0, 0, time.Local),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextDeadline(tt.deadline, tt.started)
+			if err != nil {
+				t.Fatalf("nextDeadline() error = %v", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("nextDeadline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := nextDeadline("6am", time.Now()); err == nil {
+		t.Error("Expected an error for an invalid deadline")
+	}
+}
+
+func TestDeadlineTimeout(t *testing.T) {
+	started := time.Date(2025, 1, 5, 5, 0, 0, 0, time.Local)
+	deadlineAt := started.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{"no timeout", 0, time.Hour},
+		{"shorter timeout", time.Minute, time.Minute},
+		{"longer timeout", 2 * time.Hour, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deadlineTimeout(tt.timeout, deadlineAt, started); got != tt.want {
+				t.Errorf("deadlineTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJobDeadline(t *testing.T) {
+	configRoot := t.TempDir()
+
+	path := writeTestJob(t, configRoot, "job", "command = [\"true\"]\ndeadline = \"06:00\"\ndeadline_kill = True\n")
+	_, job, err := NewScheduler().Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if job.Deadline != "06:00" || !job.DeadlineKill {
+		t.Errorf("Expected a deadline at 06:00 that kills the job, got %q, %v", job.Deadline, job.DeadlineKill)
+	}
+
+	path = writeTestJob(t, configRoot, "bad", "command = [\"true\"]\ndeadline = \"25:00\"\n")
+	if _, _, err := NewScheduler().Update(configRoot, path); err == nil {
+		t.Error("Expected an error for an invalid deadline")
+	}
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+)
+
+// NewDebugHandler returns the handler for the daemon's debug endpoint.
+// "/debug/vars" has the expvar variables of the Go runtime with the number of goroutines,
+// the scheduler stats, and the length of each queue.
+// "/debug/pprof/" has the profiles of net/http/pprof.
+func NewDebugHandler(jsc *Scheduler, runner Runner) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /debug/vars", func(w http.ResponseWriter, req *http.Request) {
+		vars := make(map[string]any)
+		expvar.Do(func(kv expvar.KeyValue) {
+			vars[kv.Key] = json.RawMessage(kv.Value.String())
+		})
+
+		vars["goroutines"] = runtime.NumGoroutine()
+		vars["scheduler"] = jsc.Stats()
+		vars["queues"] = runner.queueLengths()
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(vars)
+	})
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+	runner.AddJob(JobConfig{Name: "a", Queue: "backup"})
+	runner.AddJob(JobConfig{Name: "b", Queue: "backup"})
+
+	handler := NewDebugHandler(NewScheduler(), runner)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var vars struct {
+		Goroutines int            `json:"goroutines"`
+		MemStats   map[string]any `json:"memstats"`
+		Queues     map[string]int `json:"queues"`
+		Scheduler  map[string]any `json:"scheduler"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("Failed to decode vars: %v", err)
+	}
+
+	if vars.Goroutines == 0 || vars.MemStats == nil || vars.Scheduler == nil {
+		t.Errorf("Expected runtime and scheduler vars, got %s", rec.Body)
+	}
+	if vars.Queues["backup"] != 2 {
+		t.Errorf("Expected 2 jobs in the queue, got %v", vars.Queues)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the pprof index, got status %d", rec.Code)
+	}
+}
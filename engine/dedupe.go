@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// failureFingerprint identifies a failure by its error, exit status, and the last lines of its output.
+func (c *AppDB) failureFingerprint(completed CompletedJob) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(completed.Error + "\x00" + strconv.Itoa(completed.ExitStatus) + "\x00"))
+
+	if completed.ID != 0 {
+		for _, logName := range []string{"stdout", "stderr"} {
+			lines, err := c.RunLog(completed.ID, logName)
+			if err != nil {
+				return "", err
+			}
+
+			for _, line := range lines[max(0, len(lines)-defaultLogLines):] {
+				hash.Write([]byte(line + "\n"))
+			}
+			hash.Write([]byte{0})
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// recordFailure counts a failure of a job towards a streak of identical failures.
+// A different failure starts a new streak.
+// It returns the length of the streak and when it started.
+func (c *AppDB) recordFailure(jobName, fingerprint string, t time.Time) (int, time.Time, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var lastFingerprint string
+	var count int
+	var since time.Time
+	err = tx.QueryRow(`
+		SELECT fingerprint, count, since
+		FROM failure_streaks
+		WHERE job_name = ?`,
+		jobName,
+	).Scan(&lastFingerprint, &count, &since)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, time.Time{}, err
+	}
+
+	if err == sql.ErrNoRows || lastFingerprint != fingerprint {
+		count = 0
+		since = t
+	}
+	count++
+
+	_, err = tx.Exec(`
+		INSERT INTO failure_streaks (job_name, fingerprint, count, since)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(job_name) DO UPDATE SET
+			fingerprint = excluded.fingerprint,
+			count = excluded.count,
+			since = excluded.since`,
+		jobName,
+		fingerprint,
+		count,
+		since,
+	)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return count, since, tx.Commit()
+}
+
+// clearFailureStreak ends the streak of failures of a job.
+func (c *AppDB) clearFailureStreak(jobName string) error {
+	_, err := c.db.Exec(`DELETE FROM failure_streaks WHERE job_name = ?`, jobName)
+
+	return err
+}
+
+// dedupeFailure tracks repeated identical failures of a job.
+// It records the streak in the completed job and reports whether to skip notifying about it.
+// Notifications are sent on the 1st, 2nd, 4th, 8th, and so on identical failure in a row.
+func (r Runner) dedupeFailure(jobName string, cj *CompletedJob) (bool, error) {
+	if cj.IsSuccess() {
+		return false, r.db.clearFailureStreak(jobName)
+	}
+
+	fingerprint, err := r.db.failureFingerprint(*cj)
+	if err != nil {
+		return false, fmt.Errorf("failed to fingerprint failure: %w", err)
+	}
+
+	count, since, err := r.db.recordFailure(jobName, fingerprint, cj.Finished)
+	if err != nil {
+		return false, fmt.Errorf("failed to record failure: %w", err)
+	}
+
+	cj.Repeats = count
+	cj.RepeatingSince = since
+
+	// Skip unless the count is a power of two.
+	return count&(count-1) != 0, nil
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestJobRunnerDedupeFailures(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	notified := []CompletedJob{}
+	notify := func(jobName string, completed CompletedJob) error {
+		notified = append(notified, completed)
+		return nil
+	}
+
+	runner, err := NewRunner(db, notify, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	run := func(script string) {
+		t.Helper()
+
+		runner.AddJob(JobConfig{
+			Name:           "flaky",
+			Command:        []string{"sh", "-c", script},
+			DedupeFailures: true,
+			Env:            denv.OS(),
+			Log:            true,
+			Notify:         NotifyOnFailure,
+		})
+		_ = runner.RunQueueHead("flaky")
+	}
+
+	for range 5 {
+		run("echo disk full; exit 1")
+	}
+
+	// Notified on the 1st, 2nd, and 4th identical failure.
+	if len(notified) != 3 {
+		t.Fatalf("Expected 3 notifications, got %d", len(notified))
+	}
+	if notified[2].Repeats != 4 {
+		t.Errorf("Expected 4 repeats, got %d", notified[2].Repeats)
+	}
+
+	// A different failure is reported at once.
+	run("echo network down; exit 1")
+	if len(notified) != 4 || notified[3].Repeats != 1 {
+		t.Fatalf("Expected a notification for a new failure, got %+v", notified)
+	}
+
+	// A success ends the streak.
+	run("true")
+	run("echo network down; exit 1")
+	if len(notified) != 5 || notified[4].Repeats != 1 {
+		t.Errorf("Expected a new streak after success, got %+v", notified)
+	}
+}
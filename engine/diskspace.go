@@ -0,0 +1,42 @@
+package engine
+
+import "fmt"
+
+// LowSpacePolicy says what to do when the state directory is low on free space before a job starts.
+type LowSpacePolicy string
+
+const (
+	LowSpaceRefuse  LowSpacePolicy = "refuse"
+	LowSpaceSkipLog LowSpacePolicy = "skip-log"
+)
+
+func ParseLowSpacePolicy(policy string) (LowSpacePolicy, error) {
+	switch policy {
+	case string(LowSpaceRefuse):
+		return LowSpaceRefuse, nil
+	case string(LowSpaceSkipLog), "":
+		return LowSpaceSkipLog, nil
+	default:
+		return "", fmt.Errorf("unknown low space policy: %v", policy)
+	}
+}
+
+// checkFreeSpace returns a description of the problem if the state directory has less free space than the minimum.
+// It returns an empty string if there is enough space or the check is disabled.
+func (r Runner) checkFreeSpace() string {
+	if r.MinFreeSpace <= 0 {
+		return ""
+	}
+
+	free, err := freeSpace(r.stateRoot)
+	if err != nil {
+		// Don't stop jobs because the check failed.
+		return ""
+	}
+
+	if free >= r.MinFreeSpace {
+		return ""
+	}
+
+	return fmt.Sprintf("state directory has %d bytes free, less than the minimum of %d", free, r.MinFreeSpace)
+}
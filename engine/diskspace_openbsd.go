@@ -0,0 +1,14 @@
+package engine
+
+import "golang.org/x/sys/unix"
+
+// freeSpace returns the number of bytes available to the user on the filesystem of the path.
+// OpenBSD names the fields of statfs differently.
+func freeSpace(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return stat.F_bavail * int64(stat.F_bsize), nil
+}
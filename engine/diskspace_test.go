@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestParseLowSpacePolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected LowSpacePolicy
+		wantErr  bool
+	}{
+		{"refuse", LowSpaceRefuse, false},
+		{"skip-log", LowSpaceSkipLog, false},
+		{"", LowSpaceSkipLog, false},
+		{"panic", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLowSpacePolicy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLowSpacePolicy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseLowSpacePolicy(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	free, err := freeSpace(t.TempDir())
+	if err != nil {
+		t.Fatalf("freeSpace() error = %v", err)
+	}
+
+	if free <= 0 {
+		t.Errorf("freeSpace() = %d, want a positive number", free)
+	}
+}
+
+func TestJobRunnerLowSpace(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tests := []struct {
+		policy  LowSpacePolicy
+		wantErr string
+		wantRan bool
+	}{
+		{LowSpaceRefuse, "refused to start", false},
+		{LowSpaceSkipLog, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			db, err := OpenAppDB(tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create app database: %v", err)
+			}
+			defer db.Close()
+
+			alerts := make(chan CompletedJob, 1)
+			runner, err := NewRunner(db, func(jobName string, completed CompletedJob) error {
+				alerts <- completed
+				return nil
+			}, tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create job runner: %v", err)
+			}
+
+			// No filesystem has this much free space.
+			runner.MinFreeSpace = 1 << 62
+			runner.LowSpace = tt.policy
+
+			marker := filepath.Join(tmpDir, "ran")
+			runner.AddJob(JobConfig{
+				Name:    "low-space-job",
+				Command: []string{"touch", marker},
+				Env:     denv.OS(),
+				Log:     true,
+				Notify:  NotifyOnFailure,
+			})
+
+			err = runner.RunQueueHead("low-space-job")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("RunQueueHead() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("RunQueueHead() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if _, err := os.Stat(marker); (err == nil) != tt.wantRan {
+				t.Errorf("job ran = %v, want %v", err == nil, tt.wantRan)
+			}
+
+			if _, err := os.Stat(LogFilePath(tmpDir, "low-space-job", "stdout")); err == nil {
+				t.Error("Expected no log file")
+			}
+
+			if alert := <-alerts; !strings.Contains(alert.Error, "bytes free") {
+				t.Errorf("unexpected alert: %+v", alert)
+			}
+		})
+	}
+}
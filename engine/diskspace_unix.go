@@ -0,0 +1,15 @@
+//go:build !openbsd
+
+package engine
+
+import "golang.org/x/sys/unix"
+
+// freeSpace returns the number of bytes available to the user on the filesystem of the path.
+func freeSpace(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
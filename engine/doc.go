@@ -0,0 +1,19 @@
+// Package engine implements Regular's job scheduler and runner.
+//
+// Embed it in another Go program to load and run Regular jobs without the CLI:
+//
+//	db, err := engine.OpenAppDB(stateRoot)
+//	...
+//	runner, err := engine.NewRunner(db, engine.NotifyUserByEmail(db, configRoot), stateRoot)
+//	...
+//	jsc := engine.NewScheduler()
+//	if _, err := jsc.LoadAll(configRoot); err != nil {
+//		...
+//	}
+//
+//	go runner.Run()
+//	err = jsc.Schedule(runner)
+//
+// Pass your own NotifyWhenDone function to NewRunner to receive each CompletedJob.
+// Use the envfile package to load env files the way Regular does.
+package engine
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"log"
+	"runtime"
+
+	"dbohdan.com/denv"
+)
+
+// The variables jobs get when the environment of Regular lacks them.
+// Minimal environments, like the one of a systemd user service, often do.
+// Then a job that works in a terminal fails under the scheduler.
+var builtinEnvDefaults = denv.Env{
+	"LANG": defaultLang(),
+	"PATH": "/usr/local/bin:/usr/bin:/bin",
+}
+
+// defaultLang returns a UTF-8 locale that the OS has.
+// macOS has no "C.UTF-8".
+func defaultLang() string {
+	if runtime.GOOS == "darwin" {
+		return "en_US.UTF-8"
+	}
+
+	return "C.UTF-8"
+}
+
+// envDefaults returns the built-in defaults with "env_defaults" from the settings applied.
+func envDefaults(settings Settings) denv.Env {
+	defaults := denv.Merge(builtinEnvDefaults, settings.EnvDefaults)
+
+	for key, value := range defaults {
+		if value == "" {
+			delete(defaults, key)
+		}
+	}
+
+	return defaults
+}
+
+// withEnvDefaults returns a copy of env with the defaults for the variables that are missing or empty in it.
+func withEnvDefaults(env, defaults denv.Env) denv.Env {
+	result := denv.Merge(env)
+
+	for key, value := range defaults {
+		if result[key] == "" {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+// loadEnvDefaults loads the environment defaults from the settings in the main config root, which is the first one.
+// The extra config roots don't have settings of their own.
+// If the settings fail to load, it logs the error and uses the built-in defaults, so the jobs still load.
+func (jsc *Scheduler) loadEnvDefaults(configRoots []string) denv.Env {
+	var settings Settings
+	if len(configRoots) > 0 {
+		loaded, err := LoadSettings(configRoots[0])
+		if err == nil {
+			settings = loaded
+		} else {
+			log.Printf("Using the built-in environment defaults because the settings failed to load: %v", err)
+		}
+	}
+
+	defaults := envDefaults(settings)
+
+	jsc.mu.Lock()
+	jsc.envDefaults = defaults
+	jsc.mu.Unlock()
+
+	return defaults
+}
+
+// envDefaultsFor returns the environment defaults and loads them on first use.
+func (jsc *Scheduler) envDefaultsFor(configRoots []string) denv.Env {
+	jsc.mu.RLock()
+	defaults := jsc.envDefaults
+	jsc.mu.RUnlock()
+
+	if defaults == nil {
+		defaults = jsc.loadEnvDefaults(configRoots)
+	}
+
+	return defaults
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"dbohdan.com/denv"
+)
+
+func TestEnvDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		env      denv.Env
+		want     denv.Env
+	}{
+		{
+			"built-in defaults",
+			nil,
+			denv.Env{"HOME": "/home/user"},
+			denv.Env{"HOME": "/home/user", "LANG": defaultLang(), "PATH": "/usr/local/bin:/usr/bin:/bin"},
+		},
+		{
+			"set variables win",
+			nil,
+			denv.Env{"LANG": "de_DE.UTF-8", "PATH": "/opt/bin"},
+			denv.Env{"LANG": "de_DE.UTF-8", "PATH": "/opt/bin"},
+		},
+		{
+			"empty variables get defaults",
+			nil,
+			denv.Env{"LANG": ""},
+			denv.Env{"LANG": defaultLang(), "PATH": "/usr/local/bin:/usr/bin:/bin"},
+		},
+		{
+			"settings override and add",
+			map[string]string{"PATH": "/usr/bin:/bin", "TZ": "Europe/Berlin"},
+			denv.Env{},
+			denv.Env{"LANG": defaultLang(), "PATH": "/usr/bin:/bin", "TZ": "Europe/Berlin"},
+		},
+		{
+			"empty setting turns a default off",
+			map[string]string{"LANG": ""},
+			denv.Env{},
+			denv.Env{"PATH": "/usr/local/bin:/usr/bin:/bin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withEnvDefaults(tt.env, envDefaults(Settings{EnvDefaults: tt.settings}))
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("withEnvDefaults() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadEnvDefaults(t *testing.T) {
+	t.Setenv("LANG", "")
+	t.Setenv("TZ", "")
+
+	configRoot := t.TempDir()
+	settings := `env_defaults = {"TZ": "Europe/Berlin"}`
+	if err := os.WriteFile(filepath.Join(configRoot, settingsFileName), []byte(settings), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	// The global env file can build on the defaults.
+	if err := os.WriteFile(filepath.Join(configRoot, globalEnvFileName), []byte("LC_ALL=${LANG}\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := loadEnv(configRoot, "", NewScheduler().envDefaultsFor([]string{configRoot}))
+	if err != nil {
+		t.Fatalf("loadEnv() error = %v", err)
+	}
+
+	for key, want := range map[string]string{"LANG": defaultLang(), "LC_ALL": defaultLang(), "TZ": "Europe/Berlin"} {
+		if env[key] != want {
+			t.Errorf("Expected %s=%q, got %q", key, want, env[key])
+		}
+	}
+}
+
+func TestSchedulerEnvDefaultsFromMainRoot(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Setenv("TZ", "")
+
+	mainRoot := t.TempDir()
+	extraRoot := t.TempDir()
+
+	for root, settings := range map[string]string{
+		mainRoot:  `env_defaults = {"TZ": "Europe/Berlin"}`,
+		extraRoot: `env_defaults = {"TZ": "Asia/Tokyo"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(root, settingsFileName), []byte(settings), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestJob(t, extraRoot, "report", `command = ["true"]`)
+
+	jsc := NewScheduler()
+	if _, err := jsc.LoadAll(mainRoot, extraRoot); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	job, ok := jsc.Job("report")
+	if !ok {
+		t.Fatal("Expected the job to load")
+	}
+	if tz := job.Env["TZ"]; tz != "Europe/Berlin" {
+		t.Errorf("Expected TZ from the main config root, got %q", tz)
+	}
+
+	// Broken settings fall back to the built-in defaults.
+	if err := os.WriteFile(filepath.Join(mainRoot, settingsFileName), []byte(`env_defaults = 1`), filePerms); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jsc.LoadAll(mainRoot, extraRoot); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	job, ok = jsc.Job("report")
+	if !ok {
+		t.Fatal("Expected the job to load with broken settings")
+	}
+	if tz := job.Env["TZ"]; tz != "" {
+		t.Errorf("Expected no TZ with the built-in defaults, got %q", tz)
+	}
+}
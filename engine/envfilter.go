@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"dbohdan.com/denv"
+)
+
+// filterInheritedEnv removes the variables inherited from the OS that pass doesn't match or block does.
+// A variable is inherited when the OS has it with the same value.
+// An empty pass lets every variable through.
+// Patterns can use the wildcards of path.Match, like "LC_*".
+func filterInheritedEnv(env, osEnv denv.Env, pass, block []string) error {
+	for _, pattern := range slices.Concat(pass, block) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad variable pattern %q: %w", pattern, err)
+		}
+	}
+
+	matchesAny := func(patterns []string, key string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, key); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, key := range env.Keys() {
+		osValue, ok := osEnv[key]
+		if !ok || osValue != env[key] {
+			continue
+		}
+
+		if (len(pass) > 0 && !matchesAny(pass, key)) || matchesAny(block, key) {
+			delete(env, key)
+		}
+	}
+
+	return nil
+}
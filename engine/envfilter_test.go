@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"testing"
+
+	"dbohdan.com/denv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterInheritedEnv(t *testing.T) {
+	osEnv := denv.Env{
+		"HOME":          "/home/user",
+		"LC_ALL":        "C",
+		"PATH":          "/usr/bin",
+		"SSH_AUTH_SOCK": "/tmp/agent",
+	}
+
+	tests := []struct {
+		name     string
+		pass     []string
+		block    []string
+		expected denv.Env
+		wantErr  bool
+	}{
+		{
+			name: "pass",
+			pass: []string{"PATH", "HOME"},
+			expected: denv.Env{
+				"BACKUP_DIR": "/backup",
+				"HOME":       "/home/user",
+				"PATH":       "/usr/bin",
+			},
+		},
+		{
+			name:  "block",
+			block: []string{"SSH_AUTH_SOCK"},
+			expected: denv.Env{
+				"BACKUP_DIR": "/backup",
+				"HOME":       "/home/user",
+				"LC_ALL":     "C",
+				"PATH":       "/usr/bin",
+			},
+		},
+		{
+			name:  "pattern",
+			pass:  []string{"LC_*", "PATH"},
+			block: []string{"PATH"},
+			expected: denv.Env{
+				"BACKUP_DIR": "/backup",
+				"LC_ALL":     "C",
+			},
+		},
+		{
+			name:    "bad pattern",
+			pass:    []string{"["},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// BACKUP_DIR isn't inherited and HOME is overridden, so they stay.
+			env := denv.Merge(osEnv, denv.Env{"BACKUP_DIR": "/backup"})
+
+			err := filterInheritedEnv(env, osEnv, tt.pass, tt.block)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filterInheritedEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(tt.expected, env); diff != "" {
+				t.Errorf("filterInheritedEnv() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterInheritedEnvKeepsOverrides(t *testing.T) {
+	osEnv := denv.Env{"PATH": "/usr/bin"}
+	env := denv.Env{"PATH": "/opt/bin:/usr/bin"}
+
+	if err := filterInheritedEnv(env, osEnv, []string{"HOME"}, nil); err != nil {
+		t.Fatalf("filterInheritedEnv() error = %v", err)
+	}
+
+	if env["PATH"] != "/opt/bin:/usr/bin" {
+		t.Errorf("Expected the overridden PATH to stay, got %v", env)
+	}
+}
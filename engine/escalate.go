@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// parseEscalate reads the "escalate" dictionary.
+// It maps the number of failures in a row to the channels to add at that number.
+func parseEscalate(dict *starlark.Dict) (map[int][]string, error) {
+	steps := make(map[int][]string)
+
+	for _, item := range dict.Items() {
+		var failures int
+		if err := starlark.AsInt(item.Index(0), &failures); err != nil || failures < 1 {
+			return nil, fmt.Errorf("%q key %s must be a number of failures of at least 1", escalateVar, item.Index(0))
+		}
+
+		iterable, ok := item.Index(1).(starlark.Iterable)
+		if !ok {
+			return nil, fmt.Errorf("%q value %s isn't a list of channels", escalateVar, item.Index(1))
+		}
+
+		channels := []string{}
+		iter := iterable.Iterate()
+		var value starlark.Value
+		for iter.Next(&value) {
+			channel, ok := value.(starlark.String)
+			if !ok {
+				iter.Done()
+				return nil, fmt.Errorf("%q channel %s must be Starlark string", escalateVar, value)
+			}
+
+			if !slices.Contains(notifyChannels, channel.GoString()) {
+				iter.Done()
+				return nil, fmt.Errorf("unknown %q channel: %q", escalateVar, channel.GoString())
+			}
+
+			channels = append(channels, channel.GoString())
+		}
+		iter.Done()
+
+		steps[failures] = channels
+	}
+
+	return steps, nil
+}
+
+// escalationChannels returns the channels of every step up to a number of failures in a row.
+// It also reports whether the number is a step, that is, whether the failure escalates.
+func escalationChannels(steps map[int][]string, failures int) ([]string, bool) {
+	channels := []string{}
+	for threshold, stepChannels := range steps {
+		if threshold > failures {
+			continue
+		}
+
+		for _, channel := range stepChannels {
+			if !slices.Contains(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	slices.Sort(channels)
+
+	_, escalates := steps[failures]
+
+	return channels, escalates
+}
+
+// consecutiveFailures counts the failed runs of a job since the last successful one.
+// The attempts after the first of a run with "retries" don't count separately.
+// It stops counting at limit.
+func (c *AppDB) consecutiveFailures(jobName string, limit int) (int, error) {
+	rows, err := c.db.Query(`
+		SELECT `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE job_name = ?
+		ORDER BY id DESC`,
+		jobName,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for count < limit && rows.Next() {
+		completed, err := scanCompletedJob(rows)
+		if err != nil {
+			return 0, err
+		}
+
+		if completed.IsSuccess() {
+			break
+		}
+
+		if completed.Attempt <= 1 {
+			count++
+		}
+	}
+
+	return count, rows.Err()
+}
+
+// escalate picks the notification channels for a failed run of a job with "escalate".
+// It records the number of failures in a row in the completed job
+// and reports whether the run reached a new step, which is notified about even when deduplicated.
+// Below the first step, the run has no channels and isn't notified about.
+func (r Runner) escalate(job JobConfig, cj *CompletedJob) (bool, error) {
+	// Past the last step, the count doesn't matter.
+	limit := 0
+	for threshold := range job.Escalate {
+		limit = max(limit, threshold+1)
+	}
+
+	count, err := r.db.consecutiveFailures(job.Name, limit)
+	if err != nil {
+		return false, err
+	}
+
+	channels, escalates := escalationChannels(job.Escalate, count)
+
+	cj.Failures = count
+	cj.NotifyVia = channels
+	if escalates {
+		LogJobPrintf(job.Name, "Escalating notifications to %s after %d failures in a row", strings.Join(channels, ", "), count)
+	}
+
+	return escalates, nil
+}
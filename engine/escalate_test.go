@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestJobRunnerEscalate(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	notified := []CompletedJob{}
+	notify := func(jobName string, completed CompletedJob) error {
+		notified = append(notified, completed)
+		return nil
+	}
+
+	runner, err := NewRunner(db, notify, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	run := func(script string, dedupe bool) {
+		t.Helper()
+
+		runner.AddJob(JobConfig{
+			Name:           "flaky",
+			Command:        []string{"sh", "-c", script},
+			DedupeFailures: dedupe,
+			Env:            denv.OS(),
+			Escalate: map[int][]string{
+				2: {"plugins"},
+				3: {"email"},
+				5: {"ntfy"},
+			},
+			Notify: NotifyOnFailure,
+		})
+		_ = runner.RunQueueHead("flaky")
+	}
+
+	for range 6 {
+		run("exit 1", false)
+	}
+
+	// The first failure is below the first step.
+	want := [][]string{
+		{"plugins"},
+		{"email", "plugins"},
+		{"email", "plugins"},
+		{"email", "ntfy", "plugins"},
+		{"email", "ntfy", "plugins"},
+	}
+	if len(notified) != len(want) {
+		t.Fatalf("Expected %d notifications, got %d", len(want), len(notified))
+	}
+	for i, completed := range notified {
+		if !slices.Equal(completed.NotifyVia, want[i]) {
+			t.Errorf("Notification %d: expected channels %v, got %v", i, want[i], completed.NotifyVia)
+		}
+	}
+	if notified[3].Failures != 5 {
+		t.Errorf("Expected 5 failures in a row, got %d", notified[3].Failures)
+	}
+
+	// A success starts over, and deduplication doesn't hold back a new step.
+	// The 3rd identical failure would be skipped without "escalate".
+	notified = nil
+	run("true", true)
+	for range 3 {
+		run("exit 1", true)
+	}
+
+	if len(notified) != 2 {
+		t.Fatalf("Expected notifications for the 2nd and the 3rd failure, got %+v", notified)
+	}
+	if notified[1].Repeats != 3 || !slices.Contains(notified[1].NotifyVia, "email") {
+		t.Errorf("Expected the 3rd identical failure to escalate to email, got %+v", notified[1])
+	}
+}
+
+func TestEscalateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "not a dictionary",
+			src:     `escalate = ["email"]`,
+			wantErr: `"escalate" isn't a dictionary`,
+		},
+		{
+			name:    "zero failures",
+			src:     `escalate = {0: ["email"]}`,
+			wantErr: "at least 1",
+		},
+		{
+			name:    "string key",
+			src:     `escalate = {"3": ["email"]}`,
+			wantErr: "at least 1",
+		},
+		{
+			name:    "unknown channel",
+			src:     `escalate = {3: ["pager"]}`,
+			wantErr: `unknown "escalate" channel: "pager"`,
+		},
+		{
+			name:    "not a list",
+			src:     `escalate = {3: 5}`,
+			wantErr: "isn't a list of channels",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configRoot := t.TempDir()
+			path := writeTestJob(t, configRoot, "job", "command = [\"true\"]\n"+tt.src)
+
+			_, _, err := NewScheduler().Update(configRoot, path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
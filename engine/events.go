@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// JobEvent is a message the scheduler logged about a job.
+type JobEvent struct {
+	Time    time.Time
+	JobName string
+	Message string
+}
+
+// How many events can wait to be saved before new ones are dropped.
+const jobEventBacklog = 1000
+
+var (
+	// The events waiting to be saved and a channel closed when they have been.
+	// The events are saved in the background,
+	// so logging doesn't wait for the database, for example, while the runner holds its lock.
+	eventQueue chan JobEvent
+	eventsDone chan struct{}
+	eventMu    sync.Mutex
+)
+
+// RecordJobEvents makes LogJobPrintf also save the messages about jobs in the database.
+// The daemon records them, so "status" can show what happened to a job.
+// Pass nil to stop; it waits for the events logged before to be saved.
+func RecordJobEvents(db *AppDB) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+
+	if eventQueue != nil {
+		close(eventQueue)
+		<-eventsDone
+
+		eventQueue = nil
+		eventsDone = nil
+	}
+
+	if db == nil {
+		return
+	}
+
+	queue := make(chan JobEvent, jobEventBacklog)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for event := range queue {
+			// Don't use LogJobPrintf, which would record the failure again.
+			if err := db.saveJobEvent(event); err != nil {
+				log.Printf("Failed to save event of job %q: %v", event.JobName, err)
+			}
+		}
+	}()
+
+	eventQueue = queue
+	eventsDone = done
+}
+
+func recordJobEvent(jobName, msg string) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+
+	if eventQueue == nil {
+		return
+	}
+
+	event := JobEvent{
+		Time:    time.Now(),
+		JobName: jobName,
+		Message: msg,
+	}
+
+	select {
+	case eventQueue <- event:
+	default:
+		log.Printf("Dropped event of job %q because too many are waiting to be saved", jobName)
+	}
+}
+
+// saveJobEvent saves an event and drops the oldest events of the job over maxJobEvents.
+func (c *AppDB) saveJobEvent(event JobEvent) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.Exec(`
+		INSERT INTO job_events (time, job_name, message)
+		VALUES (?, ?, ?)`,
+		event.Time,
+		event.JobName,
+		event.Message,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM job_events
+		WHERE job_name = ? AND id <= (
+			SELECT id
+			FROM job_events
+			WHERE job_name = ?
+			ORDER BY id DESC
+			LIMIT 1 OFFSET ?
+		)`,
+		event.JobName,
+		event.JobName,
+		maxJobEvents,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// JobEvents returns the latest events of a job in chronological order.
+func (c *AppDB) JobEvents(jobName string, limit int) ([]JobEvent, error) {
+	rows, err := c.db.Query(`
+		SELECT time, job_name, message
+		FROM (
+			SELECT id, time, job_name, message
+			FROM job_events
+			WHERE job_name = ?
+			ORDER BY id DESC
+			LIMIT ?
+		)
+		ORDER BY id ASC`,
+		jobName,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []JobEvent
+	for rows.Next() {
+		var event JobEvent
+		if err := rows.Scan(&event.Time, &event.JobName, &event.Message); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestRecordJobEvents(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	// Not recorded yet.
+	LogJobPrintf("backup", "Before recording")
+
+	RecordJobEvents(db)
+	defer RecordJobEvents(nil)
+
+	for i := range maxJobEvents + 5 {
+		LogJobPrintf("backup", "Event %d", i)
+	}
+	LogJobPrintf("other", "Unrelated")
+
+	// Wait for the events to be saved.
+	RecordJobEvents(nil)
+
+	events, err := db.JobEvents("backup", 3)
+	if err != nil {
+		t.Fatalf("Failed to get job events: %v", err)
+	}
+
+	want := []string{"Event 102", "Event 103", "Event 104"}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Message != want[i] || event.JobName != "backup" {
+			t.Errorf("Event %d: expected %q, got %+v", i, want[i], event)
+		}
+	}
+
+	// Old events are dropped.
+	all, err := db.JobEvents("backup", 1000)
+	if err != nil {
+		t.Fatalf("Failed to get job events: %v", err)
+	}
+	if len(all) != maxJobEvents {
+		t.Errorf("Expected %d events, got %d", maxJobEvents, len(all))
+	}
+	if first := fmt.Sprintf("Event %d", 5); all[0].Message != first {
+		t.Errorf("Expected the oldest event to be %q, got %q", first, all[0].Message)
+	}
+}
+
+func TestRecordJobEventsSkipsRoutine(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	RecordJobEvents(db)
+	defer RecordJobEvents(nil)
+
+	runner.AddJob(JobConfig{
+		Name:    "routine",
+		Command: []string{"true"},
+		Env:     denv.OS(),
+	})
+	if err := runner.RunQueued(); err != nil {
+		t.Fatalf("RunQueued() error = %v", err)
+	}
+
+	RecordJobEvents(nil)
+
+	events, err := db.JobEvents("routine", 100)
+	if err != nil {
+		t.Fatalf("Failed to get job events: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no events for a successful run, got %+v", events)
+	}
+}
@@ -0,0 +1,302 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dbohdan.com/denv"
+	"dbohdan.com/regular/shellquote"
+)
+
+// Execution is one run of a job's command.
+type Execution struct {
+	JobName string
+	Command []string
+	Dir     string
+	Env     denv.Env
+	Timeout time.Duration
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// Executor runs job commands.
+// Execute returns an *exec.ExitError when the command exits with a nonzero status.
+type Executor interface {
+	Execute(e Execution) error
+}
+
+// ExecutorFactory creates an executor from the argument in the job's "executor" field.
+// The argument is the part after the first ":" and is empty if there is no ":".
+type ExecutorFactory func(arg string) (Executor, error)
+
+var (
+	executors   = make(map[string]ExecutorFactory)
+	executorsMu sync.RWMutex
+)
+
+func init() {
+	RegisterExecutor(localExecutorName, func(arg string) (Executor, error) {
+		if arg != "" {
+			return nil, fmt.Errorf("executor %q takes no argument", localExecutorName)
+		}
+
+		return localExecutor{}, nil
+	})
+
+	RegisterExecutor(shellExecutorName, func(arg string) (Executor, error) {
+		shell := arg
+		if shell == "" {
+			shell = "sh"
+		}
+
+		return shellExecutor{shell: shell}, nil
+	})
+
+	RegisterExecutor(sshExecutorName, func(arg string) (Executor, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("executor %q requires a host", sshExecutorName)
+		}
+
+		return sshExecutor{host: arg}, nil
+	})
+
+	RegisterExecutor(containerExecutorName, func(arg string) (Executor, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("executor %q requires an image", containerExecutorName)
+		}
+
+		return containerExecutor{image: arg}, nil
+	})
+
+	RegisterExecutor(systemdExecutorName, func(arg string) (Executor, error) {
+		return systemdScopeExecutor{properties: arg}, nil
+	})
+}
+
+// RegisterExecutor makes an executor available to jobs under a name.
+// It replaces any executor already registered under the name.
+func RegisterExecutor(name string, factory ExecutorFactory) {
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+
+	executors[name] = factory
+}
+
+// ExecutorNames returns the names of the registered executors in sorted order.
+func ExecutorNames() []string {
+	executorsMu.RLock()
+	defer executorsMu.RUnlock()
+
+	names := make([]string, 0, len(executors))
+	for name := range executors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// NewExecutor creates an executor from a spec like "local" or "ssh:backup.example.com".
+// An empty spec selects the local executor.
+func NewExecutor(spec string) (Executor, error) {
+	name, arg, _ := strings.Cut(spec, ":")
+	if name == "" {
+		name = localExecutorName
+	}
+
+	executorsMu.RLock()
+	factory, ok := executors[name]
+	executorsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown executor: %q", name)
+	}
+
+	return factory(arg)
+}
+
+// localExecutor runs the command directly.
+type localExecutor struct{}
+
+func (localExecutor) Execute(e Execution) error {
+	return runCommand(e.JobName, e.Env, e.Dir, e.Command, e.Timeout, e.Stdin, e.Stdout, e.Stderr)
+}
+
+// shellExecutor runs the first element of the command as a shell script.
+// The remaining elements become the script's positional parameters.
+type shellExecutor struct {
+	shell string
+}
+
+func (x shellExecutor) Execute(e Execution) error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	cmd := append([]string{x.shell, "-c", e.Command[0], e.JobName}, e.Command[1:]...)
+
+	return runCommand(e.JobName, e.Env, e.Dir, cmd, e.Timeout, e.Stdin, e.Stdout, e.Stderr)
+}
+
+// sshExecutor runs the command on a remote host with ssh(1).
+// The remote command starts in the user's home directory and doesn't receive the job's environment.
+type sshExecutor struct {
+	host string
+}
+
+func (x sshExecutor) Execute(e Execution) error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	cmd := []string{"ssh", "-o", "BatchMode=yes", "--", x.host, quoteCommand(e.Command)}
+
+	return runCommand(e.JobName, e.Env, e.Dir, cmd, e.Timeout, e.Stdin, e.Stdout, e.Stderr)
+}
+
+// containerExecutor runs the command in a new container with Podman or Docker.
+// The job directory is mounted at the same path in the container and is the working directory.
+type containerExecutor struct {
+	image string
+}
+
+func (x containerExecutor) Execute(e Execution) error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	engine, err := containerEngine()
+	if err != nil {
+		return err
+	}
+
+	cmd := []string{engine, "run", "--rm", "-i"}
+	if e.Dir != "" {
+		cmd = append(cmd, "--volume", e.Dir+":"+e.Dir, "--workdir", e.Dir)
+	}
+	cmd = append(cmd, x.image)
+	cmd = append(cmd, e.Command...)
+
+	return runCommand(e.JobName, e.Env, e.Dir, cmd, e.Timeout, e.Stdin, e.Stdout, e.Stderr)
+}
+
+// containerEngine finds Podman or Docker, preferring Podman.
+func containerEngine() (string, error) {
+	for _, name := range []string{"podman", "docker"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("neither podman nor docker found")
+}
+
+// systemdScopeExecutor runs the command in a transient scope unit of the user's systemd instance.
+// The properties are a comma-separated list of unit properties like "MemoryMax=1G".
+// Without systemd, it runs the command directly.
+type systemdScopeExecutor struct {
+	properties string
+}
+
+func (x systemdScopeExecutor) Execute(e Execution) error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	cmd := e.Command
+	if systemdAvailable(e.Env) {
+		cmd = scopeCommand(e.JobName, x.properties, time.Now(), e.Command)
+	} else {
+		LogJobPrintf(e.JobName, "systemd isn't available, running the command without a scope")
+	}
+
+	return runCommand(e.JobName, e.Env, e.Dir, cmd, e.Timeout, e.Stdin, e.Stdout, e.Stderr)
+}
+
+// systemdAvailable reports whether systemd-run can start units in the user's systemd instance.
+// It is a variable for tests.
+var systemdAvailable = func(env denv.Env) bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+
+	if _, err := exec.LookPath("systemd-run"); err != nil {
+		return false
+	}
+
+	// The directory exists when systemd is the init system.
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return false
+	}
+
+	if env["DBUS_SESSION_BUS_ADDRESS"] != "" {
+		return true
+	}
+
+	runtimeDir := env["XDG_RUNTIME_DIR"]
+	if runtimeDir == "" {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(runtimeDir, "bus"))
+	return err == nil
+}
+
+// scopeCommand wraps a command in systemd-run.
+// The unit is named after the job and the start time, so "systemctl --user status" finds it.
+func scopeCommand(jobName, properties string, t time.Time, cmd []string) []string {
+	unit := fmt.Sprintf("regular-%s-%d.scope", escapeUnitName(jobName), t.UnixNano())
+
+	scope := []string{
+		"systemd-run",
+		"--user",
+		"--scope",
+		"--quiet",
+		"--collect",
+		"--unit=" + unit,
+		"--description=regular job " + jobName,
+	}
+
+	for _, property := range strings.Split(properties, ",") {
+		if property = strings.TrimSpace(property); property != "" {
+			scope = append(scope, "--property="+property)
+		}
+	}
+
+	scope = append(scope, "--")
+
+	return append(scope, cmd...)
+}
+
+// escapeUnitName replaces the characters that aren't allowed in systemd unit names.
+func escapeUnitName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == ':', r == '_', r == '.', r == '-':
+			return r
+
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// quoteCommand joins a command into a string for a POSIX shell.
+func quoteCommand(cmd []string) string {
+	quoted := make([]string, len(cmd))
+	for i, arg := range cmd {
+		quoted[i] = shellquote.POSIX(arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
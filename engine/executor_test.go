@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestNewExecutor(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Executor
+		wantErr bool
+	}{
+		{"", localExecutor{}, false},
+		{"local", localExecutor{}, false},
+		{"local:arg", nil, true},
+		{"shell", shellExecutor{shell: "sh"}, false},
+		{"shell:bash", shellExecutor{shell: "bash"}, false},
+		{"ssh:backup.example.com", sshExecutor{host: "backup.example.com"}, false},
+		{"ssh", nil, true},
+		{"container:docker.io/library/alpine:3", containerExecutor{image: "docker.io/library/alpine:3"}, false},
+		{"container", nil, true},
+		{"systemd-scope", systemdScopeExecutor{}, false},
+		{"systemd-scope:MemoryMax=1G,CPUQuota=50%", systemdScopeExecutor{properties: "MemoryMax=1G,CPUQuota=50%"}, false},
+		{"nonexistent", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := NewExecutor(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExecutor(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("NewExecutor(%q) = %#v, want %#v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+type recordingExecutor struct {
+	commands *[][]string
+}
+
+func (x recordingExecutor) Execute(e Execution) error {
+	*x.commands = append(*x.commands, e.Command)
+	return nil
+}
+
+func TestRegisterExecutor(t *testing.T) {
+	commands := [][]string{}
+	RegisterExecutor("test-recording", func(arg string) (Executor, error) {
+		return recordingExecutor{commands: &commands}, nil
+	})
+
+	executor, err := NewExecutor("test-recording")
+	if err != nil {
+		t.Fatalf("NewExecutor() error = %v", err)
+	}
+
+	if err := executor.Execute(Execution{Command: []string{"true"}}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if len(commands) != 1 || commands[0][0] != "true" {
+		t.Errorf("commands = %v", commands)
+	}
+}
+
+func TestShellExecutor(t *testing.T) {
+	var stdout bytes.Buffer
+	err := shellExecutor{shell: "sh"}.Execute(Execution{
+		JobName: "test",
+		Command: []string{`echo "$0 $1 $2"; exit 3`, "foo", "bar baz"},
+		Env:     denv.Env{},
+		Stdout:  &stdout,
+	})
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("Execute() error = %v, want exit status 3", err)
+	}
+
+	if got, want := stdout.String(), "test foo bar baz\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestScopeCommand(t *testing.T) {
+	start := time.Unix(1714557600, 5)
+
+	got := scopeCommand("back up/photos", "MemoryMax=1G, CPUQuota=50%,", start, []string{"rsync", "-a", "--", "src", "dest"})
+	want := []string{
+		"systemd-run",
+		"--user",
+		"--scope",
+		"--quiet",
+		"--collect",
+		"--unit=regular-back_up_photos-1714557600000000005.scope",
+		"--description=regular job back up/photos",
+		"--property=MemoryMax=1G",
+		"--property=CPUQuota=50%",
+		"--",
+		"rsync", "-a", "--", "src", "dest",
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("scopeCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestSystemdScopeExecutorFallback(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	available := systemdAvailable
+	systemdAvailable = func(env denv.Env) bool { return false }
+	defer func() { systemdAvailable = available }()
+
+	var stdout bytes.Buffer
+	err := systemdScopeExecutor{properties: "MemoryMax=1G"}.Execute(Execution{
+		JobName: "test",
+		Command: []string{"sh", "-c", "echo direct; exit 3"},
+		Env:     denv.OS(),
+		Stdout:  &stdout,
+	})
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("Execute() error = %v, want exit status 3", err)
+	}
+
+	if got, want := stdout.String(), "direct\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestQuoteCommand(t *testing.T) {
+	got := quoteCommand([]string{"echo", "hello world", "it's"})
+	want := `echo 'hello world' 'it'"'"'s'`
+
+	if got != want {
+		t.Errorf("quoteCommand() = %q, want %q", got, want)
+	}
+}
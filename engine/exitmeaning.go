@@ -0,0 +1,28 @@
+package engine
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// parseExitMeanings reads the "exit_meanings" dictionary from exit statuses to descriptions.
+func parseExitMeanings(dict *starlark.Dict) (map[int]string, error) {
+	meanings := make(map[int]string)
+
+	for _, item := range dict.Items() {
+		var status int
+		if err := starlark.AsInt(item.Index(0), &status); err != nil || status < 0 || status > 255 {
+			return nil, fmt.Errorf("%q key %s isn't an exit status from 0 to 255", exitMeaningsVar, item.Index(0))
+		}
+
+		meaning, ok := item.Index(1).(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("%q value %s isn't Starlark string", exitMeaningsVar, item.Index(1))
+		}
+
+		meanings[status] = meaning.GoString()
+	}
+
+	return meanings, nil
+}
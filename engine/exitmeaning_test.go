@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestJobRunnerExitMeanings(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	configRoot := t.TempDir()
+	stateRoot := t.TempDir()
+
+	path := writeTestJob(t, configRoot, "sync", `
+command = ["sh", "-c", "exit $CODE"]
+exit_meanings = {0: "copied everything", 24: "some files vanished"}
+success_exit_codes = [0, 24]
+`)
+
+	db, err := OpenAppDB(stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	notify := func(jobName string, completed CompletedJob) error {
+		return nil
+	}
+
+	runner, err := NewRunner(db, notify, stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	_, job, err := NewScheduler().Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	tests := []struct {
+		code        string
+		wantMeaning string
+		wantText    string
+	}{
+		{"0", "copied everything", "0 (copied everything)"},
+		{"24", "some files vanished", "24 (some files vanished)"},
+		{"23", "", "23"},
+	}
+
+	for _, tt := range tests {
+		job.Env["CODE"] = tt.code
+		runner.AddJob(*job)
+		_ = runner.RunQueueHead("sync")
+
+		completed, err := db.LastCompleted("sync")
+		if err != nil || completed == nil {
+			t.Fatalf("Failed to get last run: %v", err)
+		}
+
+		if completed.ExitMeaning != tt.wantMeaning {
+			t.Errorf("Exit status %s: expected meaning %q, got %q", tt.code, tt.wantMeaning, completed.ExitMeaning)
+		}
+		if text := completed.ExitStatusText(); text != tt.wantText {
+			t.Errorf("Exit status %s: expected %q, got %q", tt.code, tt.wantText, text)
+		}
+	}
+
+	_, body, err := formatMessage(nil, "sync", CompletedJob{ExitStatus: 24, ExitMeaning: "some files vanished"}, logExcerpt{})
+	if err != nil {
+		t.Fatalf("formatMessage() error = %v", err)
+	}
+	if !strings.Contains(body, "Exit status 24 means: some files vanished\n") {
+		t.Errorf("Expected the meaning in the message, got %q", body)
+	}
+}
+
+func TestExitMeaningsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "not a dictionary",
+			src:     `exit_meanings = [24]`,
+			wantErr: `"exit_meanings" isn't a dictionary`,
+		},
+		{
+			name:    "out of range",
+			src:     `exit_meanings = {256: "huge"}`,
+			wantErr: "isn't an exit status from 0 to 255",
+		},
+		{
+			name:    "not a string",
+			src:     `exit_meanings = {1: 2}`,
+			wantErr: "isn't Starlark string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configRoot := t.TempDir()
+			path := writeTestJob(t, configRoot, "job", tt.src)
+
+			_, _, err := NewScheduler().Update(configRoot, path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// StaleJob is a job that has state but no longer exists in the config roots.
+type StaleJob struct {
+	Name string
+	// When the job last finished a run or wrote to its state directory.
+	LastActive time.Time
+}
+
+// FindStaleJobs returns the jobs with state in the database or the state root that aren't in the config roots
+// and haven't been active for the grace period.
+func FindStaleJobs(db *AppDB, config Config, grace time.Duration, now time.Time) ([]StaleJob, error) {
+	names, err := ListJobNames(config.ConfigRoots()...)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]struct{})
+	for _, name := range names {
+		existing[jobDirName(name)] = struct{}{}
+	}
+
+	active, err := db.lastFinished()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job activity: %w", err)
+	}
+
+	entries, err := os.ReadDir(config.StateRoot)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read state directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		modified, err := lastModified(filepath.Join(config.StateRoot, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if modified.After(active[entry.Name()]) {
+			active[entry.Name()] = modified
+		}
+	}
+
+	stale := []StaleJob{}
+	for name, lastActive := range active {
+		// Count the jobs that a "job" call defines as long as their job file exists.
+		if _, ok := existing[jobDirName(name)]; ok {
+			continue
+		}
+
+		if now.Sub(lastActive) < grace {
+			continue
+		}
+
+		stale = append(stale, StaleJob{Name: name, LastActive: lastActive})
+	}
+
+	slices.SortFunc(stale, func(a, b StaleJob) int {
+		return a.LastActive.Compare(b.LastActive)
+	})
+
+	return stale, nil
+}
+
+// RemoveJobState deletes the runs, the logs, and the pending notifications of a job
+// and its directory in the state root.
+func RemoveJobState(db *AppDB, stateRoot, jobName string) error {
+	if err := db.removeJob(jobName); err != nil {
+		return fmt.Errorf("failed to remove job %q from database: %w", jobName, err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(stateRoot, jobName)); err != nil {
+		return fmt.Errorf("failed to remove state directory of job %q: %w", jobName, err)
+	}
+
+	return nil
+}
+
+// CollectGarbage removes the state of the stale jobs.
+func CollectGarbage(db *AppDB, config Config, grace time.Duration) error {
+	stale, err := FindStaleJobs(db, config, grace, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, job := range stale {
+		if err := RemoveJobState(db, config.StateRoot, job.Name); err != nil {
+			return err
+		}
+
+		LogJobPrintf(job.Name, "Removed state of deleted job")
+	}
+
+	return nil
+}
+
+// RunGarbageCollector collects garbage at the start and then daily until the program exits.
+// The settings are read every time, so "auto_gc" can be turned on and off without a restart.
+func RunGarbageCollector(db *AppDB, config Config) {
+	for {
+		settings, err := LoadSettings(config.ConfigRoot)
+		if err != nil {
+			log.Printf("Garbage collection failed: %v", err)
+		} else if settings.AutoGC {
+			if err := CollectGarbage(db, config, settings.GCGracePeriod()); err != nil {
+				log.Printf("Garbage collection failed: %v", err)
+			}
+		}
+
+		time.Sleep(gcInterval)
+	}
+}
+
+// lastModified returns the latest modification time of a directory and the files in it.
+func lastModified(dir string) (time.Time, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latest := info.ModTime()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// lastFinished returns when the last run of each job in the database finished.
+func (c *AppDB) lastFinished() (map[string]time.Time, error) {
+	rows, err := c.db.Query(`
+		SELECT job_name, finished
+		FROM completed_jobs
+		WHERE id IN (
+			SELECT MAX(id)
+			FROM completed_jobs
+			GROUP BY job_name
+		)`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	finished := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var t time.Time
+		if err := rows.Scan(&name, &t); err != nil {
+			return nil, err
+		}
+
+		finished[name] = t
+	}
+
+	return finished, rows.Err()
+}
+
+func (c *AppDB) removeJob(jobName string) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, query := range []string{
+		`DELETE FROM job_logs WHERE completed_job_id IN (SELECT id FROM completed_jobs WHERE job_name = ?)`,
+		`DELETE FROM completed_jobs WHERE job_name = ?`,
+		`DELETE FROM pending_notifications WHERE job_name = ?`,
+		`DELETE FROM failure_streaks WHERE job_name = ?`,
+		`DELETE FROM job_events WHERE job_name = ?`,
+		`DELETE FROM snoozed_jobs WHERE job_name = ?`,
+	} {
+		if _, err := tx.Exec(query, jobName); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
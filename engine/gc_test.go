@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindStaleJobs(t *testing.T) {
+	config := Config{ConfigRoot: t.TempDir(), StateRoot: t.TempDir()}
+
+	db, err := OpenAppDB(config.StateRoot)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := os.Mkdir(filepath.Join(config.ConfigRoot, "existing"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config.ConfigRoot, "existing", jobConfigFileName), nil, filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for name, finished := range map[string]time.Time{
+		"existing": now.Add(-100 * 24 * time.Hour),
+		"old":      now.Add(-40 * 24 * time.Hour),
+		"recent":   now.Add(-24 * time.Hour),
+	} {
+		if _, err := db.saveCompletedJob(name, CompletedJob{Started: finished, Finished: finished}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A job without runs in the database, only logs.
+	if err := os.Mkdir(filepath.Join(config.StateRoot, "dir-only"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := FindStaleJobs(db, config, 30*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("FindStaleJobs() error = %v", err)
+	}
+	if len(stale) != 1 || stale[0].Name != "old" {
+		t.Fatalf("FindStaleJobs() = %+v, want old", stale)
+	}
+
+	stale, err = FindStaleJobs(db, config, 0, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("FindStaleJobs() error = %v", err)
+	}
+	names := []string{}
+	for _, job := range stale {
+		names = append(names, job.Name)
+	}
+	if len(names) != 3 || names[0] != "old" || names[1] != "recent" || names[2] != "dir-only" {
+		t.Errorf("FindStaleJobs() = %v, want [old recent dir-only]", names)
+	}
+
+	if err := RemoveJobState(db, config.StateRoot, "old"); err != nil {
+		t.Fatalf("RemoveJobState() error = %v", err)
+	}
+
+	completed, err := db.LastCompleted("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed != nil {
+		t.Errorf("Expected no runs after removal, got %+v", completed)
+	}
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// The thread-local key of the jobs that "job" calls define.
+const generatedJobsKey = "regular.generated_jobs"
+
+// generatedJob is a job that a "job" call in a job file defines.
+type generatedJob struct {
+	name    string
+	options starlark.StringDict
+}
+
+// jobBuiltin implements "job".
+// It records the name and the options of a job.
+// The jobs are created after the job file finishes running.
+func jobBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	generated, ok := thread.Local(generatedJobsKey).(*[]generatedJob)
+	if !ok {
+		return nil, fmt.Errorf("%s: can only be called from a job file", b.Name())
+	}
+
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%s: takes only keyword arguments", b.Name())
+	}
+
+	job := generatedJob{options: make(starlark.StringDict, len(kwargs))}
+	for _, kwarg := range kwargs {
+		key := string(kwarg[0].(starlark.String))
+
+		if key == "name" {
+			name, ok := kwarg[1].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("%s: %q must be Starlark string", b.Name(), key)
+			}
+
+			job.name = name.GoString()
+			continue
+		}
+
+		if _, ok := jobOptionNames()[key]; !ok || key == instanceVar {
+			return nil, fmt.Errorf("%s: unknown option %q", b.Name(), key)
+		}
+
+		job.options[key] = kwarg[1]
+	}
+
+	if err := validateJobName(job.name); err != nil || strings.Contains(job.name, generatedJobSep) {
+		return nil, fmt.Errorf("%s: invalid job name: %q", b.Name(), job.name)
+	}
+
+	for _, other := range *generated {
+		if other.name == job.name {
+			return nil, fmt.Errorf("%s: duplicate job name: %q", b.Name(), job.name)
+		}
+	}
+
+	*generated = append(*generated, job)
+
+	return starlark.None, nil
+}
+
+// generatedJobGlobals returns the options of a job that a "job" call defines.
+// The arguments of the call override the top-level options of the job file.
+func generatedJobGlobals(globals starlark.StringDict, job generatedJob) starlark.StringDict {
+	merged := maps.Clone(globals)
+	maps.Copy(merged, job.options)
+
+	return merged
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGeneratedJobs(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	configRoot := t.TempDir()
+
+	path := writeTestJob(t, configRoot, "check", `
+schedule = every(one_hour)
+tags = ["web"]
+
+for _site in ["example.com", "example.net"]:
+    job(name = _site, command = ["curl", "-f", "https://" + _site])
+
+job(name = "slow", command = ["sleep", "60"], tags = ["slow"])
+`)
+
+	jsc := NewScheduler()
+	loaded, err := jsc.LoadAll(configRoot)
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	want := []string{"check:example.com", "check:example.net", "check:slow"}
+	if !slices.Equal(loaded, want) {
+		t.Fatalf("Expected jobs %v, got %v", want, loaded)
+	}
+
+	job, err := jsc.LoadJob([]string{configRoot}, "check:example.net")
+	if err != nil {
+		t.Fatalf("LoadJob() error = %v", err)
+	}
+	if !slices.Equal(job.Command, []string{"curl", "-f", "https://example.net"}) {
+		t.Errorf("Expected the command from the call, got %v", job.Command)
+	}
+	if !slices.Equal(job.Tags, []string{"web"}) || job.ScheduleSummary() != "every 1h" {
+		t.Errorf("Expected the top-level options, got %v, %q", job.Tags, job.ScheduleSummary())
+	}
+
+	slow, _ := jsc.Job("check:slow")
+	if !slices.Equal(slow.Tags, []string{"slow"}) {
+		t.Errorf("Expected the call to override the tags, got %v", slow.Tags)
+	}
+
+	if _, err := jsc.LoadJob([]string{configRoot}, "check"); err == nil {
+		t.Error("Expected an error for the name of the job file")
+	}
+
+	// Jobs the file no longer defines are removed.
+	writeTestJob(t, configRoot, "check", `
+job(name = "example.com", command = ["true"])
+`)
+	if _, _, err := jsc.Update(configRoot, path); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if names := jobNames(jsc); !slices.Equal(names, []string{"check:example.com"}) {
+		t.Errorf("Expected only check:example.com, got %v", names)
+	}
+
+	if err := jsc.remove("check"); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if names := jobNames(jsc); len(names) != 0 {
+		t.Errorf("Expected no jobs after removing the file, got %v", names)
+	}
+}
+
+func jobNames(jsc *Scheduler) []string {
+	names := []string{}
+	for _, job := range jsc.Jobs() {
+		names = append(names, job.Name)
+	}
+
+	return names
+}
+
+func TestGeneratedJobErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "duplicate",
+			src:     "job(name = \"a\")\njob(name = \"a\")",
+			wantErr: "duplicate job name",
+		},
+		{
+			name:    "separator",
+			src:     `job(name = "a:b")`,
+			wantErr: "invalid job name",
+		},
+		{
+			name:    "no name",
+			src:     `job(command = ["true"])`,
+			wantErr: "invalid job name",
+		},
+		{
+			name:    "unknown option",
+			src:     `job(name = "a", jittr = 5)`,
+			wantErr: `unknown option "jittr"`,
+		},
+		{
+			name:    "positional",
+			src:     `job("a")`,
+			wantErr: "only keyword arguments",
+		},
+		{
+			name:    "bad option",
+			src:     `job(name = "a", retries = -1)`,
+			wantErr: `job "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configRoot := t.TempDir()
+			path := writeTestJob(t, configRoot, "gen", tt.src)
+
+			_, _, err := NewScheduler().Update(configRoot, path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
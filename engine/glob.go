@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+
+	"go.starlark.net/starlark"
+)
+
+// The thread-local key of the patterns that "glob" matches while a job file loads.
+const globsKey = "regular.globs"
+
+// newGlob returns the "glob" builtin for a job directory.
+// It lists the paths that match a pattern in sorted order.
+// A relative pattern is matched in the job directory and gives paths relative to it,
+// like a shell in the job directory.
+// The patterns matched while loading a job are recorded, so the job is reloaded when the matches change.
+func newGlob(jobDir string) *starlark.Builtin {
+	return starlark.NewBuiltin(globVar, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var pattern string
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &pattern); err != nil {
+			return nil, err
+		}
+
+		pattern = expandHome(pattern)
+
+		relative := !filepath.IsAbs(pattern)
+		if relative {
+			pattern = filepath.Join(jobDir, pattern)
+		}
+
+		if globs, ok := thread.Local(globsKey).(*[]string); ok {
+			*globs = append(*globs, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		paths := make([]starlark.Value, 0, len(matches))
+		for _, match := range matches {
+			if relative {
+				match, err = filepath.Rel(jobDir, match)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", b.Name(), err)
+				}
+			}
+
+			paths = append(paths, starlark.String(match))
+		}
+
+		return starlark.NewList(paths), nil
+	})
+}
+
+// hashGlobs hashes patterns and the paths that match them now.
+func hashGlobs(patterns []string) [sha256.Size]byte {
+	h := sha256.New()
+
+	for _, pattern := range patterns {
+		h.Write([]byte(pattern))
+		h.Write([]byte{0})
+
+		// The pattern was valid when the job loaded.
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			h.Write([]byte(match))
+			h.Write([]byte{0})
+		}
+
+		h.Write([]byte{0})
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+
+	return sum
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.starlark.net/starlark"
+)
+
+func TestLoadJobGlob(t *testing.T) {
+	jobDir := filepath.Join(t.TempDir(), "import")
+	if err := os.MkdirAll(filepath.Join(jobDir, "inbox"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.csv", "a.csv", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(jobDir, "inbox", name), nil, filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content := `
+command = ["import.sh"] + glob("inbox/*.csv")
+
+def should_run(**_):
+    return len(glob("inbox/*.json")) > 0
+`
+	jobPath := filepath.Join(jobDir, jobConfigFileName)
+	if err := os.WriteFile(jobPath, []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := loadJob(nil, jobPath)
+	if err != nil {
+		t.Fatalf("loadJob() error = %v", err)
+	}
+
+	want := []string{"import.sh", filepath.Join("inbox", "a.csv"), filepath.Join("inbox", "b.csv")}
+	if diff := cmp.Diff(want, job.Command); diff != "" {
+		t.Errorf("Command mismatch (-want +got):\n%s", diff)
+	}
+
+	// The builtin is evaluated again when "should_run" is called.
+	thread := &starlark.Thread{Name: "test"}
+	result, err := starlark.Call(thread, job.ShouldRun, nil, nil)
+	if err != nil {
+		t.Fatalf(`"should_run" call failed: %v`, err)
+	}
+	if result != starlark.False {
+		t.Errorf(`"should_run" returned %v, want "False"`, result)
+	}
+
+	if err := os.WriteFile(filepath.Join(jobDir, "inbox", "c.json"), nil, filePerms); err != nil {
+		t.Fatal(err)
+	}
+	result, err = starlark.Call(thread, job.ShouldRun, nil, nil)
+	if err != nil {
+		t.Fatalf(`"should_run" call failed: %v`, err)
+	}
+	if result != starlark.True {
+		t.Errorf(`"should_run" returned %v, want "True"`, result)
+	}
+}
+
+func TestGlobAbsolutePattern(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.csv"), nil, filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	thread := &starlark.Thread{Name: "test"}
+	result, err := starlark.Call(thread, newGlob("/elsewhere"), starlark.Tuple{starlark.String(filepath.Join(dir, "*.csv"))}, nil)
+	if err != nil {
+		t.Fatalf("glob() error = %v", err)
+	}
+
+	list, ok := result.(*starlark.List)
+	if !ok || list.Len() != 1 || list.Index(0) != starlark.String(filepath.Join(dir, "data.csv")) {
+		t.Errorf("glob() = %v, want the absolute path", result)
+	}
+
+	if _, err := starlark.Call(thread, newGlob(dir), starlark.Tuple{starlark.String("[")}, nil); err == nil {
+		t.Error("Expected an error for a malformed pattern")
+	}
+}
+
+func TestUpdateReloadsWhenGlobChanges(t *testing.T) {
+	configRoot := t.TempDir()
+
+	path := writeTestJob(t, configRoot, "import", `command = ["import.sh"] + glob("inbox/*.csv")`)
+	inbox := filepath.Join(configRoot, "import", "inbox")
+	if err := os.MkdirAll(inbox, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	jsc := NewScheduler()
+	if _, _, err := jsc.Update(configRoot, path); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if readers := jsc.readersOf(filepath.Join(inbox, "a.csv")); len(readers) != 1 || readers[0] != "import" {
+		t.Errorf("Expected import to match the CSV file, got %v", readers)
+	}
+	if readers := jsc.readersOf(filepath.Join(inbox, "a.txt")); len(readers) != 0 {
+		t.Errorf("Expected no job to match the text file, got %v", readers)
+	}
+
+	res, _, err := jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsNoChanges {
+		t.Errorf(`Expected "jobsNoChanges", got %v`, res)
+	}
+
+	// A new match reloads the job file.
+	if err := os.WriteFile(filepath.Join(inbox, "a.csv"), nil, filePerms); err != nil {
+		t.Fatal(err)
+	}
+	res, job, err := jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsUpdated {
+		t.Errorf(`Expected "jobsUpdated", got %v`, res)
+	}
+
+	want := []string{"import.sh", filepath.Join("inbox", "a.csv")}
+	if diff := cmp.Diff(want, job.Command); diff != "" {
+		t.Errorf("Command mismatch (-want +got):\n%s", diff)
+	}
+}
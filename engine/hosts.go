@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// validateHosts checks the patterns in "hosts".
+func validateHosts(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid %q pattern %q: %w", hostsVar, pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// hostMatches reports whether a hostname matches any of the patterns.
+// A pattern can match the full hostname or the part before the first dot.
+// Patterns can use the wildcards of path.Match, like "nas-*".
+func hostMatches(patterns []string, hostname string) bool {
+	short, _, _ := strings.Cut(hostname, ".")
+
+	for _, pattern := range patterns {
+		for _, name := range []string{hostname, short} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// runsOnThisHost reports whether a job with the "hosts" patterns runs on the current machine.
+// A job without "hosts" runs on every machine.
+func runsOnThisHost(patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return false
+	}
+
+	return hostMatches(patterns, hostname)
+}
+
+// RunsOnThisHost reports whether the job runs on the current machine.
+func (j JobConfig) RunsOnThisHost() bool {
+	return runsOnThisHost(j.Hosts)
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		hostname string
+		want     bool
+	}{
+		{"exact", []string{"laptop", "nas"}, "nas", true},
+		{"no match", []string{"laptop", "nas"}, "desktop", false},
+		{"glob", []string{"nas-*"}, "nas-2", true},
+		{"short name", []string{"laptop"}, "laptop.example.com", true},
+		{"full name", []string{"*.example.com"}, "laptop.example.com", true},
+		{"prefix only", []string{"lap"}, "laptop", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostMatches(tt.patterns, tt.hostname); got != tt.want {
+				t.Errorf("hostMatches(%v, %q) = %v, want %v", tt.patterns, tt.hostname, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostsShouldRun(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	configRoot := t.TempDir()
+
+	tests := []struct {
+		hosts string
+		want  bool
+	}{
+		{`[]`, true},
+		{`["` + hostname + `"]`, true},
+		{`["` + hostname + `-other"]`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hosts, func(t *testing.T) {
+			path := writeTestJob(t, configRoot, "job", "command = [\"true\"]\nhosts = "+tt.hosts+"\nshould_run = lambda **kwargs: True\n")
+
+			_, job, err := NewScheduler().Update(configRoot, path)
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			_, due, err := job.shouldRun(time.Now(), nil)
+			if err != nil {
+				t.Fatalf("shouldRun() error = %v", err)
+			}
+			if due != tt.want {
+				t.Errorf("Expected due = %v, got %v", tt.want, due)
+			}
+		})
+	}
+}
+
+func TestHostsInvalidPattern(t *testing.T) {
+	configRoot := t.TempDir()
+	path := writeTestJob(t, configRoot, "job", "command = [\"true\"]\nhosts = [\"[\"]\n")
+
+	if _, _, err := NewScheduler().Update(configRoot, path); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
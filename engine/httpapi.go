@@ -0,0 +1,284 @@
+package engine
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// NewHTTPHandler returns the handler for the daemon's HTTP API.
+func NewHTTPHandler(jsc *Scheduler, runner Runner) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /hooks/{token}", func(w http.ResponseWriter, req *http.Request) {
+		job, ok := jsc.byTriggerToken(req.PathValue("token"))
+		if !ok {
+			http.Error(w, "unknown trigger token", http.StatusNotFound)
+			return
+		}
+
+		if !job.Enable {
+			http.Error(w, "job is disabled", http.StatusConflict)
+			return
+		}
+
+		if !job.RunsOnThisHost() {
+			http.Error(w, "job doesn't run on this host", http.StatusConflict)
+			return
+		}
+
+		snoozed, err := runner.snoozed(job.Name, runner.Clock.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if snoozed {
+			http.Error(w, "job is disabled with \"regular disable\"", http.StatusConflict)
+			return
+		}
+
+		LogJobPrintf(job.Name, "Triggered by webhook from %v", req.RemoteAddr)
+		runner.AddJob(job)
+
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "queued %s\n", job.Name)
+	})
+
+	mux.HandleFunc("GET /paused", func(w http.ResponseWriter, req *http.Request) {
+		paused, err := pausedState(runner.db, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(paused)
+	})
+
+	mux.HandleFunc("GET /queues", func(w http.ResponseWriter, req *http.Request) {
+		views, err := runner.queueViews(runner.Clock.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		queues := []queueJSON{}
+		for _, view := range views {
+			queues = append(queues, newQueueJSON(view))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(queues)
+	})
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, req *http.Request) {
+		now := runner.Clock.Now()
+
+		views, err := runner.queueViews(now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = dashboardTemplate.Execute(w, struct {
+			Now    time.Time
+			Queues []queueView
+		}{now, views})
+		if err != nil {
+			log.Printf("Failed to render dashboard: %v", err)
+		}
+	})
+
+	return mux
+}
+
+// queueJSON is a queue in the response of "GET /queues".
+type queueJSON struct {
+	Queue  string       `json:"queue"`
+	Paused bool         `json:"paused"`
+	Jobs   []queuedJSON `json:"jobs"`
+}
+
+type queuedJSON struct {
+	Job     string `json:"job"`
+	Running bool   `json:"running"`
+	// Only the running job has a start time.
+	Started string `json:"started,omitempty"`
+	// Only the pending jobs have an estimated start time.
+	EstimatedStart     string  `json:"estimated_start,omitempty"`
+	MeanRuntimeSeconds float64 `json:"mean_runtime_seconds"`
+}
+
+func newQueueJSON(view queueView) queueJSON {
+	queue := queueJSON{Queue: view.Queue, Paused: view.Paused, Jobs: []queuedJSON{}}
+
+	for _, job := range view.Jobs {
+		queued := queuedJSON{
+			Job:                job.Job,
+			Running:            job.Running,
+			MeanRuntimeSeconds: job.MeanRuntime.Seconds(),
+		}
+		if !job.Started.IsZero() {
+			queued.Started = job.Started.Format(time.RFC3339)
+		}
+		if !job.EstimatedStart.IsZero() {
+			queued.EstimatedStart = job.EstimatedStart.Format(time.RFC3339)
+		}
+
+		queue.Jobs = append(queue.Jobs, queued)
+	}
+
+	return queue
+}
+
+// dashboardTemplate shows every queue as a column.
+// The page reloads itself to stay current.
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"clock": func(t time.Time) string {
+		return t.Format(time.DateTime)
+	},
+	"duration": FormatDuration,
+}).Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="10">
+<title>regular queues</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+.queues { display: flex; gap: 1em; align-items: flex-start; overflow-x: auto; }
+.queue { min-width: 14em; border: 1px solid #ccc; border-radius: 4px; padding: 0.5em; }
+.queue h2 { font-size: 1.1em; margin: 0 0 0.5em; }
+.job { border: 1px solid #ddd; border-radius: 4px; margin-bottom: 0.5em; padding: 0.4em; }
+.running { background: #dfd; border-color: #6a6; font-weight: bold; }
+.paused { color: #a60; }
+.time { color: #555; font-size: 0.9em; font-weight: normal; }
+</style>
+</head>
+<body>
+<h1>Queues</h1>
+<p class="time">As of {{clock .Now}}</p>
+{{if not .Queues}}<p>No queued jobs.</p>{{end}}
+<div class="queues">
+{{- range .Queues}}
+<div class="queue">
+<h2>{{.Queue}}{{if .Paused}} <span class="paused">(paused)</span>{{end}}</h2>
+{{- range .Jobs}}
+{{- if .Running}}
+<div class="job running">{{.Job}}<div class="time">Running since {{clock .Started}}{{if .MeanRuntime}}, usually takes {{duration .MeanRuntime}}{{end}}</div></div>
+{{- else}}
+<div class="job">{{.Job}}<div class="time">Starts around {{clock .EstimatedStart}}</div></div>
+{{- end}}
+{{- end}}
+</div>
+{{- end}}
+</div>
+</body>
+</html>
+`))
+
+// pausedJSON is the response of "GET /paused".
+type pausedJSON struct {
+	Jobs   []pausedJobJSON   `json:"jobs"`
+	Queues []pausedQueueJSON `json:"queues"`
+}
+
+type pausedJobJSON struct {
+	Job   string `json:"job"`
+	Since string `json:"since"`
+	// Empty when the job is paused until "regular enable".
+	Until string `json:"until,omitempty"`
+}
+
+type pausedQueueJSON struct {
+	Queue string `json:"queue"`
+	Since string `json:"since"`
+}
+
+// pausedState lists the snoozed jobs and the paused queues at t.
+func pausedState(db *AppDB, t time.Time) (pausedJSON, error) {
+	paused := pausedJSON{
+		Jobs:   []pausedJobJSON{},
+		Queues: []pausedQueueJSON{},
+	}
+
+	snoozes, err := db.Snoozes()
+	if err != nil {
+		return paused, fmt.Errorf("failed to get snoozed jobs: %w", err)
+	}
+
+	for _, snooze := range snoozes {
+		if !snooze.ActiveAt(t) {
+			continue
+		}
+
+		job := pausedJobJSON{Job: snooze.JobName, Since: snooze.Since.Format(time.RFC3339)}
+		if !snooze.Until.IsZero() {
+			job.Until = snooze.Until.Format(time.RFC3339)
+		}
+
+		paused.Jobs = append(paused.Jobs, job)
+	}
+
+	queues, err := db.PausedQueues()
+	if err != nil {
+		return paused, fmt.Errorf("failed to get paused queues: %w", err)
+	}
+
+	for queue, since := range queues {
+		paused.Queues = append(paused.Queues, pausedQueueJSON{Queue: queue, Since: since.Format(time.RFC3339)})
+	}
+
+	slices.SortFunc(paused.Queues, func(a, b pausedQueueJSON) int {
+		return strings.Compare(a.Queue, b.Queue)
+	})
+
+	return paused, nil
+}
+
+// ServeHTTPAPI serves the HTTP API on the listener in the background.
+// Close the returned server to stop it.
+func ServeHTTPAPI(listener net.Listener, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP server error: %v", err)
+		}
+	}()
+
+	return server
+}
+
+// Find the job with the given trigger token.
+// Jobs without a token can't be triggered.
+func (jsc *Scheduler) byTriggerToken(token string) (JobConfig, bool) {
+	if token == "" {
+		return JobConfig{}, false
+	}
+
+	jsc.mu.RLock()
+	defer jsc.mu.RUnlock()
+
+	for _, job := range jsc.byName {
+		if job.TriggerToken != "" && subtle.ConstantTimeCompare([]byte(job.TriggerToken), []byte(token)) == 1 {
+			return job, true
+		}
+	}
+
+	return JobConfig{}, false
+}
@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPHooks(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	jsc := NewScheduler()
+	jsc.byName["hooked"] = JobConfig{Name: "hooked", Enable: true, TriggerToken: "s3cret"}
+	jsc.byName["disabled"] = JobConfig{Name: "disabled", TriggerToken: "off"}
+	jsc.byName["plain"] = JobConfig{Name: "plain", Enable: true}
+	jsc.byName["snoozed"] = JobConfig{Name: "snoozed", Enable: true, TriggerToken: "zzz"}
+	runner.rememberSnooze(Snooze{JobName: "snoozed", Since: time.Now()})
+
+	handler := NewHTTPHandler(jsc, runner)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"trigger", http.MethodPost, "/hooks/s3cret", http.StatusAccepted},
+		{"wrong method", http.MethodGet, "/hooks/s3cret", http.StatusMethodNotAllowed},
+		{"unknown token", http.MethodPost, "/hooks/nope", http.StatusNotFound},
+		{"disabled job", http.MethodPost, "/hooks/off", http.StatusConflict},
+		{"snoozed job", http.MethodPost, "/hooks/zzz", http.StatusConflict},
+		{"empty token", http.MethodPost, "/hooks/", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+			}
+		})
+	}
+
+	if len(runner.queues["hooked"].jobs) != 1 {
+		t.Errorf("Expected 1 job in queue, got %d", len(runner.queues["hooked"].jobs))
+	}
+
+	if len(runner.queues) != 1 {
+		t.Errorf("Expected only the triggered job to be queued, got %v", runner.summarize())
+	}
+}
+
+func TestHTTPPaused(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	now := time.Now()
+	if err := db.SnoozeJob("snoozed", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SnoozeJob("ended", now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PauseQueue("backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHTTPHandler(NewScheduler(), runner)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/paused", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var paused pausedJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &paused); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(paused.Jobs) != 1 || paused.Jobs[0].Job != "snoozed" || paused.Jobs[0].Until == "" {
+		t.Errorf("Expected only the snoozed job with an end, got %+v", paused.Jobs)
+	}
+	if len(paused.Queues) != 1 || paused.Queues[0].Queue != "backup" {
+		t.Errorf("Expected the backup queue, got %+v", paused.Queues)
+	}
+}
+
+func TestHTTPQueues(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	// The runner loads the paused queues when it starts.
+	if err := db.PauseQueue("backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	run := func(d time.Duration) CompletedJob {
+		return CompletedJob{Started: now.Add(-d), Finished: now}
+	}
+
+	runner.history["build"] = []CompletedJob{run(10 * time.Minute), run(20 * time.Minute), {Skipped: true}}
+	runner.history["test"] = []CompletedJob{run(5 * time.Minute)}
+	runner.history["deploy"] = []CompletedJob{}
+
+	runner.queues["ci"] = jobQueue{
+		activeJob:   true,
+		activeSince: now.Add(-5 * time.Minute),
+		jobs: []JobConfig{
+			{Name: "build"},
+			{Name: "test"},
+			{Name: "deploy", startAt: now.Add(time.Hour)},
+		},
+	}
+	runner.queues["idle"] = newJobQueue()
+
+	views, err := runner.queueViews(now)
+	if err != nil {
+		t.Fatalf("queueViews() error = %v", err)
+	}
+
+	if len(views) != 2 || views[0].Queue != "backup" || !views[0].Paused || views[1].Queue != "ci" {
+		t.Fatalf("Expected the paused backup queue and the ci queue, got %+v", views)
+	}
+
+	jobs := views[1].Jobs
+	if len(jobs) != 3 {
+		t.Fatalf("Expected 3 jobs, got %+v", jobs)
+	}
+
+	// The build usually takes 15 minutes and has run for 5.
+	if !jobs[0].Running || !jobs[0].Started.Equal(now.Add(-5*time.Minute)) || jobs[0].MeanRuntime != 15*time.Minute {
+		t.Errorf("Expected the running build, got %+v", jobs[0])
+	}
+	if want := now.Add(10 * time.Minute); jobs[1].Running || !jobs[1].EstimatedStart.Equal(want) {
+		t.Errorf("Expected the test to start at %v, got %+v", want, jobs[1])
+	}
+	if want := now.Add(time.Hour); !jobs[2].EstimatedStart.Equal(want) {
+		t.Errorf("Expected the deploy to wait until %v, got %+v", want, jobs[2])
+	}
+
+	handler := NewHTTPHandler(NewScheduler(), runner)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/queues", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var queues []queueJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &queues); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(queues) != 2 || len(queues[1].Jobs) != 3 || !queues[1].Jobs[0].Running {
+		t.Errorf("Expected the backup and ci queues, got %+v", queues)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `<div class="job running">build`) {
+		t.Errorf("Expected the running job to be highlighted, got %q", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown path, got %d", rec.Code)
+	}
+}
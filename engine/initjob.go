@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The job file "regular init" creates.
+const exampleJob = `# Created by "regular init".
+# See the README for all options.
+
+# Run once a day.
+schedule = every(one_day)
+
+# The command runs in this directory.
+command = ["sh", "-c", "echo Hello from Regular"]
+
+# Send notifications when the job fails (the default).
+notify = "on-failure"
+`
+
+// InitJob creates a job directory with an example job file and returns the path of the file.
+// It doesn't overwrite an existing job.
+func InitJob(config Config, name string) (string, error) {
+	if err := validateJobName(name); err != nil {
+		return "", err
+	}
+
+	return writeNewJob(config, name, exampleJob)
+}
+
+// jobFilePath returns the path of the job file for a new job in the first config root.
+func jobFilePath(config Config, name string) string {
+	return filepath.Join(config.ConfigRoot, name, jobConfigFileName)
+}
+
+// writeNewJob creates the job directory and the job file for a new job.
+// It fails if the job file exists.
+func writeNewJob(config Config, name, src string) (string, error) {
+	path := jobFilePath(config, name)
+	if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+		return "", fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, filePerms)
+	if err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("job %q already exists", name)
+		}
+
+		return "", fmt.Errorf("failed to create job file: %w", err)
+	}
+
+	if _, err := f.WriteString(src); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write job file: %w", err)
+	}
+
+	return path, f.Close()
+}
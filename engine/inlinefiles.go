@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dbohdan.com/denv"
+)
+
+// inlineFileEnvVar returns the environment variable with the path of an inline file.
+// For example, "config.json" is in "REGULAR_FILE_CONFIG_JSON".
+func inlineFileEnvVar(name string) string {
+	return fileEnvVarPrefix + strings.Map(func(r rune) rune {
+		switch {
+
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// validateInlineFiles checks that the names of inline files are file names
+// and that every file gets its own environment variable.
+func validateInlineFiles(files map[string]string) error {
+	byVar := make(map[string]string)
+
+	for _, name := range sortedKeys(files) {
+		if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+			return fmt.Errorf("invalid %q file name: %q", filesVar, name)
+		}
+
+		envVar := inlineFileEnvVar(name)
+		if other, ok := byVar[envVar]; ok {
+			return fmt.Errorf("%q files %q and %q have the same variable %s", filesVar, other, name, envVar)
+		}
+		byVar[envVar] = name
+	}
+
+	return nil
+}
+
+// writeInlineFiles writes the inline files of a job to a new temporary directory.
+// It returns the directory and the environment variables with its path and the paths of the files.
+// The caller removes the directory.
+func writeInlineFiles(files map[string]string) (string, denv.Env, error) {
+	dir, err := os.MkdirTemp("", "regular-files-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create directory for inline files: %w", err)
+	}
+
+	env := denv.Env{filesDirEnvVar: dir}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.WriteFile(path, []byte(content), filePerms); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", nil, fmt.Errorf("failed to write inline file: %w", err)
+		}
+
+		env[inlineFileEnvVar(name)] = path
+	}
+
+	return dir, env, nil
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestInlineFileEnvVar(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"config.json", "REGULAR_FILE_CONFIG_JSON"},
+		{"Sites-2.txt", "REGULAR_FILE_SITES_2_TXT"},
+		{"ключ", "REGULAR_FILE_____"},
+	}
+
+	for _, tt := range tests {
+		if got := inlineFileEnvVar(tt.name); got != tt.want {
+			t.Errorf("inlineFileEnvVar(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInlineFilesOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			src:  `files = {"config.json": '{"verbose": true}', "hosts.txt": "a\nb\n"}`,
+		},
+		{
+			name:    "not a dict",
+			src:     `files = ["config.json"]`,
+			wantErr: "isn't a dictionary",
+		},
+		{
+			name:    "path",
+			src:     `files = {"../config.json": ""}`,
+			wantErr: "invalid",
+		},
+		{
+			name:    "same variable",
+			src:     `files = {"a.txt": "", "a-txt": ""}`,
+			wantErr: "same variable",
+		},
+		{
+			name:    "not a string",
+			src:     `files = {"a.txt": 1}`,
+			wantErr: "isn't Starlark string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestJob(t, t.TempDir(), "files", tt.src)
+
+			job, err := loadJob(denv.Env{}, path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadJob() error = %v", err)
+			}
+
+			if job.Files["hosts.txt"] != "a\nb\n" || len(job.Files) != 2 {
+				t.Errorf("Unexpected files: %v", job.Files)
+			}
+		})
+	}
+}
+
+func TestInlineFilesRun(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	runner.AddJob(JobConfig{
+		Name:    "inline",
+		Command: []string{"sh", "-c", `cat "$REGULAR_FILE_CONFIG_JSON"; echo "$REGULAR_FILES_DIR"`},
+		Env:     denv.OS(),
+		Files:   map[string]string{"config.json": `{"verbose": true}` + "\n"},
+		Stdout:  &stdout,
+	})
+	if err := runner.RunQueueHead("inline"); err != nil {
+		t.Fatalf("RunQueueHead() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || lines[0] != `{"verbose": true}` {
+		t.Fatalf("Expected the file contents and the directory, got %q", stdout.String())
+	}
+
+	if _, err := os.Stat(lines[1]); !os.IsNotExist(err) {
+		t.Errorf("Expected the directory to be removed after the run, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// inputHash returns a hash of what a run of the job depends on:
+// the command, the environment, the inline files, and the files that match the "inputs" patterns.
+// Directories are hashed with their contents.
+func (j JobConfig) inputHash() (string, error) {
+	h := sha256.New()
+
+	command, err := json.Marshal(j.Command)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "command %s\n", command)
+
+	for _, key := range j.Env.Keys() {
+		fmt.Fprintf(h, "env %q=%q\n", key, j.Env[key])
+	}
+
+	for _, name := range sortedKeys(j.Files) {
+		fmt.Fprintf(h, "inline %q %x\n", name, sha256.Sum256([]byte(j.Files[name])))
+	}
+
+	jobDir := j.Env[jobDirEnvVar]
+	for _, pattern := range j.Inputs {
+		fmt.Fprintf(h, "pattern %q\n", pattern)
+
+		pattern = expandHome(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(jobDir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid %q pattern: %w", inputsVar, err)
+		}
+
+		for _, match := range matches {
+			if err := hashTree(h, match); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashTree adds the paths and the contents of the files under root to a hash.
+// It follows symbolic links and hashes each directory once, so a link loop ends.
+func hashTree(h hash.Hash, root string) error {
+	return hashTreeOnce(h, root, make(map[string]struct{}))
+}
+
+func hashTreeOnce(h hash.Hash, root string, seen map[string]struct{}) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+
+		case entry.IsDir():
+			real, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := seen[real]; ok {
+				return filepath.SkipDir
+			}
+			seen[real] = struct{}{}
+
+			return nil
+
+		case entry.Type()&fs.ModeSymlink != 0:
+			info, err := os.Stat(path)
+			if err != nil {
+				// Hash where a dangling link points, so fixing it changes the hash.
+				target, _ := os.Readlink(path)
+				fmt.Fprintf(h, "link %q %q\n", path, target)
+
+				return nil
+			}
+
+			if info.IsDir() {
+				real, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(h, "link %q %q\n", path, real)
+
+				return hashTreeOnce(h, real, seen)
+			}
+
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+		case !entry.Type().IsRegular():
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fileHash := sha256.New()
+		if _, err := io.Copy(fileHash, f); err != nil {
+			return fmt.Errorf("failed to hash %q: %w", path, err)
+		}
+
+		fmt.Fprintf(h, "file %q %x\n", path, fileHash.Sum(nil))
+
+		return nil
+	})
+}
+
+// lastSuccessfulInputHash returns the input hash of the last successful run of a job with one.
+// It returns an empty string if there is none.
+func (c *AppDB) lastSuccessfulInputHash(jobName string) (string, error) {
+	rows, err := c.db.Query(`
+		SELECT `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE job_name = ? AND input_hash != ''
+		ORDER BY id DESC`,
+		jobName,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		completed, err := scanCompletedJob(rows)
+		if err != nil {
+			return "", err
+		}
+
+		// Success depends on the exit codes saved with the run, so check it in Go.
+		if completed.IsSuccess() {
+			return completed.InputHash, nil
+		}
+	}
+
+	return "", rows.Err()
+}
+
+// unchangedSinceSuccess reports whether a job has the same input hash as its last successful run.
+func (c *AppDB) unchangedSinceSuccess(jobName, inputHash string) (bool, error) {
+	last, err := c.lastSuccessfulInputHash(jobName)
+	if err != nil {
+		return false, err
+	}
+
+	return last != "" && last == inputHash, nil
+}
+
+// checkUnchanged hashes the inputs of a job with "skip_unchanged" and compares the hash with the last successful run.
+// If hashing fails, the job runs without a hash.
+func (r Runner) checkUnchanged(job JobConfig) (inputHash string, unchanged bool) {
+	inputHash, err := job.inputHash()
+	if err != nil {
+		LogJobPrintf(job.Name, "Failed to hash inputs: %v", err)
+		return "", false
+	}
+
+	unchanged, err = r.db.unchangedSinceSuccess(job.Name, inputHash)
+	if err != nil {
+		LogJobPrintf(job.Name, "Failed to look up last successful run: %v", err)
+		return inputHash, false
+	}
+
+	return inputHash, unchanged
+}
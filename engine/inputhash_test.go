@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestJobConfigInputHash(t *testing.T) {
+	jobDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(jobDir, "data", "nested"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(filepath.Join(jobDir, name), []byte(content), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("data/a.csv", "1")
+	write("data/nested/b.csv", "2")
+	write("ignored.txt", "3")
+
+	job := JobConfig{
+		Command: []string{"process"},
+		Env:     denv.Env{jobDirEnvVar: jobDir, "MODE": "fast"},
+		Inputs:  []string{"data"},
+	}
+
+	hash := func(j JobConfig) string {
+		t.Helper()
+
+		h, err := j.inputHash()
+		if err != nil {
+			t.Fatalf("inputHash() error = %v", err)
+		}
+
+		return h
+	}
+
+	original := hash(job)
+	if hash(job) != original {
+		t.Error("Expected the same hash for the same inputs")
+	}
+
+	write("ignored.txt", "changed")
+	if hash(job) != original {
+		t.Error("Expected files outside the inputs not to change the hash")
+	}
+
+	write("data/nested/b.csv", "changed")
+	changedFile := hash(job)
+	if changedFile == original {
+		t.Error("Expected a changed input file to change the hash")
+	}
+
+	changedEnv := job
+	changedEnv.Env = denv.Env{jobDirEnvVar: jobDir, "MODE": "slow"}
+	if hash(changedEnv) == changedFile {
+		t.Error("Expected a changed environment to change the hash")
+	}
+
+	changedCommand := job
+	changedCommand.Command = []string{"process", "--all"}
+	if hash(changedCommand) == changedFile {
+		t.Error("Expected a changed command to change the hash")
+	}
+}
+
+func TestJobConfigInputHashSymlinks(t *testing.T) {
+	jobDir := t.TempDir()
+	shared := t.TempDir()
+
+	sharedFile := filepath.Join(shared, "config.json")
+	if err := os.WriteFile(sharedFile, []byte("1"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(shared, "lib"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	libFile := filepath.Join(shared, "lib", "util.sh")
+	if err := os.WriteFile(libFile, []byte("1"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(jobDir, "data"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"data/config.json": sharedFile,
+		"data/lib":         filepath.Join(shared, "lib"),
+		"data/loop":        filepath.Join(jobDir, "data"),
+	} {
+		if err := os.Symlink(target, filepath.Join(jobDir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := JobConfig{
+		Command: []string{"process"},
+		Env:     denv.Env{jobDirEnvVar: jobDir},
+		Inputs:  []string{"data"},
+	}
+
+	hash := func() string {
+		t.Helper()
+
+		h, err := job.inputHash()
+		if err != nil {
+			t.Fatalf("inputHash() error = %v", err)
+		}
+
+		return h
+	}
+
+	original := hash()
+
+	if err := os.WriteFile(sharedFile, []byte("2"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+	changedFile := hash()
+	if changedFile == original {
+		t.Error("Expected a changed linked file to change the hash")
+	}
+
+	if err := os.WriteFile(libFile, []byte("2"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+	if hash() == changedFile {
+		t.Error("Expected a changed file in a linked directory to change the hash")
+	}
+}
+
+func TestJobRunnerSkipUnchanged(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	// A skipped run isn't notified about, including when "dedupe_failures" counts it as a success.
+	for _, dedupe := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dedupe=%v", dedupe), func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			db, err := OpenAppDB(tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create app database: %v", err)
+			}
+			defer db.Close()
+
+			notified := 0
+			notify := func(jobName string, completed CompletedJob) error {
+				notified++
+				return nil
+			}
+
+			runner, err := NewRunner(db, notify, tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create job runner: %v", err)
+			}
+
+			jobDir := filepath.Join(tmpDir, "config")
+			if err := os.Mkdir(jobDir, dirPerms); err != nil {
+				t.Fatal(err)
+			}
+			input := filepath.Join(jobDir, "input.txt")
+			counter := filepath.Join(tmpDir, "runs")
+
+			run := func(content string) {
+				t.Helper()
+
+				if err := os.WriteFile(input, []byte(content), filePerms); err != nil {
+					t.Fatal(err)
+				}
+
+				runner.AddJob(JobConfig{
+					Name:           "encode",
+					Command:        []string{"sh", "-c", `echo x >> "$0"`, counter},
+					Env:            denv.Env{jobDirEnvVar: jobDir, "PATH": os.Getenv("PATH")},
+					Inputs:         []string{"input.txt"},
+					Notify:         NotifyAlways,
+					SkipUnchanged:  true,
+					DedupeFailures: dedupe,
+				})
+				if err := runner.RunQueueHead("encode"); err != nil {
+					t.Fatalf("RunQueueHead() error = %v", err)
+				}
+			}
+
+			run("a")
+			run("a")
+			run("b")
+
+			data, err := os.ReadFile(counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runs := len(data) / len("x\n"); runs != 2 {
+				t.Errorf("Expected the command to run 2 times, ran %d times", runs)
+			}
+
+			history, err := db.History("encode", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 3 || history[0].Skipped || !history[1].Skipped || history[2].Skipped {
+				t.Errorf("Expected only the second run to be skipped, got %+v", history)
+			}
+			if notified != 2 {
+				t.Errorf("Expected 2 notifications, got %d", notified)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"crypto/sha256"
+
+	"go.starlark.net/starlark"
+
+	"dbohdan.com/denv"
+)
+
+// A compiled job file and the hashes of the inputs that produced the loaded job.
+// The scheduler uses them to skip reloading jobs whose files haven't changed.
+type cachedJob struct {
+	envHash [sha256.Size]byte
+	program *starlark.Program
+	srcHash [sha256.Size]byte
+	// The names of the jobs the file defines.
+	jobs []string
+	// The files the job file read and the hash of their contents.
+	readFiles []string
+	readHash  [sha256.Size]byte
+	// The patterns the job file matched with "glob" and the hash of their matches.
+	globs    []string
+	globHash [sha256.Size]byte
+	// The commands the job file looked up with "which" and the hash of their paths.
+	whichLookups []whichLookup
+	whichHash    [sha256.Size]byte
+}
+
+func hashEnv(env denv.Env) [sha256.Size]byte {
+	h := sha256.New()
+
+	for _, key := range env.Keys() {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(env[key]))
+		h.Write([]byte{0})
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+
+	return sum
+}
@@ -0,0 +1,627 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mna/starstruct"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+
+	"dbohdan.com/denv"
+	"dbohdan.com/regular/starlarkutil"
+)
+
+type JobConfig struct {
+	Artifacts      []string           `starlark:"artifacts"`
+	ArtifactsKeep  int                `starlark:"artifacts_keep"`
+	Attach         []string           `starlark:"attach"`
+	Command        []string           `starlark:"command"`
+	Deadline       string             `starlark:"deadline"`
+	DeadlineKill   bool               `starlark:"deadline_kill"`
+	DedupeFailures bool               `starlark:"dedupe_failures"`
+	Duplicate      bool               `starlark:"duplicate"`
+	Enable         bool               `starlark:"enable"`
+	Env            denv.Env           `starlark:"-"`
+	Escalate       map[int][]string   `starlark:"-"`
+	EnvBlock       []string           `starlark:"env_block"`
+	EnvPass        []string           `starlark:"env_pass"`
+	Executor       string             `starlark:"executor"`
+	ExitMeanings   map[int]string     `starlark:"-"`
+	Files          map[string]string  `starlark:"-"`
+	Hosts          []string           `starlark:"hosts"`
+	Inputs         []string           `starlark:"inputs"`
+	Jitter         time.Duration      `starlark:"jitter"`
+	Lock           bool               `starlark:"lock"`
+	Log            bool               `starlark:"log"`
+	LogPath        string             `starlark:"log_path"`
+	LogTo          []string           `starlark:"log_to"`
+	MaxOutput      int64              `starlark:"max_output"`
+	MaxQueue       int                `starlark:"max_queue"`
+	Name           string             `starlark:"-"`
+	Notify         NotifyMode         `starlark:"-"`
+	NotifyLines    *int               `starlark:"notify_lines"`
+	NotifyStdout   *bool              `starlark:"notify_stdout"`
+	NotifyTo       []string           `starlark:"notify_to"`
+	NotifyVia      []string           `starlark:"notify_via"`
+	OnComplete     func(CompletedJob) `starlark:"-"`
+	Queue          string             `starlark:"queue"`
+	QueueOverflow  OverflowPolicy     `starlark:"-"`
+	ResultFile     bool               `starlark:"result_file"`
+	Retries        int                `starlark:"retries"`
+	RetryDelay     time.Duration      `starlark:"retry_delay"`
+	ShouldRun      starlark.Value     `starlark:"should_run"`
+	SkipUnchanged  bool               `starlark:"skip_unchanged"`
+	StateQuota     int64              `starlark:"state_quota"`
+	Stderr         io.Writer          `starlark:"-"`
+	SuccessCodes   []int              `starlark:"success_exit_codes"`
+	Stdout         io.Writer          `starlark:"-"`
+	Syslog         bool               `starlark:"-"`
+	Tags           []string           `starlark:"tags"`
+	Timeout        time.Duration      `starlark:"timeout"`
+	TriggerToken   string             `starlark:"trigger_token"`
+
+	// The schedule of a crontab job or a job with "schedule" in one line.
+	scheduleSpec string
+	// When a queued job should start at the earliest.
+	// The jobs after it in the queue can run before then.
+	startAt time.Time
+	// When the job was added to the queue.
+	queuedAt time.Time
+	// How many times the run has been retried.
+	retried int
+	// Problems with the job file that don't stop the job from loading.
+	warnings []string
+	// The files the job file read with "read_file".
+	readFiles []string
+	// The absolute patterns the job file matched with "glob".
+	globs []string
+	// The commands the job file looked up with "which".
+	whichLookups []whichLookup
+}
+
+func (j JobConfig) QueueName() string {
+	if j.Queue == "" {
+		return j.Name
+	}
+
+	return j.Queue
+}
+
+// ShouldRunHistoryLength is how many recent runs "should_run" receives in "history".
+const ShouldRunHistoryLength = 10
+
+// shouldRun calls "should_run" with the time and the recent runs of the job, newest first.
+// It returns when the job should start and whether it is due.
+// "should_run" can return a boolean, a delay in seconds, or a Unix timestamp.
+func (j JobConfig) shouldRun(t time.Time, history []CompletedJob) (time.Time, bool, error) {
+	start, due, _, err := j.callShouldRun(t, history)
+	return start, due, err
+}
+
+// shouldRunCall describes a call to "should_run" for "regular why".
+type shouldRunCall struct {
+	// The keyword arguments with the history summarized.
+	args string
+	// The return value.
+	result string
+}
+
+// callShouldRun is shouldRun that also returns the arguments and the result of the call.
+// The call is empty when "should_run" isn't called.
+func (j JobConfig) callShouldRun(t time.Time, history []CompletedJob) (time.Time, bool, shouldRunCall, error) {
+	var call shouldRunCall
+
+	if !j.Enable || !j.RunsOnThisHost() {
+		return time.Time{}, false, call, nil
+	}
+
+	exitStatus := -1
+	finished := -1
+	started := -1
+	if len(history) > 0 {
+		exitStatus = history[0].ExitStatus
+		finished = int(history[0].Finished.Unix())
+		started = int(history[0].Started.Unix())
+	}
+
+	runs := make([]starlark.Value, len(history))
+	for i, completed := range history {
+		runs[i] = starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"error":       starlark.String(completed.Error),
+			"exit_status": starlark.MakeInt(completed.ExitStatus),
+			"finished":    starlark.MakeInt(int(completed.Finished.Unix())),
+			"started":     starlark.MakeInt(int(completed.Started.Unix())),
+		})
+	}
+
+	kvpairs := []starlark.Tuple{
+		starlark.Tuple{
+			starlark.String("minute"),
+			starlark.MakeInt(t.Minute()),
+		},
+		starlark.Tuple{
+			starlark.String("hour"),
+			starlark.MakeInt(t.Hour()),
+		},
+		starlark.Tuple{
+			starlark.String("day"),
+			starlark.MakeInt(t.Day()),
+		},
+		starlark.Tuple{
+			starlark.String("month"),
+			starlark.MakeInt(int(t.Month())),
+		},
+		starlark.Tuple{
+			starlark.String("dow"),
+			starlark.MakeInt(int(t.Weekday())),
+		},
+		starlark.Tuple{
+			starlark.String("timestamp"),
+			starlark.MakeInt(int(t.Unix())),
+		},
+		starlark.Tuple{
+			starlark.String("exit_status"),
+			starlark.MakeInt(exitStatus),
+		},
+		starlark.Tuple{
+			starlark.String("finished"),
+			starlark.MakeInt(finished),
+		},
+		starlark.Tuple{
+			starlark.String("started"),
+			starlark.MakeInt(started),
+		},
+		starlark.Tuple{
+			starlark.String("history"),
+			starlark.NewList(runs),
+		},
+	}
+
+	call.args = formatShouldRunArgs(kvpairs, len(history))
+
+	thread, done := starlarkutil.NewThread("schedule", starlarkMaxSteps, starlarkTimeout)
+	defer done()
+
+	result, err := starlark.Call(thread, j.ShouldRun, nil, kvpairs)
+	if err != nil {
+		return time.Time{}, false, call, fmt.Errorf(`failed to call "should_run": %v`, err)
+	}
+	call.result = result.String()
+
+	switch result := result.(type) {
+
+	case starlark.Bool:
+		if !result {
+			return time.Time{}, false, call, nil
+		}
+
+		return t, true, call, nil
+
+	case starlark.Int:
+		n, ok := result.Int64()
+		if !ok || n < 0 {
+			return time.Time{}, false, call, fmt.Errorf(`"should_run" returned bad delay or timestamp: %v`, result)
+		}
+
+		if n >= minShouldRunTimestamp {
+			return time.Unix(n, 0), true, call, nil
+		}
+
+		return t.Add(time.Duration(n) * time.Second), true, call, nil
+
+	default:
+		return time.Time{}, false, call, fmt.Errorf(`"should_run" returned bad value: %v`, result)
+	}
+}
+
+// ScheduleSummary describes the schedule of the job in one line.
+// It is the schedule of a crontab job, the "schedule" of the job, or the first line of the docstring of "should_run".
+func (j JobConfig) ScheduleSummary() string {
+	if j.scheduleSpec != "" {
+		return j.scheduleSpec
+	}
+
+	switch fn := j.ShouldRun.(type) {
+
+	case nil:
+		return "none"
+
+	case *starlark.Function:
+		if line, _, _ := strings.Cut(strings.TrimSpace(fn.Doc()), "\n"); line != "" {
+			return line
+		}
+	}
+
+	return shouldRunVar + "()"
+}
+
+// NextDue calls "should_run" for t and the start of every following minute within the horizon.
+// It returns the first time the job is due or false if it isn't due within the horizon.
+// The probe assumes the history of the job, newest first, stays the same.
+func (j JobConfig) NextDue(t time.Time, history []CompletedJob, horizon time.Duration) (time.Time, bool, error) {
+	if !j.Enable || !j.RunsOnThisHost() || j.ShouldRun == nil {
+		return time.Time{}, false, nil
+	}
+
+	probe := t
+	for probe.Sub(t) <= horizon {
+		start, due, err := j.shouldRun(probe, history)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		if due {
+			return start, true, nil
+		}
+
+		probe = probe.Truncate(time.Minute).Add(time.Minute)
+	}
+
+	return time.Time{}, false, nil
+}
+
+func (j JobConfig) AddToQueueIfDue(runner Runner, t time.Time) error {
+	d, err := j.decide(runner, t)
+	if err != nil {
+		d.Reason = "error: " + err.Error()
+	}
+	runner.recordDecision(j.Name, d)
+
+	if err != nil {
+		return err
+	}
+
+	if d.Queued {
+		j.startAt = d.start
+		runner.AddJob(j)
+	}
+
+	return nil
+}
+
+func jobPredeclared(dir string, envDict *starlark.Dict) starlark.StringDict {
+	predeclared := starlark.StringDict{
+		dailyAtVar:   starlark.NewBuiltin(dailyAtVar, dailyAt),
+		envVar:       envDict,
+		everyVar:     starlark.NewBuiltin(everyVar, every),
+		globVar:      newGlob(dir),
+		jobVar:       starlark.NewBuiltin(jobVar, jobBuiltin),
+		jsonVar:      json.Module,
+		oneDayVar:    starlark.MakeInt(24 * 60 * 60),
+		oneHourVar:   starlark.MakeInt(60 * 60),
+		oneMinuteVar: starlark.MakeInt(60),
+		readFileVar:  newReadFile(dir),
+		whichVar:     newWhich(dir, envDict),
+	}
+	starlarkutil.AddPredeclared(predeclared)
+
+	return predeclared
+}
+
+// Compile a job file without executing it.
+// The program can be reused to load the job with a different env.
+// Top-level "if" and "for" statements are allowed, so a job file can call "job" in a loop.
+func compileJob(path string, src []byte) (*starlark.Program, error) {
+	predeclared := jobPredeclared(jobDir(path), starlark.NewDict(0))
+
+	_, program, err := starlark.SourceProgramOptions(&syntax.FileOptions{TopLevelControl: true}, path, src, predeclared.Has)
+	if err != nil {
+		return nil, err
+	}
+
+	return program, nil
+}
+
+// loadJob loads a job file that defines one job.
+// The job file can load the template file in the directory above its job directory.
+func loadJob(env denv.Env, path string) (JobConfig, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return JobConfig{Name: jobNameFromPath(path)}, err
+	}
+
+	program, err := compileJob(path, src)
+	if err != nil {
+		return JobConfig{Name: jobNameFromPath(path)}, err
+	}
+
+	jobs, err := loadJobProgram(env, filepath.Dir(jobDir(path)), path, program)
+	if err != nil {
+		return JobConfig{Name: jobNameFromPath(path)}, err
+	}
+
+	if len(jobs) != 1 {
+		return JobConfig{Name: jobNameFromPath(path)}, fmt.Errorf("job file defines %d jobs with %q", len(jobs), jobVar)
+	}
+
+	return jobs[0], nil
+}
+
+// loadJobProgram runs a compiled job file and returns the jobs it defines.
+// A job file that calls "job" defines a job for each call, and its top-level options are the defaults for them.
+// Otherwise, the file defines one job named after its directory.
+// The job file can load the template file in configRoot.
+func loadJobProgram(env denv.Env, configRoot, path string, program *starlark.Program) ([]JobConfig, error) {
+	thread, done := starlarkutil.NewThread("job", starlarkMaxSteps, starlarkTimeout)
+	defer done()
+
+	generated := []generatedJob{}
+	thread.SetLocal(generatedJobsKey, &generated)
+
+	readFiles := []string{}
+	thread.SetLocal(readFilesKey, &readFiles)
+
+	globs := []string{}
+	thread.SetLocal(globsKey, &globs)
+
+	whichLookups := []whichLookup{}
+	thread.SetLocal(whichLookupsKey, &whichLookups)
+
+	envDict, err := envToDict(env)
+	if err != nil {
+		return nil, err
+	}
+
+	predeclared := jobPredeclared(jobDir(path), envDict)
+	thread.Load = templateLoader(configRoot, predeclared)
+
+	globals, err := program.Init(thread, predeclared)
+	if err != nil {
+		return nil, err
+	}
+	globals.Freeze()
+
+	globals, err = applyInstance(globals)
+	if err != nil {
+		return nil, err
+	}
+
+	name := jobNameFromPath(path)
+	if len(generated) == 0 {
+		job, err := jobFromGlobals(name, envDict, globals)
+		if err != nil {
+			return nil, err
+		}
+		job.readFiles = readFiles
+		job.globs = globs
+		job.whichLookups = whichLookups
+
+		return []JobConfig{job}, nil
+	}
+
+	jobs := make([]JobConfig, 0, len(generated))
+	for _, g := range generated {
+		job, err := jobFromGlobals(generatedJobName(name, g.name), envDict, generatedJobGlobals(globals, g))
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", g.name, err)
+		}
+		job.readFiles = readFiles
+		job.globs = globs
+		job.whichLookups = whichLookups
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// jobFromGlobals converts the options of a job to a JobConfig.
+func jobFromGlobals(name string, envDict *starlark.Dict, globals starlark.StringDict) (JobConfig, error) {
+	job := JobConfig{
+		Name: name,
+	}
+
+	for _, name := range unknownJobVars(globals) {
+		job.warnings = append(job.warnings, unknownJobVarWarning(name))
+	}
+
+	stringDict := starlark.StringDict(globals)
+
+	if err := starstruct.FromStarlark(stringDict, &job); err != nil {
+		return job, fmt.Errorf(`failed to convert job to struct: %w`, err)
+	}
+
+	if len(job.Command) == 0 {
+		job.Command = []string{jobExecutableFileName}
+	}
+
+	if _, err := NewExecutor(job.Executor); err != nil {
+		return job, err
+	}
+
+	if scheduleValue, exists := globals[scheduleVar]; exists {
+		if err := applySchedule(&job, scheduleValue); err != nil {
+			return job, err
+		}
+	}
+
+	enableValue, exists := globals[enableVar]
+	job.Enable = !exists || enableValue == starlark.True
+
+	logValue, exists := globals[logVar]
+	job.Log = !exists || logValue == starlark.True
+
+	if _, exists := globals[artifactsKeepVar]; !exists {
+		job.ArtifactsKeep = defaultArtifactsKeep
+	}
+
+	// "log_to" takes precedence over "log".
+	if _, exists := globals[logToVar]; exists {
+		job.Log = false
+
+		for _, dest := range job.LogTo {
+			switch dest {
+
+			case logToFile:
+				job.Log = true
+
+			case logToSyslog:
+				job.Syslog = true
+
+			default:
+				return job, fmt.Errorf("unknown %q destination: %q", logToVar, dest)
+			}
+		}
+	}
+
+	finalEnvDict := envDict
+	_, exists = globals[envVar]
+	if exists {
+		var ok bool
+		finalEnvDict, ok = globals[envVar].(*starlark.Dict)
+		if !ok {
+			return job, fmt.Errorf("%q isn't a dictionary", envVar)
+		}
+	}
+
+	var err error
+	job.Env, err = dictToEnv(envVar, finalEnvDict)
+	if err != nil {
+		return job, err
+	}
+
+	if filesValue, exists := globals[filesVar]; exists {
+		filesDict, ok := filesValue.(*starlark.Dict)
+		if !ok {
+			return job, fmt.Errorf("%q isn't a dictionary", filesVar)
+		}
+
+		job.Files, err = dictToEnv(filesVar, filesDict)
+		if err != nil {
+			return job, err
+		}
+
+		if err := validateInlineFiles(job.Files); err != nil {
+			return job, err
+		}
+	}
+
+	if meaningsValue, exists := globals[exitMeaningsVar]; exists {
+		meaningsDict, ok := meaningsValue.(*starlark.Dict)
+		if !ok {
+			return job, fmt.Errorf("%q isn't a dictionary", exitMeaningsVar)
+		}
+
+		job.ExitMeanings, err = parseExitMeanings(meaningsDict)
+		if err != nil {
+			return job, err
+		}
+	}
+
+	if escalateValue, exists := globals[escalateVar]; exists {
+		escalateDict, ok := escalateValue.(*starlark.Dict)
+		if !ok {
+			return job, fmt.Errorf("%q isn't a dictionary", escalateVar)
+		}
+
+		job.Escalate, err = parseEscalate(escalateDict)
+		if err != nil {
+			return job, err
+		}
+	}
+
+	if len(job.EnvPass) > 0 || len(job.EnvBlock) > 0 {
+		if err := filterInheritedEnv(job.Env, denv.OS(), job.EnvPass, job.EnvBlock); err != nil {
+			return job, err
+		}
+	}
+
+	if err := validateHosts(job.Hosts); err != nil {
+		return job, err
+	}
+
+	if job.Deadline != "" {
+		if _, err := parseDeadline(job.Deadline); err != nil {
+			return job, err
+		}
+	}
+
+	if job.LogPath != "" {
+		if _, err := strftime(job.LogPath, time.Now()); err != nil {
+			return job, fmt.Errorf("invalid %q: %w", logPathVar, err)
+		}
+	}
+
+	for _, pattern := range job.Artifacts {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return job, fmt.Errorf("invalid %q pattern %q: %w", artifactsVar, pattern, err)
+		}
+	}
+
+	if job.ArtifactsKeep < 0 {
+		return job, fmt.Errorf("%q must not be negative", artifactsKeepVar)
+	}
+
+	if job.MaxOutput < 0 {
+		return job, fmt.Errorf("%q must not be negative", maxOutputVar)
+	}
+
+	if job.NotifyLines != nil && *job.NotifyLines < 0 {
+		return job, fmt.Errorf("%q must not be negative", notifyLinesVar)
+	}
+
+	if job.Retries < 0 {
+		return job, fmt.Errorf("%q must not be negative", retriesVar)
+	}
+
+	if job.RetryDelay < 0 {
+		return job, fmt.Errorf("%q must not be negative", retryDelayVar)
+	}
+
+	if job.StateQuota < 0 {
+		return job, fmt.Errorf("%q must not be negative", stateQuotaVar)
+	}
+
+	for _, code := range job.SuccessCodes {
+		if code < 0 || code > 255 {
+			return job, fmt.Errorf("%q value %d isn't an exit status from 0 to 255", successCodesVar, code)
+		}
+	}
+
+	job.Jitter *= time.Second
+	job.RetryDelay *= time.Second
+	job.Timeout *= time.Second
+
+	notifyModeString := ""
+	notifyModeValue, exists := globals[notifyModeVar]
+	if exists {
+		value, ok := notifyModeValue.(starlark.String)
+		if !ok {
+			return job, fmt.Errorf("%q must be Starlark string", notifyModeVar)
+		}
+
+		notifyModeString = value.GoString()
+	}
+	job.Notify, _ = parseNotifyMode(notifyModeString)
+
+	for _, channel := range job.NotifyVia {
+		if !slices.Contains(notifyChannels, channel) {
+			return job, fmt.Errorf("unknown %q channel: %q", notifyViaVar, channel)
+		}
+	}
+
+	overflowString := ""
+	overflowValue, exists := globals[queueOverflowVar]
+	if exists {
+		value, ok := overflowValue.(starlark.String)
+		if !ok {
+			return job, fmt.Errorf("%q must be Starlark string", queueOverflowVar)
+		}
+
+		overflowString = value.GoString()
+	}
+	job.QueueOverflow, err = parseOverflowPolicy(overflowString)
+	if err != nil {
+		return job, err
+	}
+
+	return job, nil
+}
@@ -0,0 +1,538 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+	"github.com/google/go-cmp/cmp"
+	"go.starlark.net/starlark"
+)
+
+func TestJobConfigQueueName(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      JobConfig
+		expected string
+	}{
+		{
+			name: "empty queue returns job name",
+			job: JobConfig{
+				Name:  "test-job",
+				Queue: "",
+			},
+			expected: "test-job",
+		},
+		{
+			name: "specified queue returns queue name",
+			job: JobConfig{
+				Name:  "test-job",
+				Queue: "custom-queue",
+			},
+			expected: "custom-queue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.job.QueueName()
+
+			if result != tt.expected {
+				t.Errorf("QueueName() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadJob(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "jobconfig-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	jobContent := `
+command = ["sleep", "1"]
+duplicate = False
+enable = False
+env["TEST_VAR"] = "test_value"
+jitter = 5
+log = True
+max_output = 1024
+notify = "always"
+queue = "test-queue"
+retries = 2
+retry_delay = 30
+success_exit_codes = [0, 24]
+
+def should_run(**_):
+    return True
+`
+
+	jobPath := filepath.Join(tmpDir, "config.star")
+	if err := os.WriteFile(jobPath, []byte(jobContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Test loading the job.
+	env := denv.Env{"INITIAL_VAR": "initial_value"}
+	job, err := loadJob(env, jobPath)
+	if err != nil {
+		t.Fatalf("loadJob() error = %v", err)
+	}
+
+	// Verify job properties.
+	tests := []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"Enable", job.Enable, false},
+		{"Command", job.Command, []string{"sleep", "1"}},
+		{"Duplicate", job.Duplicate, false},
+		{"Log", job.Log, true},
+		{"MaxOutput", job.MaxOutput, int64(1024)},
+		{"Queue", job.Queue, "test-queue"},
+		{"Retries", job.Retries, 2},
+		{"RetryDelay", job.RetryDelay, 30 * time.Second},
+		{"Jitter", job.Jitter, 5 * time.Second},
+		{"Name", job.Name, filepath.Base(filepath.Dir(jobPath))},
+		{"Notify", job.Notify, NotifyMode("always")},
+		{"SuccessCodes", job.SuccessCodes, []int{0, 24}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !cmp.Equal(tt.got, tt.expected) {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.expected)
+			}
+		})
+	}
+
+	// Test environment variables.
+	if v, ok := job.Env["TEST_VAR"]; !ok || v != "test_value" {
+		t.Errorf(`Env["TEST_VAR"] = %q, want "test_value"`, v)
+	}
+
+	if v, ok := job.Env["INITIAL_VAR"]; !ok || v != "initial_value" {
+		t.Errorf(`Env["INITIAL_VAR"] = %q, want "initial_value"`, v)
+	}
+
+	// Test the should_run function.
+	thread := &starlark.Thread{Name: "test"}
+	result, err := starlark.Call(thread, job.ShouldRun, nil, nil)
+	if err != nil {
+		t.Errorf(`"should_run" call failed: %v`, err)
+	}
+	if result != starlark.True {
+		t.Errorf(`"should_run" returned %v, want "True"`, result)
+	}
+}
+
+func TestLoadJobWithNewEnv(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "jobconfig-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	jobContent := `
+env = {"BAR": "new-bar", "BAZ": "baz"}
+
+def should_run(**_):
+    return True
+`
+
+	jobPath := filepath.Join(tmpDir, "config.star")
+	if err := os.WriteFile(jobPath, []byte(jobContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Test loading the job with env override.
+	env := denv.Env{"FOO": "foo", "BAR": "bar"}
+	job, err := loadJob(env, jobPath)
+	if err != nil {
+		t.Fatalf("loadJob() error = %v", err)
+	}
+
+	if _, ok := job.Env["FOO"]; ok {
+		t.Errorf("Env contains FOO when it shouldn't")
+	}
+
+	if v, ok := job.Env["BAR"]; !ok || v != "new-bar" {
+		t.Errorf(`Env["BAR"] = %q, want "new_value"`, v)
+	}
+
+	if v, ok := job.Env["BAZ"]; !ok || v != "baz" {
+		t.Errorf(`Env["BAZ"] = %q, want "baz"`, v)
+	}
+
+	if job.Name == "" {
+		t.Error("Job name is empty")
+	}
+
+	if job.ShouldRun == nil {
+		t.Error("should_run function is missing")
+	}
+}
+
+func TestShouldRunExecutionLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	jobContent := `
+def should_run(**_):
+    for i in range(1000000000):
+        pass
+
+    return True
+`
+
+	jobPath := filepath.Join(tmpDir, "config.star")
+	if err := os.WriteFile(jobPath, []byte(jobContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := loadJob(denv.Env{}, jobPath)
+	if err != nil {
+		t.Fatalf("loadJob() error = %v", err)
+	}
+	job.Enable = true
+
+	if _, _, err := job.shouldRun(time.Now(), nil); err == nil {
+		t.Error(`expected error from runaway "should_run"`)
+	}
+}
+
+func TestLoadJobUnknownExecutor(t *testing.T) {
+	jobPath := filepath.Join(t.TempDir(), "config.star")
+	if err := os.WriteFile(jobPath, []byte(`executor = "teleport"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadJob(denv.Env{}, jobPath); err == nil {
+		t.Error("expected error for unknown executor")
+	}
+}
+
+func TestLoadJobLogTo(t *testing.T) {
+	tests := []struct {
+		content    string
+		wantLog    bool
+		wantSyslog bool
+		wantErr    bool
+	}{
+		{``, true, false, false},
+		{`log = False`, false, false, false},
+		{`log_to = ["syslog"]`, false, true, false},
+		{`log_to = ["file", "syslog"]`, true, true, false},
+		{`log_to = []`, false, false, false},
+		{`log_to = ["printer"]`, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			jobPath := filepath.Join(t.TempDir(), "config.star")
+			if err := os.WriteFile(jobPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := loadJob(denv.Env{}, jobPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if job.Log != tt.wantLog || job.Syslog != tt.wantSyslog {
+				t.Errorf("Log = %v, Syslog = %v; want %v, %v", job.Log, job.Syslog, tt.wantLog, tt.wantSyslog)
+			}
+		})
+	}
+}
+
+func TestLoadJobNotifyVia(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{``, nil, false},
+		{`notify_via = ["email", "ntfy"]`, []string{"email", "ntfy"}, false},
+		{`notify_via = ["pager"]`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			jobPath := filepath.Join(t.TempDir(), "config.star")
+			content := tt.content + "\nnotify_to = [\"admin@example.com\"]"
+			if err := os.WriteFile(jobPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := loadJob(denv.Env{}, jobPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !slices.Equal(job.NotifyVia, tt.want) {
+				t.Errorf("NotifyVia = %v, want %v", job.NotifyVia, tt.want)
+			}
+			if !slices.Equal(job.NotifyTo, []string{"admin@example.com"}) {
+				t.Errorf("NotifyTo = %v", job.NotifyTo)
+			}
+		})
+	}
+}
+
+func TestShouldRunHistory(t *testing.T) {
+	jobPath := filepath.Join(t.TempDir(), "config.star")
+	content := `
+def should_run(timestamp, history, **_):
+    # Retry hourly until a run succeeds, then run daily.
+    if not history:
+        return True
+    last = history[0]
+    if last.exit_status != 0:
+        return timestamp - last.finished >= one_hour
+    return timestamp - last.finished >= one_day
+`
+	if err := os.WriteFile(jobPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := loadJob(denv.Env{}, jobPath)
+	if err != nil {
+		t.Fatalf("loadJob() error = %v", err)
+	}
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	run := func(ago time.Duration, exitStatus int) CompletedJob {
+		return CompletedJob{
+			ExitStatus: exitStatus,
+			Started:    now.Add(-ago),
+			Finished:   now.Add(-ago),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		history []CompletedJob
+		want    bool
+	}{
+		{"never ran", nil, true},
+		{"failed recently", []CompletedJob{run(time.Minute, 1)}, false},
+		{"failed an hour ago", []CompletedJob{run(time.Hour, 1), run(2*time.Hour, 1)}, true},
+		{"succeeded an hour ago", []CompletedJob{run(time.Hour, 0), run(2*time.Hour, 1)}, false},
+		{"succeeded a day ago", []CompletedJob{run(24*time.Hour, 0)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := job.shouldRun(now, tt.history)
+			if err != nil {
+				t.Fatalf("shouldRun() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("shouldRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRunStart(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		content string
+		want    time.Time
+		wantDue bool
+		wantErr bool
+	}{
+		{"True", now, true, false},
+		{"False", time.Time{}, false, false},
+		{"0", now, true, false},
+		{"90", now.Add(90 * time.Second), true, false},
+		{"1714568400", time.Unix(1714568400, 0), true, false},
+		{"-1", time.Time{}, false, true},
+		{`"soon"`, time.Time{}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			jobPath := filepath.Join(t.TempDir(), "config.star")
+			content := "def should_run(**_):\n    return " + tt.content
+			if err := os.WriteFile(jobPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := loadJob(denv.Env{}, jobPath)
+			if err != nil {
+				t.Fatalf("loadJob() error = %v", err)
+			}
+
+			start, due, err := job.shouldRun(now, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("shouldRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if due != tt.wantDue || !start.Equal(tt.want) {
+				t.Errorf("shouldRun() = %v, %v; want %v, %v", start, due, tt.want, tt.wantDue)
+			}
+		})
+	}
+}
+
+func TestNextDue(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	history := []CompletedJob{{
+		Started:  start.Add(-2 * time.Hour),
+		Finished: start.Add(-2 * time.Hour),
+	}}
+
+	tests := []struct {
+		name     string
+		content  string
+		history  []CompletedJob
+		from     time.Time
+		wantDue  bool
+		expected time.Time
+	}{
+		{
+			name:     "due now",
+			content:  "def should_run(**_):\n    return True",
+			wantDue:  true,
+			expected: start,
+		},
+		{
+			name:     "at a minute",
+			content:  "def should_run(minute, **_):\n    return minute == 30",
+			wantDue:  true,
+			expected: start.Add(30 * time.Minute),
+		},
+		{
+			name:     "since finished",
+			content:  "def should_run(timestamp, finished, **_):\n    return timestamp - finished >= one_day",
+			history:  history,
+			wantDue:  true,
+			expected: start.Add(22 * time.Hour),
+		},
+		{
+			name:     "starts of minutes",
+			content:  "def should_run(minute, **_):\n    return minute == 1",
+			from:     start.Add(30 * time.Second),
+			wantDue:  true,
+			expected: start.Add(time.Minute),
+		},
+		{
+			name:     "with a delay",
+			content:  "def should_run(minute, **_):\n    return 90 if minute == 30 else False",
+			wantDue:  true,
+			expected: start.Add(31*time.Minute + 30*time.Second),
+		},
+		{
+			name:    "beyond the horizon",
+			content: "def should_run(dow, **_):\n    return dow == 6",
+			wantDue: false,
+		},
+		{
+			name:    "disabled",
+			content: "enable = False\ndef should_run(**_):\n    return True",
+			wantDue: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobPath := filepath.Join(t.TempDir(), "config.star")
+			if err := os.WriteFile(jobPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := loadJob(denv.Env{}, jobPath)
+			if err != nil {
+				t.Fatalf("loadJob() error = %v", err)
+			}
+
+			from := tt.from
+			if from.IsZero() {
+				from = start
+			}
+
+			next, due, err := job.NextDue(from, tt.history, 48*time.Hour)
+			if err != nil {
+				t.Fatalf("NextDue() error = %v", err)
+			}
+
+			if due != tt.wantDue || !next.Equal(tt.expected) {
+				t.Errorf("NextDue() = %v, %v; want %v, %v", next, due, tt.expected, tt.wantDue)
+			}
+		})
+	}
+}
+
+func TestUnknownJobVars(t *testing.T) {
+	configRoot := t.TempDir()
+
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"options only", "command = [\"true\"]\njitter = 5\nnotify = \"never\"\n", nil},
+		{"typo", "command = [\"true\"]\njittr = 5\n", []string{"did you mean \"jitter\""}},
+		{"unknown", "command = [\"true\"]\nmy_setting = 5\n", []string{"my_setting"}},
+		{"private helper", "_days = 3\ncommand = [\"echo\", str(_days)]\n", nil},
+		{"function helper", "def helper():\n    return 1\n\ncommand = [\"true\"]\n", nil},
+		{"reassigned env", "env = {}\ncommand = [\"true\"]\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestJob(t, configRoot, "job", tt.src)
+
+			job, err := loadJob(denv.Env{}, path)
+			if err != nil {
+				t.Fatalf("loadJob() error = %v", err)
+			}
+
+			warnings := job.Warnings()
+			if len(warnings) != len(tt.want) {
+				t.Fatalf("Expected %d warnings, got %v", len(tt.want), warnings)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(warnings[i], want) {
+					t.Errorf("Expected a warning with %q, got %q", want, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"jitter", "jitter", 0},
+		{"jittr", "jitter", 1},
+		{"notfy_to", "notify_to", 1},
+		{"retires", "retries", 2},
+		{"", "log", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
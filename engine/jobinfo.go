@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+// The names of env vars whose values JobInfo doesn't include.
+var secretEnvRegexp = regexp.MustCompile("(?i)(key|password|secret|token)")
+
+// JobInfo describes a job loaded by the daemon and its state in the runner.
+type JobInfo struct {
+	Name      string        `msgpack:"name"`
+	Duplicate bool          `msgpack:"duplicate"`
+	Enable    bool          `msgpack:"enable"`
+	Env       denv.Env      `msgpack:"env"` // See infoEnv.
+	Executor  string        `msgpack:"executor"`
+	Hosts     []string      `msgpack:"hosts"`
+	Jitter    time.Duration `msgpack:"jitter"`
+	Log       bool          `msgpack:"log"`
+	Notify    NotifyMode    `msgpack:"notify"`
+	Queue     string        `msgpack:"queue"`
+	Schedule  string        `msgpack:"schedule"`
+	Tags      []string      `msgpack:"tags"`
+
+	// Whether the job is running and how many more runs are waiting in its queue.
+	// Only the daemon knows these.
+	Running bool `msgpack:"running"`
+	Pending int  `msgpack:"pending"`
+
+	// How many jobs are ahead of the first pending run in the queue and how long it has waited.
+	Position int           `msgpack:"position"`
+	Waiting  time.Duration `msgpack:"waiting"`
+}
+
+func newJobInfo(job JobConfig) JobInfo {
+	return JobInfo{
+		Name:      job.Name,
+		Duplicate: job.Duplicate,
+		Enable:    job.Enable,
+		Env:       infoEnv(job.Env),
+		Executor:  job.Executor,
+		Hosts:     job.Hosts,
+		Jitter:    job.Jitter,
+		Log:       job.Log,
+		Notify:    job.Notify,
+		Queue:     job.QueueName(),
+		Schedule:  job.ScheduleSummary(),
+		Tags:      job.Tags,
+	}
+}
+
+// infoEnv returns the env vars of a job without the ones it gets unchanged from the OS.
+// The values of the vars with secret-looking names are redacted,
+// so they aren't sent over the socket or shown by "status".
+func infoEnv(env denv.Env) denv.Env {
+	osEnv := denv.OS()
+	info := denv.Env{}
+
+	for key, value := range env {
+		if osValue, ok := osEnv[key]; ok && osValue == value {
+			continue
+		}
+
+		if secretEnvRegexp.MatchString(key) {
+			value = redactedValue
+		}
+
+		info[key] = value
+	}
+
+	return info
+}
+
+// RunsOnThisHost reports whether the job runs on the current machine.
+func (j JobInfo) RunsOnThisHost() bool {
+	return runsOnThisHost(j.Hosts)
+}
+
+// HasTag reports whether the job has any of the tags.
+func (j JobInfo) HasTag(tags ...string) bool {
+	for _, tag := range tags {
+		if slices.Contains(j.Tags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadJobInfo gets the jobs from the daemon if one is running.
+// Otherwise, load them from the config directory.
+func LoadJobInfo(config Config) (jobs []JobInfo, fromDaemon bool, err error) {
+	jobs, fromDaemon, err = QueryDaemonJobs()
+	if err != nil || fromDaemon {
+		return jobs, fromDaemon, err
+	}
+
+	jsc := NewScheduler()
+	jsc.loadEnvDefaults(config.ConfigRoots())
+
+	files, err := findJobFiles(config.ConfigRoots())
+	if err == nil {
+		for _, file := range files {
+			if _, _, err = jsc.Update(file.root, file.path); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error looking for jobs in config dir: %v", err)
+	}
+
+	if _, err := jsc.UpdateCrontab(config.ConfigRoots()...); err != nil {
+		return nil, false, err
+	}
+
+	for _, job := range jsc.byName {
+		jobs = append(jobs, newJobInfo(job))
+	}
+
+	return jobs, false, nil
+}
+
+// ListJobNames lists the jobs in the config directories and the crontabs.
+// It only loads the job files that may call "job" to list the jobs they define,
+// so it agrees with the daemon and stays fast for the other job files.
+// A name is only listed once when several directories have a job with it.
+func ListJobNames(configRoots ...string) ([]string, error) {
+	jsc := NewScheduler()
+
+	names := []string{}
+	listed := make(map[string]struct{})
+	for _, configRoot := range configRoots {
+		entries, err := os.ReadDir(configRoot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			if _, ok := listed[entry.Name()]; ok || !entry.IsDir() {
+				continue
+			}
+
+			file, ok := findJobFile(configRoots, entry.Name())
+			if !ok {
+				continue
+			}
+			listed[entry.Name()] = struct{}{}
+
+			names = append(names, definedJobNames(jsc, configRoots, entry.Name(), file.path)...)
+		}
+	}
+
+	crontabJobs, err := NewScheduler().UpdateCrontab(configRoots...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range crontabJobs {
+		if _, ok := listed[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// definedJobNames returns the names of the jobs a job file defines.
+// A job file that fails to load is listed under its directory name, so commands can report the error.
+func definedJobNames(jsc *Scheduler, configRoots []string, dirName, path string) []string {
+	src, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(src, []byte(jobVar+"(")) {
+		return []string{dirName}
+	}
+
+	jobs, err := jsc.LoadJobs(configRoots, dirName)
+	if err != nil {
+		return []string{dirName}
+	}
+
+	names := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+
+	return names
+}
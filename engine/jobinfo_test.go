@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"testing"
+
+	"dbohdan.com/denv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListJobNames(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	base := t.TempDir()
+	override := t.TempDir()
+
+	writeTestJob(t, base, "backup", `command = ["true"]`)
+	writeTestJob(t, base, "check", `
+for _site in ["example.com", "example.net"]:
+    job(name = _site, command = ["curl", "https://" + _site])
+`)
+	writeTestJob(t, base, "broken", `job(name = "x", command = 1 // 0)`)
+
+	// The job file in the later root wins.
+	writeTestJob(t, override, "check", `job(name = "example.org", command = ["true"])`)
+
+	names, err := ListJobNames(base, override)
+	if err != nil {
+		t.Fatalf("ListJobNames() error = %v", err)
+	}
+
+	want := []string{"backup", "broken", "check:example.org"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("Job names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInfoEnv(t *testing.T) {
+	t.Setenv("REGULAR_TEST_INHERITED", "same")
+
+	env := denv.Env{
+		"API_TOKEN":              "hunter2",
+		"BACKUP_DIR":             "/srv/backup",
+		"REGULAR_TEST_INHERITED": "same",
+	}
+
+	want := denv.Env{
+		"API_TOKEN":  redactedValue,
+		"BACKUP_DIR": "/srv/backup",
+	}
+	if diff := cmp.Diff(want, infoEnv(env)); diff != "" {
+		t.Errorf("Env mismatch (-want +got):\n%s", diff)
+	}
+}
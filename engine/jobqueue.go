@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverflowPolicy says what to do when a job is added to a queue with max_queue pending jobs.
+type OverflowPolicy string
+
+const (
+	OverflowAlert      OverflowPolicy = "alert"
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	OverflowReject     OverflowPolicy = "reject"
+)
+
+type jobQueue struct {
+	activeJob bool
+	// When the active job left the queue to run.
+	activeSince time.Time
+	jobs        []JobConfig
+
+	// The limit on pending jobs and what to do over it from the last job added with max_queue.
+	// They apply to every job added to the queue.
+	maxQueue int
+	overflow OverflowPolicy
+}
+
+func newJobQueue() jobQueue {
+	return jobQueue{
+		jobs: []JobConfig{},
+	}
+}
+
+func parseOverflowPolicy(policy string) (OverflowPolicy, error) {
+	switch policy {
+	case string(OverflowAlert):
+		return OverflowAlert, nil
+	case string(OverflowDropOldest), "":
+		return OverflowDropOldest, nil
+	case string(OverflowReject):
+		return OverflowReject, nil
+	default:
+		return "", fmt.Errorf("unknown queue overflow policy: %v", policy)
+	}
+}
+
+// pending returns the number of jobs waiting to run.
+func (q jobQueue) pending() int {
+	if q.activeJob {
+		return len(q.jobs) - 1
+	}
+
+	return len(q.jobs)
+}
+
+// dropOldestPending removes the first job that isn't running or a retry and returns it.
+// It returns false if every pending job is a retry.
+func (q *jobQueue) dropOldestPending() (JobConfig, bool) {
+	start := 0
+	if q.activeJob {
+		start = 1
+	}
+
+	for i := start; i < len(q.jobs); i++ {
+		if q.jobs[i].retried > 0 {
+			continue
+		}
+
+		dropped := q.jobs[i]
+		q.jobs = append(q.jobs[:i:i], q.jobs[i+1:]...)
+
+		return dropped, true
+	}
+
+	return JobConfig{}, false
+}
@@ -0,0 +1,931 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"dbohdan.com/denv"
+	"dbohdan.com/regular/envfile"
+)
+
+type Runner struct {
+	// Called after every run regardless of the job's notification mode.
+	// Errors are logged.
+	OnComplete NotifyWhenDone
+
+	// The minimum free space in bytes in the state directory to start a job with logging (0 disables the check)
+	// and what to do when there is less.
+	MinFreeSpace int64
+	LowSpace     LowSpacePolicy
+
+	// The minimum time between the starts of a job that isn't forced to run.
+	// 0 disables the guard.
+	MinInterval time.Duration
+
+	// The time the start times of queued jobs are compared with and how the runner waits.
+	Clock Clock
+
+	db        *AppDB
+	notify    NotifyWhenDone
+	queues    map[string]jobQueue
+	stateRoot string
+
+	// The recent completed runs of each job, newest first.
+	// Entries are filled from the database on first use and updated when the runner finishes a job.
+	history map[string][]CompletedJob
+
+	// The last scheduling decision for each job and the last one that queued it.
+	decisions       map[string]Decision
+	queuedDecisions map[string]Decision
+
+	// The snoozes from "regular disable" by job name.
+	// They are loaded from the database when the runner is created,
+	// so the scheduler doesn't query it for every job on every tick.
+	snoozes map[string]Snooze
+
+	// When each queue paused with "regular pause" was paused.
+	// Like the snoozes, they are loaded when the runner is created.
+	paused map[string]time.Time
+
+	// The alerts being sent in the background.
+	alerts *sync.WaitGroup
+
+	mu *sync.Mutex
+}
+
+func NewRunner(db *AppDB, notify NotifyWhenDone, stateRoot string) (Runner, error) {
+	runner := Runner{
+		Clock:           SystemClock{},
+		db:              db,
+		notify:          notify,
+		queues:          make(map[string]jobQueue),
+		stateRoot:       stateRoot,
+		history:         make(map[string][]CompletedJob),
+		decisions:       make(map[string]Decision),
+		queuedDecisions: make(map[string]Decision),
+		snoozes:         make(map[string]Snooze),
+		paused:          make(map[string]time.Time),
+		alerts:          &sync.WaitGroup{},
+		mu:              &sync.Mutex{},
+	}
+
+	if db != nil {
+		if err := runner.loadSnoozes(); err != nil {
+			return runner, err
+		}
+
+		if err := runner.loadPausedQueues(); err != nil {
+			return runner, err
+		}
+	}
+
+	return runner, nil
+}
+
+// LastCompleted returns the last completed run of a job or nil if the job has never run.
+func (r Runner) LastCompleted(jobName string) (*CompletedJob, error) {
+	history, err := r.History(jobName)
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+
+	return &history[0], nil
+}
+
+// History returns up to ShouldRunHistoryLength recent runs of a job, newest first.
+func (r Runner) History(jobName string) ([]CompletedJob, error) {
+	r.mu.Lock()
+	history, ok := r.history[jobName]
+	r.mu.Unlock()
+
+	if ok {
+		return history, nil
+	}
+
+	history, err := r.db.History(jobName, ShouldRunHistoryLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of %q: %w", jobName, err)
+	}
+
+	r.mu.Lock()
+	// Don't overwrite a run that finished while we were querying the database.
+	if cached, ok := r.history[jobName]; ok {
+		history = cached
+	} else {
+		r.history[jobName] = history
+	}
+	r.mu.Unlock()
+
+	return history, nil
+}
+
+// tooSoon reports whether a job last started less than the minimum interval before t.
+// It logs a warning if it did.
+func (r Runner) tooSoon(jobName string, history []CompletedJob, t time.Time) bool {
+	if r.MinInterval <= 0 || len(history) == 0 {
+		return false
+	}
+
+	since := t.Sub(history[0].Started)
+	if since >= r.MinInterval {
+		return false
+	}
+
+	LogJobPrintf(
+		jobName,
+		"Warning: not starting because the last run started %v ago and the minimum interval is %v",
+		FormatDuration(since),
+		FormatDuration(r.MinInterval),
+	)
+
+	return true
+}
+
+func (r Runner) AddJob(job JobConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queueName := job.QueueName()
+
+	queue, ok := r.queues[queueName]
+	if !ok {
+		queue = newJobQueue()
+	}
+
+	// The limit belongs to the queue, so it also applies to the jobs in it that don't set one.
+	if job.MaxQueue > 0 {
+		queue.maxQueue = job.MaxQueue
+		queue.overflow = job.QueueOverflow
+	}
+	r.queues[queueName] = queue
+
+	// A retry continues a run that was already queued, so it is neither deduplicated nor limited.
+	// Dropping it would lose the run without marking it dead.
+	retry := job.retried > 0
+
+	if !job.Duplicate && !retry {
+		for _, otherJob := range queue.jobs {
+			if otherJob.Name == job.Name {
+				return
+			}
+		}
+	}
+
+	if queue.maxQueue > 0 && !retry && queue.pending() >= queue.maxQueue {
+		switch queue.overflow {
+
+		case OverflowAlert:
+			// Only alert when the queue first goes over the limit.
+			if queue.pending() == queue.maxQueue {
+				LogJobPrintf(job.Name, "Queue %v is over its limit of %v pending jobs", queueName, queue.maxQueue)
+
+				r.alerts.Add(1)
+				go func() {
+					defer r.alerts.Done()
+					r.alertQueueOverflow(job, queueName, queue.maxQueue)
+				}()
+			}
+
+		case OverflowReject:
+			LogJobPrintf(job.Name, "Rejected job because queue %v has %v pending jobs", queueName, queue.pending())
+			return
+
+		default:
+			dropped, ok := queue.dropOldestPending()
+			if !ok {
+				LogJobPrintf(job.Name, "Dropped job because queue %v is full of retries", queueName)
+				return
+			}
+
+			LogJobPrintf(dropped.Name, "Dropped oldest pending job because queue %v is full", queueName)
+		}
+	}
+
+	job.queuedAt = r.Clock.Now()
+	queue.jobs = append(queue.jobs, job)
+	r.queues[queueName] = queue
+
+	// Report the queue length before the job was added.
+	if len(queue.jobs) == 1 {
+		logJobRoutinePrintf(
+			job.Name,
+			"Put job in empty runner queue: %v",
+			queueName,
+		)
+	} else {
+		logJobRoutinePrintf(
+			job.Name,
+			"Put job in runner queue of length %v: %v",
+			len(queue.jobs)-1,
+			queueName,
+		)
+	}
+}
+
+// alertQueueOverflow notifies the user that a queue has too many pending jobs.
+// The notification reports the overflow as an error of the job that was added.
+func (r Runner) alertQueueOverflow(job JobConfig, queueName string, limit int) {
+	r.alert(job, fmt.Sprintf("queue %q is over its limit of %v pending jobs", queueName, limit))
+}
+
+// alert notifies the user about a problem with a job outside a run.
+func (r Runner) alert(job JobConfig, message string) {
+	if r.notify == nil {
+		return
+	}
+
+	now := r.Clock.Now()
+	cj := CompletedJob{
+		Error:     message,
+		Started:   now,
+		Finished:  now,
+		NotifyTo:  job.NotifyTo,
+		NotifyVia: job.NotifyVia,
+	}
+
+	if err := r.notify(job.Name, cj); err != nil {
+		LogJobPrintf(job.Name, "Failed to send alert: %v", err)
+	}
+}
+
+func (r Runner) activateQueueHead(queueName string) (*JobConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queue, ok := r.queues[queueName]
+
+	if !ok {
+		return nil, fmt.Errorf("requested to run head of nonexistent queue: %v", queueName)
+	}
+
+	if queue.activeJob || len(queue.jobs) == 0 {
+		return nil, nil
+	}
+
+	// A job that should start later stays in the queue and lets the jobs after it run.
+	now := r.Clock.Now()
+	i := slices.IndexFunc(queue.jobs, func(job JobConfig) bool {
+		return !job.startAt.After(now)
+	})
+	if i == -1 {
+		return nil, nil
+	}
+
+	// The active job is the first in the queue.
+	job := queue.jobs[i]
+	copy(queue.jobs[1:i+1], queue.jobs[:i])
+	queue.jobs[0] = job
+
+	queue.activeJob = true
+	queue.activeSince = r.Clock.Now()
+	r.queues[queueName] = queue
+
+	return &job, nil
+}
+
+// RunQueueHead runs the first job in a queue that is due to start.
+// It does nothing if a job in the queue is running or no job is due.
+func (r Runner) RunQueueHead(queueName string) error {
+	job, err := r.activateQueueHead(queueName)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return nil
+	}
+
+	jobStateDir := filepath.Join(r.stateRoot, job.Name)
+
+	if job.Jitter > 0 {
+		sleepDuration := time.Duration(job.Jitter.Seconds()*rand.Float64()) * time.Second
+		logJobRoutinePrintf(job.Name, "Waiting %v before start", FormatDuration(sleepDuration))
+
+		r.Clock.Sleep(sleepDuration)
+	}
+
+	cj := CompletedJob{
+		Attempt:          job.retried + 1,
+		Command:          job.Command,
+		NotifyLines:      job.NotifyLines,
+		NotifyStdout:     job.NotifyStdout,
+		NotifyTo:         job.NotifyTo,
+		NotifyVia:        job.NotifyVia,
+		SuccessExitCodes: job.SuccessCodes,
+	}
+	cj.Hostname, cj.Username = runOrigin()
+
+	skipReason := ""
+	if job.SkipUnchanged {
+		cj.InputHash, cj.Skipped = r.checkUnchanged(*job)
+		skipReason = "the command, the environment, and the inputs are unchanged since the last successful run"
+	}
+
+	// Hold the job lock until the run finishes.
+	unlockJob := func() {}
+	var lockErr error
+	if job.Lock && !cj.Skipped {
+		unlock, locked, err := lockJob(jobStateDir)
+		switch {
+
+		case err != nil:
+			lockErr = fmt.Errorf("failed to take job lock: %w", err)
+
+		case !locked:
+			cj.Skipped = true
+			skipReason = "another process holds the job lock"
+
+		default:
+			unlockJob = unlock
+		}
+	}
+
+	cj.Started = r.Clock.Now()
+
+	// The deadline is checked when the job loads.
+	var deadlineAt time.Time
+	if job.Deadline != "" {
+		deadlineAt, _ = nextDeadline(job.Deadline, cj.Started)
+	}
+
+	if cj.Skipped {
+		LogJobPrintf(job.Name, "Skipped because %s", skipReason)
+	} else {
+		logJobRoutinePrintf(job.Name, "Started")
+	}
+
+	stdoutFilePath := LogFilePath(r.stateRoot, job.Name, "stdout")
+	stderrFilePath := LogFilePath(r.stateRoot, job.Name, "stderr")
+
+	logs := []logFile{
+		{name: "stdout", path: stdoutFilePath},
+		{name: "stderr", path: stderrFilePath},
+	}
+	if cj.Skipped {
+		// The log files are from an earlier run.
+		logs = nil
+	}
+
+	runErr := func() error {
+		if cj.Skipped {
+			return nil
+		}
+
+		if lockErr != nil {
+			return lockErr
+		}
+
+		captureLog := job.Log
+
+		if job.Log {
+			if problem := r.checkFreeSpace(); problem != "" {
+				if r.LowSpace == LowSpaceRefuse {
+					return fmt.Errorf("refused to start: %s", problem)
+				}
+
+				LogJobPrintf(job.Name, "Not capturing output: %s", problem)
+				go r.alert(*job, "output not captured: "+problem)
+
+				// Don't let the previous run's logs pass for this run's.
+				_ = os.Remove(stdoutFilePath)
+				_ = os.Remove(stderrFilePath)
+
+				captureLog = false
+				logs = nil
+			}
+		}
+
+		var stdoutFile, stderrFile io.Writer
+		if captureLog {
+			if err := os.MkdirAll(jobStateDir, dirPerms); err != nil {
+				return fmt.Errorf("failed to create job state directory: %w", err)
+			}
+
+			stdoutF, err := os.OpenFile(
+				stdoutFilePath,
+				os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
+				filePerms,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create stdout log file: %w", err)
+			}
+			defer stdoutF.Close()
+			stdoutFile = stdoutF
+
+			stderrF, err := os.OpenFile(
+				stderrFilePath,
+				os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
+				filePerms,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create stderr log file: %w", err)
+			}
+			defer stderrF.Close()
+			stderrFile = stderrF
+
+			if job.MaxOutput > 0 {
+				stdoutTrunc := newTruncatingWriter(stdoutF, job.MaxOutput)
+				stderrTrunc := newTruncatingWriter(stderrF, job.MaxOutput)
+				defer func() {
+					if err := errors.Join(stdoutTrunc.Close(), stderrTrunc.Close()); err != nil {
+						LogJobPrintf(job.Name, "Failed to write truncated output: %v", err)
+					}
+				}()
+
+				stdoutFile = stdoutTrunc
+				stderrFile = stderrTrunc
+			}
+		}
+
+		if job.Syslog {
+			js, err := newJobSyslog(job.Name)
+			if err == nil {
+				defer func() {
+					if err := js.Close(); err != nil {
+						LogJobPrintf(job.Name, "Failed to write output to syslog: %v", err)
+					}
+				}()
+
+				stdoutFile = teeOptional(stdoutFile, js.Stdout)
+				stderrFile = teeOptional(stderrFile, js.Stderr)
+			} else {
+				// Don't fail the job because syslog is unavailable.
+				LogJobPrintf(job.Name, "Failed to connect to syslog: %v", err)
+			}
+		}
+
+		// Append stdout and stderr to the custom log file.
+		// Writes to a file opened for appending don't interleave within a write.
+		if job.LogPath != "" {
+			logPathFile, err := openLogPath(job.LogPath, jobStateDir, cj.Started)
+			if err != nil {
+				return fmt.Errorf("failed to open %q file: %w", logPathVar, err)
+			}
+			defer logPathFile.Close()
+
+			stdoutFile = teeOptional(stdoutFile, logPathFile)
+			stderrFile = teeOptional(stderrFile, logPathFile)
+		}
+
+		// Tee output to optional extra writers (e.g., a socket client).
+		if job.Stdout != nil {
+			stdoutFile = teeOptional(stdoutFile, job.Stdout)
+		}
+		if job.Stderr != nil {
+			stderrFile = teeOptional(stderrFile, job.Stderr)
+		}
+
+		executor, err := NewExecutor(job.Executor)
+		if err != nil {
+			return err
+		}
+
+		// Fetch secrets from the keyring only when the job starts, so they aren't kept in memory.
+		env, err := envfile.ResolveKeyring(job.Env)
+		if err != nil {
+			return err
+		}
+
+		// Let the job know how long it waited in its queue, including the delay before start.
+		env = denv.Merge(env, denv.Env{
+			queueEnvVar:     job.QueueName(),
+			queueWaitEnvVar: strconv.Itoa(int(cj.Started.Sub(job.queuedAt).Seconds())),
+		})
+
+		if len(job.Files) > 0 {
+			filesDir, filesEnv, err := writeInlineFiles(job.Files)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := os.RemoveAll(filesDir); err != nil {
+					LogJobPrintf(job.Name, "Failed to remove inline files: %v", err)
+				}
+			}()
+
+			env = denv.Merge(env, filesEnv)
+		}
+
+		timeout := job.Timeout
+		if job.DeadlineKill && !deadlineAt.IsZero() {
+			timeout = deadlineTimeout(timeout, deadlineAt, cj.Started)
+		}
+
+		stopDeadline := r.watchDeadline(*job, deadlineAt)
+		defer stopDeadline()
+
+		err = executor.Execute(Execution{
+			JobName: job.Name,
+			Command: job.Command,
+			Dir:     job.Env[jobDirEnvVar],
+			Env:     env,
+			Timeout: timeout,
+			Stdout:  stdoutFile,
+			Stderr:  stderrFile,
+		})
+		if err != nil && job.DeadlineKill && !deadlineAt.IsZero() && !r.Clock.Now().Before(deadlineAt) {
+			LogJobPrintf(job.Name, "Killed at deadline %s", job.Deadline)
+
+			return fmt.Errorf("deadline %s exceeded: %w", job.Deadline, err)
+		}
+
+		return err
+	}()
+	unlockJob()
+
+	cj.Error = ""
+	if runErr != nil {
+		cj.Error = runErr.Error()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		cj.ExitStatus = exitErr.ExitCode()
+
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			cj.Signal = int(status.Signal())
+			LogJobPrintf(job.Name, "Killed by %s", cj.SignalName())
+		}
+	}
+	if cj.Signal == 0 && (runErr == nil || exitErr != nil) {
+		cj.ExitMeaning = job.ExitMeanings[cj.ExitStatus]
+	}
+
+	if !cj.Skipped {
+		logJobRoutinePrintf(job.Name, "Finished")
+	}
+	cj.Finished = r.Clock.Now()
+
+	// Retry a failed run until "retries" runs out and then mark it dead.
+	retry := false
+	if job.Retries > 0 && !cj.IsSuccess() {
+		retry = job.retried < job.Retries
+		cj.Dead = !retry
+	}
+
+	r.mu.Lock()
+	queue, ok := r.queues[queueName]
+	if ok {
+		queue.activeJob = false
+		queue.jobs = queue.jobs[1:]
+		r.queues[queueName] = queue
+	}
+	r.mu.Unlock()
+
+	runID, saveErr := r.db.saveCompletedJob(job.Name, cj, logs)
+
+	if len(job.Artifacts) > 0 && saveErr == nil && !cj.Skipped {
+		r.saveArtifacts(*job, runID)
+	}
+
+	// Clean up after saving, so the database has the logs of this run.
+	if job.StateQuota > 0 {
+		removed, err := enforceQuota(jobStateDir, job.StateQuota)
+		if err != nil {
+			LogJobPrintf(job.Name, "Failed to enforce state quota: %v", err)
+		}
+		for _, path := range removed {
+			LogJobPrintf(job.Name, "Removed %v to stay within state quota", path)
+		}
+	}
+
+	r.mu.Lock()
+	if saveErr == nil {
+		cj.ID = runID
+	}
+	if history, ok := r.history[job.Name]; ok && saveErr == nil {
+		history = append([]CompletedJob{cj}, history...)
+		r.history[job.Name] = history[:min(len(history), ShouldRunHistoryLength)]
+	} else {
+		// Let the next lookup go to the database.
+		delete(r.history, job.Name)
+	}
+	r.mu.Unlock()
+
+	if job.ResultFile {
+		if err := writeResultFile(r.stateRoot, job.Name, cj); err != nil {
+			LogJobPrintf(job.Name, "Failed to write result file: %v", err)
+		}
+	}
+
+	if len(job.Attach) > 0 && !cj.Skipped {
+		cj.Attachments = resolveAttachments(*job, jobStateDir)
+	}
+
+	// A skipped run has nothing new to report.
+	skipNotify := cj.Skipped
+	if retry {
+		r.retry(*job, cj)
+		skipNotify = true
+	} else if cj.Dead {
+		LogJobPrintf(job.Name, "Giving up after %d attempts", cj.Attempt)
+	}
+
+	if job.DedupeFailures && saveErr == nil && !retry {
+		skip, err := r.dedupeFailure(job.Name, &cj)
+		if err != nil {
+			LogJobPrintf(job.Name, "Failed to check for repeated failure: %v", err)
+		}
+
+		skipNotify = skipNotify || skip
+		if skip {
+			LogJobPrintf(job.Name, "Not notifying about the same failure %d times in a row", cj.Repeats)
+		}
+	}
+
+	if len(job.Escalate) > 0 && !cj.IsSuccess() && saveErr == nil && !retry {
+		escalates, err := r.escalate(*job, &cj)
+		if err != nil {
+			LogJobPrintf(job.Name, "Failed to count failures in a row: %v", err)
+		} else if len(cj.NotifyVia) == 0 {
+			// The failures haven't reached the first step.
+			skipNotify = true
+		} else if escalates {
+			skipNotify = false
+		}
+	}
+
+	var notifyErr error
+	if !skipNotify {
+		notifyErr = notifyIfNeeded(r.notify, job.Notify, job.Name, cj)
+	}
+
+	if job.OnComplete != nil {
+		job.OnComplete(cj)
+	}
+
+	if r.OnComplete != nil {
+		if err := r.OnComplete(job.Name, cj); err != nil {
+			LogJobPrintf(job.Name, "Completion hook failed: %v", err)
+		}
+	}
+
+	if notifyErr != nil {
+		return newJobError(job.Name, fmt.Errorf("failed to notify about completed job: %w", notifyErr))
+	}
+
+	if saveErr != nil {
+		return newJobError(job.Name, fmt.Errorf("failed to save completed job: %w", saveErr))
+	}
+
+	// The failure isn't final until the last retry.
+	if runErr != nil && !retry {
+		return newJobError(job.Name, fmt.Errorf("command failed: %w", runErr))
+	}
+
+	return nil
+}
+
+// retry queues a failed run again after the retry delay of the job.
+func (r Runner) retry(job JobConfig, failed CompletedJob) {
+	job.retried++
+	job.startAt = r.Clock.Now().Add(job.RetryDelay)
+
+	// Whoever requested the run only waits for the first attempt.
+	job.OnComplete = nil
+	job.Stdout = nil
+	job.Stderr = nil
+
+	reason := failed.Error
+	if reason == "" {
+		reason = fmt.Sprintf("exit status %d", failed.ExitStatus)
+	}
+	LogJobPrintf(
+		job.Name,
+		"Retrying in %v (retry %d of %d) after failure: %v",
+		FormatDuration(job.RetryDelay),
+		job.retried,
+		job.Retries,
+		reason,
+	)
+
+	r.AddJob(job)
+}
+
+// RunQueued runs every queued job to completion, one queue after another.
+// It stops at the first error.
+func (r Runner) RunQueued() error {
+	for _, queueName := range r.queueNames() {
+		if err := r.runQueue(queueName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunQueuedParallel runs every queued job to completion with up to n queues at the same time.
+// Each queue stops at its first error.
+// It waits for all queues and returns their errors.
+func (r Runner) RunQueuedParallel(n int) error {
+	queueNames := r.queueNames()
+
+	errs := make([]error, len(queueNames))
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(max(n, 1), len(queueNames)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				errs[i] = r.runQueue(queueNames[i])
+			}
+		}()
+	}
+
+	for i := range queueNames {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (r Runner) queueNames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queueNames := make([]string, 0, len(r.queues))
+	for queueName := range r.queues {
+		queueNames = append(queueNames, queueName)
+	}
+	slices.Sort(queueNames)
+
+	return queueNames
+}
+
+// queueLengths returns how many jobs are in each queue, including a running one.
+func (r Runner) queueLengths() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lengths := make(map[string]int, len(r.queues))
+	for queueName, queue := range r.queues {
+		lengths[queueName] = len(queue.jobs)
+	}
+
+	return lengths
+}
+
+func (r Runner) runQueue(queueName string) error {
+	for r.queueLen(queueName) > 0 {
+		if jobName, wait := r.nextStart(queueName); wait > 0 {
+			logJobRoutinePrintf(jobName, "Waiting %v before start", FormatDuration(wait))
+
+			r.Clock.Sleep(wait)
+		}
+
+		if err := r.RunQueueHead(queueName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextStart returns the job in a queue that should start first and how long until it should.
+func (r Runner) nextStart(queueName string) (string, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobName := ""
+	var startAt time.Time
+	for _, job := range r.queues[queueName].jobs {
+		if jobName == "" || job.startAt.Before(startAt) {
+			jobName = job.Name
+			startAt = job.startAt
+		}
+	}
+
+	return jobName, startAt.Sub(r.Clock.Now())
+}
+
+func (r Runner) queueLen(queueName string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.queues[queueName].jobs)
+}
+
+// teeOptional returns extra alone if base is nil, otherwise an io.MultiWriter
+// of both. Avoids creating a MultiWriter wrapping a nil base, which exec.Cmd
+// would treat as a non-nil writer and fail to send to /dev/null.
+func teeOptional(base, extra io.Writer) io.Writer {
+	if base == nil {
+		return extra
+	}
+	return io.MultiWriter(base, extra)
+}
+
+func (r Runner) Run() {
+	ticker := r.Clock.NewTicker(runInterval)
+	defer ticker.Stop()
+
+	for range ticker.C() {
+		names := []string{}
+
+		r.mu.Lock()
+		for queueName, _ := range r.queues {
+			if _, ok := r.paused[queueName]; !ok {
+				names = append(names, queueName)
+			}
+		}
+		r.mu.Unlock()
+
+		for _, queueName := range names {
+			go WithLog(func() error {
+				return r.RunQueueHead(queueName)
+			})
+		}
+	}
+}
+
+// Count the running and pending instances of each job in the queues.
+func (r Runner) jobStates() map[string]JobInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make(map[string]JobInfo)
+
+	for _, queue := range r.queues {
+		for i, job := range queue.jobs {
+			state := states[job.Name]
+
+			if i == 0 && queue.activeJob {
+				state.Running = true
+			} else {
+				// The first pending instance is the one that has waited the longest.
+				if state.Pending == 0 {
+					state.Position = i
+					state.Waiting = r.Clock.Now().Sub(job.queuedAt)
+				}
+
+				state.Pending++
+			}
+
+			states[job.Name] = state
+		}
+	}
+
+	return states
+}
+
+// This function doesn't lock the runner or the queues.
+// It is left to the caller.
+func (r Runner) summarize() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sb strings.Builder
+
+	for queueName, queue := range r.queues {
+		sb.WriteString(queueName + ": ")
+
+		for i, job := range queue.jobs {
+			sb.WriteString(job.Name)
+
+			if i != len(queue.jobs)-1 {
+				sb.WriteString(", ")
+			}
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func runCommand(jobName string, env denv.Env, dir string, cmd []string, timeout time.Duration, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(cmd) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	c := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+	c.Dir = dir
+	c.Env = env.Strings()
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	return c.Run()
+}
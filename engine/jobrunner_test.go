@@ -0,0 +1,764 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+	"go.starlark.net/starlark"
+)
+
+func TestJobRunner(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir, err := os.MkdirTemp("", "jobrunner-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	// Test adding a job.
+	t.Run("AddJob", func(t *testing.T) {
+		job := JobConfig{
+			Name:    "test-job",
+			Command: []string{"echo", "hello"},
+			Env:     denv.Env{},
+		}
+		runner.AddJob(job)
+
+		if len(runner.queues["test-job"].jobs) != 1 {
+			t.Errorf("Expected 1 job in queue, got %d", len(runner.queues["test-job"].jobs))
+		}
+	})
+
+	// Test duplicate jobs.
+	t.Run("DuplicateJobs", func(t *testing.T) {
+		job := JobConfig{
+			Duplicate: true,
+			Name:      "duplicate-job",
+			Command:   []string{"echo", "test"},
+			Env:       denv.Env{},
+		}
+
+		runner.AddJob(job)
+		// Add same job again.
+		runner.AddJob(job)
+
+		if len(runner.queues["duplicate-job"].jobs) != 2 {
+			t.Errorf("Expected 2 jobs in queue, got %d", len(runner.queues["duplicate-job"].jobs))
+		}
+	})
+
+	// Test running a job.
+	t.Run("RunJob", func(t *testing.T) {
+		job := JobConfig{
+			Name:    "run-test-job",
+			Command: []string{"echo", "Hello, world!"},
+			Env:     denv.OS(),
+			Log:     true,
+		}
+		runner.AddJob(job)
+
+		err := runner.RunQueueHead("run-test-job")
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if len(runner.queues["run-test-job"].jobs) != 0 {
+			t.Errorf("Expected 0 jobs remaining in queue, got %d", len(runner.queues["run-test-job"].jobs))
+		}
+
+		// Verify that the log files were created.
+		logFiles := []string{stdoutFileName, stderrFileName}
+		for _, f := range logFiles {
+			path := filepath.Join(tmpDir, job.Name, f)
+
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				t.Errorf("Expected log file %q to exist", path)
+			}
+		}
+	})
+
+	// Test truncating the output.
+	t.Run("MaxOutput", func(t *testing.T) {
+		job := JobConfig{
+			Name:      "max-output-job",
+			Command:   []string{"sh", "-c", "printf 'head%01000dtail'"},
+			Env:       denv.OS(),
+			Log:       true,
+			MaxOutput: 100,
+		}
+		runner.AddJob(job)
+
+		if err := runner.RunQueueHead("max-output-job"); err != nil {
+			t.Fatalf("Failed to run job: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, job.Name, stdoutFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		output := string(data)
+		if !strings.HasPrefix(output, "head") || !strings.HasSuffix(output, "tail") || !strings.Contains(output, "bytes truncated") {
+			t.Errorf("unexpected truncated output: %q", output)
+		}
+	})
+
+	// Test a failed job.
+	t.Run("FailedJob", func(t *testing.T) {
+		job := JobConfig{
+			Name:    "fail-test-job",
+			Command: []string{"sh", "-c", "exit 2"},
+			Env:     denv.Env{},
+		}
+		runner.AddJob(job)
+
+		err := runner.RunQueueHead("fail-test-job")
+		if err == nil {
+			t.Errorf("Expected an error running job: %v", err)
+		}
+
+		completed, err := runner.LastCompleted("fail-test-job")
+		if err != nil {
+			t.Errorf("Failed to get completed job: %v", err)
+			return
+		}
+		if completed == nil {
+			t.Error("Expected completed job record, got nil")
+			return
+		}
+		if completed.ExitStatus != 2 {
+			t.Errorf("Expected exit status 2, got %d", completed.ExitStatus)
+		}
+	})
+
+	// Test the queue summary.
+	t.Run("QueueSummary", func(t *testing.T) {
+		summary := runner.summarize()
+
+		if summary == "" {
+			t.Error("Expected non-empty queue summary")
+		}
+	})
+
+	// Test that Stdout and OnComplete extension points fire.
+	t.Run("ExtensionPoints", func(t *testing.T) {
+		var buf bytes.Buffer
+		var done CompletedJob
+		called := false
+
+		job := JobConfig{
+			Name:    "extension-test-job",
+			Command: []string{"sh", "-c", "printf hi"},
+			Env:     denv.OS(),
+			Stdout:  &buf,
+			OnComplete: func(cj CompletedJob) {
+				called = true
+				done = cj
+			},
+		}
+		runner.AddJob(job)
+
+		if err := runner.RunQueueHead("extension-test-job"); err != nil {
+			t.Errorf("runQueueHead: %v", err)
+		}
+
+		if got := buf.String(); got != "hi" {
+			t.Errorf("captured stdout = %q, want %q", got, "hi")
+		}
+		if !called {
+			t.Error("OnComplete was not invoked")
+		}
+		if done.ExitStatus != 0 {
+			t.Errorf("OnComplete saw exit %d, want 0", done.ExitStatus)
+		}
+	})
+}
+
+func TestFuncRunCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    []string
+		wantErr    bool
+		exitStatus int
+	}{
+		{
+			name:       "successful command",
+			command:    []string{"true"},
+			wantErr:    false,
+			exitStatus: 0,
+		},
+		{
+			name:       "failed command",
+			command:    []string{"false"},
+			wantErr:    true,
+			exitStatus: 1,
+		},
+		{
+			name:       "nonexistent command",
+			command:    []string{"this-is-a-nonexistent-command"},
+			wantErr:    true,
+			exitStatus: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runCommand(tt.name, denv.Env{}, "", tt.command, 0, nil, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var exitErr *exec.ExitError
+			exitStatus := 0
+			if errors.As(err, &exitErr) {
+				exitStatus = exitErr.ExitCode()
+			}
+			if exitStatus != tt.exitStatus {
+				t.Errorf("runCommand() exit status = %d, want %d", exitStatus, tt.exitStatus)
+			}
+		})
+	}
+}
+
+func TestJobRunnerLastCompletedCache(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	// A job that has never run is cached as missing.
+	completed, err := runner.LastCompleted("cache-test-job")
+	if err != nil {
+		t.Fatalf("lastCompleted: %v", err)
+	}
+	if completed != nil {
+		t.Fatalf("Expected no completed job, got %+v", completed)
+	}
+
+	// Finishing a job updates the cache.
+	runner.AddJob(JobConfig{
+		Name:    "cache-test-job",
+		Command: []string{"true"},
+		Env:     denv.OS(),
+	})
+	if err := runner.RunQueueHead("cache-test-job"); err != nil {
+		t.Fatalf("runQueueHead: %v", err)
+	}
+
+	completed, err = runner.LastCompleted("cache-test-job")
+	if err != nil {
+		t.Fatalf("lastCompleted: %v", err)
+	}
+	if completed == nil {
+		t.Fatal("Expected completed job, got nil")
+	}
+
+	// Later lookups don't query the database.
+	if _, err := db.saveCompletedJob("cache-test-job", CompletedJob{ExitStatus: 99}, nil); err != nil {
+		t.Fatalf("saveCompletedJob: %v", err)
+	}
+
+	cached, err := runner.LastCompleted("cache-test-job")
+	if err != nil {
+		t.Fatalf("lastCompleted: %v", err)
+	}
+	if cached != completed {
+		t.Errorf("Expected cached completed job, got %+v", cached)
+	}
+}
+
+func TestJobRunnerJobStates(t *testing.T) {
+	runner, err := NewRunner(nil, nil, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	runner.queues["shared"] = jobQueue{
+		activeJob: true,
+		jobs: []JobConfig{
+			{Name: "a"},
+			{Name: "b"},
+			{Name: "a"},
+		},
+	}
+	runner.queues["c"] = jobQueue{
+		jobs: []JobConfig{{Name: "c"}},
+	}
+
+	states := runner.jobStates()
+
+	tests := []struct {
+		name     string
+		running  bool
+		pending  int
+		position int
+	}{
+		{"a", true, 1, 2},
+		{"b", false, 1, 1},
+		{"c", false, 1, 0},
+	}
+
+	for _, tt := range tests {
+		state := states[tt.name]
+		if state.Running != tt.running || state.Pending != tt.pending {
+			t.Errorf("%s: running = %v, pending = %d; want %v, %d", tt.name, state.Running, state.Pending, tt.running, tt.pending)
+		}
+		if state.Position != tt.position {
+			t.Errorf("%s: position = %d, want %d", tt.name, state.Position, tt.position)
+		}
+	}
+}
+
+func TestJobRunnerQueueEnv(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	runner.AddJob(JobConfig{
+		Name:    "queue-env",
+		Command: []string{"sh", "-c", `echo "$REGULAR_QUEUE $REGULAR_QUEUE_WAIT_SECONDS"`},
+		Env:     denv.OS(),
+		Queue:   "nightly",
+		Stdout:  &stdout,
+		startAt: time.Now().Add(1100 * time.Millisecond),
+	})
+	if err := runner.RunQueued(); err != nil {
+		t.Fatalf("RunQueued() error = %v", err)
+	}
+
+	if got := strings.TrimSpace(stdout.String()); got != "nightly 1" {
+		t.Errorf("Expected %q, got %q", "nightly 1", got)
+	}
+}
+
+func TestJobRunnerMaxQueue(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tests := []struct {
+		name     string
+		policy   OverflowPolicy
+		active   bool
+		expected []string
+		alerts   int
+	}{
+		{"drop oldest", OverflowDropOldest, false, []string{"2", "3"}, 0},
+		{"drop oldest while running", OverflowDropOldest, true, []string{"0", "2", "3"}, 0},
+		{"reject", OverflowReject, false, []string{"1", "2"}, 0},
+		{"alert", OverflowAlert, false, []string{"1", "2", "3", "4"}, 1},
+		{"drop oldest except retries", OverflowDropOldest, false, []string{"r", "r", "3"}, 0},
+		{"reject except retries", OverflowReject, false, []string{"r", "1", "r"}, 0},
+		{"limit from another job", OverflowReject, false, []string{"1", "2"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := make(chan CompletedJob, 10)
+			notify := func(jobName string, completed CompletedJob) error {
+				alerts <- completed
+				return nil
+			}
+
+			runner, err := NewRunner(nil, notify, t.TempDir())
+			if err != nil {
+				t.Fatalf("Failed to create job runner: %v", err)
+			}
+
+			if tt.active {
+				runner.queues["q"] = jobQueue{
+					activeJob: true,
+					jobs:      []JobConfig{{Name: "0", Queue: "q"}},
+				}
+			}
+
+			ids := []string{"1", "2", "3"}
+			switch {
+
+			case tt.policy == OverflowAlert:
+				ids = append(ids, "4")
+
+			case strings.HasSuffix(tt.name, "except retries"):
+				ids = []string{"r", "1", "r", "2", "3"}
+			}
+
+			for _, id := range ids {
+				// A retry of "r" is neither deduplicated nor limited.
+				retried := 0
+				if id == "r" {
+					retried = 1
+				}
+
+				// Only the first job sets the limit of the shared queue.
+				maxQueue := 2
+				if tt.name == "limit from another job" && id != "1" {
+					maxQueue = 0
+				}
+
+				runner.AddJob(JobConfig{
+					Name:          id,
+					Queue:         "q",
+					MaxQueue:      maxQueue,
+					QueueOverflow: tt.policy,
+					retried:       retried,
+				})
+			}
+
+			names := []string{}
+			for _, job := range runner.queues["q"].jobs {
+				names = append(names, job.Name)
+			}
+
+			if !slices.Equal(names, tt.expected) {
+				t.Errorf("queue = %v, want %v", names, tt.expected)
+			}
+
+			// Wait for the alerts sent in the background.
+			runner.alerts.Wait()
+			close(alerts)
+
+			n := 0
+			for alert := range alerts {
+				n++
+				if !strings.Contains(alert.Error, "over its limit") {
+					t.Errorf("unexpected alert: %+v", alert)
+				}
+			}
+			if n != tt.alerts {
+				t.Errorf("got %d alerts, want %d", n, tt.alerts)
+			}
+		})
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected OverflowPolicy
+		wantErr  bool
+	}{
+		{"alert", OverflowAlert, false},
+		{"drop-oldest", OverflowDropOldest, false},
+		{"reject", OverflowReject, false},
+		{"", OverflowDropOldest, false},
+		{"invalid", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOverflowPolicy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOverflowPolicy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseOverflowPolicy(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestJobRunnerRunQueuedParallel(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	for _, name := range []string{"sleep-a", "sleep-b"} {
+		runner.AddJob(JobConfig{
+			Name:    name,
+			Command: []string{"sleep", "0.5"},
+			Env:     denv.OS(),
+		})
+	}
+	runner.AddJob(JobConfig{
+		Name:    "fail",
+		Command: []string{"false"},
+		Env:     denv.OS(),
+	})
+
+	started := time.Now()
+	err = runner.RunQueuedParallel(3)
+	elapsed := time.Since(started)
+
+	if err == nil || !strings.Contains(err.Error(), "fail") {
+		t.Errorf("Expected the error of the failed job, got %v", err)
+	}
+
+	if elapsed > 900*time.Millisecond {
+		t.Errorf("Queues didn't run in parallel: took %v", elapsed)
+	}
+
+	for _, name := range []string{"sleep-a", "sleep-b", "fail"} {
+		completed, err := runner.LastCompleted(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if completed == nil {
+			t.Errorf("Job %q didn't run", name)
+		}
+	}
+}
+
+func TestJobRunnerSignal(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	runner.AddJob(JobConfig{
+		Name:    "killed-job",
+		Command: []string{"sh", "-c", "kill -TERM $$"},
+		Env:     denv.OS(),
+	})
+	if err := runner.RunQueueHead("killed-job"); err == nil {
+		t.Fatal("Expected an error from a killed job")
+	}
+
+	completed, err := db.LastCompleted("killed-job")
+	if err != nil {
+		t.Fatalf("lastCompleted: %v", err)
+	}
+	if completed == nil {
+		t.Fatal("Expected completed job, got nil")
+	}
+
+	if completed.Signal != int(syscall.SIGTERM) {
+		t.Errorf("Signal = %d, want %d", completed.Signal, syscall.SIGTERM)
+	}
+	if got := completed.SignalName(); got != "SIGTERM (15)" {
+		t.Errorf("SignalName() = %q", got)
+	}
+}
+
+func TestJobRunnerStartAt(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	startAt := time.Now().Add(200 * time.Millisecond)
+	runner.AddJob(JobConfig{
+		Name:    "delayed-job",
+		Command: []string{"true"},
+		Env:     denv.OS(),
+		startAt: startAt,
+	})
+	if err := runner.RunQueued(); err != nil {
+		t.Fatalf("RunQueued() error = %v", err)
+	}
+
+	completed, err := runner.LastCompleted("delayed-job")
+	if err != nil || completed == nil {
+		t.Fatalf("LastCompleted() = %v, %v", completed, err)
+	}
+
+	if completed.Started.Before(startAt) {
+		t.Errorf("job started at %v, before %v", completed.Started, startAt)
+	}
+}
+
+func TestJobRunnerMinInterval(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+	runner.MinInterval = time.Hour
+
+	alwaysDue := starlark.NewBuiltin("should_run", func(_ *starlark.Thread, _ *starlark.Builtin, _ starlark.Tuple, _ []starlark.Tuple) (starlark.Value, error) {
+		return starlark.True, nil
+	})
+	job := JobConfig{
+		Name:      "loop-job",
+		Command:   []string{"true"},
+		Enable:    true,
+		Env:       denv.OS(),
+		ShouldRun: alwaysDue,
+	}
+
+	// The first run isn't limited.
+	now := time.Now()
+	if err := job.AddToQueueIfDue(runner, now); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if n := runner.queueLen("loop-job"); n != 1 {
+		t.Fatalf("Expected the job to be queued, queue length is %d", n)
+	}
+	if err := runner.RunQueueHead("loop-job"); err != nil {
+		t.Fatalf("RunQueueHead() error = %v", err)
+	}
+
+	// A minute later is too soon.
+	if err := job.AddToQueueIfDue(runner, now.Add(time.Minute)); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if n := runner.queueLen("loop-job"); n != 0 {
+		t.Errorf("Expected the job not to be queued, queue length is %d", n)
+	}
+
+	// After the interval the job runs again.
+	if err := job.AddToQueueIfDue(runner, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if n := runner.queueLen("loop-job"); n != 1 {
+		t.Errorf("Expected the job to be queued, queue length is %d", n)
+	}
+}
+
+func TestJobRunnerRetries(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	notified := []CompletedJob{}
+	notify := func(jobName string, completed CompletedJob) error {
+		notified = append(notified, completed)
+		return nil
+	}
+
+	runner, err := NewRunner(db, notify, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	// Succeeds on the second attempt.
+	counter := filepath.Join(tmpDir, "attempts")
+	runner.AddJob(JobConfig{
+		Name:    "flaky",
+		Command: []string{"sh", "-c", `echo x >> "$0"; [ "$(wc -l < "$0")" -ge 2 ]`, counter},
+		Env:     denv.OS(),
+		Notify:  NotifyOnFailure,
+		Retries: 3,
+	})
+
+	// Always fails.
+	runner.AddJob(JobConfig{
+		Name:    "broken",
+		Command: []string{"false"},
+		Env:     denv.OS(),
+		Notify:  NotifyOnFailure,
+		Retries: 2,
+	})
+
+	if err := runner.RunQueuedParallel(2); err == nil {
+		t.Fatal("Expected the broken job to fail")
+	}
+
+	flaky, err := db.History("flaky", 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(flaky) != 2 || !flaky[0].IsSuccess() || flaky[0].Attempt != 2 {
+		t.Errorf("Expected the flaky job to succeed on attempt 2, got %+v", flaky)
+	}
+
+	broken, err := db.History("broken", 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(broken) != 3 {
+		t.Fatalf("Expected 3 attempts of the broken job, got %d", len(broken))
+	}
+	if !broken[0].Dead || broken[1].Dead {
+		t.Errorf("Expected only the last attempt to be dead")
+	}
+
+	// Only the dead run is notified about.
+	if len(notified) != 1 || !notified[0].Dead || notified[0].Attempt != 3 {
+		t.Errorf("Expected one notification about the dead run, got %+v", notified)
+	}
+
+	dead, err := db.Runs("", true, 10)
+	if err != nil {
+		t.Fatalf("Failed to get dead runs: %v", err)
+	}
+	if len(dead) != 1 || dead[0].JobName != "broken" || dead[0].ID != broken[0].ID {
+		t.Errorf("Expected the last run of the broken job to be dead, got %+v", dead)
+	}
+}
@@ -0,0 +1,785 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bep/debounce"
+	"github.com/syncthing/notify"
+
+	"dbohdan.com/denv"
+	"dbohdan.com/regular/envfile"
+)
+
+type Scheduler struct {
+	byName      map[string]JobConfig
+	cache       map[string]cachedJob
+	fromCrontab map[string]struct{}
+
+	// Where to record configuration changes or nil.
+	auditDB *AppDB
+
+	// The environment defaults from the settings or nil until they are loaded.
+	// LoadAll reloads them, and the daemon calls it when the settings change.
+	envDefaults denv.Env
+
+	// The time the schedules see and the ticks of the schedule loop.
+	clock Clock
+
+	mu sync.RWMutex
+
+	stats   SchedulerStats
+	statsMu sync.Mutex
+}
+
+type updateJobsResult int
+
+const (
+	jobsNoChanges updateJobsResult = iota
+	jobsAddedNew
+	jobsUpdated
+)
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		byName:      make(map[string]JobConfig),
+		cache:       make(map[string]cachedJob),
+		clock:       SystemClock{},
+		fromCrontab: make(map[string]struct{}),
+	}
+}
+
+// SetClock makes the scheduler check the schedules against another clock.
+// Call it before Schedule.
+func (jsc *Scheduler) SetClock(clock Clock) {
+	jsc.clock = clock
+}
+
+// Evaluate "should_run" for every job concurrently and queue the jobs that are due.
+// The lock is only held while copying the jobs, so job updates don't wait for slow schedules.
+// A job whose "should_run" fails is logged and doesn't keep the other jobs from being queued.
+func (jsc *Scheduler) addDueJobsToQueue(runner Runner, t time.Time) {
+	jobs := jsc.Jobs()
+
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(jobs)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				err := jobs[i].AddToQueueIfDue(runner, t)
+				if err != nil {
+					LogJobPrintf(jobs[i].Name, "Scheduling error: %v", err)
+				}
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
+
+// Job returns the loaded job with the given name.
+func (jsc *Scheduler) Job(name string) (JobConfig, bool) {
+	jsc.mu.RLock()
+	job, ok := jsc.byName[name]
+	jsc.mu.RUnlock()
+
+	return job, ok
+}
+
+// Jobs returns the loaded jobs sorted by name.
+func (jsc *Scheduler) Jobs() []JobConfig {
+	jsc.mu.RLock()
+	jobs := make([]JobConfig, 0, len(jsc.byName))
+	for _, job := range jsc.byName {
+		jobs = append(jobs, job)
+	}
+	jsc.mu.RUnlock()
+
+	slices.SortFunc(jobs, func(a, b JobConfig) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return jobs
+}
+
+// LoadJob loads a single job by name from its job directory or, if there is no directory, the crontabs.
+// The job directory in the last config root that has one wins.
+func (jsc *Scheduler) LoadJob(configRoots []string, name string) (*JobConfig, error) {
+	jobs, err := jsc.LoadJobs(configRoots, name)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Name == name {
+			return &job, nil
+		}
+
+		names = append(names, job.Name)
+	}
+
+	return nil, fmt.Errorf("job %q not found; its job file defines: %s", name, strings.Join(names, ", "))
+}
+
+// LoadJobs loads every job in the job file that defines the named job.
+// The file defines several jobs when it calls "job".
+func (jsc *Scheduler) LoadJobs(configRoots []string, name string) ([]JobConfig, error) {
+	jsc.envDefaultsFor(configRoots)
+
+	file, ok := findJobFile(configRoots, jobDirName(name))
+	if !ok {
+		if _, err := jsc.UpdateCrontab(configRoots...); err != nil {
+			return nil, err
+		}
+
+		if job, ok := jsc.Job(name); ok {
+			return []JobConfig{job}, nil
+		}
+	}
+
+	_, jobs, err := jsc.update(file.root, file.path)
+	return jobs, err
+}
+
+// jobFile is the config file of a job and the config root it is in.
+type jobFile struct {
+	root string
+	path string
+}
+
+// findJobFile finds the config file of a job in the config roots.
+// When there is none, it returns the path in the last root.
+func findJobFile(configRoots []string, name string) (jobFile, bool) {
+	var file jobFile
+
+	for i := len(configRoots) - 1; i >= 0; i-- {
+		root := configRoots[i]
+		file = jobFile{root: root, path: filepath.Join(root, name, jobConfigFileName)}
+
+		if _, err := os.Stat(file.path); err == nil {
+			return file, true
+		}
+	}
+
+	return file, false
+}
+
+// findJobFiles finds the config files of every job in the config roots.
+// A job in a later root overrides a job with the same name in an earlier one.
+func findJobFiles(configRoots []string) (map[string]jobFile, error) {
+	files := make(map[string]jobFile)
+
+	for _, root := range configRoots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && filepath.Base(path) == jobConfigFileName {
+				files[jobNameFromPath(path)] = jobFile{root: root, path: path}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// configRootOf returns the config root that contains path or "" if none does.
+// The longest root wins when roots are nested.
+func configRootOf(configRoots []string, path string) string {
+	found := ""
+
+	for _, root := range configRoots {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if len(root) > len(found) {
+			found = root
+		}
+	}
+
+	return found
+}
+
+// exists reports whether a job or the jobs a job file defines with "job" are loaded.
+func (jsc *Scheduler) exists(name string) bool {
+	jsc.mu.RLock()
+	defer jsc.mu.RUnlock()
+
+	if _, exists := jsc.byName[name]; exists {
+		return true
+	}
+
+	_, loaded := jsc.loadedJobs(jsc.cache[name].jobs)
+	return loaded
+}
+
+// LoadAll loads or reloads every job in the config roots.
+// A job in a later root overrides a job with the same name in an earlier one.
+// Jobs that are already loaded are updated in place, so they stay loaded during a reload.
+// Jobs that fail to load or whose files are gone are removed.
+func (jsc *Scheduler) LoadAll(configRoots ...string) ([]string, error) {
+	loadedJobs := []string{}
+
+	jsc.loadEnvDefaults(configRoots)
+
+	found, err := findJobFiles(configRoots)
+	if err != nil {
+		return loadedJobs, err
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, jobName := range names {
+		file := found[jobName]
+
+		res, jobs, err := jsc.update(file.root, file.path)
+		if err == nil {
+			for _, job := range jobs {
+				loadedJobs = append(loadedJobs, job.Name)
+
+				if res != jobsNoChanges {
+					logJobWarnings(job)
+				}
+			}
+		} else {
+			LogJobPrintf(jobName, "Error loading job: %v", err)
+
+			if jsc.remove(jobName) == nil {
+				jsc.audit(AuditRemove, jobName, file.path)
+				LogJobPrintf(jobName, "Removed job after load error")
+			}
+		}
+	}
+
+	for _, job := range jsc.Jobs() {
+		jsc.mu.RLock()
+		_, isCrontabJob := jsc.fromCrontab[job.Name]
+		jsc.mu.RUnlock()
+
+		if _, ok := found[jobDirName(job.Name)]; ok || isCrontabJob {
+			continue
+		}
+
+		if jsc.remove(job.Name) == nil {
+			jsc.audit(AuditRemove, job.Name, filepath.Join(job.Env[jobDirEnvVar], jobConfigFileName))
+			LogJobPrintf(job.Name, "Removed job because config file is gone")
+		}
+	}
+
+	crontabJobs, err := jsc.UpdateCrontab(configRoots...)
+	if err != nil {
+		log.Printf("Error loading crontab at startup: %v", err)
+	}
+	loadedJobs = append(loadedJobs, crontabJobs...)
+
+	return loadedJobs, nil
+}
+
+func (jsc *Scheduler) Schedule(runner Runner) error {
+	ticker := jsc.clock.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	current := jsc.clock.Now()
+	var last time.Time
+
+	jsc.addDueJobsToQueue(runner, current)
+
+	for range ticker.C() {
+		last = current
+		current = jsc.clock.Now()
+		lag := current.Sub(last) - scheduleInterval
+
+		// Account for missed time.
+		// Do not run missed jobs if more than maxMissedTime has elapsed.
+		// On an overloaded system, the ticker can miss a minute.
+		// For example, this may happen because Regular was swapped out.
+		// It would prevent Regular from running jobs scheduled for that minute and that minute alone.
+		// The purpose of this approach is to catch up on missed jobs.
+		// However, we should run days' worth of missed jobs after system hibernation.
+		if current.Sub(last) > maxMissedTime {
+			last = current
+		}
+
+		checkStart := time.Now()
+		for t := last; t.Before(current); t = t.Add(time.Minute) {
+			jsc.addDueJobsToQueue(runner, t)
+		}
+
+		jsc.recordTick(current, lag, time.Since(checkStart))
+	}
+
+	return nil
+}
+
+// Update loads or reloads a job file.
+// It returns the first job when the file defines several with "job".
+func (jsc *Scheduler) Update(configRoot, jobPath string) (updateJobsResult, *JobConfig, error) {
+	res, jobs, err := jsc.update(configRoot, jobPath)
+	if err != nil {
+		return res, nil, err
+	}
+
+	return res, &jobs[0], nil
+}
+
+// update loads or reloads every job a job file defines.
+// It removes the jobs the file defined before but no longer does.
+func (jsc *Scheduler) update(configRoot, jobPath string) (updateJobsResult, []JobConfig, error) {
+	jobDir := jobDir(jobPath)
+	jobName := jobNameFromPath(jobPath)
+
+	// The defaults are normally loaded already from the main config root.
+	env, err := loadEnv(configRoot, jobDir, jsc.envDefaultsFor([]string{configRoot}))
+	if err != nil {
+		return jobsNoChanges, nil, err
+	}
+
+	src, err := os.ReadFile(jobPath)
+	if err != nil {
+		return jobsNoChanges, nil, fmt.Errorf("failed to load job: %v", err)
+	}
+
+	// Reload the job when the template file it may use changes.
+	templateSrc, err := readTemplate(configRoot)
+	if err != nil {
+		return jobsNoChanges, nil, fmt.Errorf("failed to read template file: %v", err)
+	}
+
+	srcHash := sha256.Sum256(append(src, templateSrc...))
+	envHash := hashEnv(env)
+
+	jsc.mu.RLock()
+	cached, cacheHit := jsc.cache[jobName]
+	existing, loaded := jsc.loadedJobs(cached.jobs)
+	jsc.mu.RUnlock()
+
+	if cacheHit && loaded && cached.srcHash == srcHash && cached.envHash == envHash &&
+		cached.readHash == hashFiles(cached.readFiles) && cached.globHash == hashGlobs(cached.globs) &&
+		cached.whichHash == hashWhichLookups(cached.whichLookups) {
+		return jobsNoChanges, existing, nil
+	}
+
+	// Reuse the compiled program when only the env has changed.
+	program := cached.program
+	if !cacheHit || cached.srcHash != srcHash {
+		program, err = compileJob(jobPath, src)
+		if err != nil {
+			return jobsNoChanges, nil, fmt.Errorf("failed to load job: %v", err)
+		}
+	}
+
+	jobs, err := loadJobProgram(env, configRoot, jobPath, program)
+	if err != nil {
+		return jobsNoChanges, nil, fmt.Errorf("failed to load job: %v", err)
+	}
+
+	res := jobsAddedNew
+	names := make([]string, 0, len(jobs))
+	removed := []string{}
+
+	jsc.mu.Lock()
+	for _, job := range jobs {
+		if _, exists := jsc.byName[job.Name]; exists {
+			res = jobsUpdated
+		}
+
+		jsc.byName[job.Name] = job
+		delete(jsc.fromCrontab, job.Name)
+		names = append(names, job.Name)
+	}
+
+	for _, name := range cached.jobs {
+		if !slices.Contains(names, name) {
+			delete(jsc.byName, name)
+			removed = append(removed, name)
+		}
+	}
+
+	jsc.cache[jobName] = cachedJob{
+		envHash:   envHash,
+		jobs:      names,
+		program:   program,
+		readFiles: jobs[0].readFiles,
+		readHash:  hashFiles(jobs[0].readFiles),
+		globs:     jobs[0].globs,
+		globHash:  hashGlobs(jobs[0].globs),
+		srcHash:   srcHash,
+
+		whichLookups: jobs[0].whichLookups,
+		whichHash:    hashWhichLookups(jobs[0].whichLookups),
+	}
+	jsc.mu.Unlock()
+
+	for _, name := range removed {
+		jsc.audit(AuditRemove, name, jobPath)
+		LogJobPrintf(name, "Removed job because the job file no longer defines it")
+	}
+
+	return res, jobs, nil
+}
+
+// loadedJobs returns the named jobs if they are all loaded from job files.
+// The caller must hold the lock.
+func (jsc *Scheduler) loadedJobs(names []string) ([]JobConfig, bool) {
+	if len(names) == 0 {
+		return nil, false
+	}
+
+	jobs := make([]JobConfig, 0, len(names))
+	for _, name := range names {
+		job, exists := jsc.byName[name]
+		_, isCrontabJob := jsc.fromCrontab[name]
+		if !exists || isCrontabJob {
+			return nil, false
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, true
+}
+
+// Load the environment for a job from the OS, the global env files, and the job env files.
+// The defaults fill in the variables the OS environment lacks.
+// Encrypted env files override plaintext ones in the same directory.
+// An empty jobDir skips the job env files.
+func loadEnv(configRoot, jobDir string, defaults denv.Env) (denv.Env, error) {
+	files := []envfile.File{
+		{Name: "global", Path: filepath.Join(configRoot, globalEnvFileName)},
+		{Name: "encrypted global", Path: filepath.Join(configRoot, encryptedEnvFileName), Decrypt: decryptAge(configRoot)},
+	}
+	if jobDir != "" {
+		files = append(files,
+			envfile.File{Name: "job", Path: filepath.Join(jobDir, jobEnvFileName)},
+			envfile.File{Name: "encrypted job", Path: filepath.Join(jobDir, encryptedEnvFileName), Decrypt: decryptAge(configRoot)},
+		)
+	}
+
+	env, err := envfile.LoadOver(withEnvDefaults(denv.OS(), defaults), files...)
+	if err != nil {
+		return nil, err
+	}
+
+	if jobDir != "" {
+		env[jobDirEnvVar] = jobDir
+	}
+
+	return env, nil
+}
+
+// decryptAge decrypts with the age identity from the settings.
+// The settings are only loaded when there is an encrypted file.
+func decryptAge(configRoot string) envfile.Decrypter {
+	return func(path string) ([]byte, error) {
+		settings, err := LoadSettings(configRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		return envfile.Age(settings.AgeIdentity)(path)
+	}
+}
+
+// Replace the jobs from the crontab files in the config roots with their current contents.
+// Jobs defined in job directories take precedence over crontab jobs with the same name.
+// A crontab entry in a later root overrides one with the same name in an earlier root.
+func (jsc *Scheduler) UpdateCrontab(configRoots ...string) ([]string, error) {
+	defaults := jsc.envDefaultsFor(configRoots)
+
+	byName := make(map[string]JobConfig)
+	for _, configRoot := range configRoots {
+		env, err := loadEnv(configRoot, "", defaults)
+		if err != nil {
+			return nil, err
+		}
+		env[jobDirEnvVar] = configRoot
+
+		rootJobs, err := loadCrontab(env, filepath.Join(configRoot, crontabFileName))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load crontab: %w", err)
+		}
+
+		for _, job := range rootJobs {
+			byName[job.Name] = job
+		}
+	}
+
+	jobs := make([]JobConfig, 0, len(byName))
+	for _, job := range byName {
+		jobs = append(jobs, job)
+	}
+	slices.SortFunc(jobs, func(a, b JobConfig) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	jsc.mu.Lock()
+	defer jsc.mu.Unlock()
+
+	for name := range jsc.fromCrontab {
+		delete(jsc.byName, name)
+	}
+	jsc.fromCrontab = make(map[string]struct{})
+
+	loadedJobs := []string{}
+	for _, job := range jobs {
+		if _, exists := jsc.byName[job.Name]; exists {
+			LogJobPrintf(job.Name, "Ignoring crontab entry because a job directory with the same name exists")
+			continue
+		}
+
+		jsc.byName[job.Name] = job
+		jsc.fromCrontab[job.Name] = struct{}{}
+		loadedJobs = append(loadedJobs, job.Name)
+	}
+
+	return loadedJobs, nil
+}
+
+// readersOf returns the names of the job files that read a file with "read_file"
+// or matched a pattern that matches it with "glob".
+func (jsc *Scheduler) readersOf(path string) []string {
+	jsc.mu.RLock()
+	defer jsc.mu.RUnlock()
+
+	matchesGlob := func(pattern string) bool {
+		matched, _ := filepath.Match(pattern, path)
+		return matched
+	}
+
+	readers := []string{}
+	for name, cached := range jsc.cache {
+		if slices.Contains(cached.readFiles, path) || slices.ContainsFunc(cached.globs, matchesGlob) {
+			readers = append(readers, name)
+		}
+	}
+
+	return readers
+}
+
+// logJobWarnings logs the problems found when loading a job.
+func logJobWarnings(job JobConfig) {
+	for _, warning := range job.Warnings() {
+		LogJobPrintf(job.Name, "Warning: %s", warning)
+	}
+}
+
+// remove removes a job.
+// When the name is that of a job file that defines several jobs, it removes all of them.
+func (jsc *Scheduler) remove(name string) error {
+	jsc.mu.Lock()
+	defer jsc.mu.Unlock()
+
+	names := []string{name}
+	if cached, ok := jsc.cache[name]; ok && len(cached.jobs) > 0 {
+		names = cached.jobs
+	}
+
+	removed := false
+	for _, name := range names {
+		if _, exists := jsc.byName[name]; exists {
+			delete(jsc.byName, name)
+			removed = true
+		}
+	}
+
+	if !removed {
+		return fmt.Errorf("failed to find job to remove: %v", name)
+	}
+
+	delete(jsc.cache, name)
+	return nil
+}
+
+// Remove every job but keep the compiled job files, so reloading them is cheap.
+func (jsc *Scheduler) removeAll() {
+	jsc.mu.Lock()
+	defer jsc.mu.Unlock()
+
+	jsc.byName = make(map[string]JobConfig)
+	jsc.fromCrontab = make(map[string]struct{})
+}
+
+// globalEnvDebounceKey is the per-job-debouncer key reserved for reloads
+// after a change to the global env files or the settings. Job names cannot contain a path separator, so this can never
+// collide with a real job name.
+const globalEnvDebounceKey = "/global.env"
+
+// crontabDebounceKey is the per-job-debouncer key reserved for crontab reloads.
+const crontabDebounceKey = "/crontab"
+
+// WatchChanges reloads jobs on changes in the config roots and queues jobs with a "run-now" file.
+// The caller watches every root with eventChan.
+func (jsc *Scheduler) WatchChanges(configRoots []string, runner Runner, eventChan <-chan notify.EventInfo) error {
+	var debouncerMu sync.Mutex
+	debouncers := map[string]func(func()){}
+	debouncerFor := func(key string) func(func()) {
+		debouncerMu.Lock()
+		defer debouncerMu.Unlock()
+
+		d, ok := debouncers[key]
+		if !ok {
+			d = debounce.New(debounceInterval)
+			debouncers[key] = d
+		}
+
+		return d
+	}
+
+	for eventInfo := range eventChan {
+		event := eventInfo.Event()
+		eventPath := eventInfo.Path()
+
+		basename := filepath.Base(eventPath)
+		jobName := jobNameFromPath(eventPath)
+		configRoot := configRootOf(configRoots, eventPath)
+		jobConfigPath := path.Join(configRoot, jobName, jobConfigFileName)
+
+		updateJob := func(jobName string) {
+			// The job may be in another root that overrides this one.
+			file, _ := findJobFile(configRoots, jobName)
+			jobConfigPath := file.path
+
+			res, jobs, err := jsc.update(file.root, jobConfigPath)
+			if err != nil {
+				// If the file doesn't exist or there is another error, remove the job.
+				removeErr := jsc.remove(jobName)
+				if removeErr == nil {
+					jsc.audit(AuditRemove, jobName, jobConfigPath)
+
+					if os.IsNotExist(err) {
+						LogJobPrintf(jobName, "Removed job because config file is gone")
+					} else {
+						LogJobPrintf(jobName, "Removed job after update error: %v", err)
+					}
+				} else {
+					// Log both errors if removal fails.
+					LogJobPrintf(jobName, "Failed to remove job: %v (original error: %v)", removeErr, err)
+				}
+
+				// Do not proceed after handling an error or job removal.
+				return
+			}
+
+			if res != jobsNoChanges {
+				for _, job := range jobs {
+					logJobWarnings(job)
+				}
+			}
+
+			switch res {
+
+			case jobsNoChanges:
+				// This case might not happen often with file events, but log just in case.
+				logJobRoutinePrintf(jobName, "Job checked; no effective changes detected")
+
+			case jobsUpdated:
+				jsc.audit(AuditUpdate, jobName, jobConfigPath)
+				LogJobPrintf(jobName, "Updated job")
+
+			case jobsAddedNew:
+				jsc.audit(AuditAdd, jobName, jobConfigPath)
+				LogJobPrintf(jobName, "Added job")
+			}
+		}
+		handleUpdate := func() {
+			updateJob(jobName)
+		}
+
+		inConfigRoot := filepath.Dir(eventPath) == filepath.Clean(configRoot)
+		isGlobalEnv := basename == globalEnvFileName ||
+			inConfigRoot && (basename == encryptedEnvFileName || basename == settingsFileName || basename == templateFileName)
+
+		if isGlobalEnv {
+			debouncerFor(globalEnvDebounceKey)(func() {
+				jsc.audit(AuditReload, "", eventPath)
+				loadedJobs, err := jsc.LoadAll(configRoots...)
+				if err == nil {
+					log.Printf("Reloaded jobs because %s changed: %s", basename, strings.Join(loadedJobs, ", "))
+				} else {
+					log.Printf("Failed to reload jobs because %s changed: %v", basename, err)
+				}
+			})
+		} else if basename == crontabFileName && inConfigRoot {
+			debouncerFor(crontabDebounceKey)(func() {
+				jsc.audit(AuditReload, "", eventPath)
+				loadedJobs, err := jsc.UpdateCrontab(configRoots...)
+				if err == nil {
+					log.Printf("Reloaded jobs from crontab: %s", strings.Join(loadedJobs, ", "))
+				} else {
+					log.Printf("Failed to reload crontab: %v", err)
+				}
+			})
+		} else if basename == jobConfigFileName {
+			if _, err := os.Stat(eventPath); err == nil {
+				// Debounce updates to handle rapid saves.
+				debouncerFor(jobName)(handleUpdate)
+			} else if _, ok := findJobFile(configRoots, jobName); ok && os.IsNotExist(err) {
+				// Fall back to the job in another root.
+				debouncerFor(jobName)(handleUpdate)
+			} else if os.IsNotExist(err) {
+				// If the file doesn't exist by the time debounce runs, treat as removal
+				errRemove := jsc.remove(jobName)
+				if errRemove == nil {
+					jsc.audit(AuditRemove, jobName, eventPath)
+					LogJobPrintf(jobName, "Removed job because config file is gone")
+				} else {
+					LogJobPrintf(jobName, "Failed to remove job with config file gone: %v", errRemove)
+				}
+			} else {
+				LogJobPrintf(jobName, "Error calling os.Stat on file %q before update: %v", eventPath, err)
+			}
+		} else if basename == runNowFileName && event != notify.Remove && filepath.Dir(filepath.Dir(eventPath)) == filepath.Clean(configRoot) {
+			jsc.triggerRunNow(runner, eventPath)
+		} else if (basename == jobEnvFileName || basename == encryptedEnvFileName) && jsc.exists(jobName) {
+			debouncerFor(jobName)(handleUpdate)
+		} else if readers := jsc.readersOf(eventPath); len(readers) > 0 {
+			// Reload the jobs whose job files read the file with "read_file" or listed it with "glob".
+			for _, reader := range readers {
+				debouncerFor(reader)(func() {
+					updateJob(reader)
+				})
+			}
+		} else if event == notify.Create {
+			// Handle creation of other files or dirs.
+			// If a directory is created, check if it contains a job config file.
+			if info, err := os.Stat(eventPath); err == nil && info.IsDir() {
+				if _, err := os.Stat(jobConfigPath); err == nil {
+					debouncerFor(jobName)(handleUpdate)
+				}
+			}
+		}
+	}
+
+	return nil
+}
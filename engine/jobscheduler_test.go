@@ -0,0 +1,474 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewJobScheduler(t *testing.T) {
+	jsc := NewScheduler()
+
+	if jsc.byName == nil {
+		t.Error(`"byName" map should be initialized`)
+	}
+}
+
+func TestJobSchedulerUpdate(t *testing.T) {
+	jsc := NewScheduler()
+
+	configRoot := t.TempDir()
+	jobDir := filepath.Join(configRoot, "test-job")
+	jobPath := filepath.Join(jobDir, jobConfigFileName)
+
+	// Test updating a job with no job file.
+	result, _, err := jsc.Update(configRoot, jobPath)
+	if err == nil {
+		t.Error("expected error when updating non-existent job")
+	}
+
+	if result != jobsNoChanges {
+		t.Errorf(`expected "jobsNoChanges", got %v`, result)
+	}
+}
+
+func TestJobSchedulerRemove(t *testing.T) {
+	jsc := NewScheduler()
+
+	// Test removing a non-existent job.
+	err := jsc.remove("nonexistent")
+	if err == nil {
+		t.Error("expected error when removing non-existent job")
+	}
+
+	// Add a mock job and test removal.
+	jsc.byName["test-job"] = JobConfig{}
+
+	err = jsc.remove("test-job")
+	if err != nil {
+		t.Errorf("unexpected error removing existing job: %v", err)
+	}
+
+	if _, exists := jsc.byName["test-job"]; exists {
+		t.Error("job should have been removed")
+	}
+}
+
+func TestJobNameFromPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/path/to/jobdir/config.star", "jobdir"},
+		{"jobdir/config.star", "jobdir"},
+		{"config.star", "."},
+	}
+
+	for _, tt := range tests {
+		result := jobNameFromPath(tt.path)
+		if result != tt.expected {
+			t.Errorf("jobNameFromPath(%q) = %q, want %q", tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestJobSchedulerUpdateCache(t *testing.T) {
+	jsc := NewScheduler()
+
+	configRoot := t.TempDir()
+	jobPath := writeTestJob(t, configRoot, "test-job", "jitter = 1\n")
+
+	result, _, err := jsc.Update(configRoot, jobPath)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if result != jobsAddedNew {
+		t.Errorf(`expected "jobsAddedNew", got %v`, result)
+	}
+
+	// Reloading an unchanged file is a no-op.
+	result, _, err = jsc.Update(configRoot, jobPath)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if result != jobsNoChanges {
+		t.Errorf(`expected "jobsNoChanges", got %v`, result)
+	}
+
+	// A changed file is reloaded.
+	writeTestJob(t, configRoot, "test-job", "jitter = 2\n")
+
+	result, job, err := jsc.Update(configRoot, jobPath)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if result != jobsUpdated {
+		t.Errorf(`expected "jobsUpdated", got %v`, result)
+	}
+	if job.Jitter != 2*time.Second {
+		t.Errorf("expected jitter 2s, got %v", job.Jitter)
+	}
+
+	// A changed env is applied with the cached program.
+	envPath := filepath.Join(configRoot, "test-job", jobEnvFileName)
+	if err := os.WriteFile(envPath, []byte("FOO=bar\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	result, job, err = jsc.Update(configRoot, jobPath)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if result != jobsUpdated {
+		t.Errorf(`expected "jobsUpdated", got %v`, result)
+	}
+	if job.Env["FOO"] != "bar" {
+		t.Errorf(`expected Env["FOO"] = "bar", got %q`, job.Env["FOO"])
+	}
+}
+
+func writeTestJob(t testing.TB, configRoot, name, content string) string {
+	t.Helper()
+
+	dir := filepath.Join(configRoot, name)
+	if err := os.MkdirAll(dir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, jobConfigFileName)
+	if err := os.WriteFile(path, []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+const benchmarkJobContent = `
+command = ["sh", "-c", "echo " + quote(env.get("HOME", ""))]
+jitter = one_minute
+queue = "bench"
+
+def should_run(minute, hour, finished, timestamp, **_):
+    return minute == 0 and hour in [3, 15] and timestamp - finished >= one_hour
+`
+
+func setUpBenchmarkJobs(b *testing.B, count int) string {
+	b.Helper()
+
+	configRoot := b.TempDir()
+	for i := 0; i < count; i++ {
+		writeTestJob(b, configRoot, fmt.Sprintf("job-%03d", i), benchmarkJobContent)
+	}
+
+	return configRoot
+}
+
+// Reload hundreds of unchanged jobs with a warm cache, as after a burst of watcher events.
+func BenchmarkLoadAllCached(b *testing.B) {
+	configRoot := setUpBenchmarkJobs(b, 300)
+
+	jsc := NewScheduler()
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := jsc.LoadAll(configRoot); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Reload hundreds of jobs after the global env changes, reusing the compiled programs.
+func BenchmarkLoadAllRecompute(b *testing.B) {
+	configRoot := setUpBenchmarkJobs(b, 300)
+
+	jsc := NewScheduler()
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		jsc.removeAll()
+		if _, err := jsc.LoadAll(configRoot); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Load hundreds of jobs from scratch, as at startup.
+func BenchmarkLoadAllCold(b *testing.B) {
+	configRoot := setUpBenchmarkJobs(b, 300)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		jsc := NewScheduler()
+		if _, err := jsc.LoadAll(configRoot); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAddDueJobsToQueueError(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stderr)
+
+	configRoot := t.TempDir()
+	stateRoot := t.TempDir()
+
+	jobs := map[string]string{
+		"broken": "def should_run(**_):\n    return \"soon\"\n",
+		"due":    "def should_run(**_):\n    return True\n",
+	}
+	for name, content := range jobs {
+		jobDir := filepath.Join(configRoot, name)
+		if err := os.Mkdir(jobDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(jobDir, jobConfigFileName), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jsc := NewScheduler()
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		t.Fatalf("loadAll() error = %v", err)
+	}
+
+	db, err := OpenAppDB(stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	// The broken job is logged, and the other job is still queued.
+	jsc.addDueJobsToQueue(runner, time.Now())
+
+	if !strings.Contains(logBuf.String(), "[broken] Scheduling error") {
+		t.Errorf("Expected the scheduling error in the log, got %q", logBuf.String())
+	}
+
+	if _, ok := runner.queues["due"]; !ok {
+		t.Error("Expected the due job to be queued")
+	}
+}
+
+func TestAddDueJobsToQueue(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	stateRoot := t.TempDir()
+	db, err := OpenAppDB(stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to open app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, stateRoot)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	configRoot := t.TempDir()
+	jsc := NewScheduler()
+
+	for i := 0; i < 50; i++ {
+		due := "True"
+		if i%2 == 1 {
+			due = "False"
+		}
+
+		writeTestJob(t, configRoot, fmt.Sprintf("job-%02d", i), fmt.Sprintf("def should_run(**_):\n    return %s\n", due))
+	}
+
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		t.Fatalf("loadAll: %v", err)
+	}
+
+	jsc.addDueJobsToQueue(runner, time.Now())
+
+	if len(runner.queues) != 25 {
+		t.Errorf("expected 25 queued jobs, got %d", len(runner.queues))
+	}
+
+	for name := range runner.queues {
+		var n int
+		if _, err := fmt.Sscanf(name, "job-%d", &n); err != nil || n%2 != 0 {
+			t.Errorf("unexpected job in queue: %v", name)
+		}
+	}
+
+	// Every failing schedule is logged, and the other jobs are still queued.
+	writeTestJob(t, configRoot, "broken", "def should_run(**_):\n    return \"soon\"\n")
+	writeTestJob(t, configRoot, "broken-too", "def should_run(**_):\n    return 1 // 0\n")
+	writeTestJob(t, configRoot, "late", "def should_run(**_):\n    return True\n")
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		t.Fatalf("loadAll: %v", err)
+	}
+
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(io.Discard)
+
+	jsc.addDueJobsToQueue(runner, time.Now())
+
+	for _, name := range []string{"broken", "broken-too"} {
+		if !strings.Contains(logBuf.String(), "["+name+"] Scheduling error") {
+			t.Errorf("Expected the scheduling error of %q in the log, got %q", name, logBuf.String())
+		}
+	}
+
+	if _, ok := runner.queues["late"]; !ok {
+		t.Errorf("expected the job without errors to be queued")
+	}
+}
+
+// Evaluate the schedules of hundreds of jobs, as on every scheduler tick.
+func BenchmarkAddDueJobsToQueue(b *testing.B) {
+	configRoot := setUpBenchmarkJobs(b, 300)
+
+	stateRoot := b.TempDir()
+	db, err := OpenAppDB(stateRoot)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, stateRoot)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	jsc := NewScheduler()
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		b.Fatal(err)
+	}
+
+	t := time.Date(2025, 1, 1, 12, 30, 0, 0, time.Local)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		jsc.addDueJobsToQueue(runner, t)
+	}
+}
+
+func TestSchedulerRecordTick(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	jsc := NewScheduler()
+	tick := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	jsc.recordTick(tick, 5*time.Second, 100*time.Millisecond)
+	jsc.recordTick(tick.Add(time.Minute), -time.Second, 50*time.Millisecond)
+
+	expected := SchedulerStats{
+		LastTick:     tick.Add(time.Minute),
+		Lag:          0,
+		MaxLag:       5 * time.Second,
+		CheckTime:    50 * time.Millisecond,
+		MaxCheckTime: 100 * time.Millisecond,
+		Ticks:        2,
+	}
+	if stats := jsc.Stats(); stats != expected {
+		t.Errorf("Stats() = %+v, want %+v", stats, expected)
+	}
+}
+
+func TestJobSchedulerReload(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	configRoot := t.TempDir()
+	jsc := NewScheduler()
+
+	job := "command = [\"true\"]\n"
+	writeTestJob(t, configRoot, "kept", job)
+	writeTestJob(t, configRoot, "deleted", job)
+	writeTestJob(t, configRoot, "broken", "if env.get(\"BREAK\"):\n    fail(\"broken\")\n"+job)
+
+	if _, err := jsc.LoadAll(configRoot); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	globalEnvPath := filepath.Join(configRoot, globalEnvFileName)
+	if err := os.WriteFile(globalEnvPath, []byte("GREETING=hello\nBREAK=1\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(configRoot, "deleted")); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := jsc.LoadAll(configRoot)
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	if len(loaded) != 1 || loaded[0] != "kept" {
+		t.Errorf("Expected only %q reloaded, got %v", "kept", loaded)
+	}
+
+	kept, ok := jsc.Job("kept")
+	if !ok {
+		t.Fatal("Expected the job to stay loaded")
+	}
+	if kept.Env["GREETING"] != "hello" {
+		t.Errorf("Expected the new global env, got %q", kept.Env["GREETING"])
+	}
+
+	for _, name := range []string{"broken", "deleted"} {
+		if jsc.exists(name) {
+			t.Errorf("Expected %q to be removed", name)
+		}
+	}
+}
+
+func TestJobSchedulerConfigRoots(t *testing.T) {
+	personal := t.TempDir()
+	shared := t.TempDir()
+	jsc := NewScheduler()
+
+	writeTestJob(t, personal, "mine", `command = ["true"]`)
+	writeTestJob(t, personal, "both", `command = ["personal"]`)
+	writeTestJob(t, shared, "both", `command = ["shared"]`)
+
+	loaded, err := jsc.LoadAll(personal, shared)
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	if len(loaded) != 2 || loaded[0] != "both" || loaded[1] != "mine" {
+		t.Errorf("Expected %q and %q loaded, got %v", "both", "mine", loaded)
+	}
+
+	both, ok := jsc.Job("both")
+	if !ok {
+		t.Fatal("Expected the job to be loaded")
+	}
+	if both.Command[0] != "shared" {
+		t.Errorf("Expected the later root to win, got command %v", both.Command)
+	}
+	if both.Env[jobDirEnvVar] != filepath.Join(shared, "both") {
+		t.Errorf("Expected the job dir in the later root, got %q", both.Env[jobDirEnvVar])
+	}
+
+	job, err := NewScheduler().LoadJob([]string{personal, shared}, "mine")
+	if err != nil {
+		t.Fatalf("LoadJob() error = %v", err)
+	}
+	if job.Command[0] != "true" {
+		t.Errorf("Expected the job from the earlier root, got command %v", job.Command)
+	}
+}
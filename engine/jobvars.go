@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.starlark.net/starlark"
+)
+
+// jobOptionNames returns the names of the top-level variables that configure a job.
+var jobOptionNames = sync.OnceValue(func() map[string]struct{} {
+	names := map[string]struct{}{
+		envVar:           {},
+		escalateVar:      {},
+		exitMeaningsVar:  {},
+		filesVar:         {},
+		instanceVar:      {},
+		notifyModeVar:    {},
+		queueOverflowVar: {},
+		scheduleVar:      {},
+	}
+
+	jobType := reflect.TypeOf(JobConfig{})
+	for i := range jobType.NumField() {
+		tag := jobType.Field(i).Tag.Get("starlark")
+		if tag != "" && tag != "-" {
+			names[tag] = struct{}{}
+		}
+	}
+
+	return names
+})
+
+// unknownJobVars returns the sorted names of the top-level variables in a job file that aren't options.
+// Starlark drops them silently, so they are usually typos.
+// Functions and variables whose names start with "_" are helpers and never reported.
+func unknownJobVars(globals starlark.StringDict) []string {
+	unknown := []string{}
+
+	for name, value := range globals {
+		if _, ok := jobOptionNames()[name]; ok || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		if _, ok := value.(*starlark.Function); ok {
+			continue
+		}
+
+		unknown = append(unknown, name)
+	}
+
+	slices.Sort(unknown)
+
+	return unknown
+}
+
+// Suggest an option for an unknown variable at most this many edits away.
+const maxSuggestionDistance = 2
+
+// unknownJobVarWarning describes an unknown variable and suggests the closest option.
+func unknownJobVarWarning(name string) string {
+	best := ""
+	bestDistance := maxSuggestionDistance + 1
+	for option := range jobOptionNames() {
+		distance := editDistance(name, option)
+		if distance < bestDistance || distance == bestDistance && option < best {
+			best = option
+			bestDistance = distance
+		}
+	}
+
+	if best != "" {
+		return fmt.Sprintf("unknown variable %q is ignored (did you mean %q?)", name, best)
+	}
+
+	return fmt.Sprintf(`unknown variable %q is ignored (start the names of helper variables with "_")`, name)
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// Warnings returns the problems found when loading the job that didn't stop it from loading.
+func (j JobConfig) Warnings() []string {
+	return j.warnings
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/flock"
+)
+
+// LockStateDir takes the lock that keeps instances of Regular from using the same state directory at the same time.
+// It returns ok=false if another instance holds the lock.
+// Call unlock to release the lock.
+func LockStateDir(stateRoot string) (unlock func(), ok bool, err error) {
+	return tryLock(filepath.Join(stateRoot, appLockFileName))
+}
+
+// lockJob takes the lock file in the state directory of a job with "lock".
+// When machines share the directory, only one of them runs the job at a time.
+// It returns ok=false if another process holds the lock.
+func lockJob(jobStateDir string) (unlock func(), ok bool, err error) {
+	if err := os.MkdirAll(jobStateDir, dirPerms); err != nil {
+		return nil, false, fmt.Errorf("failed to create job state directory: %w", err)
+	}
+
+	return tryLock(filepath.Join(jobStateDir, jobLockFileName))
+}
+
+// tryLock takes an exclusive lock on a file without waiting.
+func tryLock(path string) (unlock func(), ok bool, err error) {
+	fileLock := flock.New(path)
+
+	locked, err := fileLock.TryLock()
+	if err != nil {
+		return nil, false, fmt.Errorf("error checking lock file: %w", err)
+	}
+	if !locked {
+		return nil, false, nil
+	}
+
+	return func() {
+		_ = fileLock.Unlock()
+	}, true, nil
+}
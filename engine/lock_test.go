@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestJobRunnerLock(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	counter := filepath.Join(tmpDir, "runs")
+	run := func() {
+		t.Helper()
+
+		runner.AddJob(JobConfig{
+			Name:    "backup",
+			Command: []string{"sh", "-c", `echo x >> "$0"`, counter},
+			Env:     denv.Env{"PATH": os.Getenv("PATH")},
+			Lock:    true,
+		})
+		if err := runner.RunQueueHead("backup"); err != nil {
+			t.Fatalf("RunQueueHead() error = %v", err)
+		}
+	}
+
+	// Another machine holds the lock.
+	unlock, ok, err := lockJob(filepath.Join(tmpDir, "backup"))
+	if err != nil || !ok {
+		t.Fatalf("Failed to take job lock: %v", err)
+	}
+
+	run()
+
+	last, err := db.LastCompleted("backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || !last.Skipped {
+		t.Errorf("Expected a skipped run while the lock is held, got %+v", last)
+	}
+	if _, err := os.Stat(counter); !os.IsNotExist(err) {
+		t.Error("Expected the command not to run while the lock is held")
+	}
+
+	unlock()
+	run()
+
+	last, err = db.LastCompleted("backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || last.Skipped || !last.IsSuccess() {
+		t.Errorf("Expected a successful run after the lock is released, got %+v", last)
+	}
+
+	// The run releases the lock.
+	unlock, ok, err = lockJob(filepath.Join(tmpDir, "backup"))
+	if err != nil || !ok {
+		t.Errorf("Expected the lock to be free after the run, got ok = %v, err = %v", ok, err)
+	} else {
+		unlock()
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"unicode"
+	"unicode/utf8"
+)
+
+func capitalizeFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// WithLog calls f and logs the error it returns, if any.
+// Errors about a job are logged with the job name.
+func WithLog(f func() error) {
+	if err := f(); err != nil {
+		msg := capitalizeFirst(err.Error())
+
+		if je, ok := err.(*JobError); ok {
+			LogJobPrintf(je.JobName, "%v", msg)
+		} else {
+			log.Printf("%v", msg)
+		}
+	}
+}
+
+// LogJobPrintf logs a message about a job with the standard logger.
+// It also records the message as an event of the job after RecordJobEvents.
+func LogJobPrintf(job, format string, v ...any) {
+	values := append([]any{job}, v...)
+	log.Printf("[%s] "+format, values...)
+
+	recordJobEvent(job, fmt.Sprintf(format, v...))
+}
+
+// logJobRoutinePrintf logs a message about a job that the runner logs for every run.
+// It isn't recorded as an event, so the events keep what went differently.
+func logJobRoutinePrintf(job, format string, v ...any) {
+	values := append([]any{job}, v...)
+	log.Printf("[%s] "+format, values...)
+}
@@ -0,0 +1,19 @@
+package engine
+
+import "path/filepath"
+
+type logFile struct {
+	name string
+	path string
+}
+
+// LogFilePath returns the path of the file with the output of the latest run of a job.
+// logName is "stdout" or "stderr".
+func LogFilePath(stateRoot, jobName, logName string) string {
+	fileName := stdoutFileName
+	if logName == "stderr" {
+		fileName = stderrFileName
+	}
+
+	return filepath.Join(stateRoot, jobName, fileName)
+}
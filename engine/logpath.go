@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The strftime conversions "log_path" supports and their Go layouts.
+var strftimeLayouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'B': "January",
+	'd': "02",
+	'F': "2006-01-02",
+	'H': "15",
+	'I': "03",
+	'm': "01",
+	'M': "04",
+	'p': "PM",
+	'S': "05",
+	'T': "15:04:05",
+	'y': "06",
+	'Y': "2006",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+// strftime formats a time like the C function.
+// It supports the conversions in strftimeLayouts, "%j", "%s", and "%%".
+func strftime(format string, t time.Time) (string, error) {
+	var sb strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			sb.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		if i == len(format) {
+			return "", fmt.Errorf("incomplete conversion at the end of %q", format)
+		}
+
+		switch c := format[i]; c {
+
+		case '%':
+			sb.WriteByte('%')
+
+		case 'j':
+			sb.WriteString(fmt.Sprintf("%03d", t.YearDay()))
+
+		case 's':
+			sb.WriteString(strconv.FormatInt(t.Unix(), 10))
+
+		default:
+			layout, ok := strftimeLayouts[c]
+			if !ok {
+				return "", fmt.Errorf("unknown conversion %q in %q", "%"+string(c), format)
+			}
+
+			sb.WriteString(t.Format(layout))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// expandLogPath expands a "log_path" for a run that started at t.
+// A relative path is in the state directory of the job.
+func expandLogPath(pattern, jobStateDir string, t time.Time) (string, error) {
+	path, err := strftime(expandHome(pattern), t)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(jobStateDir, path)
+	}
+
+	return path, nil
+}
+
+// openLogPath opens the "log_path" file of a run for appending.
+// It creates the missing directories.
+func openLogPath(pattern, jobStateDir string, t time.Time) (*os.File, error) {
+	path, err := expandLogPath(pattern, jobStateDir, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerms)
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestStrftime(t *testing.T) {
+	at := time.Date(2025, 3, 7, 14, 5, 9, 0, time.UTC)
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"backup.log", "backup.log", false},
+		{"%Y-%m-%d.log", "2025-03-07.log", false},
+		{"%F_%H%M%S", "2025-03-07_140509", false},
+		{"%T %p %I", "14:05:09 PM 02", false},
+		{"%a %A %b %B %y", "Fri Friday Mar March 25", false},
+		{"day %j", "day 066", false},
+		{"%s", "1741356309", false},
+		{"100%%", "100%", false},
+		{"%Q", "", true},
+		{"trailing %", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := strftime(tt.format, at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("strftime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("strftime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandLogPath(t *testing.T) {
+	at := time.Date(2025, 3, 7, 14, 5, 9, 0, time.UTC)
+
+	got, err := expandLogPath("logs/%Y.log", "/state/job", at)
+	if err != nil {
+		t.Fatalf("expandLogPath() error = %v", err)
+	}
+	if want := "/state/job/logs/2025.log"; got != want {
+		t.Errorf("expandLogPath() = %q, want %q", got, want)
+	}
+
+	got, err = expandLogPath("/var/log/job-%m.log", "/state/job", at)
+	if err != nil {
+		t.Fatalf("expandLogPath() error = %v", err)
+	}
+	if want := "/var/log/job-03.log"; got != want {
+		t.Errorf("expandLogPath() = %q, want %q", got, want)
+	}
+}
+
+func TestJobRunnerLogPath(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	logPath := filepath.Join(tmpDir, "custom", "%Y", "output.log")
+	for range 2 {
+		runner.AddJob(JobConfig{
+			Name:    "logged",
+			Command: []string{"sh", "-c", "echo out; echo err >&2"},
+			Env:     denv.OS(),
+			LogPath: logPath,
+		})
+		if err := runner.RunQueueHead("logged"); err != nil {
+			t.Fatalf("RunQueueHead() error = %v", err)
+		}
+	}
+
+	path, err := expandLogPath(logPath, "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the custom log: %v", err)
+	}
+
+	// Both streams of both runs are appended.
+	if len(content) != len("out\nerr\n")*2 {
+		t.Errorf("Unexpected custom log content: %q", content)
+	}
+
+	// Without "log", the state directory has no log files.
+	if _, err := os.Stat(LogFilePath(tmpDir, "logged", "stdout")); !os.IsNotExist(err) {
+		t.Errorf("Expected no stdout log in the state directory: %v", err)
+	}
+}
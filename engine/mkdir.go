@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+)
+
+// CreateDirectories creates the config and state directories if they don't exist.
+// It returns the directories it created.
+func CreateDirectories(config Config) ([]string, error) {
+	created := []string{}
+
+	type namedDir struct {
+		name string
+		path string
+	}
+
+	dirs := []namedDir{}
+	for _, root := range config.ConfigRoots() {
+		dirs = append(dirs, namedDir{"config", root})
+	}
+	dirs = append(dirs, namedDir{"state", config.StateRoot})
+
+	for _, dir := range dirs {
+		_, err := os.Stat(dir.path)
+		missing := os.IsNotExist(err)
+
+		if err := os.MkdirAll(dir.path, dirPerms); err != nil {
+			return created, fmt.Errorf("failed to create %s directory %q: %w", dir.name, dir.path, err)
+		}
+
+		if missing {
+			created = append(created, dir.path)
+		}
+	}
+
+	return created, nil
+}
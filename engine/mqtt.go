@@ -0,0 +1,251 @@
+package engine
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MQTT 3.1.1 packet types and flags.
+// Regular only publishes with QoS 0, so it needs just a few packets.
+const (
+	mqttConnect    = 0x10
+	mqttConnAck    = 0x20
+	mqttPublish    = 0x30
+	mqttDisconnect = 0xe0
+
+	mqttRetain         = 0x01
+	mqttCleanSession   = 0x02
+	mqttPasswordFlag   = 0x40
+	mqttUsernameFlag   = 0x80
+	mqttProtocolLevel  = 4
+	mqttKeepAlive      = 60
+	mqttDefaultPort    = "1883"
+	mqttDefaultTLSPort = "8883"
+)
+
+// mqttStatus is the message published when a job completes.
+type mqttStatus struct {
+	Job        string    `json:"job"`
+	Success    bool      `json:"success"`
+	ExitStatus int       `json:"exit_status"`
+	Error      string    `json:"error"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Duration   float64   `json:"duration"`
+}
+
+// PublishMQTT returns a function that publishes the status of every completed job to the MQTT broker in the settings.
+// It does nothing if no broker is configured.
+func PublishMQTT(configRoot string) NotifyWhenDone {
+	return func(jobName string, completed CompletedJob) error {
+		settings, err := LoadSettings(configRoot)
+		if err != nil {
+			return err
+		}
+
+		if settings.MQTTBroker == "" {
+			return nil
+		}
+
+		status, err := json.Marshal(mqttStatus{
+			Job:        jobName,
+			Success:    completed.IsSuccess(),
+			ExitStatus: completed.ExitStatus,
+			Error:      completed.Error,
+			Started:    completed.Started,
+			Finished:   completed.Finished,
+			Duration:   completed.Finished.Sub(completed.Started).Seconds(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode MQTT message: %w", err)
+		}
+
+		topic := settings.MQTTTopic
+		if topic == "" {
+			topic = defaultMQTTTopic
+		}
+		topic = strings.ReplaceAll(topic, "{job}", jobName)
+
+		password, err := resolveSecret(settings.MQTTPassword)
+		if err != nil {
+			return err
+		}
+
+		return mqttPublishOnce(settings.MQTTBroker, settings.MQTTUsername, password, topic, status)
+	}
+}
+
+// mqttPublishOnce connects to a broker, publishes a retained message, and disconnects.
+// The broker URL has the scheme "mqtt" (or "tcp") or "mqtts" (or "ssl") for TLS.
+func mqttPublishOnce(broker, username, password, topic string, payload []byte) error {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return fmt.Errorf("invalid MQTT broker URL: %w", err)
+	}
+
+	useTLS := false
+	port := mqttDefaultPort
+	switch u.Scheme {
+
+	case "mqtt", "tcp":
+
+	case "mqtts", "ssl":
+		useTLS = true
+		port = mqttDefaultTLSPort
+
+	default:
+		return fmt.Errorf("unsupported MQTT broker URL scheme: %q", u.Scheme)
+	}
+
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+
+	dialer := &net.Dialer{Timeout: notifierTimeout}
+	var conn net.Conn
+	if useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(notifierTimeout)); err != nil {
+		return err
+	}
+
+	clientID := "regular-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if _, err := conn.Write(mqttConnectPacket(clientID, username, password)); err != nil {
+		return fmt.Errorf("failed to send MQTT connect: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	packetType, body, err := mqttReadPacket(r)
+	if err != nil {
+		return fmt.Errorf("failed to read MQTT connect acknowledgment: %w", err)
+	}
+	if packetType != mqttConnAck || len(body) != 2 {
+		return fmt.Errorf("unexpected MQTT packet type: %#x", packetType)
+	}
+	if body[1] != 0 {
+		return fmt.Errorf("MQTT broker refused connection with code %d", body[1])
+	}
+
+	if _, err := conn.Write(mqttPublishPacket(topic, payload, true)); err != nil {
+		return fmt.Errorf("failed to publish MQTT message: %w", err)
+	}
+
+	if _, err := conn.Write([]byte{mqttDisconnect, 0}); err != nil {
+		return fmt.Errorf("failed to disconnect from MQTT broker: %w", err)
+	}
+
+	return nil
+}
+
+func mqttConnectPacket(clientID, username, password string) []byte {
+	var flags byte = mqttCleanSession
+	if username != "" {
+		flags |= mqttUsernameFlag
+
+		if password != "" {
+			flags |= mqttPasswordFlag
+		}
+	}
+
+	body := mqttString("MQTT")
+	body = append(body, mqttProtocolLevel, flags)
+	body = binary.BigEndian.AppendUint16(body, mqttKeepAlive)
+	body = append(body, mqttString(clientID)...)
+
+	if flags&mqttUsernameFlag != 0 {
+		body = append(body, mqttString(username)...)
+	}
+	if flags&mqttPasswordFlag != 0 {
+		body = append(body, mqttString(password)...)
+	}
+
+	return mqttPacket(mqttConnect, body)
+}
+
+func mqttPublishPacket(topic string, payload []byte, retain bool) []byte {
+	var header byte = mqttPublish
+	if retain {
+		header |= mqttRetain
+	}
+
+	body := append(mqttString(topic), payload...)
+
+	return mqttPacket(header, body)
+}
+
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+
+	// The remaining length is a variable-length integer with 7 bits per byte.
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+
+		if n == 0 {
+			break
+		}
+	}
+
+	return append(packet, body...)
+}
+
+func mqttString(s string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(s)))
+	return append(b, s...)
+}
+
+// mqttReadPacket reads a packet and returns its type with the flags cleared and its body.
+func mqttReadPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length := 0
+	for multiplier := 1; ; multiplier *= 128 {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+
+		if multiplier > 128*128*128 {
+			return 0, nil, fmt.Errorf("malformed MQTT remaining length")
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header & 0xf0, body, nil
+}
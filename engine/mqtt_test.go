@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A fake broker that accepts one connection and records the published message.
+func fakeMQTTBroker(t *testing.T, returnCode byte) (string, <-chan []byte) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	published := make(chan []byte, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		packetType, _, err := mqttReadPacket(r)
+		if err != nil || packetType != mqttConnect {
+			t.Errorf("expected connect packet, got %#x, %v", packetType, err)
+			return
+		}
+
+		if _, err := conn.Write([]byte{mqttConnAck, 2, 0, returnCode}); err != nil {
+			return
+		}
+
+		packetType, body, err := mqttReadPacket(r)
+		if err != nil {
+			return
+		}
+		if packetType == mqttPublish {
+			published <- body
+		}
+	}()
+
+	return listener.Addr().String(), published
+}
+
+func TestPublishMQTT(t *testing.T) {
+	addr, published := fakeMQTTBroker(t, 0)
+
+	configRoot := t.TempDir()
+	settings := `mqtt_broker = "mqtt://` + addr + `"
+mqtt_username = "user"
+mqtt_password = "pass"
+mqtt_topic = "home/jobs/{job}"
+`
+	if err := os.WriteFile(filepath.Join(configRoot, settingsFileName), []byte(settings), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	completed := CompletedJob{ExitStatus: 1, Started: started, Finished: started.Add(90 * time.Second)}
+
+	if err := PublishMQTT(configRoot)("backup", completed); err != nil {
+		t.Fatalf("PublishMQTT() error = %v", err)
+	}
+
+	var body []byte
+	select {
+	case body = <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message published")
+	}
+
+	topicLen := int(binary.BigEndian.Uint16(body))
+	if topic := string(body[2 : 2+topicLen]); topic != "home/jobs/backup" {
+		t.Errorf("topic = %q", topic)
+	}
+
+	var status mqttStatus
+	if err := json.Unmarshal(body[2+topicLen:], &status); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+
+	if status.Job != "backup" || status.Success || status.ExitStatus != 1 || status.Duration != 90 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestPublishMQTTRefused(t *testing.T) {
+	addr, _ := fakeMQTTBroker(t, 5)
+
+	if err := mqttPublishOnce("tcp://"+addr, "", "", "topic", []byte("{}")); err == nil {
+		t.Error("expected error when the broker refuses the connection")
+	}
+}
+
+func TestMQTTPacketLength(t *testing.T) {
+	packet := mqttPacket(mqttPublish, make([]byte, 321))
+
+	r := bufio.NewReader(bytes.NewReader(packet))
+	packetType, body, err := mqttReadPacket(r)
+	if err != nil {
+		t.Fatalf("mqttReadPacket() error = %v", err)
+	}
+
+	if packetType != mqttPublish || len(body) != 321 {
+		t.Errorf("got type %#x and length %d", packetType, len(body))
+	}
+}
+
+func TestPublishMQTTUnconfigured(t *testing.T) {
+	if err := PublishMQTT(t.TempDir())("backup", CompletedJob{}); err != nil {
+		t.Errorf("PublishMQTT() error = %v", err)
+	}
+}
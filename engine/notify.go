@@ -0,0 +1,324 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"os/exec"
+	"os/user"
+	"strings"
+
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+const (
+	smtpServer   = "127.0.0.1"
+	smtpPort     = 25
+	fromUsername = "regular"
+
+	errorText      = "Error: %v\n\n"
+	exitStatusText = "Exit status: %v\n\n"
+	meaningText    = "Exit status %d means: %v\n\n"
+	signalText     = "Killed by signal: %v\n\n"
+	repeatsText    = "Failed the same way %d times in a row since %v\n\n"
+	failuresText   = "Failed %d times in a row\n\n"
+	deadText       = "Gave up after %d attempts\n\n"
+	startedText    = "Started:  %v\n"
+	finishedText   = "Finished: %v\n"
+	durationText   = "Duration: %v\n"
+	hostText       = "Host: %v\n"
+	commandText    = "Command: %v\n"
+	timeFormat     = "2006-01-02 15:04:05 -0700"
+	deadSubject    = "Job %q is dead"
+	failureSubject = "Job %q failed"
+	successSubject = "Job %q succeeded"
+)
+
+type NotifyMode string
+
+const (
+	NotifyAlways    NotifyMode = "always"
+	NotifyNever     NotifyMode = "never"
+	NotifyOnFailure NotifyMode = "on-failure"
+	NotifyOnSuccess NotifyMode = "on-success"
+)
+
+type NotifyWhenDone func(string, CompletedJob) error
+
+// The channels a job can choose with "notify_via".
+var notifyChannels = []string{
+	notifyViaEmail,
+	notifyViaMatrix,
+	notifyViaNtfy,
+	notifyViaPlugins,
+	notifyViaXMPP,
+}
+
+func parseNotifyMode(mode string) (NotifyMode, error) {
+	switch mode {
+	case string(NotifyAlways):
+		return NotifyAlways, nil
+	case string(NotifyNever):
+		return NotifyNever, nil
+	case string(NotifyOnFailure), "":
+		return NotifyOnFailure, nil
+	case string(NotifyOnSuccess):
+		return NotifyOnSuccess, nil
+	default:
+		return "", fmt.Errorf("unknown notify mode: %v", mode)
+	}
+}
+
+func notifyIfNeeded(notify NotifyWhenDone, mode NotifyMode, jobName string, completed CompletedJob) error {
+	if mode == NotifyNever {
+		return nil
+	}
+
+	success := completed.IsSuccess()
+	if !(mode == NotifyAlways || mode == NotifyOnFailure && !success || mode == NotifyOnSuccess && success) {
+		return nil
+	}
+
+	return notify(jobName, completed)
+}
+
+func localUserAddress(username string) string {
+	return username + "@localhost"
+}
+
+// NotifyUserByEmail emails the current user or the job's recipients.
+// The transport is set in the settings file in the config root.
+func NotifyUserByEmail(db *AppDB, configRoot string) NotifyWhenDone {
+	return func(jobName string, completed CompletedJob) error {
+		if !completed.notifiesVia(notifyViaEmail) {
+			return nil
+		}
+
+		settings, err := LoadSettings(configRoot)
+		if err != nil {
+			return err
+		}
+
+		subject, text, err := formatMessage(db, jobName, completed, newLogExcerpt(settings, completed))
+		if err != nil {
+			return fmt.Errorf("failed to format notification message: %v", err)
+		}
+
+		return sendEmail(settings, completed.NotifyTo, subject, text, completed.Attachments)
+	}
+}
+
+// sendEmail sends a message with the transport in the settings.
+// Without recipients, it goes to the current user.
+// Attachments over the size limit are left out.
+func sendEmail(settings Settings, to []string, subject, text string, attachments []string) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %v", err)
+	}
+
+	attachments = limitAttachments(attachments)
+
+	if settings.EmailTransport == emailTransportSendmail {
+		sendmailPath := settings.SendmailPath
+		if sendmailPath == "" {
+			sendmailPath = defaultSendmailPath
+		}
+
+		if len(to) == 0 {
+			to = []string{currentUser.Username}
+		}
+
+		return sendSendmail(sendmailPath, to, subject, text, attachments)
+	}
+
+	if len(to) == 0 {
+		to = []string{localUserAddress(currentUser.Username)}
+	}
+
+	return sendSMTP(currentUser.Username, to, subject, text, attachments)
+}
+
+func sendSMTP(username string, to []string, subject, text string, attachments []string) error {
+	server := mail.NewSMTPClient()
+	server.Host = smtpServer
+	server.Port = smtpPort
+	server.Username = username
+
+	smtpClient, err := server.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %v\n", err)
+	}
+
+	email := mail.NewMSG()
+	email.SetFrom(localUserAddress(fromUsername)).
+		AddTo(to...).
+		SetSubject(subject).
+		SetBody(mail.TextPlain, text)
+
+	for _, path := range attachments {
+		email.Attach(&mail.File{FilePath: path})
+	}
+	if email.Error != nil {
+		return fmt.Errorf("failed to attach file: %w", email.Error)
+	}
+
+	if err := email.Send(smtpClient); err != nil {
+		return fmt.Errorf("failed to send email: %v\n", err)
+	}
+
+	return nil
+}
+
+// sendSendmail pipes the message to a sendmail binary like cron does.
+// The recipients are read from the message headers.
+func sendSendmail(sendmailPath string, to []string, subject, text string, attachments []string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\n", localUserAddress(fromUsername))
+	fmt.Fprintf(&msg, "To: %s\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\n")
+
+	if len(attachments) == 0 {
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\n")
+		msg.WriteString("\n")
+		msg.WriteString(text)
+	} else if err := writeMultipart(&msg, text, attachments); err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(sendmailPath, "-t", "-oi")
+	cmd.Stdin = &msg
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to send email with sendmail: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// runDetails describes when, where, and what a job ran.
+// Unknown details are left out.
+func runDetails(completed CompletedJob) string {
+	var sb strings.Builder
+
+	if !completed.Started.IsZero() {
+		sb.WriteString(fmt.Sprintf(startedText, completed.Started.Format(timeFormat)))
+		sb.WriteString(fmt.Sprintf(finishedText, completed.Finished.Format(timeFormat)))
+		sb.WriteString(fmt.Sprintf(durationText, FormatDuration(completed.Finished.Sub(completed.Started))))
+	}
+
+	if completed.Hostname != "" {
+		host := completed.Hostname
+		if completed.Username != "" {
+			host = completed.Username + "@" + host
+		}
+
+		sb.WriteString(fmt.Sprintf(hostText, host))
+	}
+
+	if len(completed.Command) > 0 {
+		sb.WriteString(fmt.Sprintf(commandText, completed.CommandLine()))
+	}
+
+	return sb.String()
+}
+
+// logExcerpt is how much of the output of a run goes into a notification.
+type logExcerpt struct {
+	lines  int
+	stdout bool
+}
+
+// newLogExcerpt applies the job's choice over the global settings over the defaults.
+func newLogExcerpt(settings Settings, completed CompletedJob) logExcerpt {
+	excerpt := logExcerpt{lines: defaultLogLines, stdout: true}
+
+	for _, lines := range []*int{settings.NotifyLines, completed.NotifyLines} {
+		if lines != nil {
+			excerpt.lines = *lines
+		}
+	}
+
+	for _, stdout := range []*bool{settings.NotifyStdout, completed.NotifyStdout} {
+		if stdout != nil {
+			excerpt.stdout = *stdout
+		}
+	}
+
+	return excerpt
+}
+
+// logNames returns the logs to include.
+func (e logExcerpt) logNames() []string {
+	if e.lines == 0 {
+		return nil
+	}
+
+	if !e.stdout {
+		return []string{"stderr"}
+	}
+
+	return []string{"stdout", "stderr"}
+}
+
+func formatMessage(db *AppDB, jobName string, completed CompletedJob, excerpt logExcerpt) (string, string, error) {
+	subjectTemplate := successSubject
+	if completed.Dead {
+		subjectTemplate = deadSubject
+	} else if !completed.IsSuccess() {
+		subjectTemplate = failureSubject
+	}
+	subject := fmt.Sprintf(subjectTemplate, jobName)
+
+	var sb strings.Builder
+	if completed.Error != "" {
+		sb.WriteString(fmt.Sprintf(errorText, completed.Error))
+	} else if completed.ExitStatus != 0 {
+		sb.WriteString(fmt.Sprintf(exitStatusText, completed.ExitStatus))
+	}
+	if completed.Signal != 0 {
+		sb.WriteString(fmt.Sprintf(signalText, completed.SignalName()))
+	}
+	if completed.ExitMeaning != "" {
+		sb.WriteString(fmt.Sprintf(meaningText, completed.ExitStatus, completed.ExitMeaning))
+	}
+
+	if completed.Dead {
+		sb.WriteString(fmt.Sprintf(deadText, completed.Attempt))
+	}
+
+	if completed.Repeats > 1 {
+		sb.WriteString(fmt.Sprintf(repeatsText, completed.Repeats, completed.RepeatingSince.Format(timeFormat)))
+	} else if completed.Failures > 1 {
+		sb.WriteString(fmt.Sprintf(failuresText, completed.Failures))
+	}
+
+	details := runDetails(completed)
+	if details != "" {
+		sb.WriteString(details + "\n")
+	}
+
+	if db != nil {
+		for _, logName := range excerpt.logNames() {
+			lines, err := db.runLogTail(jobName, completed.ID, logName, excerpt.lines)
+			if err != nil {
+				return "", "", fmt.Errorf("error reading log: %w", err)
+			}
+
+			if len(lines) == 0 {
+				continue
+			}
+
+			sb.WriteString(logName + ":\n")
+
+			for _, line := range lines {
+				sb.WriteString("> " + line + "\n")
+			}
+		}
+	}
+
+	return subject, sb.String(), nil
+}
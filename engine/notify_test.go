@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNotifyMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected NotifyMode
+		wantErr  bool
+	}{
+		{"always", NotifyAlways, false},
+		{"never", NotifyNever, false},
+		{"on-failure", NotifyOnFailure, false},
+		{"on-success", NotifyOnSuccess, false},
+		{"", NotifyOnFailure, false},
+		{"invalid", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("parseNotifyMode(%q)", tt.input), func(t *testing.T) {
+			got, err := parseNotifyMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseNotifyMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+				return
+			}
+			if got != tt.expected {
+				t.Errorf("parseNotifyMode(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNotifyIfNeeded(t *testing.T) {
+	var notified bool
+	mockNotify := func(jobName string, completed CompletedJob) error {
+		notified = true
+		return nil
+	}
+
+	tests := []struct {
+		name         string
+		mode         NotifyMode
+		job          CompletedJob
+		shouldNotify bool
+	}{
+		{
+			name:         "always mode success",
+			mode:         NotifyAlways,
+			job:          CompletedJob{ExitStatus: 0},
+			shouldNotify: true,
+		},
+		{
+			name:         "always mode failure",
+			mode:         NotifyAlways,
+			job:          CompletedJob{ExitStatus: 1},
+			shouldNotify: true,
+		},
+		{
+			name:         "never mode success",
+			mode:         NotifyNever,
+			job:          CompletedJob{ExitStatus: 0},
+			shouldNotify: false,
+		},
+		{
+			name:         "never mode failure",
+			mode:         NotifyNever,
+			job:          CompletedJob{ExitStatus: 1},
+			shouldNotify: false,
+		},
+		{
+			name:         "on-failure mode success",
+			mode:         NotifyOnFailure,
+			job:          CompletedJob{ExitStatus: 0},
+			shouldNotify: false,
+		},
+		{
+			name:         "on-failure mode failure",
+			mode:         NotifyOnFailure,
+			job:          CompletedJob{ExitStatus: 1},
+			shouldNotify: true,
+		},
+		{
+			name:         "on-success mode success",
+			mode:         NotifyOnSuccess,
+			job:          CompletedJob{ExitStatus: 0},
+			shouldNotify: true,
+		},
+		{
+			name:         "on-success mode failure",
+			mode:         NotifyOnSuccess,
+			job:          CompletedJob{ExitStatus: 1},
+			shouldNotify: false,
+		},
+		{
+			name:         "on-success mode error",
+			mode:         NotifyOnSuccess,
+			job:          CompletedJob{Error: "timeout"},
+			shouldNotify: false,
+		},
+		{
+			name:         "on-failure mode allowed exit status",
+			mode:         NotifyOnFailure,
+			job:          CompletedJob{ExitStatus: 24, SuccessExitCodes: []int{0, 24}},
+			shouldNotify: false,
+		},
+		{
+			name:         "on-failure mode zero not allowed",
+			mode:         NotifyOnFailure,
+			job:          CompletedJob{ExitStatus: 0, SuccessExitCodes: []int{1}},
+			shouldNotify: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notified = false
+			err := notifyIfNeeded(mockNotify, tt.mode, "test-job", tt.job)
+			if err != nil {
+				t.Errorf("notifyIfNeeded() error = %v", err)
+			}
+			if notified != tt.shouldNotify {
+				t.Errorf("notifyIfNeeded() notified = %v, want %v", notified, tt.shouldNotify)
+			}
+		})
+	}
+}
+
+func TestNewLogExcerpt(t *testing.T) {
+	zero, five, twenty := 0, 5, 20
+	no := false
+
+	tests := []struct {
+		name      string
+		settings  Settings
+		completed CompletedJob
+		lines     int
+		logNames  []string
+	}{
+		{"defaults", Settings{}, CompletedJob{}, defaultLogLines, []string{"stdout", "stderr"}},
+		{"global", Settings{NotifyLines: &twenty, NotifyStdout: &no}, CompletedJob{}, 20, []string{"stderr"}},
+		{"job overrides global", Settings{NotifyLines: &twenty}, CompletedJob{NotifyLines: &five}, 5, []string{"stdout", "stderr"}},
+		{"no logs", Settings{}, CompletedJob{NotifyLines: &zero}, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excerpt := newLogExcerpt(tt.settings, tt.completed)
+
+			if excerpt.lines != tt.lines {
+				t.Errorf("lines = %d, want %d", excerpt.lines, tt.lines)
+			}
+			if !slices.Equal(excerpt.logNames(), tt.logNames) {
+				t.Errorf("logNames() = %v, want %v", excerpt.logNames(), tt.logNames)
+			}
+		})
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		job         CompletedJob
+		wantSubject string
+		wantBody    string
+		wantError   bool
+	}{
+		{
+			name:        "success case",
+			job:         CompletedJob{ExitStatus: 0},
+			wantSubject: `Job "test-job" succeeded`,
+			wantBody:    "",
+			wantError:   false,
+		},
+		{
+			name:        "failure with exit status",
+			job:         CompletedJob{ExitStatus: 1},
+			wantSubject: `Job "test-job" failed`,
+			wantBody:    "Exit status: 1\n\n",
+			wantError:   false,
+		},
+		{
+			name:        "failure with error message",
+			job:         CompletedJob{Error: "test error"},
+			wantSubject: `Job "test-job" failed`,
+			wantBody:    "Error: test error\n\n",
+			wantError:   false,
+		},
+		{
+			name: "failure with run details",
+			job: CompletedJob{
+				ExitStatus: 2,
+				Started:    time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC),
+				Finished:   time.Date(2025, 1, 1, 3, 1, 30, 0, time.UTC),
+				Hostname:   "server",
+				Username:   "backup",
+				Command:    []string{"rsync", "-a", "/home/", "/backup dir/"},
+			},
+			wantSubject: `Job "test-job" failed`,
+			wantBody: "Exit status: 2\n\n" +
+				"Started:  2025-01-01 03:00:00 +0000\n" +
+				"Finished: 2025-01-01 03:01:30 +0000\n" +
+				"Duration: 1m30s\n" +
+				"Host: backup@server\n" +
+				"Command: rsync -a /home/ '/backup dir/'\n\n",
+			wantError: false,
+		},
+		{
+			name:        "dead run",
+			job:         CompletedJob{ExitStatus: 1, Attempt: 4, Dead: true},
+			wantSubject: `Job "test-job" is dead`,
+			wantBody:    "Exit status: 1\n\nGave up after 4 attempts\n\n",
+			wantError:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, body, err := formatMessage(nil, "test-job", tt.job, newLogExcerpt(Settings{}, tt.job))
+			if (err != nil) != tt.wantError {
+				t.Errorf("formatMessage() error = %v, wantError %v", err, tt.wantError)
+				return
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("formatMessage() subject = %v, want %v", subject, tt.wantSubject)
+			}
+			if body != tt.wantBody {
+				t.Errorf("formatMessage() body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSendSendmail(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "message")
+	argsOutput := filepath.Join(dir, "args")
+
+	script := filepath.Join(dir, "sendmail")
+	content := "#! /bin/sh\necho \"$@\" > " + argsOutput + "\ncat > " + output + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sendSendmail(script, []string{"alice", "bob@example.com"}, `Job "test-job" failed`, "Exit status: 1\n", nil); err != nil {
+		t.Fatalf("sendSendmail() error = %v", err)
+	}
+
+	args, err := os.ReadFile(argsOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "-t -oi" {
+		t.Errorf("arguments = %q, want %q", got, "-t -oi")
+	}
+
+	msg, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"From: regular@localhost\n",
+		"To: alice, bob@example.com\n",
+		"Subject: Job \"test-job\" failed\n",
+		"\n\nExit status: 1\n",
+	} {
+		if !strings.Contains(string(msg), want) {
+			t.Errorf("message %q doesn't contain %q", msg, want)
+		}
+	}
+}
+
+func TestSendSendmailError(t *testing.T) {
+	if err := sendSendmail("/nonexistent/sendmail", []string{"alice"}, "subject", "text", nil); err == nil {
+		t.Error("sendSendmail() succeeded with a missing binary")
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"dbohdan.com/regular/envfile"
+)
+
+// NotifyBackends sends notifications through the backends configured in the settings file.
+// The settings are read on every notification, so changes take effect without a restart.
+func NotifyBackends(db *AppDB, configRoot string) NotifyWhenDone {
+	return func(jobName string, completed CompletedJob) error {
+		settings, err := LoadSettings(configRoot)
+		if err != nil {
+			return err
+		}
+
+		backends := []namedSendFunc{}
+		for _, backend := range settings.notifyBackends() {
+			if completed.notifiesVia(backend.name) && !completed.retriesOnly(backend.name) {
+				backends = append(backends, backend)
+			}
+		}
+		if len(backends) == 0 {
+			return nil
+		}
+
+		subject, text, err := formatMessage(db, jobName, completed, newLogExcerpt(settings, completed))
+		if err != nil {
+			return fmt.Errorf("failed to format notification message: %v", err)
+		}
+
+		errs := []error{}
+		for _, backend := range backends {
+			if err := backend.send(subject, text); err != nil {
+				errs = append(errs, &channelError{Channel: backend.name, Err: err})
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+// namedSendFunc sends a notification message through one backend.
+// The name is the channel in "notify_via".
+type namedSendFunc struct {
+	name string
+	send func(subject, text string) error
+}
+
+// notifyBackends returns the backends with complete settings.
+func (s Settings) notifyBackends() []namedSendFunc {
+	backends := []namedSendFunc{}
+
+	if s.MatrixHomeserver != "" && s.MatrixAccessToken != "" && s.MatrixRoomID != "" {
+		backends = append(backends, namedSendFunc{notifyViaMatrix, func(subject, text string) error {
+			return sendMatrix(s, subject, text)
+		}})
+	}
+
+	if s.NtfyURL != "" {
+		backends = append(backends, namedSendFunc{notifyViaNtfy, func(subject, text string) error {
+			return sendNtfy(s, subject, text)
+		}})
+	}
+
+	if s.XMPPJID != "" && s.XMPPPassword != "" && s.XMPPRecipient != "" {
+		backends = append(backends, namedSendFunc{notifyViaXMPP, func(subject, text string) error {
+			return sendXMPP(s, subject, text)
+		}})
+	}
+
+	return backends
+}
+
+// resolveSecret gets a secret setting from the system keyring if it has the form "keyring:service/account".
+func resolveSecret(value string) (string, error) {
+	if !strings.HasPrefix(value, envfile.KeyringPrefix) {
+		return value, nil
+	}
+
+	env, err := envfile.ResolveKeyring(map[string]string{"secret": value})
+	if err != nil {
+		return "", err
+	}
+
+	return env["secret"], nil
+}
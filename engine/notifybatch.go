@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSubject = "%d jobs failed"
+	batchIntro   = "These jobs failed within %v:\n\n"
+	batchHeader  = "== %s ==\n\n"
+)
+
+// heldEmail is a failure email that waits for others to send with.
+type heldEmail struct {
+	jobName   string
+	completed CompletedJob
+	settings  Settings
+	// The message is formatted when the job fails, so it has the output of that run.
+	text string
+}
+
+// EmailBatcher holds failure emails for "email_batch" seconds after the first one.
+// When several jobs fail in that time, for example, because the network is down,
+// it sends one email about all of them to each set of recipients.
+type EmailBatcher struct {
+	db         *AppDB
+	configRoot string
+
+	// Sends the email about one job right away.
+	single NotifyWhenDone
+	// Sends a combined email.
+	send func(settings Settings, to []string, subject, text string, attachments []string) error
+
+	mu    sync.Mutex
+	held  []heldEmail
+	timer *time.Timer
+}
+
+// NewEmailBatcher returns a batcher that sends single emails with the notifier.
+func NewEmailBatcher(db *AppDB, configRoot string, single NotifyWhenDone) *EmailBatcher {
+	return &EmailBatcher{
+		db:         db,
+		configRoot: configRoot,
+		single:     single,
+		send:       sendEmail,
+	}
+}
+
+// Notify holds a failure email when batching is on and sends other emails right away.
+func (b *EmailBatcher) Notify(jobName string, completed CompletedJob) error {
+	if !completed.notifiesVia(notifyViaEmail) {
+		return nil
+	}
+
+	settings, err := LoadSettings(b.configRoot)
+	if err != nil {
+		return err
+	}
+
+	if settings.EmailBatch == 0 || completed.IsSuccess() {
+		return b.single(jobName, completed)
+	}
+
+	_, text, err := formatMessage(b.db, jobName, completed, newLogExcerpt(settings, completed))
+	if err != nil {
+		return fmt.Errorf("failed to format notification message: %v", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.held = append(b.held, heldEmail{
+		jobName:   jobName,
+		completed: completed,
+		settings:  settings,
+		text:      text,
+	})
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(time.Duration(settings.EmailBatch)*time.Second, b.Flush)
+	}
+
+	return nil
+}
+
+// Flush sends the held emails.
+// Call it before the program exits.
+func (b *EmailBatcher) Flush() {
+	b.mu.Lock()
+	held := b.held
+	b.held = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+
+	keys := []string{}
+	byRecipients := make(map[string][]heldEmail)
+	for _, h := range held {
+		key := strings.Join(h.completed.NotifyTo, "\x00")
+		if _, ok := byRecipients[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		byRecipients[key] = append(byRecipients[key], h)
+	}
+
+	for _, key := range keys {
+		b.sendBatch(byRecipients[key])
+	}
+}
+
+// sendBatch sends one email about the jobs with the same recipients.
+// If it fails, it sends the emails one by one, so they can be retried.
+func (b *EmailBatcher) sendBatch(batch []heldEmail) {
+	if len(batch) > 1 {
+		subject, text := batchMessage(batch)
+
+		attachments := []string{}
+		for _, h := range batch {
+			attachments = append(attachments, h.completed.Attachments...)
+		}
+
+		err := b.send(batch[0].settings, batch[0].completed.NotifyTo, subject, text, attachments)
+		if err == nil {
+			for _, h := range batch {
+				LogJobPrintf(h.jobName, "Sent email notification about %d jobs", len(batch))
+			}
+
+			return
+		}
+
+		log.Printf("Failed to send email about %d jobs; sending them separately: %v", len(batch), err)
+	}
+
+	for _, h := range batch {
+		if err := b.single(h.jobName, h.completed); err != nil {
+			LogJobPrintf(h.jobName, "Failed to send email notification: %v", err)
+		}
+	}
+}
+
+// batchMessage combines the messages about the jobs in a batch.
+func batchMessage(batch []heldEmail) (string, string) {
+	var sb strings.Builder
+
+	window := time.Duration(batch[0].settings.EmailBatch) * time.Second
+	sb.WriteString(fmt.Sprintf(batchIntro, FormatDuration(window)))
+	for _, h := range batch {
+		sb.WriteString("- " + h.jobName + "\n")
+	}
+
+	for _, h := range batch {
+		sb.WriteString("\n" + fmt.Sprintf(batchHeader, h.jobName))
+		sb.WriteString(h.text)
+	}
+
+	return fmt.Sprintf(batchSubject, len(batch)), sb.String()
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type batchRecorder struct {
+	mu       sync.Mutex
+	single   []string
+	combined []string
+	sendErr  error
+}
+
+func (r *batchRecorder) batcher(configRoot string) *EmailBatcher {
+	b := NewEmailBatcher(nil, configRoot, func(jobName string, completed CompletedJob) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.single = append(r.single, jobName)
+		return nil
+	})
+
+	b.send = func(settings Settings, to []string, subject, text string, attachments []string) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.sendErr != nil {
+			return r.sendErr
+		}
+
+		r.combined = append(r.combined, subject+"\n"+text)
+		return nil
+	}
+
+	return b
+}
+
+func writeBatchSettings(t *testing.T, window string) string {
+	t.Helper()
+
+	configRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configRoot, settingsFileName), []byte("email_batch = "+window), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	return configRoot
+}
+
+func TestEmailBatcher(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	recorder := &batchRecorder{}
+	b := recorder.batcher(writeBatchSettings(t, "one_hour"))
+
+	failed := CompletedJob{ExitStatus: 1}
+	toAlice := CompletedJob{ExitStatus: 1, NotifyTo: []string{"alice@example.com"}}
+
+	for _, n := range []struct {
+		job       string
+		completed CompletedJob
+	}{
+		{"backup", failed},
+		{"sync", failed},
+		{"report", toAlice},
+		{"ok", CompletedJob{}},
+		{"quiet", CompletedJob{ExitStatus: 1, NotifyVia: []string{notifyViaNtfy}}},
+	} {
+		if err := b.Notify(n.job, n.completed); err != nil {
+			t.Fatalf("Notify(%q) error = %v", n.job, err)
+		}
+	}
+
+	// Success emails aren't held.
+	if !slices.Equal(recorder.single, []string{"ok"}) || len(recorder.combined) != 0 {
+		t.Fatalf("Expected only the success email before the flush, got %v and %v", recorder.single, recorder.combined)
+	}
+
+	b.Flush()
+
+	if !slices.Equal(recorder.single, []string{"ok", "report"}) {
+		t.Errorf("Expected a single email about report, got %v", recorder.single)
+	}
+	if len(recorder.combined) != 1 {
+		t.Fatalf("Expected one combined email, got %d", len(recorder.combined))
+	}
+
+	for _, want := range []string{"2 jobs failed", "within 1h", "- backup\n- sync\n", "== backup ==", "== sync ==", "Exit status: 1"} {
+		if !strings.Contains(recorder.combined[0], want) {
+			t.Errorf("Expected %q in combined email, got %q", want, recorder.combined[0])
+		}
+	}
+
+	// Nothing is left to send.
+	b.Flush()
+	if len(recorder.single) != 2 || len(recorder.combined) != 1 {
+		t.Errorf("Expected no more emails, got %v and %d combined", recorder.single, len(recorder.combined))
+	}
+}
+
+func TestEmailBatcherFallback(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	recorder := &batchRecorder{sendErr: errors.New("network is down")}
+	b := recorder.batcher(writeBatchSettings(t, "60"))
+
+	for _, job := range []string{"backup", "sync"} {
+		if err := b.Notify(job, CompletedJob{ExitStatus: 1}); err != nil {
+			t.Fatalf("Notify(%q) error = %v", job, err)
+		}
+	}
+
+	b.Flush()
+
+	if !slices.Equal(recorder.single, []string{"backup", "sync"}) {
+		t.Errorf("Expected separate emails after the combined one failed, got %v", recorder.single)
+	}
+}
+
+func TestEmailBatcherOff(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	recorder := &batchRecorder{}
+	b := recorder.batcher(t.TempDir())
+
+	if err := b.Notify("backup", CompletedJob{ExitStatus: 1}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if !slices.Equal(recorder.single, []string{"backup"}) {
+		t.Errorf("Expected the email to be sent right away, got %v", recorder.single)
+	}
+}
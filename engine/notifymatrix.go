@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sendMatrix posts a message to a Matrix room with the client-server API.
+func sendMatrix(settings Settings, subject, text string) error {
+	message, err := json.Marshal(map[string]string{
+		"msgtype": "m.text",
+		"body":    subject + "\n\n" + text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Matrix message: %w", err)
+	}
+
+	txnID := "regular-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	endpoint := fmt.Sprintf(
+		"%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(settings.MatrixHomeserver, "/"),
+		url.PathEscape(settings.MatrixRoomID),
+		txnID,
+	)
+
+	token, err := resolveSecret(settings.MatrixAccessToken)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("failed to create Matrix request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: notifierTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Matrix message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Matrix homeserver returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendMatrix(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+
+		w.Write([]byte(`{"event_id": "$1"}`))
+	}))
+	defer server.Close()
+
+	settings := Settings{
+		MatrixHomeserver:  server.URL + "/",
+		MatrixAccessToken: "secret-token",
+		MatrixRoomID:      "!room:example.com",
+	}
+
+	if err := sendMatrix(settings, "Job failed", "details"); err != nil {
+		t.Fatalf("sendMatrix() error = %v", err)
+	}
+
+	if !strings.HasPrefix(gotPath, "/_matrix/client/v3/rooms/%21room:example.com/send/m.room.message/") {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotAuth != "Bearer secret-token" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if gotBody["msgtype"] != "m.text" || gotBody["body"] != "Job failed\n\ndetails" {
+		t.Errorf("body = %v", gotBody)
+	}
+}
+
+func TestSendMatrixError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errcode": "M_FORBIDDEN"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	settings := Settings{
+		MatrixHomeserver:  server.URL,
+		MatrixAccessToken: "bad-token",
+		MatrixRoomID:      "!room:example.com",
+	}
+
+	err := sendMatrix(settings, "Job failed", "details")
+	if err == nil || !strings.Contains(err.Error(), "M_FORBIDDEN") {
+		t.Errorf("sendMatrix() error = %v, want M_FORBIDDEN", err)
+	}
+}
+
+func TestNotifyBackendsUnconfigured(t *testing.T) {
+	if backends := (Settings{MatrixHomeserver: "https://example.com"}).notifyBackends(); len(backends) != 0 {
+		t.Errorf("expected no backends with incomplete settings, got %d", len(backends))
+	}
+
+	if err := NotifyBackends(nil, t.TempDir())("job", CompletedJob{}); err != nil {
+		t.Errorf("NotifyBackends() error = %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sendNtfy publishes a message to an ntfy topic.
+func sendNtfy(settings Settings, subject, text string) error {
+	token, err := resolveSecret(settings.NtfyToken)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, settings.NtfyURL, strings.NewReader(text))
+	if err != nil {
+		return fmt.Errorf("failed to create ntfy request: %w", err)
+	}
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", subject))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: notifierTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send ntfy message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ntfy server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSendNtfy(t *testing.T) {
+	var gotPath, gotTitle, gotAuth, gotBody string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotTitle = r.Header.Get("Title")
+		gotAuth = r.Header.Get("Authorization")
+
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+
+		w.Write([]byte(`{"id": "1"}`))
+	}))
+	defer server.Close()
+
+	settings := Settings{
+		NtfyURL:   server.URL + "/backups",
+		NtfyToken: "tk_secret",
+	}
+
+	if err := sendNtfy(settings, "Job failed", "details"); err != nil {
+		t.Fatalf("sendNtfy() error = %v", err)
+	}
+
+	if gotPath != "/backups" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotTitle != "Job failed" {
+		t.Errorf("Title = %q", gotTitle)
+	}
+	if gotAuth != "Bearer tk_secret" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if gotBody != "details" {
+		t.Errorf("body = %q", gotBody)
+	}
+}
+
+func TestSendNtfyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := sendNtfy(Settings{NtfyURL: server.URL + "/backups"}, "Job failed", "details")
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("sendNtfy() error = %v, want unauthorized", err)
+	}
+}
+
+func TestNotifyBackendsVia(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	configRoot := t.TempDir()
+	settings := `ntfy_url = "` + server.URL + `/backups"`
+	if err := os.WriteFile(filepath.Join(configRoot, settingsFileName), []byte(settings), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	notify := NotifyBackends(nil, configRoot)
+
+	if err := notify("job", CompletedJob{NotifyVia: []string{notifyViaEmail}}); err != nil {
+		t.Errorf("NotifyBackends() error = %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("sent %d ntfy messages for an email-only job", requests)
+	}
+
+	if err := notify("job", CompletedJob{NotifyVia: []string{notifyViaNtfy}}); err != nil {
+		t.Errorf("NotifyBackends() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("sent %d ntfy messages, want 1", requests)
+	}
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pluginReport is the JSON document a notifier plugin receives on stdin.
+type pluginReport struct {
+	Job        string `json:"job"`
+	Success    bool   `json:"success"`
+	ExitStatus int    `json:"exit_status"`
+	// What the exit status means according to "exit_meanings".
+	ExitMeaning string    `json:"exit_meaning"`
+	Error       string    `json:"error"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	Subject     string    `json:"subject"`
+	Message     string    `json:"message"`
+	Stdout      []string  `json:"stdout"`
+	Stderr      []string  `json:"stderr"`
+	// The paths of the files the job attaches to notifications.
+	Attachments []string `json:"attachments"`
+}
+
+// NotifyAll combines notifiers into one that calls each of them in order.
+// It returns the errors of all failed notifiers.
+func NotifyAll(notifiers ...NotifyWhenDone) NotifyWhenDone {
+	return func(jobName string, completed CompletedJob) error {
+		errs := []error{}
+
+		for _, notify := range notifiers {
+			if err := notify(jobName, completed); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+// NotifyPlugins runs every executable in the notifiers directory in the config root.
+// Each plugin receives a JSON completion report on stdin.
+// The directory is read on every notification, so plugins can be added and removed while Regular is running.
+func NotifyPlugins(db *AppDB, configRoot string) NotifyWhenDone {
+	return func(jobName string, completed CompletedJob) error {
+		if !completed.notifiesVia(notifyViaPlugins) {
+			return nil
+		}
+
+		plugins, err := findNotifierPlugins(filepath.Join(configRoot, notifiersDirName))
+		if err != nil {
+			return err
+		}
+
+		if len(plugins) == 0 {
+			return nil
+		}
+
+		settings, err := LoadSettings(configRoot)
+		if err != nil {
+			return err
+		}
+
+		report, err := newPluginReport(db, jobName, completed, newLogExcerpt(settings, completed))
+		if err != nil {
+			return err
+		}
+
+		input, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification report: %w", err)
+		}
+
+		errs := []error{}
+		for _, plugin := range plugins {
+			name := filepath.Base(plugin)
+			if completed.retriesOnly(name) {
+				continue
+			}
+
+			if err := runNotifierPlugin(plugin, input); err != nil {
+				errs = append(errs, &channelError{Channel: name, Err: err})
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+// findNotifierPlugins returns the paths of the executable files in dir.
+// Hidden files are ignored.
+func findNotifierPlugins(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read notifiers directory: %w", err)
+	}
+
+	plugins := []string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		// Follow symlinks.
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		plugins = append(plugins, path)
+	}
+
+	return plugins, nil
+}
+
+func newPluginReport(db *AppDB, jobName string, completed CompletedJob, excerpt logExcerpt) (pluginReport, error) {
+	subject, message, err := formatMessage(db, jobName, completed, excerpt)
+	if err != nil {
+		return pluginReport{}, fmt.Errorf("failed to format notification message: %v", err)
+	}
+
+	report := pluginReport{
+		Job:         jobName,
+		Success:     completed.IsSuccess(),
+		ExitStatus:  completed.ExitStatus,
+		ExitMeaning: completed.ExitMeaning,
+		Error:       completed.Error,
+		Started:     completed.Started,
+		Finished:    completed.Finished,
+		Subject:     subject,
+		Message:     message,
+		Stdout:      []string{},
+		Stderr:      []string{},
+		Attachments: completed.Attachments,
+	}
+	if report.Attachments == nil {
+		report.Attachments = []string{}
+	}
+
+	if db != nil {
+		lines := map[string]*[]string{"stdout": &report.Stdout, "stderr": &report.Stderr}
+		for _, logName := range excerpt.logNames() {
+			logLines, err := db.runLogTail(jobName, completed.ID, logName, excerpt.lines)
+			if err != nil {
+				return pluginReport{}, fmt.Errorf("error reading log: %w", err)
+			}
+
+			*lines[logName] = append(*lines[logName], logLines...)
+		}
+	}
+
+	return report, nil
+}
+
+func runNotifierPlugin(path string, input []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifierPluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notifier plugin %q failed: %w: %s", filepath.Base(path), err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNotifyPlugins(t *testing.T) {
+	configRoot := t.TempDir()
+	pluginDir := filepath.Join(configRoot, notifiersDirName)
+	if err := os.MkdirAll(pluginDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(t.TempDir(), "report.json")
+	plugin := "#! /bin/sh\ncat > '" + outPath + "'\n"
+	if err := os.WriteFile(filepath.Join(pluginDir, "capture"), []byte(plugin), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither of these should run.
+	if err := os.WriteFile(filepath.Join(pluginDir, "README"), []byte("not a plugin"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, ".hidden"), []byte("#! /bin/sh\nexit 1\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	completed := CompletedJob{
+		ExitStatus: 3,
+		Started:    started,
+		Finished:   started.Add(time.Minute),
+	}
+
+	if err := NotifyPlugins(nil, configRoot)("backup", completed); err != nil {
+		t.Fatalf("NotifyPlugins() error = %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("plugin did not write report: %v", err)
+	}
+
+	var report pluginReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+
+	if report.Job != "backup" || report.Success || report.ExitStatus != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if !report.Started.Equal(completed.Started) || !report.Finished.Equal(completed.Finished) {
+		t.Errorf("unexpected times: %v, %v", report.Started, report.Finished)
+	}
+	if report.Subject != `Job "backup" failed` {
+		t.Errorf("Subject = %q", report.Subject)
+	}
+}
+
+func TestNotifyPluginsFailure(t *testing.T) {
+	configRoot := t.TempDir()
+	pluginDir := filepath.Join(configRoot, notifiersDirName)
+	if err := os.MkdirAll(pluginDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(pluginDir, "broken"), []byte("#! /bin/sh\necho oops\nexit 1\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NotifyPlugins(nil, configRoot)("backup", CompletedJob{})
+	if err == nil {
+		t.Fatal("expected an error from a failing plugin")
+	}
+}
+
+func TestNotifyPluginsNoDir(t *testing.T) {
+	if err := NotifyPlugins(nil, t.TempDir())("backup", CompletedJob{}); err != nil {
+		t.Errorf("NotifyPlugins() error = %v", err)
+	}
+}
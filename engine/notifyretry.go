@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// pendingNotification is a failed notification waiting to be retried.
+type pendingNotification struct {
+	ID       int64
+	Notifier string
+	// The channel of the notifier that failed or an empty string for all of them.
+	Channel     string
+	JobName     string
+	Completed   CompletedJob
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+}
+
+// channelError is a failure to notify through one channel of a notifier, such as a backend or a plugin.
+// A notifier that sends through several channels returns one for each channel that failed,
+// so only those channels are retried.
+type channelError struct {
+	Channel string
+	Err     error
+}
+
+func (e *channelError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *channelError) Unwrap() error {
+	return e.Err
+}
+
+// failedChannels maps the channels that failed to their errors.
+// An error that isn't about one channel fails all channels, which is the empty string.
+func failedChannels(err error) map[string]error {
+	failed := make(map[string]error)
+
+	var walk func(err error) bool
+	walk = func(err error) bool {
+		if ce, ok := err.(*channelError); ok {
+			failed[ce.Channel] = ce.Err
+			return true
+		}
+
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			return false
+		}
+
+		for _, e := range joined.Unwrap() {
+			if !walk(e) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if !walk(err) {
+		return map[string]error{"": err}
+	}
+
+	return failed
+}
+
+// RetryQueue saves failed notifications in the app database and retries them with exponential backoff.
+// Pending notifications survive restarts.
+type RetryQueue struct {
+	db        *AppDB
+	mu        sync.Mutex
+	notifiers map[string]NotifyWhenDone
+}
+
+func NewRetryQueue(db *AppDB) *RetryQueue {
+	return &RetryQueue{
+		db:        db,
+		notifiers: make(map[string]NotifyWhenDone),
+	}
+}
+
+// Wrap registers a notifier under a name and returns a notifier that queues its failed notifications.
+// The name identifies the notifier in the database, so it must stay the same between restarts.
+func (q *RetryQueue) Wrap(name string, notify NotifyWhenDone) NotifyWhenDone {
+	q.mu.Lock()
+	q.notifiers[name] = notify
+	q.mu.Unlock()
+
+	return func(jobName string, completed CompletedJob) error {
+		err := notify(jobName, completed)
+		if err == nil || q.db == nil {
+			return err
+		}
+
+		for channel, channelErr := range failedChannels(err) {
+			pending := pendingNotification{
+				Notifier:    name,
+				Channel:     channel,
+				JobName:     jobName,
+				Completed:   completed,
+				Attempts:    1,
+				NextAttempt: time.Now().Add(notifyRetryDelay(1)),
+				LastError:   channelErr.Error(),
+			}
+			if saveErr := q.db.savePendingNotification(pending); saveErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to queue notification for retry: %w", saveErr))
+			}
+		}
+
+		return fmt.Errorf("%w (will retry)", err)
+	}
+}
+
+// Retry sends the queued notifications that are due.
+func (q *RetryQueue) Retry(now time.Time) error {
+	pending, err := q.db.duePendingNotifications(now)
+	if err != nil {
+		return fmt.Errorf("failed to get pending notifications: %w", err)
+	}
+
+	for _, p := range pending {
+		q.mu.Lock()
+		notify, ok := q.notifiers[p.Notifier]
+		q.mu.Unlock()
+
+		if !ok {
+			// The notifier may be registered later.
+			continue
+		}
+
+		completed := p.Completed
+		completed.retryChannel = p.Channel
+
+		err := notify(p.JobName, completed)
+		if err == nil {
+			LogJobPrintf(p.JobName, "Sent %s notification after %d failed attempt(s)", p.description(), p.Attempts)
+
+			if err := q.db.removePendingNotification(p.ID); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		p.Attempts++
+		if p.Attempts >= maxNotifyAttempts {
+			LogJobPrintf(p.JobName, "Giving up on %s notification after %d attempts: %v", p.description(), p.Attempts, err)
+
+			if err := q.db.removePendingNotification(p.ID); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		p.NextAttempt = now.Add(notifyRetryDelay(p.Attempts))
+		p.LastError = err.Error()
+		if err := q.db.updatePendingNotification(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run retries due notifications until the program exits.
+func (q *RetryQueue) Run() {
+	for {
+		if err := q.Retry(time.Now()); err != nil {
+			log.Printf("Notification retry failed: %v", err)
+		}
+
+		time.Sleep(notifyRetryInterval)
+	}
+}
+
+// description names the notifier and the channel for the log.
+func (p pendingNotification) description() string {
+	if p.Channel == "" {
+		return p.Notifier
+	}
+
+	return p.Notifier + " (" + p.Channel + ")"
+}
+
+// notifyRetryDelay returns how long to wait after a given number of failed attempts.
+func notifyRetryDelay(attempts int) time.Duration {
+	delay := notifyRetryBaseDelay
+	for i := 1; i < attempts && delay < notifyRetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, notifyRetryMaxDelay)
+}
+
+// savePendingNotification saves the whole run, so the retry describes it like the first attempt.
+// The columns before "channel" are required by databases created by older versions.
+func (c *AppDB) savePendingNotification(p pendingNotification) error {
+	completed, err := json.Marshal(p.Completed)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(`
+		INSERT INTO pending_notifications (
+			notifier,
+			job_name,
+			run_id,
+			error,
+			exit_status,
+			started,
+			finished,
+			attempts,
+			next_attempt,
+			last_error,
+			channel,
+			completed
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Notifier,
+		p.JobName,
+		p.Completed.ID,
+		p.Completed.Error,
+		p.Completed.ExitStatus,
+		p.Completed.Started,
+		p.Completed.Finished,
+		p.Attempts,
+		p.NextAttempt,
+		p.LastError,
+		p.Channel,
+		string(completed),
+	)
+
+	return err
+}
+
+func (c *AppDB) duePendingNotifications(now time.Time) ([]pendingNotification, error) {
+	rows, err := c.db.Query(`
+		SELECT
+			id,
+			notifier,
+			channel,
+			job_name,
+			attempts,
+			next_attempt,
+			last_error,
+			completed
+		FROM pending_notifications
+		ORDER BY id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := []pendingNotification{}
+	for rows.Next() {
+		var p pendingNotification
+		var completed string
+		if err := rows.Scan(
+			&p.ID,
+			&p.Notifier,
+			&p.Channel,
+			&p.JobName,
+			&p.Attempts,
+			&p.NextAttempt,
+			&p.LastError,
+			&completed,
+		); err != nil {
+			return nil, err
+		}
+
+		// Compare in Go because SQLite compares the stored times as strings.
+		if p.NextAttempt.After(now) {
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(completed), &p.Completed); err != nil {
+			return nil, fmt.Errorf("failed to decode run of pending notification: %w", err)
+		}
+
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+func (c *AppDB) updatePendingNotification(p pendingNotification) error {
+	_, err := c.db.Exec(`
+		UPDATE pending_notifications
+		SET attempts = ?, next_attempt = ?, last_error = ?
+		WHERE id = ?`,
+		p.Attempts,
+		p.NextAttempt,
+		p.LastError,
+		p.ID,
+	)
+
+	return err
+}
+
+func (c *AppDB) removePendingNotification(id int64) error {
+	_, err := c.db.Exec(`DELETE FROM pending_notifications WHERE id = ?`, id)
+
+	return err
+}
@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNotifyRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := notifyRetryDelay(tt.attempts); got != tt.expected {
+			t.Errorf("notifyRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.expected)
+		}
+	}
+}
+
+func TestRetryQueue(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	fail := true
+	sent := []string{}
+	notify := func(jobName string, completed CompletedJob) error {
+		if fail {
+			return errors.New("server down")
+		}
+
+		sent = append(sent, jobName)
+		return nil
+	}
+
+	queue := NewRetryQueue(db)
+	wrapped := queue.Wrap("test", notify)
+
+	lines := 0
+	failed := CompletedJob{ID: 1, ExitStatus: 1, NotifyLines: &lines, NotifyTo: []string{"admin@example.com"}, NotifyVia: []string{"email"}}
+	if err := wrapped("retry-job", failed); err == nil {
+		t.Fatal("Expected the wrapped notifier to report the failure")
+	}
+
+	now := time.Now()
+
+	// Nothing is due before the backoff delay.
+	if err := queue.Retry(now); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	pending, err := db.duePendingNotifications(now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].Completed.ExitStatus != 1 {
+		t.Fatalf("unexpected pending notifications: %+v", pending)
+	}
+	if diff := cmp.Diff(failed.NotifyTo, pending[0].Completed.NotifyTo); diff != "" {
+		t.Errorf("NotifyTo mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(failed.NotifyVia, pending[0].Completed.NotifyVia); diff != "" {
+		t.Errorf("NotifyVia mismatch (-want +got):\n%s", diff)
+	}
+	if got := pending[0].Completed.NotifyLines; got == nil || *got != 0 {
+		t.Errorf("Expected NotifyLines 0, got %v", got)
+	}
+	if got := pending[0].Completed.NotifyStdout; got != nil {
+		t.Errorf("Expected no NotifyStdout, got %v", *got)
+	}
+
+	// A failed retry backs off further.
+	later := now.Add(2 * time.Minute)
+	if err := queue.Retry(later); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	pending, err = db.duePendingNotifications(later.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Attempts != 2 || !pending[0].NextAttempt.After(later) {
+		t.Fatalf("unexpected pending notifications after retry: %+v", pending)
+	}
+
+	// A successful retry removes the notification.
+	fail = false
+	if err := queue.Retry(later.Add(time.Hour)); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if len(sent) != 1 || sent[0] != "retry-job" {
+		t.Errorf("sent = %v", sent)
+	}
+
+	pending, err = db.duePendingNotifications(later.Add(24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending notifications, got %+v", pending)
+	}
+}
+
+func TestRetryQueueGivesUp(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	queue := NewRetryQueue(db)
+	wrapped := queue.Wrap("test", func(string, CompletedJob) error {
+		return errors.New("server down")
+	})
+	_ = wrapped("doomed-job", CompletedJob{})
+
+	now := time.Now()
+	for range maxNotifyAttempts {
+		now = now.Add(24 * time.Hour)
+		if err := queue.Retry(now); err != nil {
+			t.Fatalf("Retry() error = %v", err)
+		}
+	}
+
+	pending, err := db.duePendingNotifications(now.Add(24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected the notification to be dropped, got %+v", pending)
+	}
+}
+
+func TestRetryQueuePerChannel(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	fail := map[string]bool{"ntfy": true}
+	sent := map[string]int{}
+	notify := func(jobName string, completed CompletedJob) error {
+		var errs []error
+		for _, channel := range []string{"ntfy", "slack"} {
+			if completed.retriesOnly(channel) {
+				continue
+			}
+
+			if fail[channel] {
+				errs = append(errs, &channelError{Channel: channel, Err: errors.New("server down")})
+				continue
+			}
+
+			sent[channel]++
+		}
+
+		return errors.Join(errs...)
+	}
+
+	queue := NewRetryQueue(db)
+	wrapped := queue.Wrap("backends", notify)
+
+	now := time.Now().Round(0)
+	failed := CompletedJob{
+		ID:          7,
+		Error:       "killed",
+		ExitStatus:  -1,
+		Started:     now.Add(-time.Minute),
+		Finished:    now,
+		Signal:      9,
+		ExitMeaning: "out of memory",
+		Hostname:    "host",
+		Repeats:     2,
+		Failures:    3,
+		Skipped:     true,
+	}
+	if err := wrapped("channel-job", failed); err == nil {
+		t.Fatal("Expected the wrapped notifier to report the failure")
+	}
+
+	pending, err := db.duePendingNotifications(now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Channel != "ntfy" {
+		t.Fatalf("Expected one pending notification for ntfy, got %+v", pending)
+	}
+	if diff := cmp.Diff(failed, pending[0].Completed, cmp.AllowUnexported(CompletedJob{})); diff != "" {
+		t.Errorf("Saved run mismatch (-want +got):\n%s", diff)
+	}
+
+	fail["ntfy"] = false
+	if err := queue.Retry(now.Add(time.Hour)); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+
+	if diff := cmp.Diff(map[string]int{"ntfy": 1, "slack": 1}, sent); diff != "" {
+		t.Errorf("Notifications mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunLogTail(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+	db, err := OpenAppDB(dir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	saveRun := func(output string) int64 {
+		path := filepath.Join(dir, "stderr.log")
+		if err := os.WriteFile(path, []byte(output), filePerms); err != nil {
+			t.Fatal(err)
+		}
+
+		id, err := db.saveCompletedJob("tail-job", CompletedJob{ExitStatus: 1}, []logFile{{name: "stderr", path: path}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return id
+	}
+
+	first := saveRun("one\ntwo\nthree\n")
+	saveRun("later\n")
+
+	lines, err := db.runLogTail("tail-job", first, "stderr", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"two", "three"}, lines); diff != "" {
+		t.Errorf("Lines of the run mismatch (-want +got):\n%s", diff)
+	}
+
+	lines, err = db.runLogTail("tail-job", 0, "stderr", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"later"}, lines); diff != "" {
+		t.Errorf("Lines of the latest run mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	xmppNSClient  = "jabber:client"
+	xmppNSStream  = "http://etherx.jabber.org/streams"
+	xmppNSTLS     = "urn:ietf:params:xml:ns:xmpp-tls"
+	xmppNSSASL    = "urn:ietf:params:xml:ns:xmpp-sasl"
+	xmppNSBind    = "urn:ietf:params:xml:ns:xmpp-bind"
+	xmppPort      = "5222"
+	xmppResource  = "regular"
+	xmppPlainMech = "PLAIN"
+)
+
+// xmppTLSConfig returns the TLS configuration for a server.
+// Tests replace it to trust their certificate.
+var xmppTLSConfig = func(domain string) *tls.Config {
+	return &tls.Config{ServerName: domain}
+}
+
+type xmppFeatures struct {
+	StartTLS   *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-tls starttls"`
+	Mechanisms []string  `xml:"urn:ietf:params:xml:ns:xmpp-sasl mechanisms>mechanism"`
+	Bind       *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+}
+
+type xmppConn struct {
+	conn   net.Conn
+	dec    *xml.Decoder
+	domain string
+}
+
+// sendXMPP sends a chat message with a minimal XMPP client.
+// It requires STARTTLS and authenticates with SASL PLAIN.
+func sendXMPP(settings Settings, subject, text string) error {
+	user, domain, ok := strings.Cut(settings.XMPPJID, "@")
+	if !ok || user == "" || domain == "" {
+		return fmt.Errorf("invalid XMPP JID: %q", settings.XMPPJID)
+	}
+	domain, _, _ = strings.Cut(domain, "/")
+
+	password, err := resolveSecret(settings.XMPPPassword)
+	if err != nil {
+		return err
+	}
+
+	addr := settings.XMPPServer
+	if addr == "" {
+		addr = xmppServerAddr(domain)
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, notifierTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to XMPP server: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(notifierTimeout)); err != nil {
+		return err
+	}
+
+	x := &xmppConn{conn: conn, domain: domain}
+	if err := x.login(user, password); err != nil {
+		return fmt.Errorf("XMPP login failed: %w", err)
+	}
+
+	var body strings.Builder
+	if err := xml.EscapeText(&body, []byte(subject+"\n\n"+text)); err != nil {
+		return err
+	}
+
+	var to strings.Builder
+	if err := xml.EscapeText(&to, []byte(settings.XMPPRecipient)); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("<message to='%s' type='chat'><body>%s</body></message></stream:stream>", to.String(), body.String())
+	if _, err := io.WriteString(x.conn, message); err != nil {
+		return fmt.Errorf("failed to send XMPP message: %w", err)
+	}
+
+	return nil
+}
+
+// xmppServerAddr looks up the client SRV record of the domain.
+func xmppServerAddr(domain string) string {
+	_, records, err := net.LookupSRV("xmpp-client", "tcp", domain)
+	if err == nil && len(records) > 0 {
+		target := strings.TrimSuffix(records[0].Target, ".")
+		return net.JoinHostPort(target, strconv.Itoa(int(records[0].Port)))
+	}
+
+	return net.JoinHostPort(domain, xmppPort)
+}
+
+func (x *xmppConn) login(user, password string) error {
+	features, err := x.openStream()
+	if err != nil {
+		return err
+	}
+
+	if features.StartTLS == nil {
+		return fmt.Errorf("server doesn't support STARTTLS")
+	}
+
+	if _, err := io.WriteString(x.conn, "<starttls xmlns='"+xmppNSTLS+"'/>"); err != nil {
+		return err
+	}
+
+	if name, err := x.nextElementName(); err != nil {
+		return err
+	} else if name != "proceed" {
+		return fmt.Errorf("server refused STARTTLS")
+	}
+
+	tlsConn := tls.Client(x.conn, xmppTLSConfig(x.domain))
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	x.conn = tlsConn
+
+	features, err = x.openStream()
+	if err != nil {
+		return err
+	}
+
+	hasPlain := false
+	for _, mech := range features.Mechanisms {
+		hasPlain = hasPlain || mech == xmppPlainMech
+	}
+	if !hasPlain {
+		return fmt.Errorf("server doesn't support SASL PLAIN")
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte("\x00" + user + "\x00" + password))
+	auth := "<auth xmlns='" + xmppNSSASL + "' mechanism='" + xmppPlainMech + "'>" + credentials + "</auth>"
+	if _, err := io.WriteString(x.conn, auth); err != nil {
+		return err
+	}
+
+	if name, err := x.nextElementName(); err != nil {
+		return err
+	} else if name != "success" {
+		return fmt.Errorf("authentication failed")
+	}
+
+	features, err = x.openStream()
+	if err != nil {
+		return err
+	}
+
+	if features.Bind == nil {
+		return fmt.Errorf("server doesn't support resource binding")
+	}
+
+	bind := "<iq type='set' id='bind1'><bind xmlns='" + xmppNSBind + "'><resource>" + xmppResource + "</resource></bind></iq>"
+	if _, err := io.WriteString(x.conn, bind); err != nil {
+		return err
+	}
+
+	var iq struct {
+		Type string `xml:"type,attr"`
+	}
+	if err := x.nextElement(&iq); err != nil {
+		return err
+	}
+	if iq.Type != "result" {
+		return fmt.Errorf("failed to bind resource")
+	}
+
+	return nil
+}
+
+// openStream starts a new stream and returns the features the server offers.
+func (x *xmppConn) openStream() (xmppFeatures, error) {
+	header := fmt.Sprintf(
+		"<?xml version='1.0'?><stream:stream to='%s' version='1.0' xmlns='%s' xmlns:stream='%s'>",
+		x.domain,
+		xmppNSClient,
+		xmppNSStream,
+	)
+	if _, err := io.WriteString(x.conn, header); err != nil {
+		return xmppFeatures{}, err
+	}
+
+	x.dec = xml.NewDecoder(x.conn)
+
+	// Skip to the start of the server's stream.
+	for {
+		token, err := x.dec.Token()
+		if err != nil {
+			return xmppFeatures{}, err
+		}
+
+		if se, ok := token.(xml.StartElement); ok {
+			if se.Name.Space != xmppNSStream || se.Name.Local != "stream" {
+				return xmppFeatures{}, fmt.Errorf("unexpected element: %v", se.Name.Local)
+			}
+
+			break
+		}
+	}
+
+	var features xmppFeatures
+	if err := x.nextElement(&features); err != nil {
+		return xmppFeatures{}, err
+	}
+
+	return features, nil
+}
+
+func (x *xmppConn) nextStart() (xml.StartElement, error) {
+	for {
+		token, err := x.dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+
+		switch t := token.(type) {
+
+		case xml.StartElement:
+			if t.Name.Space == xmppNSStream && t.Name.Local == "error" {
+				return xml.StartElement{}, fmt.Errorf("stream error")
+			}
+
+			return t, nil
+
+		case xml.EndElement:
+			return xml.StartElement{}, fmt.Errorf("server closed the stream")
+		}
+	}
+}
+
+func (x *xmppConn) nextElement(v any) error {
+	se, err := x.nextStart()
+	if err != nil {
+		return err
+	}
+
+	return x.dec.DecodeElement(v, &se)
+}
+
+func (x *xmppConn) nextElementName() (string, error) {
+	se, err := x.nextStart()
+	if err != nil {
+		return "", err
+	}
+
+	if err := x.dec.Skip(); err != nil {
+		return "", err
+	}
+
+	return se.Name.Local, nil
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeXMPPServer accepts one client and records the message it sends.
+func fakeXMPPServer(t *testing.T, password string) (string, *tls.Config, chan string) {
+	t.Helper()
+
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(tlsServer.Close)
+
+	clientConfig := tlsServer.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	clientConfig.ServerName = "example.com"
+	serverConfig := &tls.Config{Certificates: tlsServer.TLS.Certificates}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	bodies := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var c net.Conn = conn
+		dec := xml.NewDecoder(c)
+
+		next := func(v any) string {
+			for {
+				token, err := dec.Token()
+				if err != nil {
+					return ""
+				}
+
+				if se, ok := token.(xml.StartElement); ok {
+					if se.Name.Local == "stream" {
+						continue
+					}
+
+					if err := dec.DecodeElement(v, &se); err != nil {
+						return ""
+					}
+
+					return se.Name.Local
+				}
+			}
+		}
+		send := func(s string) {
+			_, _ = io.WriteString(c, s)
+		}
+		streamHeader := "<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>"
+
+		var ignored struct{}
+		send(streamHeader + "<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/></stream:features>")
+		if next(&ignored) != "starttls" {
+			return
+		}
+		send("<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>")
+
+		c = tls.Server(conn, serverConfig)
+		dec = xml.NewDecoder(c)
+
+		send(streamHeader + "<stream:features><mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms></stream:features>")
+		var auth struct {
+			Data string `xml:",chardata"`
+		}
+		if next(&auth) != "auth" {
+			return
+		}
+		if auth.Data != base64.StdEncoding.EncodeToString([]byte("\x00bot\x00"+password)) {
+			send("<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><not-authorized/></failure>")
+			return
+		}
+		send("<success xmlns='urn:ietf:params:xml:ns:xmpp-sasl'/>")
+
+		dec = xml.NewDecoder(c)
+		send(streamHeader + "<stream:features><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'/></stream:features>")
+		if next(&ignored) != "iq" {
+			return
+		}
+		send("<iq type='result' id='bind1'/>")
+
+		var message struct {
+			To   string `xml:"to,attr"`
+			Body string `xml:"body"`
+		}
+		if next(&message) != "message" || message.To != "admin@example.com" {
+			return
+		}
+		bodies <- message.Body
+	}()
+
+	return listener.Addr().String(), clientConfig, bodies
+}
+
+func TestSendXMPP(t *testing.T) {
+	addr, clientConfig, bodies := fakeXMPPServer(t, "secret")
+
+	saved := xmppTLSConfig
+	xmppTLSConfig = func(string) *tls.Config { return clientConfig }
+	defer func() { xmppTLSConfig = saved }()
+
+	settings := Settings{
+		XMPPJID:       "bot@example.com",
+		XMPPPassword:  "secret",
+		XMPPRecipient: "admin@example.com",
+		XMPPServer:    addr,
+	}
+
+	if err := sendXMPP(settings, "Job failed", "<details>"); err != nil {
+		t.Fatalf("sendXMPP() error = %v", err)
+	}
+
+	if body := <-bodies; body != "Job failed\n\n<details>" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSendXMPPBadPassword(t *testing.T) {
+	addr, clientConfig, _ := fakeXMPPServer(t, "secret")
+
+	saved := xmppTLSConfig
+	xmppTLSConfig = func(string) *tls.Config { return clientConfig }
+	defer func() { xmppTLSConfig = saved }()
+
+	settings := Settings{
+		XMPPJID:       "bot@example.com",
+		XMPPPassword:  "wrong",
+		XMPPRecipient: "admin@example.com",
+		XMPPServer:    addr,
+	}
+
+	if err := sendXMPP(settings, "Job failed", "details"); err == nil {
+		t.Error("sendXMPP() succeeded with a wrong password")
+	}
+}
+
+func TestSendXMPPInvalidJID(t *testing.T) {
+	if err := sendXMPP(Settings{XMPPJID: "example.com"}, "subject", "text"); err == nil {
+		t.Error("sendXMPP() accepted a JID without a user")
+	}
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"database/sql"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// PauseQueue stops the daemon from starting the jobs waiting in a queue.
+// The jobs keep being queued, and a running job finishes.
+// Pausing a paused queue does nothing.
+func (c *AppDB) PauseQueue(queue string) error {
+	_, err := c.db.Exec(`
+		INSERT OR IGNORE INTO paused_queues (queue, since)
+		VALUES (?, ?)`,
+		queue,
+		time.Now(),
+	)
+
+	return err
+}
+
+// ResumeQueue lets the daemon start the jobs in a paused queue.
+// It returns false if the queue wasn't paused.
+func (c *AppDB) ResumeQueue(queue string) (bool, error) {
+	res, err := c.db.Exec(`DELETE FROM paused_queues WHERE queue = ?`, queue)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// PausedQueues maps the names of the paused queues to when they were paused.
+func (c *AppDB) PausedQueues() (map[string]time.Time, error) {
+	rows, err := c.db.Query(`SELECT queue, since FROM paused_queues`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paused := make(map[string]time.Time)
+	for rows.Next() {
+		var queue string
+		var since time.Time
+
+		if err := rows.Scan(&queue, &since); err != nil {
+			return nil, err
+		}
+
+		paused[queue] = since
+	}
+
+	return paused, rows.Err()
+}
+
+// loadPausedQueues reads the paused queues into the runner.
+func (r Runner) loadPausedQueues() error {
+	paused, err := r.db.PausedQueues()
+	if err != nil {
+		return fmt.Errorf("failed to get paused queues: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	maps.Copy(r.paused, paused)
+
+	return nil
+}
+
+// rememberPausedQueue updates the runner after "regular pause" has paused a queue.
+// Like PauseQueue, it keeps the time of an earlier pause.
+func (r Runner) rememberPausedQueue(queue string, since time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.paused[queue]; !ok {
+		r.paused[queue] = since
+	}
+}
+
+// forgetPausedQueue updates the runner after "regular resume" has resumed a queue.
+func (r Runner) forgetPausedQueue(queue string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.paused, queue)
+}
+
+// pausedSince returns when a queue was paused or zero if it isn't.
+func (r Runner) pausedSince(queue string) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.paused[queue]
+}
+
+// pausedQueues returns a copy of the paused queues.
+func (r Runner) pausedQueues() map[string]time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return maps.Clone(r.paused)
+}
+
+// Snoozes returns the snoozes of all jobs sorted by job name.
+// Like JobSnooze, it includes the snoozes that have ended but the scheduler hasn't removed.
+func (c *AppDB) Snoozes() ([]Snooze, error) {
+	rows, err := c.db.Query(`
+		SELECT job_name, since, until
+		FROM snoozed_jobs
+		ORDER BY job_name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snoozes := []Snooze{}
+	for rows.Next() {
+		var snooze Snooze
+		var until sql.NullTime
+
+		if err := rows.Scan(&snooze.JobName, &snooze.Since, &until); err != nil {
+			return nil, err
+		}
+
+		if until.Valid {
+			snooze.Until = until.Time
+		}
+
+		snoozes = append(snoozes, snooze)
+	}
+
+	return snoozes, rows.Err()
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestAppDBPauseQueue(t *testing.T) {
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.PauseQueue("backup"); err != nil {
+		t.Fatalf("PauseQueue() error = %v", err)
+	}
+
+	paused, err := db.PausedQueues()
+	if err != nil {
+		t.Fatalf("PausedQueues() error = %v", err)
+	}
+
+	since, ok := paused["backup"]
+	if !ok || len(paused) != 1 {
+		t.Fatalf("Expected only the backup queue to be paused, got %v", paused)
+	}
+
+	// Pausing again keeps the original time.
+	if err := db.PauseQueue("backup"); err != nil {
+		t.Fatalf("PauseQueue() error = %v", err)
+	}
+
+	paused, err = db.PausedQueues()
+	if err != nil {
+		t.Fatalf("PausedQueues() error = %v", err)
+	}
+	if !paused["backup"].Equal(since) {
+		t.Errorf("Expected the queue to be paused since %v, got %v", since, paused["backup"])
+	}
+
+	resumed, err := db.ResumeQueue("backup")
+	if err != nil || !resumed {
+		t.Fatalf("ResumeQueue() = %v, %v; want true, nil", resumed, err)
+	}
+
+	resumed, err = db.ResumeQueue("backup")
+	if err != nil || resumed {
+		t.Errorf("ResumeQueue() = %v, %v; want false, nil", resumed, err)
+	}
+
+	paused, err = db.PausedQueues()
+	if err != nil || len(paused) != 0 {
+		t.Errorf("PausedQueues() = %v, %v; want none", paused, err)
+	}
+}
+
+func TestPauseOverSocket(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	sendToRunner(t, runner, Request{Verb: VerbPause, Queue: "backup"})
+	since := runner.pausedSince("backup")
+	if since.IsZero() {
+		t.Fatal("Expected the queue to be paused")
+	}
+
+	// Pausing again keeps the time of the first pause.
+	sendToRunner(t, runner, Request{Verb: VerbPause, Queue: "backup"})
+	if again := runner.pausedSince("backup"); !again.Equal(since) {
+		t.Errorf("Expected the pause to start at %v, got %v", since, again)
+	}
+
+	sendToRunner(t, runner, Request{Verb: VerbResume, Queue: "backup"})
+	if paused := runner.pausedQueues(); len(paused) != 0 {
+		t.Errorf("Expected no paused queues, got %v", paused)
+	}
+}
+
+func TestAppDBSnoozes(t *testing.T) {
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	until := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+	if err := db.SnoozeJob("sync", until); err != nil {
+		t.Fatalf("SnoozeJob() error = %v", err)
+	}
+	if err := db.SnoozeJob("backup", time.Time{}); err != nil {
+		t.Fatalf("SnoozeJob() error = %v", err)
+	}
+
+	snoozes, err := db.Snoozes()
+	if err != nil {
+		t.Fatalf("Snoozes() error = %v", err)
+	}
+
+	if len(snoozes) != 2 || snoozes[0].JobName != "backup" || snoozes[1].JobName != "sync" {
+		t.Fatalf("Expected snoozes of backup and sync, got %v", snoozes)
+	}
+	if !snoozes[0].Until.IsZero() || !snoozes[1].Until.Equal(until) {
+		t.Errorf("Expected the snoozes to end never and at %v, got %v", until, snoozes)
+	}
+}
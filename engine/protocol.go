@@ -1,39 +1,64 @@
-package main
+package engine
 
 import (
 	"sync"
+	"time"
 
 	"github.com/vmihailenco/msgpack/v5"
 )
 
 // Frame types in the response stream.
 const (
-	frameStdout = "stdout"
-	frameStderr = "stderr"
-	frameLog    = "log"
-	frameExit   = "exit"
+	FrameStdout    = "stdout"
+	FrameStderr    = "stderr"
+	FrameLog       = "log"
+	FrameExit      = "exit"
+	FrameJobs      = "jobs"
+	FrameStats     = "stats"
+	FrameWhy       = "why"
+	FrameWhyQueued = "why-queued"
 )
 
 // Verb names in the request.
 const (
-	verbRun = "run"
+	VerbDisable = "disable"
+	VerbEnable  = "enable"
+	VerbJobs    = "jobs"
+	VerbPause   = "pause"
+	VerbResume  = "resume"
+	VerbRun     = "run"
+	VerbStats   = "stats"
+	VerbWhy     = "why"
 )
 
 // Request is sent once by the client at the start of a connection.
 type Request struct {
-	Verb  string `msgpack:"verb"`
-	Job   string `msgpack:"job"`
-	Force bool   `msgpack:"force,omitempty"`
+	Verb     string `msgpack:"verb"`
+	Job      string `msgpack:"job"`
+	Force    bool   `msgpack:"force,omitempty"`
+	NoJitter bool   `msgpack:"no_jitter,omitempty"`
+
+	// Environment variables that override the job's for this run.
+	Env map[string]string `msgpack:"env,omitempty"`
+
+	// The queue for "pause" and "resume".
+	Queue string `msgpack:"queue,omitempty"`
+
+	// When a job disabled with "disable" is enabled again or zero for "until enabled".
+	Until time.Time `msgpack:"until,omitempty"`
 }
 
 // Frame is one element of the response stream. Exactly one payload field is
 // populated per frame, determined by Type.
 type Frame struct {
-	Type  string `msgpack:"type"`
-	Data  []byte `msgpack:"data,omitempty"`
-	Msg   string `msgpack:"msg,omitempty"`
-	Code  int    `msgpack:"code,omitempty"`
-	Error string `msgpack:"error,omitempty"`
+	Type      string          `msgpack:"type"`
+	Data      []byte          `msgpack:"data,omitempty"`
+	Msg       string          `msgpack:"msg,omitempty"`
+	Code      int             `msgpack:"code,omitempty"`
+	Error     string          `msgpack:"error,omitempty"`
+	Jobs      []JobInfo       `msgpack:"jobs,omitempty"`
+	Scheduler *SchedulerStats `msgpack:"scheduler,omitempty"`
+	Decision  *Decision       `msgpack:"decision,omitempty"`
 }
 
 // frameSender serializes access to a shared msgpack encoder so the runner's
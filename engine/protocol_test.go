@@ -1,15 +1,18 @@
-package main
+package engine
 
 import (
 	"bytes"
 	"sync"
 	"testing"
+	"time"
 
+	"dbohdan.com/denv"
+	"github.com/google/go-cmp/cmp"
 	"github.com/vmihailenco/msgpack/v5"
 )
 
 func TestRequestRoundTrip(t *testing.T) {
-	in := Request{Verb: verbRun, Job: "backup", Force: true}
+	in := Request{Verb: VerbRun, Job: "backup", Force: true, NoJitter: true, Env: map[string]string{"DRY_RUN": "1"}}
 
 	encoded, err := msgpack.Marshal(in)
 	if err != nil {
@@ -21,17 +24,17 @@ func TestRequestRoundTrip(t *testing.T) {
 		t.Fatalf("unmarshal: %v", err)
 	}
 
-	if out != in {
-		t.Errorf("round trip mismatch: got %+v, want %+v", out, in)
+	if diff := cmp.Diff(in, out); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
 	}
 }
 
 func TestFrameRoundTripVariants(t *testing.T) {
 	cases := []Frame{
-		{Type: frameStdout, Data: []byte("hello\nworld\x00\xff")},
-		{Type: frameStderr, Data: []byte{}},
-		{Type: frameLog, Msg: "Started"},
-		{Type: frameExit, Code: 7, Error: "boom"},
+		{Type: FrameStdout, Data: []byte("hello\nworld\x00\xff")},
+		{Type: FrameStderr, Data: []byte{}},
+		{Type: FrameLog, Msg: "Started"},
+		{Type: FrameExit, Code: 7, Error: "boom"},
 	}
 
 	for _, in := range cases {
@@ -58,8 +61,8 @@ func TestFrameWriterStreamsAndSerializes(t *testing.T) {
 	var buf bytes.Buffer
 	enc := msgpack.NewEncoder(&buf)
 	sender := newFrameSender(enc)
-	stdout := newFrameWriter(sender, frameStdout)
-	stderr := newFrameWriter(sender, frameStderr)
+	stdout := newFrameWriter(sender, FrameStdout)
+	stderr := newFrameWriter(sender, FrameStderr)
 
 	// Concurrent writes mimic exec.Cmd potentially writing to both streams.
 	var wg sync.WaitGroup
@@ -89,7 +92,7 @@ func TestFrameWriterStreamsAndSerializes(t *testing.T) {
 		if err := dec.Decode(&f); err != nil {
 			break
 		}
-		if f.Type != frameStdout && f.Type != frameStderr {
+		if f.Type != FrameStdout && f.Type != FrameStderr {
 			t.Errorf("unexpected frame type %q", f.Type)
 		}
 		if len(f.Data) != 1 {
@@ -101,3 +104,34 @@ func TestFrameWriterStreamsAndSerializes(t *testing.T) {
 		t.Errorf("expected 100 frames, got %d", got)
 	}
 }
+
+func TestJobsFrameRoundTrip(t *testing.T) {
+	in := Frame{
+		Type: FrameJobs,
+		Jobs: []JobInfo{
+			{
+				Name:    "backup",
+				Enable:  true,
+				Env:     denv.Env{"FOO": "bar"},
+				Jitter:  5 * time.Second,
+				Queue:   "backup",
+				Running: true,
+				Pending: 2,
+			},
+		},
+	}
+
+	encoded, err := msgpack.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var out Frame
+	if err := msgpack.Unmarshal(encoded, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if !cmp.Equal(out, in) {
+		t.Errorf("round trip mismatch: %v", cmp.Diff(in, out))
+	}
+}
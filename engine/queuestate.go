@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// queuedJob is a job left in a queue when the daemon stopped.
+type queuedJob struct {
+	JobName string
+	StartAt time.Time
+	// How many times the run has been retried.
+	Retried int
+}
+
+// replaceQueuedJobs replaces the saved queue contents.
+func (c *AppDB) replaceQueuedJobs(jobs []queuedJob) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM queued_jobs`); err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		_, err := tx.Exec(`
+			INSERT INTO queued_jobs (
+				job_name,
+				start_at,
+				retried
+			) VALUES (?, ?, ?)`,
+			job.JobName,
+			job.StartAt,
+			job.Retried,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queuedJobs returns the saved queue contents in the order they were queued.
+func (c *AppDB) queuedJobs() ([]queuedJob, error) {
+	rows, err := c.db.Query(`
+		SELECT job_name, start_at, retried
+		FROM queued_jobs
+		ORDER BY id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []queuedJob{}
+	for rows.Next() {
+		var job queuedJob
+		if err := rows.Scan(&job.JobName, &job.StartAt, &job.Retried); err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// SaveQueues saves the jobs in the queues to the database so RestoreQueues can add them back after a restart.
+// A running job is saved too because it won't finish.
+func (r Runner) SaveQueues() error {
+	jobs := []queuedJob{}
+
+	for _, queueName := range r.queueNames() {
+		r.mu.Lock()
+		for _, job := range r.queues[queueName].jobs {
+			jobs = append(jobs, queuedJob{JobName: job.Name, StartAt: job.startAt, Retried: job.retried})
+		}
+		r.mu.Unlock()
+	}
+
+	if err := r.db.replaceQueuedJobs(jobs); err != nil {
+		return fmt.Errorf("failed to save queues: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreQueues adds the jobs saved by SaveQueues back to the queues and clears the saved queues.
+// It looks up the current config of each job and skips jobs that no longer exist.
+// It returns the names of the restored jobs.
+func (r Runner) RestoreQueues(lookup func(name string) (JobConfig, bool)) ([]string, error) {
+	saved, err := r.db.queuedJobs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved queues: %w", err)
+	}
+
+	restored := []string{}
+	for _, queued := range saved {
+		job, ok := lookup(queued.JobName)
+		if !ok {
+			LogJobPrintf(queued.JobName, "Not restoring queued job because it no longer exists")
+			continue
+		}
+
+		job.startAt = queued.StartAt
+		job.retried = queued.Retried
+		r.AddJob(job)
+
+		restored = append(restored, job.Name)
+	}
+
+	if err := r.db.replaceQueuedJobs(nil); err != nil {
+		return restored, fmt.Errorf("failed to clear saved queues: %w", err)
+	}
+
+	return restored, nil
+}
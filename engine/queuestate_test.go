@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"slices"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestRunnerSaveRestoreQueues(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	startAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	runner.AddJob(JobConfig{Name: "backup", Queue: "disk", Env: denv.OS()})
+	runner.AddJob(JobConfig{Name: "scrub", Queue: "disk", Env: denv.OS(), startAt: startAt, retried: 2})
+	runner.AddJob(JobConfig{Name: "deleted", Env: denv.OS()})
+
+	if err := runner.SaveQueues(); err != nil {
+		t.Fatalf("SaveQueues() error = %v", err)
+	}
+
+	// A new daemon starts with empty queues and the current config.
+	restarted, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	jobs := map[string]JobConfig{
+		"backup": {Name: "backup", Queue: "disk", Env: denv.OS()},
+		"scrub":  {Name: "scrub", Queue: "disk", Env: denv.OS()},
+	}
+	lookup := func(name string) (JobConfig, bool) {
+		job, ok := jobs[name]
+		return job, ok
+	}
+
+	restored, err := restarted.RestoreQueues(lookup)
+	if err != nil {
+		t.Fatalf("RestoreQueues() error = %v", err)
+	}
+
+	if expected := []string{"backup", "scrub"}; !slices.Equal(restored, expected) {
+		t.Errorf("Expected restored jobs %v, got %v", expected, restored)
+	}
+
+	queue := restarted.queues["disk"]
+	if len(queue.jobs) != 2 || queue.jobs[0].Name != "backup" || queue.jobs[1].Name != "scrub" {
+		t.Fatalf("Unexpected restored queue: %+v", queue.jobs)
+	}
+	if !queue.jobs[1].startAt.Equal(startAt) {
+		t.Errorf("Expected start time %v, got %v", startAt, queue.jobs[1].startAt)
+	}
+	if queue.jobs[1].retried != 2 {
+		t.Errorf("Expected the restored job to be retry 2, got %d", queue.jobs[1].retried)
+	}
+
+	// The saved queues are only restored once.
+	restored, err = restarted.RestoreQueues(lookup)
+	if err != nil {
+		t.Fatalf("RestoreQueues() error = %v", err)
+	}
+	if len(restored) != 0 {
+		t.Errorf("Expected nothing restored the second time, got %v", restored)
+	}
+}
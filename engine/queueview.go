@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"slices"
+	"time"
+)
+
+// queueView is a queue as the dashboard shows it.
+type queueView struct {
+	Queue  string
+	Paused bool
+	Jobs   []queueViewJob
+}
+
+// queueViewJob is a running or a pending job in a queue.
+type queueViewJob struct {
+	Job     string
+	Running bool
+	// When the running job started.
+	Started time.Time
+	// When a pending job should start if every job before it takes its mean run time.
+	// A job that has never run counts as instant.
+	EstimatedStart time.Time
+	// The mean run time of the recent runs or zero without history.
+	MeanRuntime time.Duration
+}
+
+// meanRuntime returns the mean run time of the runs that weren't skipped.
+func meanRuntime(history []CompletedJob) time.Duration {
+	var total time.Duration
+	runs := 0
+
+	for _, completed := range history {
+		if completed.Skipped {
+			continue
+		}
+
+		total += max(completed.Finished.Sub(completed.Started), 0)
+		runs++
+	}
+
+	if runs == 0 {
+		return 0
+	}
+
+	return total / time.Duration(runs)
+}
+
+// queueViews lists the queues with their jobs in order and estimates when the pending jobs start.
+// The estimates use the recent history of each job.
+// A job waits for its start time, for example, when it is retried.
+// Empty queues are left out unless they are paused.
+// The result is sorted by queue name.
+func (r Runner) queueViews(now time.Time) ([]queueView, error) {
+	type snapshot struct {
+		name  string
+		queue jobQueue
+	}
+
+	paused := r.pausedQueues()
+
+	r.mu.Lock()
+	names := make([]string, 0, len(r.queues)+len(paused))
+	for name := range r.queues {
+		names = append(names, name)
+	}
+	for name := range paused {
+		if _, ok := r.queues[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	snapshots := []snapshot{}
+	for _, name := range names {
+		queue, ok := r.queues[name]
+		if !ok {
+			queue = newJobQueue()
+		}
+
+		// Adding and dropping jobs can reuse the slice.
+		queue.jobs = slices.Clone(queue.jobs)
+
+		snapshots = append(snapshots, snapshot{name: name, queue: queue})
+	}
+	r.mu.Unlock()
+
+	views := []queueView{}
+	for _, s := range snapshots {
+		_, isPaused := paused[s.name]
+		if len(s.queue.jobs) == 0 && !isPaused {
+			continue
+		}
+
+		view := queueView{Queue: s.name, Paused: isPaused, Jobs: []queueViewJob{}}
+
+		next := now
+		for i, job := range s.queue.jobs {
+			history, err := r.History(job.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			queued := queueViewJob{Job: job.Name, MeanRuntime: meanRuntime(history)}
+
+			if i == 0 && s.queue.activeJob {
+				queued.Running = true
+				queued.Started = s.queue.activeSince
+				next = maxTime(next, queued.Started.Add(queued.MeanRuntime))
+			} else {
+				queued.EstimatedStart = maxTime(next, job.startAt)
+				next = queued.EstimatedStart.Add(queued.MeanRuntime)
+			}
+
+			view.Jobs = append(view.Jobs, queued)
+		}
+
+		views = append(views, view)
+	}
+
+	return views, nil
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// enforceQuota removes the oldest files in the state directory of a job until their total size is within the quota.
+// The job lock, the result file, and the artifacts, which are pruned separately, aren't counted or removed.
+// It returns the paths of the removed files.
+func enforceQuota(dir string, quota int64) ([]string, error) {
+	type file struct {
+		path     string
+		size     int64
+		modified time.Time
+	}
+
+	files := []file{}
+	var total int64
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if filepath.Dir(path) == filepath.Clean(dir) {
+			switch entry.Name() {
+
+			case artifactsDirName:
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+
+			case jobLockFileName, resultFileName:
+				return nil
+			}
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, file{path: path, size: info.Size(), modified: info.ModTime()})
+		total += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(files, func(a, b file) int {
+		return a.modified.Compare(b.modified)
+	})
+
+	removed := []string{}
+	for _, f := range files {
+		if total <= quota {
+			break
+		}
+
+		if err := os.Remove(f.path); err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, f.path)
+		total -= f.size
+	}
+
+	return removed, nil
+}
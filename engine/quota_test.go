@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnforceQuota(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	files := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"oldest.log", 100, 3 * time.Hour},
+		{"sub/older.log", 100, 2 * time.Hour},
+		{"newer.log", 100, time.Hour},
+		{"newest.log", 100, 0},
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", f.size)), filePerms); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := enforceQuota(dir, 250)
+	if err != nil {
+		t.Fatalf("enforceQuota failed: %v", err)
+	}
+
+	expected := []string{filepath.Join(dir, "oldest.log"), filepath.Join(dir, "sub/older.log")}
+	if !slices.Equal(removed, expected) {
+		t.Errorf("Expected %v removed, got %v", expected, removed)
+	}
+
+	for _, name := range []string{"newer.log", "newest.log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to remain: %v", name, err)
+		}
+	}
+
+	removed, err = enforceQuota(dir, 250)
+	if err != nil {
+		t.Fatalf("enforceQuota failed: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected nothing removed within quota, got %v", removed)
+	}
+}
+
+func TestEnforceQuotaMissingDir(t *testing.T) {
+	removed, err := enforceQuota(filepath.Join(t.TempDir(), "missing"), 1)
+	if err != nil {
+		t.Fatalf("enforceQuota failed: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected nothing removed, got %v", removed)
+	}
+}
+
+func TestEnforceQuotaKeepsJobFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+
+	kept := []string{
+		jobLockFileName,
+		resultFileName,
+		filepath.Join(artifactsDirName, "1", "report.html"),
+	}
+	for _, name := range append(kept, "stdout.log") {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", 100)), filePerms); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the log counts toward the quota.
+	removed, err := enforceQuota(dir, 100)
+	if err != nil {
+		t.Fatalf("enforceQuota failed: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected nothing removed, got %v", removed)
+	}
+
+	removed, err = enforceQuota(dir, 0)
+	if err != nil {
+		t.Fatalf("enforceQuota failed: %v", err)
+	}
+	if expected := []string{filepath.Join(dir, "stdout.log")}; !slices.Equal(removed, expected) {
+		t.Errorf("Expected %v removed, got %v", expected, removed)
+	}
+
+	for _, name := range kept {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to remain: %v", name, err)
+		}
+	}
+}
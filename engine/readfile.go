@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.starlark.net/starlark"
+)
+
+// The thread-local key of the files that "read_file" reads while a job file loads.
+const readFilesKey = "regular.read_files"
+
+// newReadFile returns the "read_file" builtin for a job directory.
+// It returns the contents of a file as a string.
+// A relative path is in the job directory.
+// The files read while loading a job are recorded, so the job is reloaded when they change.
+func newReadFile(jobDir string) *starlark.Builtin {
+	return starlark.NewBuiltin(readFileVar, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var path string
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &path); err != nil {
+			return nil, err
+		}
+
+		path = filepath.Clean(expandHome(path))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(jobDir, path)
+		}
+
+		if reads, ok := thread.Local(readFilesKey).(*[]string); ok {
+			*reads = append(*reads, path)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		return starlark.String(data), nil
+	})
+}
+
+// hashFiles hashes the paths and the contents of files.
+// A missing file hashes differently from an empty one.
+func hashFiles(paths []string) [sha256.Size]byte {
+	h := sha256.New()
+
+	for _, path := range paths {
+		h.Write([]byte(path))
+		h.Write([]byte{0})
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			h.Write([]byte{1})
+			continue
+		}
+
+		h.Write([]byte{0})
+		h.Write(data)
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+
+	return sum
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadFileJSON(t *testing.T) {
+	configRoot := t.TempDir()
+
+	path := writeTestJob(t, configRoot, "check", `
+_config = json.decode(read_file("sites.json"))
+for _site in _config["sites"]:
+    job(name = _site, command = ["curl", "-f", "https://" + _site])
+`)
+	sitesPath := filepath.Join(configRoot, "check", "sites.json")
+	if err := os.WriteFile(sitesPath, []byte(`{"sites": ["example.com"]}`), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	jsc := NewScheduler()
+	res, _, err := jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsAddedNew {
+		t.Errorf(`Expected "jobsAddedNew", got %v`, res)
+	}
+	if names := jobNames(jsc); !slices.Equal(names, []string{"check:example.com"}) {
+		t.Errorf("Expected check:example.com, got %v", names)
+	}
+	if readers := jsc.readersOf(sitesPath); !slices.Equal(readers, []string{"check"}) {
+		t.Errorf("Expected check to read the JSON file, got %v", readers)
+	}
+
+	res, _, err = jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsNoChanges {
+		t.Errorf(`Expected "jobsNoChanges", got %v`, res)
+	}
+
+	// A change to the file reloads the job file.
+	if err := os.WriteFile(sitesPath, []byte(`{"sites": ["example.com", "example.net"]}`), filePerms); err != nil {
+		t.Fatal(err)
+	}
+	res, _, err = jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsUpdated {
+		t.Errorf(`Expected "jobsUpdated", got %v`, res)
+	}
+	if names := jobNames(jsc); !slices.Equal(names, []string{"check:example.com", "check:example.net"}) {
+		t.Errorf("Expected two jobs, got %v", names)
+	}
+
+	if err := os.Remove(sitesPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := jsc.Update(configRoot, path); err == nil || !strings.Contains(err.Error(), "read_file") {
+		t.Errorf("Expected a read_file error, got %v", err)
+	}
+}
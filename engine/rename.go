@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RenameJob renames a job directory and moves the state directory and the history of the job to the new name.
+// The database changes are only committed when both directories have been renamed.
+func RenameJob(db *AppDB, config Config, oldName, newName string) error {
+	if err := validateJobName(newName); err != nil {
+		return err
+	}
+
+	// Rename the job in the config directory it is loaded from.
+	file, _ := findJobFile(config.ConfigRoots(), oldName)
+
+	oldConfigDir := filepath.Join(file.root, oldName)
+	newConfigDir := filepath.Join(file.root, newName)
+	oldStateDir := filepath.Join(config.StateRoot, oldName)
+	newStateDir := filepath.Join(config.StateRoot, newName)
+
+	if _, err := os.Stat(filepath.Join(oldConfigDir, jobConfigFileName)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no job directory for %q (crontab jobs are renamed by editing the crontab)", oldName)
+		}
+
+		return fmt.Errorf("failed to find job %q: %w", oldName, err)
+	}
+
+	for _, dir := range []string{newConfigDir, newStateDir} {
+		if _, err := os.Stat(dir); err == nil {
+			return fmt.Errorf("%q already exists", dir)
+		}
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, table := range []string{"completed_jobs", "failure_streaks", "job_events", "pending_notifications", "queued_jobs", "snoozed_jobs"} {
+		if _, err := tx.Exec(`UPDATE `+table+` SET job_name = ? WHERE job_name = ?`, newName, oldName); err != nil {
+			return fmt.Errorf("failed to rename job in table %q: %w", table, err)
+		}
+	}
+
+	if err := os.Rename(oldConfigDir, newConfigDir); err != nil {
+		return fmt.Errorf("failed to rename job directory: %w", err)
+	}
+
+	err = os.Rename(oldStateDir, newStateDir)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Join(
+			fmt.Errorf("failed to rename state directory: %w", err),
+			os.Rename(newConfigDir, oldConfigDir),
+		)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Join(
+			fmt.Errorf("failed to rename job in database: %w", err),
+			os.Rename(newConfigDir, oldConfigDir),
+			os.Rename(newStateDir, oldStateDir),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenameJob(t *testing.T) {
+	config := Config{ConfigRoot: t.TempDir(), StateRoot: t.TempDir()}
+
+	db, err := OpenAppDB(config.StateRoot)
+	if err != nil {
+		t.Fatalf("Failed to open app database: %v", err)
+	}
+	defer db.Close()
+
+	writeTestJob(t, config.ConfigRoot, "old", "command = [\"true\"]\n")
+	writeTestJob(t, config.ConfigRoot, "other", "command = [\"true\"]\n")
+
+	if err := os.MkdirAll(filepath.Join(config.StateRoot, "old"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if _, err := db.saveCompletedJob("old", CompletedJob{Started: now, Finished: now}, nil); err != nil {
+		t.Fatalf("saveCompletedJob() error = %v", err)
+	}
+
+	for _, newName := range []string{"", "..", "a/b", "other"} {
+		if err := RenameJob(db, config, "old", newName); err == nil {
+			t.Errorf("Expected an error renaming to %q", newName)
+		}
+	}
+
+	if err := RenameJob(db, config, "missing", "new"); err == nil {
+		t.Error("Expected an error renaming a missing job")
+	}
+
+	if err := RenameJob(db, config, "old", "new"); err != nil {
+		t.Fatalf("RenameJob() error = %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(config.ConfigRoot, "new", jobConfigFileName),
+		filepath.Join(config.StateRoot, "new"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to exist: %v", path, err)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(config.ConfigRoot, "old"),
+		filepath.Join(config.StateRoot, "old"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone: %v", path, err)
+		}
+	}
+
+	completed, err := db.LastCompleted("new")
+	if err != nil || completed == nil {
+		t.Errorf("Expected the history under the new name, got %v, %v", completed, err)
+	}
+
+	completed, err = db.LastCompleted("old")
+	if err != nil || completed != nil {
+		t.Errorf("Expected no history under the old name, got %v, %v", completed, err)
+	}
+}
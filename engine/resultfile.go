@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// resultFile is the contents of the result file of a job.
+type resultFile struct {
+	Job        string    `json:"job"`
+	ID         int64     `json:"id"`
+	Success    bool      `json:"success"`
+	ExitStatus int       `json:"exit_status"`
+	Error      string    `json:"error"`
+	Signal     int       `json:"signal"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Duration   float64   `json:"duration"`
+	Hostname   string    `json:"hostname"`
+	Username   string    `json:"username"`
+	Command    []string  `json:"command"`
+	Attempt    int       `json:"attempt"`
+	Dead       bool      `json:"dead"`
+}
+
+// ResultFilePath returns the path of the result file of a job.
+func ResultFilePath(stateRoot, jobName string) string {
+	return filepath.Join(stateRoot, jobName, resultFileName)
+}
+
+// writeResultFile replaces the result file of a job with a completed run.
+// It writes a temporary file and renames it, so readers never see a partial file.
+func writeResultFile(stateRoot, jobName string, completed CompletedJob) error {
+	data, err := json.MarshalIndent(resultFile{
+		Job:        jobName,
+		ID:         completed.ID,
+		Success:    completed.IsSuccess(),
+		ExitStatus: completed.ExitStatus,
+		Error:      completed.Error,
+		Signal:     completed.Signal,
+		Started:    completed.Started,
+		Finished:   completed.Finished,
+		Duration:   completed.Finished.Sub(completed.Started).Seconds(),
+		Hostname:   completed.Hostname,
+		Username:   completed.Username,
+		Command:    completed.Command,
+		Attempt:    completed.Attempt,
+		Dead:       completed.Dead,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	path := ResultFilePath(stateRoot, jobName)
+	if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+		return fmt.Errorf("failed to create job state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+resultFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary result file: %w", err)
+	}
+
+	_, err = tmp.Write(append(data, '\n'))
+	if err = errors.Join(err, tmp.Close()); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write temporary result file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace result file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestJobRunnerResultFile(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	for _, script := range []string{"exit 0", "exit 3"} {
+		runner.AddJob(JobConfig{
+			Name:       "checked",
+			Command:    []string{"sh", "-c", script},
+			Env:        denv.OS(),
+			ResultFile: true,
+		})
+		_ = runner.RunQueueHead("checked")
+	}
+
+	data, err := os.ReadFile(ResultFilePath(tmpDir, "checked"))
+	if err != nil {
+		t.Fatalf("Failed to read result file: %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to decode result file: %v", err)
+	}
+
+	// The file has the last run.
+	last, err := db.LastCompleted("checked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["job"] != "checked" || result["success"] != false || result["exit_status"] != 3.0 || result["id"] != float64(last.ID) {
+		t.Errorf("Unexpected result file: %s", data)
+	}
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Join(tmpDir, "checked"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != resultFileName {
+			t.Errorf("Unexpected file in state directory: %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// triggerRunNow queues the job of a "run-now" file once and removes the file.
+// Only the caller that removes the file queues the job, so several events for one file queue it once.
+func (jsc *Scheduler) triggerRunNow(runner Runner, path string) {
+	jobName := jobNameFromPath(path)
+
+	if err := os.Remove(path); err != nil {
+		if !os.IsNotExist(err) {
+			LogJobPrintf(jobName, "Failed to remove run-now file: %v", err)
+		}
+
+		return
+	}
+
+	job, ok := jsc.Job(jobName)
+	if !ok {
+		log.Printf("Ignored run-now file for unknown job %q", jobName)
+		return
+	}
+
+	snoozed, err := runner.snoozed(job.Name, runner.Clock.Now())
+	switch {
+
+	case err != nil:
+		LogJobPrintf(jobName, "Ignored run-now file because checking the snooze failed: %v", err)
+
+	case !job.Enable:
+		LogJobPrintf(jobName, "Ignored run-now file because the job is disabled")
+
+	case !job.RunsOnThisHost():
+		LogJobPrintf(jobName, "Ignored run-now file because the job doesn't run on this host")
+
+	case snoozed:
+		LogJobPrintf(jobName, "Ignored run-now file because the job is disabled with \"regular disable\"")
+
+	default:
+		LogJobPrintf(jobName, "Triggered by run-now file")
+		runner.AddJob(job)
+	}
+}
+
+// TriggerRunNowFiles queues the loaded jobs that have a "run-now" file in any config root.
+// It picks up the files created while the daemon wasn't running.
+func (jsc *Scheduler) TriggerRunNowFiles(runner Runner, configRoots []string) {
+	for _, job := range jsc.Jobs() {
+		for _, configRoot := range configRoots {
+			path := filepath.Join(configRoot, job.Name, runNowFileName)
+			if _, err := os.Stat(path); err == nil {
+				jsc.triggerRunNow(runner, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTriggerRunNowFiles(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+	configRoot := filepath.Join(tmpDir, "config")
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	jsc := NewScheduler()
+	jsc.byName["triggered"] = JobConfig{Name: "triggered", Enable: true}
+	jsc.byName["disabled"] = JobConfig{Name: "disabled"}
+	jsc.byName["plain"] = JobConfig{Name: "plain", Enable: true}
+	jsc.byName["snoozed"] = JobConfig{Name: "snoozed", Enable: true}
+	runner.rememberSnooze(Snooze{JobName: "snoozed", Since: time.Now()})
+
+	for _, name := range []string{"triggered", "disabled", "plain", "snoozed"} {
+		if err := os.MkdirAll(filepath.Join(configRoot, name), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"triggered", "disabled", "snoozed"} {
+		if err := os.WriteFile(filepath.Join(configRoot, name, runNowFileName), nil, filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jsc.TriggerRunNowFiles(runner, []string{configRoot})
+
+	tests := []struct {
+		name   string
+		queued int
+	}{
+		{"triggered", 1},
+		{"disabled", 0},
+		{"snoozed", 0},
+		{"plain", 0},
+	}
+
+	for _, tt := range tests {
+		if n := runner.queueLen(tt.name); n != tt.queued {
+			t.Errorf("Expected %d runs of %s in queue, got %d", tt.queued, tt.name, n)
+		}
+
+		if _, err := os.Stat(filepath.Join(configRoot, tt.name, runNowFileName)); !os.IsNotExist(err) {
+			t.Errorf("Expected no run-now file for %s, got %v", tt.name, err)
+		}
+	}
+
+	// The job is only queued once per file.
+	jsc.TriggerRunNowFiles(runner, []string{configRoot})
+	if n := runner.queueLen("triggered"); n != 1 {
+		t.Errorf("Expected 1 run in queue, got %d", n)
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"log"
+	"time"
+)
+
+// SchedulerStats describes how well the scheduler keeps up with the clock.
+type SchedulerStats struct {
+	// When the last tick fired.
+	LastTick time.Time `msgpack:"last_tick"`
+	// How late the last tick fired compared to the schedule interval and the most since start.
+	Lag    time.Duration `msgpack:"lag"`
+	MaxLag time.Duration `msgpack:"max_lag"`
+	// How long checking which jobs are due took on the last tick and the most since start.
+	CheckTime    time.Duration `msgpack:"check_time"`
+	MaxCheckTime time.Duration `msgpack:"max_check_time"`
+	// The number of ticks since start.
+	Ticks int64 `msgpack:"ticks"`
+}
+
+// recordTick updates the stats after a tick.
+// It logs a warning when the scheduler falls behind.
+func (jsc *Scheduler) recordTick(tick time.Time, lag, checkTime time.Duration) {
+	if lag < 0 {
+		lag = 0
+	}
+
+	jsc.statsMu.Lock()
+	jsc.stats.LastTick = tick
+	jsc.stats.Lag = lag
+	jsc.stats.MaxLag = max(jsc.stats.MaxLag, lag)
+	jsc.stats.CheckTime = checkTime
+	jsc.stats.MaxCheckTime = max(jsc.stats.MaxCheckTime, checkTime)
+	jsc.stats.Ticks++
+	jsc.statsMu.Unlock()
+
+	if lag > schedulerLagWarning {
+		log.Printf("Warning: scheduler tick was %v late", FormatDuration(lag))
+	}
+
+	if checkTime > schedulerLagWarning {
+		log.Printf("Warning: checking which jobs are due took %v", FormatDuration(checkTime))
+	}
+}
+
+// Stats returns the scheduler stats.
+func (jsc *Scheduler) Stats() SchedulerStats {
+	jsc.statsMu.Lock()
+	defer jsc.statsMu.Unlock()
+
+	return jsc.stats
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// schedule is a value of "schedule" in a job file other than a crontab schedule string.
+type schedule interface {
+	starlark.Value
+
+	// shouldRun decides whether the job is due from the "should_run" arguments.
+	shouldRun(args map[string]int64) bool
+	// summary describes the schedule in one line.
+	summary() string
+}
+
+// everySchedule is the value of "every(interval)".
+// The job runs when the interval has passed since the last run finished.
+type everySchedule struct {
+	interval time.Duration
+}
+
+var _ schedule = everySchedule{}
+
+func (s everySchedule) String() string {
+	return fmt.Sprintf("%s(%d)", everyVar, int64(s.interval.Seconds()))
+}
+
+func (s everySchedule) Type() string {
+	return everyVar
+}
+
+func (s everySchedule) Freeze() {}
+
+func (s everySchedule) Truth() starlark.Bool {
+	return starlark.True
+}
+
+func (s everySchedule) Hash() (uint32, error) {
+	return starlark.String(s.String()).Hash()
+}
+
+func (s everySchedule) shouldRun(args map[string]int64) bool {
+	if args["finished"] < 0 {
+		return true
+	}
+
+	return time.Duration(args["timestamp"]-args["finished"])*time.Second >= s.interval
+}
+
+func (s everySchedule) summary() string {
+	return everyVar + " " + FormatDuration(s.interval)
+}
+
+// every is the "every" builtin.
+// It takes the interval in seconds.
+func every(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var seconds int
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &seconds); err != nil {
+		return nil, err
+	}
+
+	if seconds <= 0 {
+		return nil, fmt.Errorf("%s: interval must be positive", b.Name())
+	}
+
+	return everySchedule{interval: time.Duration(seconds) * time.Second}, nil
+}
+
+// dailyAtSchedule is the value of "daily_at(time, timezone)".
+// The job runs once per civil day in the time zone at or after the time.
+type dailyAtSchedule struct {
+	hour     int
+	minute   int
+	location *time.Location
+}
+
+var _ schedule = dailyAtSchedule{}
+
+func (s dailyAtSchedule) String() string {
+	return fmt.Sprintf("%s(%q, timezone=%q)", dailyAtVar, s.clock(), s.location)
+}
+
+func (s dailyAtSchedule) Type() string {
+	return dailyAtVar
+}
+
+func (s dailyAtSchedule) Freeze() {}
+
+func (s dailyAtSchedule) Truth() starlark.Bool {
+	return starlark.True
+}
+
+func (s dailyAtSchedule) Hash() (uint32, error) {
+	return starlark.String(s.String()).Hash()
+}
+
+func (s dailyAtSchedule) clock() string {
+	return fmt.Sprintf("%02d:%02d", s.hour, s.minute)
+}
+
+// target returns the run time on the day of a time.
+// When a DST change skips the time, the run time is that much later.
+// When the time repeats, time.Date picks one of the two instants.
+// Either way, there is one run time per day.
+func (s dailyAtSchedule) target(t time.Time) time.Time {
+	t = t.In(s.location)
+	target := time.Date(t.Year(), t.Month(), t.Day(), s.hour, s.minute, 0, 0, s.location)
+
+	if target.Hour() != s.hour || target.Minute() != s.minute {
+		_, offset := target.Zone()
+		_, laterOffset := target.Add(dstCheckWindow).Zone()
+
+		target = target.Add(time.Duration(laterOffset-offset) * time.Second)
+	}
+
+	return target
+}
+
+func (s dailyAtSchedule) shouldRun(args map[string]int64) bool {
+	target := s.target(time.Unix(args["timestamp"], 0))
+
+	if time.Unix(args["timestamp"], 0).Before(target) {
+		return false
+	}
+
+	// The job hasn't run since today's run time.
+	return args["started"] < 0 || time.Unix(args["started"], 0).Before(target)
+}
+
+func (s dailyAtSchedule) summary() string {
+	return fmt.Sprintf("daily at %s %s", s.clock(), s.location)
+}
+
+// dailyAt is the "daily_at" builtin.
+// It takes a time like "03:30" and an optional IANA time zone name.
+// The default time zone is the local one.
+func dailyAt(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var clock, timezone string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "time", &clock, "timezone?", &timezone); err != nil {
+		return nil, err
+	}
+
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return nil, fmt.Errorf("%s: time must be in the format HH:MM: %q", b.Name(), clock)
+	}
+
+	location := time.Local
+	if timezone != "" {
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+	}
+
+	return dailyAtSchedule{
+		hour:     parsed.Hour(),
+		minute:   parsed.Minute(),
+		location: location,
+	}, nil
+}
+
+// shouldRunBuiltin makes a "should_run" function for a schedule.
+func shouldRunBuiltin(s schedule) *starlark.Builtin {
+	return starlark.NewBuiltin(shouldRunVar, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		values := map[string]int64{}
+
+		for _, kv := range kwargs {
+			key, ok := kv[0].(starlark.String)
+			if !ok {
+				continue
+			}
+
+			var n int64
+			if err := starlark.AsInt(kv[1], &n); err != nil {
+				continue
+			}
+
+			values[key.GoString()] = n
+		}
+
+		for _, key := range []string{"timestamp", "started", "finished"} {
+			if _, ok := values[key]; !ok {
+				return starlark.None, fmt.Errorf("%s: missing argument %q", b.Name(), key)
+			}
+		}
+
+		return starlark.Bool(s.shouldRun(values)), nil
+	})
+}
+
+// applySchedule sets "should_run" from the value of "schedule":
+// a crontab schedule like "0 3 * * *", "every(interval)", or "daily_at(time)".
+func applySchedule(job *JobConfig, value starlark.Value) error {
+	if job.ShouldRun != nil {
+		return fmt.Errorf("can't have both %q and %q", scheduleVar, shouldRunVar)
+	}
+
+	switch value := value.(type) {
+
+	case starlark.String:
+		sched, err := parseCronSchedule(value.GoString())
+		if err != nil {
+			return fmt.Errorf("bad %q: %w", scheduleVar, err)
+		}
+
+		job.ShouldRun = sched.shouldRunBuiltin()
+		job.scheduleSpec = value.GoString()
+
+	case schedule:
+		job.ShouldRun = shouldRunBuiltin(value)
+		job.scheduleSpec = value.summary()
+
+	default:
+		return fmt.Errorf("%q must be a crontab schedule string, %s(...), or %s(...)", scheduleVar, everyVar, dailyAtVar)
+	}
+
+	return nil
+}
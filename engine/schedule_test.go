@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestLoadJobSchedule(t *testing.T) {
+	start := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		content     string
+		history     []CompletedJob
+		wantSummary string
+		wantNext    time.Time
+		wantErr     bool
+	}{
+		{
+			name:        "crontab",
+			content:     `schedule = "0 3 * * *"`,
+			wantSummary: "0 3 * * *",
+			wantNext:    start.Add(time.Hour),
+		},
+		{
+			name:        "every without history",
+			content:     `schedule = every(one_hour)`,
+			wantSummary: "every 1h",
+			wantNext:    start,
+		},
+		{
+			name:        "every",
+			content:     `schedule = every(6 * one_hour)`,
+			history:     []CompletedJob{{Started: start.Add(-time.Hour), Finished: start.Add(-time.Hour)}},
+			wantSummary: "every 6h",
+			wantNext:    start.Add(5 * time.Hour),
+		},
+		{
+			name:        "daily at",
+			content:     `schedule = daily_at("03:30", timezone="UTC")`,
+			history:     []CompletedJob{{Started: start.Add(-time.Hour), Finished: start.Add(-time.Hour)}},
+			wantSummary: "daily at 03:30 UTC",
+			wantNext:    start.Add(90 * time.Minute),
+		},
+		{
+			name:    "bad daily at time",
+			content: `schedule = daily_at("3.30")`,
+			wantErr: true,
+		},
+		{
+			name:    "bad time zone",
+			content: `schedule = daily_at("03:30", timezone="Mars/Olympus_Mons")`,
+			wantErr: true,
+		},
+		{
+			name:    "bad crontab",
+			content: `schedule = "daily"`,
+			wantErr: true,
+		},
+		{
+			name:    "bad interval",
+			content: `schedule = every(0)`,
+			wantErr: true,
+		},
+		{
+			name:    "bad type",
+			content: `schedule = 3600`,
+			wantErr: true,
+		},
+		{
+			name:    "with should_run",
+			content: "schedule = every(one_hour)\ndef should_run(**_):\n    return True",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobPath := filepath.Join(t.TempDir(), "config.star")
+			if err := os.WriteFile(jobPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := loadJob(denv.Env{}, jobPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := job.ScheduleSummary(); got != tt.wantSummary {
+				t.Errorf("ScheduleSummary() = %q, want %q", got, tt.wantSummary)
+			}
+
+			next, due, err := job.NextDue(start, tt.history, 48*time.Hour)
+			if err != nil {
+				t.Fatalf("NextDue() error = %v", err)
+			}
+
+			if !due || !next.Equal(tt.wantNext) {
+				t.Errorf("NextDue() = %v, %v; want %v", next, due, tt.wantNext)
+			}
+		})
+	}
+}
+
+// Simulate a scheduler that runs the job whenever it is due on the days of DST changes.
+func TestDailyAtDST(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		hour   int
+		minute int
+		from   time.Time
+		want   string
+	}{
+		// 02:30 doesn't exist on 2024-03-10.
+		{"skipped", 2, 30, time.Date(2024, 3, 10, 0, 0, 0, 0, location), "03:30"},
+		// 01:30 happens twice on 2024-11-03.
+		{"repeated", 1, 30, time.Date(2024, 11, 3, 0, 0, 0, 0, location), "01:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := JobConfig{
+				Enable:    true,
+				ShouldRun: shouldRunBuiltin(dailyAtSchedule{hour: tt.hour, minute: tt.minute, location: location}),
+			}
+
+			runs := map[string]int{}
+			var times []time.Time
+			var history []CompletedJob
+			for now := tt.from; now.Before(tt.from.Add(24 * time.Hour)); now = now.Add(time.Minute) {
+				_, due, err := job.shouldRun(now, history)
+				if err != nil {
+					t.Fatalf("shouldRun() error = %v", err)
+				}
+
+				if due {
+					runs[now.In(location).Format(time.DateOnly)]++
+					times = append(times, now)
+					history = []CompletedJob{{Started: now, Finished: now}}
+				}
+			}
+
+			if len(runs) != 1 {
+				t.Errorf("ran on %d days, want 1: %v", len(runs), runs)
+			}
+			for day, n := range runs {
+				if n != 1 {
+					t.Errorf("ran %d times on %s", n, day)
+				}
+			}
+			for _, run := range times {
+				if clock := run.In(location).Format("15:04"); clock != tt.want {
+					t.Errorf("ran at %v, want %s", run, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Stop pinging the watchdog when the scheduler hasn't ticked for this long.
+// The ticker drops ticks while a check is slow, so a few missed minutes mean the loop is stuck.
+const watchdogMaxTickAge = 3 * scheduleInterval
+
+// SdNotify sends a state like "READY=1" to the service manager.
+// It does nothing when the service manager doesn't listen, that is, when NOTIFY_SOCKET isn't set.
+func SdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// A name that starts with "@" is in the abstract namespace, which package net handles.
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to service manager: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to notify service manager: %w", err)
+	}
+
+	return nil
+}
+
+// watchdogInterval returns how often the service manager expects a watchdog ping.
+// It returns false when the watchdog is off or meant for another process.
+func watchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// schedulerHealthy reports whether the scheduler has ticked recently.
+// Before the first tick, it counts from start.
+func schedulerHealthy(stats SchedulerStats, start, now time.Time) bool {
+	last := stats.LastTick
+	if last.IsZero() {
+		last = start
+	}
+
+	return now.Sub(last) < watchdogMaxTickAge
+}
+
+// RunWatchdog pings the watchdog of the service manager while the scheduler keeps ticking.
+// When the scheduler loop hangs, the pings stop and the service manager restarts Regular.
+// It returns at once when the watchdog is off.
+func (jsc *Scheduler) RunWatchdog(start time.Time) {
+	interval, ok := watchdogInterval()
+	if !ok {
+		return
+	}
+
+	// Ping twice per interval, as systemd recommends.
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	warned := false
+	for now := range ticker.C {
+		if !schedulerHealthy(jsc.Stats(), start, now) {
+			if !warned {
+				log.Printf("Warning: scheduler hasn't ticked for %v; stopped pinging watchdog", FormatDuration(watchdogMaxTickAge))
+				warned = true
+			}
+
+			continue
+		}
+		warned = false
+
+		if err := SdNotify("WATCHDOG=1"); err != nil {
+			log.Printf("Watchdog error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSdNotify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	if err := SdNotify("READY=1"); err != nil {
+		t.Fatalf("SdNotify() error = %v", err)
+	}
+
+	buf := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read notification: %v", err)
+	}
+
+	if got := string(buf[:n]); got != "READY=1" {
+		t.Errorf("Expected %q, got %q", "READY=1", got)
+	}
+}
+
+func TestSdNotifyNoSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	if err := SdNotify("READY=1"); err != nil {
+		t.Errorf("Expected no error without a socket, got %v", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	tests := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+		ok   bool
+	}{
+		{"off", "", "", 0, false},
+		{"on", "30000000", "", 30 * time.Second, true},
+		{"this process", "30000000", pid, 30 * time.Second, true},
+		{"other process", "30000000", "1", 0, false},
+		{"invalid", "soon", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+
+			got, ok := watchdogInterval()
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("watchdogInterval() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSchedulerHealthy(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		stats SchedulerStats
+		now   time.Time
+		want  bool
+	}{
+		{"just started", SchedulerStats{}, start.Add(time.Minute), true},
+		{"no tick since start", SchedulerStats{}, start.Add(time.Hour), false},
+		{"recent tick", SchedulerStats{LastTick: start.Add(time.Hour)}, start.Add(time.Hour + time.Minute), true},
+		{"stuck", SchedulerStats{LastTick: start.Add(time.Hour)}, start.Add(2 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedulerHealthy(tt.stats, start, tt.now); got != tt.want {
+				t.Errorf("schedulerHealthy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mna/starstruct"
+	"go.starlark.net/starlark"
+
+	"dbohdan.com/denv"
+	"dbohdan.com/regular/starlarkutil"
+)
+
+// Settings are the global settings from the settings file in the config root.
+type Settings struct {
+	// Path to the age identity file that decrypts encrypted env files.
+	AgeIdentity string `starlark:"age_identity"`
+
+	// How to send email: "smtp" (default) or "sendmail".
+	EmailTransport string `starlark:"email_transport"`
+	// Values for environment variables that jobs get when the environment of Regular lacks them.
+	// They override the built-in defaults for PATH and LANG.
+	// An empty value turns a default off.
+	EnvDefaults denv.Env `starlark:"-"`
+
+	// The sendmail binary for the "sendmail" transport.
+	SendmailPath string `starlark:"sendmail_path"`
+	// How long in seconds the daemon holds failure emails to send the ones that pile up in one message.
+	// 0 sends every email right away.
+	EmailBatch int64 `starlark:"email_batch"`
+
+	// The minimum free space in bytes in the state directory to start a job with logging.
+	// 0 disables the check.
+	MinFreeSpace int64 `starlark:"min_free_space"`
+	// What to do when there is less: "skip-log" (default) or "refuse".
+	LowSpace string `starlark:"low_space"`
+
+	// The minimum time in seconds between the starts of a scheduled job.
+	// It guards against a should_run function that is always true.
+	// 0 disables the guard.
+	MinInterval int64 `starlark:"min_interval"`
+
+	// The number of lines of each log in notifications (the default is 10)
+	// and whether to include stdout (the default is true).
+	// Jobs can override them.
+	NotifyLines  *int  `starlark:"notify_lines"`
+	NotifyStdout *bool `starlark:"notify_stdout"`
+
+	// Remove the state of deleted jobs automatically once a day.
+	AutoGC bool `starlark:"auto_gc"`
+	// How long in seconds to keep the state of a deleted job since it was last active.
+	// 0 means the default of 30 days.
+	GCGrace int64 `starlark:"gc_grace"`
+
+	// Matrix notifications.
+	MatrixHomeserver  string `starlark:"matrix_homeserver"`
+	MatrixAccessToken string `starlark:"matrix_access_token"`
+	MatrixRoomID      string `starlark:"matrix_room_id"`
+
+	// ntfy notifications.
+	// The URL includes the topic, like "https://ntfy.sh/mytopic".
+	NtfyURL   string `starlark:"ntfy_url"`
+	NtfyToken string `starlark:"ntfy_token"`
+
+	// XMPP notifications.
+	// The server is found through DNS unless set as "host:port".
+	XMPPJID       string `starlark:"xmpp_jid"`
+	XMPPPassword  string `starlark:"xmpp_password"`
+	XMPPRecipient string `starlark:"xmpp_recipient"`
+	XMPPServer    string `starlark:"xmpp_server"`
+
+	// MQTT status messages.
+	// "{job}" in the topic is replaced with the job name.
+	MQTTBroker   string `starlark:"mqtt_broker"`
+	MQTTUsername string `starlark:"mqtt_username"`
+	MQTTPassword string `starlark:"mqtt_password"`
+	MQTTTopic    string `starlark:"mqtt_topic"`
+}
+
+// LoadSettings loads the settings file in the config root.
+// A missing settings file gives the default settings.
+func LoadSettings(configRoot string) (Settings, error) {
+	settings := Settings{}
+	path := filepath.Join(configRoot, settingsFileName)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+
+		return settings, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	thread, done := starlarkutil.NewThread("settings", starlarkMaxSteps, starlarkTimeout)
+	defer done()
+
+	envDict, err := envToDict(denv.OS())
+	if err != nil {
+		return settings, err
+	}
+
+	predeclared := starlark.StringDict{
+		envVar:       envDict,
+		oneDayVar:    starlark.MakeInt(24 * 60 * 60),
+		oneHourVar:   starlark.MakeInt(60 * 60),
+		oneMinuteVar: starlark.MakeInt(60),
+	}
+	starlarkutil.AddPredeclared(predeclared)
+
+	globals, err := starlark.ExecFile(thread, path, src, predeclared)
+	if err != nil {
+		return settings, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if err := starstruct.FromStarlark(globals, &settings); err != nil {
+		return settings, fmt.Errorf("failed to convert settings to struct: %w", err)
+	}
+
+	if value, exists := globals[envDefaultsVar]; exists {
+		dict, ok := value.(*starlark.Dict)
+		if !ok {
+			return settings, fmt.Errorf("%q isn't a dictionary", envDefaultsVar)
+		}
+
+		settings.EnvDefaults, err = dictToEnv(envDefaultsVar, dict)
+		if err != nil {
+			return settings, err
+		}
+	}
+
+	settings.AgeIdentity = expandHome(settings.AgeIdentity)
+
+	settings.SendmailPath = expandHome(settings.SendmailPath)
+
+	if _, err := ParseLowSpacePolicy(settings.LowSpace); err != nil {
+		return settings, err
+	}
+
+	switch settings.EmailTransport {
+	case "", emailTransportSMTP, emailTransportSendmail:
+	default:
+		return settings, fmt.Errorf("unknown email transport: %v", settings.EmailTransport)
+	}
+
+	if settings.EmailBatch < 0 {
+		return settings, fmt.Errorf("%q must not be negative", "email_batch")
+	}
+
+	if settings.MinInterval < 0 {
+		return settings, fmt.Errorf("%q must not be negative", "min_interval")
+	}
+
+	if settings.NotifyLines != nil && *settings.NotifyLines < 0 {
+		return settings, fmt.Errorf("%q must not be negative", notifyLinesVar)
+	}
+
+	if settings.GCGrace < 0 {
+		return settings, fmt.Errorf("%q must not be negative", "gc_grace")
+	}
+
+	return settings, nil
+}
+
+// GCGracePeriod returns how long to keep the state of a deleted job.
+func (s Settings) GCGracePeriod() time.Duration {
+	if s.GCGrace == 0 {
+		return defaultGCGrace
+	}
+
+	return time.Duration(s.GCGrace) * time.Second
+}
+
+func envToDict(env denv.Env) (*starlark.Dict, error) {
+	envDict := starlark.NewDict(len(env))
+	for k, v := range env {
+		if err := envDict.SetKey(starlark.String(k), starlark.String(v)); err != nil {
+			return nil, fmt.Errorf("failed to set env dict key: %w", err)
+		}
+	}
+
+	return envDict, nil
+}
+
+// dictToEnv converts a Starlark dictionary of strings like "env" to an environment.
+// name identifies the dictionary in errors.
+func dictToEnv(name string, dict *starlark.Dict) (denv.Env, error) {
+	env := make(denv.Env)
+	for _, item := range dict.Items() {
+		key, ok := item.Index(0).(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("%q key %q must be Starlark string", name, item.Index(0))
+		}
+
+		value, ok := item.Index(1).(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("%q value %q isn't Starlark string", name, item.Index(1))
+		}
+
+		env[key.GoString()] = value.GoString()
+	}
+
+	return env, nil
+}
+
+// expandHome replaces a leading "~/" in a path with the user's home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, rest)
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadSettings(t *testing.T) {
+	configRoot := t.TempDir()
+
+	settings, err := LoadSettings(configRoot)
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if !cmp.Equal(settings, Settings{}) {
+		t.Errorf("expected default settings, got %+v", settings)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	content := `age_identity = "~/.config/age/" + "key.txt"`
+	if err := os.WriteFile(filepath.Join(configRoot, settingsFileName), []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err = LoadSettings(configRoot)
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+
+	if want := filepath.Join(home, ".config/age/key.txt"); settings.AgeIdentity != want {
+		t.Errorf("AgeIdentity = %q, want %q", settings.AgeIdentity, want)
+	}
+}
+
+func TestLoadEnvEncrypted(t *testing.T) {
+	configRoot := t.TempDir()
+	jobDir := filepath.Join(configRoot, "job")
+	if err := os.MkdirAll(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(jobDir, encryptedEnvFileName), []byte("not really encrypted"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an identity in the settings, the encrypted file can't be loaded.
+	if _, err := loadEnv(configRoot, jobDir, nil); err == nil {
+		t.Error("expected error loading encrypted env without an identity")
+	}
+}
+
+func TestLoadSettingsEmailTransport(t *testing.T) {
+	configRoot := t.TempDir()
+	path := filepath.Join(configRoot, settingsFileName)
+
+	if err := os.WriteFile(path, []byte(`email_transport = "sendmail"`), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadSettings(configRoot)
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if settings.EmailTransport != emailTransportSendmail {
+		t.Errorf("EmailTransport = %q, want %q", settings.EmailTransport, emailTransportSendmail)
+	}
+
+	if err := os.WriteFile(path, []byte(`email_transport = "pigeon"`), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadSettings(configRoot); err == nil {
+		t.Error("LoadSettings() accepted an unknown email transport")
+	}
+}
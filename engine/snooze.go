@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Snooze keeps the scheduler from queueing a job for a while.
+type Snooze struct {
+	JobName string
+	Since   time.Time
+	// When the scheduler queues the job again or zero for when the job is enabled with "regular enable".
+	Until time.Time
+}
+
+// ActiveAt reports whether the snooze keeps the job from running at t.
+func (s Snooze) ActiveAt(t time.Time) bool {
+	return s.Until.IsZero() || t.Before(s.Until)
+}
+
+// SnoozeJob stops the scheduler from queueing a job until a time or, with a zero time, until UnsnoozeJob.
+// It replaces an earlier snooze of the job.
+func (c *AppDB) SnoozeJob(jobName string, until time.Time) error {
+	untilValue := sql.NullTime{Time: until, Valid: !until.IsZero()}
+
+	_, err := c.db.Exec(`
+		INSERT OR REPLACE INTO snoozed_jobs (job_name, since, until)
+		VALUES (?, ?, ?)`,
+		jobName,
+		time.Now(),
+		untilValue,
+	)
+
+	return err
+}
+
+// UnsnoozeJob removes the snooze of a job.
+// It returns false if the job wasn't snoozed.
+func (c *AppDB) UnsnoozeJob(jobName string) (bool, error) {
+	res, err := c.db.Exec(`DELETE FROM snoozed_jobs WHERE job_name = ?`, jobName)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// JobSnooze returns the snooze of a job or nil if there is none.
+// An expired snooze is returned until the scheduler removes it.
+func (c *AppDB) JobSnooze(jobName string) (*Snooze, error) {
+	snooze := Snooze{JobName: jobName}
+	var until sql.NullTime
+
+	err := c.db.QueryRow(`
+		SELECT since, until
+		FROM snoozed_jobs
+		WHERE job_name = ?`,
+		jobName,
+	).Scan(&snooze.Since, &until)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if until.Valid {
+		snooze.Until = until.Time
+	}
+
+	return &snooze, nil
+}
+
+// loadSnoozes reads the snoozes of all jobs into the runner.
+func (r Runner) loadSnoozes() error {
+	snoozes, err := r.db.Snoozes()
+	if err != nil {
+		return fmt.Errorf("failed to get snoozed jobs: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, snooze := range snoozes {
+		r.snoozes[snooze.JobName] = snooze
+	}
+
+	return nil
+}
+
+// rememberSnooze updates the runner after "regular disable" has saved a snooze.
+func (r Runner) rememberSnooze(snooze Snooze) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.snoozes[snooze.JobName] = snooze
+}
+
+// forgetSnooze updates the runner after "regular enable" has removed a snooze.
+func (r Runner) forgetSnooze(jobName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.snoozes, jobName)
+}
+
+// snoozed reports whether a snooze keeps the scheduler from queueing a job at t.
+// It removes a snooze that has ended, which enables the job again.
+func (r Runner) snoozed(jobName string, t time.Time) (bool, error) {
+	r.mu.Lock()
+	snooze, ok := r.snoozes[jobName]
+	r.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	if snooze.ActiveAt(t) {
+		return true, nil
+	}
+
+	if _, err := r.db.UnsnoozeJob(jobName); err != nil {
+		return false, err
+	}
+	r.forgetSnooze(jobName)
+	LogJobPrintf(jobName, "Enabled again because the snooze ended")
+
+	return false, nil
+}
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.starlark.net/starlark"
+)
+
+func TestAppDBSnooze(t *testing.T) {
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	snooze, err := db.JobSnooze("backup")
+	if err != nil || snooze != nil {
+		t.Fatalf("JobSnooze() = %v, %v; want nil, nil", snooze, err)
+	}
+
+	until := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+	if err := db.SnoozeJob("backup", until); err != nil {
+		t.Fatalf("SnoozeJob() error = %v", err)
+	}
+
+	snooze, err = db.JobSnooze("backup")
+	if err != nil || snooze == nil {
+		t.Fatalf("JobSnooze() = %v, %v; want a snooze", snooze, err)
+	}
+	if !snooze.Until.Equal(until) {
+		t.Errorf("Expected the snooze to end at %v, got %v", until, snooze.Until)
+	}
+	if !snooze.ActiveAt(until.Add(-time.Second)) || snooze.ActiveAt(until) {
+		t.Errorf("Expected the snooze to be active only before %v", until)
+	}
+
+	// A new snooze replaces the old one.
+	if err := db.SnoozeJob("backup", time.Time{}); err != nil {
+		t.Fatalf("SnoozeJob() error = %v", err)
+	}
+
+	snooze, err = db.JobSnooze("backup")
+	if err != nil || snooze == nil {
+		t.Fatalf("JobSnooze() = %v, %v; want a snooze", snooze, err)
+	}
+	if !snooze.Until.IsZero() || !snooze.ActiveAt(until.AddDate(100, 0, 0)) {
+		t.Errorf("Expected an indefinite snooze, got one until %v", snooze.Until)
+	}
+
+	removed, err := db.UnsnoozeJob("backup")
+	if err != nil || !removed {
+		t.Fatalf("UnsnoozeJob() = %v, %v; want true, nil", removed, err)
+	}
+
+	removed, err = db.UnsnoozeJob("backup")
+	if err != nil || removed {
+		t.Errorf("UnsnoozeJob() = %v, %v; want false, nil", removed, err)
+	}
+}
+
+func TestJobRunnerSnooze(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	// The runner loads the snooze when it starts.
+	now := time.Now()
+	if err := db.SnoozeJob("snoozed-job", now.Add(time.Hour)); err != nil {
+		t.Fatalf("SnoozeJob() error = %v", err)
+	}
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	alwaysDue := starlark.NewBuiltin("should_run", func(_ *starlark.Thread, _ *starlark.Builtin, _ starlark.Tuple, _ []starlark.Tuple) (starlark.Value, error) {
+		return starlark.True, nil
+	})
+	job := JobConfig{
+		Name:      "snoozed-job",
+		Command:   []string{"true"},
+		Enable:    true,
+		Env:       denv.OS(),
+		ShouldRun: alwaysDue,
+	}
+
+	if err := job.AddToQueueIfDue(runner, now); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if n := runner.queueLen(job.Name); n != 0 {
+		t.Errorf("Expected the snoozed job not to be queued, queue length is %d", n)
+	}
+
+	// The scheduler enables the job when the snooze ends.
+	if err := job.AddToQueueIfDue(runner, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if n := runner.queueLen(job.Name); n != 1 {
+		t.Errorf("Expected the job to be queued, queue length is %d", n)
+	}
+
+	snooze, err := db.JobSnooze(job.Name)
+	if err != nil || snooze != nil {
+		t.Errorf("Expected the ended snooze to be removed, got %v, %v", snooze, err)
+	}
+}
+
+func TestSnoozeOverSocket(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	now := time.Now()
+
+	sendToRunner(t, runner, Request{Verb: VerbDisable, Job: "backup", Until: now.Add(time.Hour)})
+	if snoozed, err := runner.snoozed("backup", now); err != nil || !snoozed {
+		t.Errorf("snoozed() = %v, %v; want true, nil", snoozed, err)
+	}
+	if snoozed, _ := runner.snoozed("backup", now.Add(30*time.Minute)); !snoozed {
+		t.Error("Expected the snooze to last an hour")
+	}
+
+	sendToRunner(t, runner, Request{Verb: VerbEnable, Job: "backup"})
+	if snoozed, err := runner.snoozed("backup", now); err != nil || snoozed {
+		t.Errorf("snoozed() = %v, %v; want false, nil", snoozed, err)
+	}
+}
+
+// sendToRunner sends a request to the socket handler and expects it to succeed.
+func sendToRunner(t *testing.T, runner Runner, req Request) {
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go handleConn(server, NewScheduler(), runner)
+
+	if err := msgpack.NewEncoder(client).Encode(req); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	var f Frame
+	if err := msgpack.NewDecoder(client).Decode(&f); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if f.Type != FrameExit || f.Code != exitOK {
+		t.Fatalf("unexpected frame: %+v", f)
+	}
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// DefaultSocketPath returns the path where the daemon listens by default.
+// REGULAR_SOCK overrides everything. Otherwise the path is built from
+// $XDG_RUNTIME_DIR or one of the well-known per-user runtime directories,
+// falling back to a per-user subdir under os.TempDir().
+func DefaultSocketPath() (string, error) {
+	if envPath := os.Getenv(socketEnv); envPath != "" {
+		return envPath, nil
+	}
+
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	candidates := []string{}
+	subdir := dirName
+
+	if envDir := os.Getenv("XDG_RUNTIME_DIR"); envDir != "" {
+		candidates = append(candidates, envDir)
+	}
+
+	if runtime.GOOS == "freebsd" {
+		candidates = append(candidates, filepath.Join("/var/run/xdg", currentUser.Username))
+	}
+
+	candidates = append(
+		candidates,
+		filepath.Join("/run/user", currentUser.Uid),
+		filepath.Join("/var/run/user", currentUser.Uid),
+	)
+
+	var runtimeDir string
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			runtimeDir = candidate
+			break
+		}
+	}
+
+	if runtimeDir == "" {
+		runtimeDir = os.TempDir()
+		subdir = dirName + "-" + currentUser.Username + "@" + hostname
+	}
+
+	return filepath.Join(runtimeDir, subdir, appSocketFileName), nil
+}
+
+// CheckSocketSecurity verifies a socket file is a Unix domain socket owned
+// by the current user with mode 0600. Run on every client connection so we
+// don't talk to a socket dropped in the runtime dir by someone else.
+func CheckSocketSecurity(socket string) error {
+	info, err := os.Stat(socket)
+	if err != nil {
+		return fmt.Errorf("failed to stat socket: %w", err)
+	}
+
+	if (info.Mode() & os.ModeSocket) == 0 {
+		return errors.New("path is not a Unix domain socket")
+	}
+
+	if info.Mode().Perm() != filePerms {
+		return fmt.Errorf("incorrect socket permissions: %v", info.Mode().Perm())
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errors.New("failed to get socket system info")
+	}
+
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("socket owned by wrong user: %d", stat.Uid)
+	}
+
+	return nil
+}
+
+// QueryDaemonJobs asks a running daemon for its jobs and their state.
+// It reports ok=false without an error when no daemon is listening.
+func QueryDaemonJobs() (jobs []JobInfo, ok bool, err error) {
+	ok, err = queryDaemon(Request{Verb: VerbJobs}, func(f Frame) {
+		if f.Type == FrameJobs {
+			jobs = f.Jobs
+		}
+	})
+	if !ok || err != nil {
+		return nil, false, err
+	}
+
+	return jobs, true, nil
+}
+
+// QueryDaemonStats asks a running daemon for the scheduler stats.
+// It returns nil without an error when no daemon is listening.
+func QueryDaemonStats() (*SchedulerStats, error) {
+	var stats *SchedulerStats
+	_, err := queryDaemon(Request{Verb: VerbStats}, func(f Frame) {
+		if f.Type == FrameStats {
+			stats = f.Scheduler
+		}
+	})
+
+	return stats, err
+}
+
+// QueryDaemonDecision asks a running daemon for its last scheduling decision for a job
+// and, if that decision didn't queue the job, the last one that did.
+// It reports ok=false without an error when no daemon is listening
+// and returns nil decisions when the daemon hasn't made them.
+func QueryDaemonDecision(jobName string) (d, queued *Decision, ok bool, err error) {
+	ok, err = queryDaemon(Request{Verb: VerbWhy, Job: jobName}, func(f Frame) {
+		switch f.Type {
+		case FrameWhy:
+			d = f.Decision
+		case FrameWhyQueued:
+			queued = f.Decision
+		}
+	})
+
+	return d, queued, ok, err
+}
+
+// TellDaemonDisabled tells a running daemon that "regular disable" has snoozed a job until a time.
+// A zero time means until enabled.
+// It does nothing when no daemon is listening.
+func TellDaemonDisabled(jobName string, until time.Time) error {
+	_, err := queryDaemon(Request{Verb: VerbDisable, Job: jobName, Until: until}, func(Frame) {})
+	return err
+}
+
+// TellDaemonEnabled tells a running daemon that "regular enable" has removed the snooze of a job.
+// It does nothing when no daemon is listening.
+func TellDaemonEnabled(jobName string) error {
+	_, err := queryDaemon(Request{Verb: VerbEnable, Job: jobName}, func(Frame) {})
+	return err
+}
+
+// TellDaemonPaused tells a running daemon that "regular pause" has paused a queue.
+// It does nothing when no daemon is listening.
+func TellDaemonPaused(queue string) error {
+	_, err := queryDaemon(Request{Verb: VerbPause, Queue: queue}, func(Frame) {})
+	return err
+}
+
+// TellDaemonResumed tells a running daemon that "regular resume" has resumed a queue.
+// It does nothing when no daemon is listening.
+func TellDaemonResumed(queue string) error {
+	_, err := queryDaemon(Request{Verb: VerbResume, Queue: queue}, func(Frame) {})
+	return err
+}
+
+// queryDaemon sends a request to a running daemon and passes every frame before the exit frame to handle.
+// It reports ok=false without an error when no daemon is listening.
+func queryDaemon(req Request, handle func(Frame)) (ok bool, err error) {
+	socketPath, err := DefaultSocketPath()
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve socket path: %w", err)
+	}
+
+	if _, err := os.Stat(socketPath); err != nil {
+		return false, nil
+	}
+
+	if err := CheckSocketSecurity(socketPath); err != nil {
+		return false, fmt.Errorf("refusing to use socket %s: %w", socketPath, err)
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, socketDialTimeout)
+	if err != nil {
+		// A stale socket from a daemon that is gone.
+		return false, nil
+	}
+	defer conn.Close()
+
+	if err := msgpack.NewEncoder(conn).Encode(req); err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	dec := msgpack.NewDecoder(conn)
+	for {
+		var f Frame
+		if err := dec.Decode(&f); err != nil {
+			return false, fmt.Errorf("failed to read frame: %w", err)
+		}
+
+		if f.Type == FrameExit {
+			if f.Error != "" {
+				return false, errors.New(f.Error)
+			}
+
+			return true, nil
+		}
+
+		handle(f)
+	}
+}
@@ -1,6 +1,6 @@
 //go:build integration
 
-package main
+package engine
 
 import (
 	"bytes"
@@ -39,6 +39,16 @@ command = ["sh", "-c", "exit 7"]
 log = False
 notify = "never"
 
+def should_run(**_):
+    return False
+`)
+
+	mustWriteJob(t, configDir, "jittery", `
+command = ["true"]
+jitter = one_day
+log = False
+notify = "never"
+
 def should_run(**_):
     return False
 `)
@@ -65,7 +75,7 @@ def should_run(**_):
 	}
 
 	t.Run("streams stdout and stderr", func(t *testing.T) {
-		stdout, stderr, exit, err := callDaemon(sock, Request{Verb: verbRun, Job: "echoer", Force: true})
+		stdout, stderr, exit, err := callDaemon(sock, Request{Verb: VerbRun, Job: "echoer", Force: true})
 		if err != nil {
 			t.Fatalf("call: %v", err)
 		}
@@ -81,7 +91,7 @@ def should_run(**_):
 	})
 
 	t.Run("propagates non-zero exit", func(t *testing.T) {
-		_, _, exit, err := callDaemon(sock, Request{Verb: verbRun, Job: "boomer", Force: true})
+		_, _, exit, err := callDaemon(sock, Request{Verb: VerbRun, Job: "boomer", Force: true})
 		if err != nil {
 			t.Fatalf("call: %v", err)
 		}
@@ -91,7 +101,7 @@ def should_run(**_):
 	})
 
 	t.Run("not due exits cleanly without running", func(t *testing.T) {
-		_, _, exit, err := callDaemon(sock, Request{Verb: verbRun, Job: "echoer"})
+		_, _, exit, err := callDaemon(sock, Request{Verb: VerbRun, Job: "echoer"})
 		if err != nil {
 			t.Fatalf("call: %v", err)
 		}
@@ -100,8 +110,66 @@ def should_run(**_):
 		}
 	})
 
+	t.Run("skips jitter", func(t *testing.T) {
+		started := time.Now()
+
+		_, _, exit, err := callDaemon(sock, Request{Verb: VerbRun, Job: "jittery", Force: true, NoJitter: true})
+		if err != nil {
+			t.Fatalf("call: %v", err)
+		}
+		if exit.Code != 0 {
+			t.Errorf("exit code = %d, want 0", exit.Code)
+		}
+		if elapsed := time.Since(started); elapsed > 10*time.Second {
+			t.Errorf("run took %v despite NoJitter", elapsed)
+		}
+	})
+
+	t.Run("lists jobs", func(t *testing.T) {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+
+		if err := msgpack.NewEncoder(conn).Encode(Request{Verb: VerbJobs}); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+
+		var f Frame
+		if err := msgpack.NewDecoder(conn).Decode(&f); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if f.Type != FrameJobs {
+			t.Fatalf("frame type = %q, want %q", f.Type, FrameJobs)
+		}
+		if len(f.Jobs) != 3 || f.Jobs[0].Name != "boomer" || f.Jobs[1].Name != "echoer" || f.Jobs[2].Name != "jittery" {
+			t.Errorf("unexpected jobs: %+v", f.Jobs)
+		}
+	})
+
+	t.Run("reports scheduler stats", func(t *testing.T) {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+
+		if err := msgpack.NewEncoder(conn).Encode(Request{Verb: VerbStats}); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+
+		var f Frame
+		if err := msgpack.NewDecoder(conn).Decode(&f); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if f.Type != FrameStats || f.Scheduler == nil {
+			t.Fatalf("unexpected frame: %+v", f)
+		}
+	})
+
 	t.Run("rejects unknown job", func(t *testing.T) {
-		_, _, exit, err := callDaemon(sock, Request{Verb: verbRun, Job: "no-such-job", Force: true})
+		_, _, exit, err := callDaemon(sock, Request{Verb: VerbRun, Job: "no-such-job", Force: true})
 		if err != nil {
 			t.Fatalf("call: %v", err)
 		}
@@ -125,7 +193,7 @@ func mustWriteJob(t *testing.T, configDir, name, body string) {
 func buildBinary(t *testing.T) string {
 	t.Helper()
 	bin := filepath.Join(t.TempDir(), "regular")
-	out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput()
+	out, err := exec.Command("go", "build", "-o", bin, "..").CombinedOutput()
 	if err != nil {
 		t.Fatalf("go build: %v\n%s", err, out)
 	}
@@ -169,14 +237,14 @@ func callDaemon(sock string, req Request) (stdout, stderr []byte, exit Frame, er
 			return so.Bytes(), se.Bytes(), exit, decErr
 		}
 		switch f.Type {
-		case frameStdout:
+		case FrameStdout:
 			so.Write(f.Data)
-		case frameStderr:
+		case FrameStderr:
 			se.Write(f.Data)
-		case frameLog:
+		case FrameLog:
 			// Treat log messages as stderr for assertion convenience.
 			se.WriteString(f.Msg + "\n")
-		case frameExit:
+		case FrameExit:
 			return so.Bytes(), se.Bytes(), f, nil
 		}
 	}
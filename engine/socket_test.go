@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"net"
@@ -10,9 +10,9 @@ import (
 func TestDefaultSocketPathRespectsEnv(t *testing.T) {
 	t.Setenv(socketEnv, "/tmp/regular-test.sock")
 
-	got, err := defaultSocketPath()
+	got, err := DefaultSocketPath()
 	if err != nil {
-		t.Fatalf("defaultSocketPath: %v", err)
+		t.Fatalf("DefaultSocketPath: %v", err)
 	}
 	if got != "/tmp/regular-test.sock" {
 		t.Errorf("got %q, want override from env", got)
@@ -23,9 +23,9 @@ func TestDefaultSocketPathFallback(t *testing.T) {
 	// Force the temp-dir fallback by clearing the runtime hints.
 	t.Setenv(socketEnv, "")
 	t.Setenv("XDG_RUNTIME_DIR", "")
-	got, err := defaultSocketPath()
+	got, err := DefaultSocketPath()
 	if err != nil {
-		t.Fatalf("defaultSocketPath: %v", err)
+		t.Fatalf("DefaultSocketPath: %v", err)
 	}
 	if got == "" {
 		t.Error("expected non-empty path")
@@ -39,7 +39,7 @@ func TestCheckSocketSecurity(t *testing.T) {
 	dir := t.TempDir()
 
 	t.Run("missing", func(t *testing.T) {
-		if err := checkSocketSecurity(filepath.Join(dir, "nope")); err == nil {
+		if err := CheckSocketSecurity(filepath.Join(dir, "nope")); err == nil {
 			t.Error("expected error for missing path")
 		}
 	})
@@ -49,7 +49,7 @@ func TestCheckSocketSecurity(t *testing.T) {
 		if err := os.WriteFile(path, []byte("x"), filePerms); err != nil {
 			t.Fatal(err)
 		}
-		if err := checkSocketSecurity(path); err == nil {
+		if err := CheckSocketSecurity(path); err == nil {
 			t.Error("expected error for non-socket")
 		}
 	})
@@ -64,7 +64,7 @@ func TestCheckSocketSecurity(t *testing.T) {
 		if err := os.Chmod(path, 0o644); err != nil {
 			t.Fatal(err)
 		}
-		if err := checkSocketSecurity(path); err == nil {
+		if err := CheckSocketSecurity(path); err == nil {
 			t.Error("expected error for 0644 socket")
 		}
 	})
@@ -79,7 +79,7 @@ func TestCheckSocketSecurity(t *testing.T) {
 		if err := os.Chmod(path, filePerms); err != nil {
 			t.Fatal(err)
 		}
-		if err := checkSocketSecurity(path); err != nil {
+		if err := CheckSocketSecurity(path); err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
 	})
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"golang.org/x/sys/unix"
+
+	"dbohdan.com/denv"
+)
+
+// ListenSocket creates and binds a Unix-domain listener at path with mode
+// 0600. It first tries to detect an already-running daemon and bails if it
+// finds one; otherwise it removes a stale socket file and binds.
+func ListenSocket(path string) (net.Listener, error) {
+	// Probe for a live daemon. A short Dial succeeds only if something is
+	// actually accepting connections.
+	if conn, err := net.DialTimeout("unix", path, 50*time.Millisecond); err == nil {
+		_ = conn.Close()
+		return nil, errors.New("found another daemon responding on socket")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// Best-effort cleanup of any stale socket file from a prior crash.
+	_ = os.Remove(path)
+
+	// Force 0600 on the new socket.
+	old := unix.Umask(0o177)
+	defer unix.Umask(old)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	return listener, nil
+}
+
+// ServeSocket runs the accept loop until the listener is closed. Each
+// connection is handled in its own goroutine.
+func ServeSocket(listener net.Listener, jsc *Scheduler, runner Runner) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Socket accept error: %v", err)
+			continue
+		}
+
+		go handleConn(conn, jsc, runner)
+	}
+}
+
+// handleConn reads one Request and submits it through the runner, streaming
+// stdout/stderr/log/exit frames back to the client.
+func handleConn(conn net.Conn, jsc *Scheduler, runner Runner) {
+	defer conn.Close()
+
+	dec := msgpack.NewDecoder(conn)
+	enc := msgpack.NewEncoder(conn)
+	sender := newFrameSender(enc)
+
+	sendExit := func(code int, errMsg string) {
+		_ = sender.send(Frame{Type: FrameExit, Code: code, Error: errMsg})
+	}
+
+	var req Request
+	if err := dec.Decode(&req); err != nil {
+		sendExit(exitError, fmt.Sprintf("failed to read request: %v", err))
+		return
+	}
+
+	switch req.Verb {
+	case VerbJobs:
+		listJobsOverSocket(jsc, runner, sender)
+	case VerbRun:
+		runOverSocket(jsc, runner, sender, req)
+	case VerbStats:
+		stats := jsc.Stats()
+		_ = sender.send(Frame{Type: FrameStats, Scheduler: &stats})
+		sendExit(exitOK, "")
+	case VerbWhy:
+		if d, ok := runner.LastDecision(req.Job); ok {
+			_ = sender.send(Frame{Type: FrameWhy, Decision: &d})
+
+			if queued, ok := runner.LastQueuedDecision(req.Job); ok && !d.Queued {
+				_ = sender.send(Frame{Type: FrameWhyQueued, Decision: &queued})
+			}
+		}
+		sendExit(exitOK, "")
+	case VerbDisable:
+		// The client has saved the snooze in the database.
+		runner.rememberSnooze(Snooze{JobName: req.Job, Since: time.Now(), Until: req.Until})
+		sendExit(exitOK, "")
+	case VerbEnable:
+		runner.forgetSnooze(req.Job)
+		sendExit(exitOK, "")
+	case VerbPause:
+		// The client has saved the pause in the database.
+		runner.rememberPausedQueue(req.Queue, time.Now())
+		sendExit(exitOK, "")
+	case VerbResume:
+		runner.forgetPausedQueue(req.Queue)
+		sendExit(exitOK, "")
+	default:
+		sendExit(exitBadUsage, fmt.Sprintf("unknown verb: %q", req.Verb))
+	}
+}
+
+func listJobsOverSocket(jsc *Scheduler, runner Runner, sender *frameSender) {
+	states := runner.jobStates()
+
+	jsc.mu.RLock()
+	jobs := make([]JobInfo, 0, len(jsc.byName))
+	for _, job := range jsc.byName {
+		info := newJobInfo(job)
+		info.Running = states[job.Name].Running
+		info.Pending = states[job.Name].Pending
+		info.Position = states[job.Name].Position
+		info.Waiting = states[job.Name].Waiting
+
+		jobs = append(jobs, info)
+	}
+	jsc.mu.RUnlock()
+
+	slices.SortFunc(jobs, func(a, b JobInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	_ = sender.send(Frame{Type: FrameJobs, Jobs: jobs})
+	_ = sender.send(Frame{Type: FrameExit, Code: exitOK})
+}
+
+func runOverSocket(jsc *Scheduler, runner Runner, sender *frameSender, req Request) {
+	sendExit := func(code int, errMsg string) {
+		_ = sender.send(Frame{Type: FrameExit, Code: code, Error: errMsg})
+	}
+	sendLog := func(msg string) {
+		_ = sender.send(Frame{Type: FrameLog, Msg: msg})
+	}
+
+	jsc.mu.RLock()
+	job, ok := jsc.byName[req.Job]
+	jsc.mu.RUnlock()
+	if !ok {
+		sendExit(exitError, fmt.Sprintf("unknown job: %q", req.Job))
+		return
+	}
+
+	// Stamp on per-request writers and a completion signal.
+	done := make(chan CompletedJob, 1)
+	job.Stdout = newFrameWriter(sender, FrameStdout)
+	job.Stderr = newFrameWriter(sender, FrameStderr)
+	job.OnComplete = func(cj CompletedJob) {
+		done <- cj
+	}
+
+	if req.NoJitter {
+		job.Jitter = 0
+	}
+
+	// Don't modify the environment of the scheduled job.
+	job.Env = denv.Merge(job.Env, req.Env)
+
+	if req.Force {
+		runner.AddJob(job)
+	} else {
+		history, err := runner.History(job.Name)
+		if err != nil {
+			sendExit(exitError, fmt.Sprintf("failed to look up history: %v", err))
+			return
+		}
+		start, shouldRun, err := job.shouldRun(runner.Clock.Now(), history)
+		if err != nil {
+			sendExit(exitError, fmt.Sprintf("should_run failed: %v", err))
+			return
+		}
+		if !shouldRun {
+			sendLog("not due to run; pass --force to override")
+			sendExit(exitOK, "")
+			return
+		}
+		if runner.tooSoon(job.Name, history, runner.Clock.Now()) {
+			sendLog("started too recently; pass --force to override")
+			sendExit(exitOK, "")
+			return
+		}
+		job.startAt = start
+		runner.AddJob(job)
+	}
+
+	cj := <-done
+	exitCode := cj.ExitStatus
+	if cj.Error != "" && exitCode == 0 {
+		exitCode = exitError
+	}
+	sendExit(exitCode, cj.Error)
+}
@@ -0,0 +1,310 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Warn when a queue is busy for more than this share of the time.
+// Past it, a few slow runs are enough to build a backlog.
+const queueBusyWarning = 0.8
+
+// Warn when a host last ran a job this long before the host that ran one last.
+const staleHostWarning = 24 * time.Hour
+
+// JobStats summarizes the runs of a job in a period.
+type JobStats struct {
+	Name     string
+	Runs     int
+	Failures int
+
+	// The total and the longest run time.
+	TotalRuntime time.Duration
+	MaxRuntime   time.Duration
+
+	// When the first and the last run in the period started.
+	FirstStarted time.Time
+	LastStarted  time.Time
+}
+
+// MeanRuntime returns the average run time.
+func (s JobStats) MeanRuntime() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+
+	return s.TotalRuntime / time.Duration(s.Runs)
+}
+
+// MeanInterval returns the average time between the starts of runs.
+// It is zero with fewer than two runs.
+func (s JobStats) MeanInterval() time.Duration {
+	if s.Runs < 2 {
+		return 0
+	}
+
+	return s.LastStarted.Sub(s.FirstStarted) / time.Duration(s.Runs-1)
+}
+
+// JobStats summarizes the runs that started at or after since.
+// Skipped runs don't run the command, so they aren't counted.
+// The result is sorted by job name.
+func (c *AppDB) JobStats(since time.Time) ([]JobStats, error) {
+	rows, err := c.db.Query(`
+		SELECT job_name, ` + completedJobColumns + `
+		FROM completed_jobs
+		ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byName := make(map[string]*JobStats)
+	for rows.Next() {
+		var name string
+		completed, err := scanCompletedJob(scanFunc(func(dest ...any) error {
+			return rows.Scan(append([]any{&name}, dest...)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		// SQLite stores the times as strings, so compare them here.
+		if completed.Started.Before(since) || completed.Skipped {
+			continue
+		}
+
+		stats, ok := byName[name]
+		if !ok {
+			stats = &JobStats{Name: name, FirstStarted: completed.Started}
+			byName[name] = stats
+		}
+
+		runtime := max(completed.Finished.Sub(completed.Started), 0)
+
+		stats.Runs++
+		if !completed.IsSuccess() {
+			stats.Failures++
+		}
+		stats.TotalRuntime += runtime
+		stats.MaxRuntime = max(stats.MaxRuntime, runtime)
+		if completed.Started.Before(stats.FirstStarted) {
+			stats.FirstStarted = completed.Started
+		}
+		if completed.Started.After(stats.LastStarted) {
+			stats.LastStarted = completed.Started
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]JobStats, 0, len(byName))
+	for _, stats := range byName {
+		result = append(result, *stats)
+	}
+
+	slices.SortFunc(result, func(a, b JobStats) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return result, nil
+}
+
+// QueueLoad estimates how much of the time the jobs in a queue need.
+type QueueLoad struct {
+	Queue string
+	Jobs  []string
+
+	// The sum of the mean run times of the jobs in the queue.
+	Runtime time.Duration
+	// The shortest mean interval between the runs of a job in the queue.
+	// It is zero when no job has run twice.
+	Interval time.Duration
+	// The share of the period the queue spent running jobs.
+	Busy float64
+}
+
+// Overlapping reports whether one run of every job in the queue takes longer than the shortest interval.
+// The runs then start later and later and the backlog grows.
+func (q QueueLoad) Overlapping() bool {
+	return q.Interval > 0 && q.Runtime > q.Interval
+}
+
+// Saturated reports whether the queue was busy for most of the period.
+func (q QueueLoad) Saturated() bool {
+	return q.Busy > queueBusyWarning
+}
+
+// QueueLoads groups job stats by queue over a period.
+// queues maps job names to queue names.
+// Jobs missing from it, like deleted jobs, are in the queue named after the job.
+// The result is sorted by queue name.
+func QueueLoads(stats []JobStats, queues map[string]string, period time.Duration) []QueueLoad {
+	byQueue := make(map[string]*QueueLoad)
+	total := make(map[string]time.Duration)
+	for _, s := range stats {
+		queue, ok := queues[s.Name]
+		if !ok {
+			queue = s.Name
+		}
+
+		load, ok := byQueue[queue]
+		if !ok {
+			load = &QueueLoad{Queue: queue}
+			byQueue[queue] = load
+		}
+
+		load.Jobs = append(load.Jobs, s.Name)
+		load.Runtime += s.MeanRuntime()
+
+		interval := s.MeanInterval()
+		if interval > 0 && (load.Interval == 0 || interval < load.Interval) {
+			load.Interval = interval
+		}
+
+		total[queue] += s.TotalRuntime
+	}
+
+	result := make([]QueueLoad, 0, len(byQueue))
+	for _, load := range byQueue {
+		slices.Sort(load.Jobs)
+		if period > 0 {
+			load.Busy = float64(total[load.Queue]) / float64(period)
+		}
+
+		result = append(result, *load)
+	}
+
+	slices.SortFunc(result, func(a, b QueueLoad) int {
+		return strings.Compare(a.Queue, b.Queue)
+	})
+
+	return result
+}
+
+// HostStats summarizes the runs of a job on one host.
+type HostStats struct {
+	Host     string
+	Name     string
+	Runs     int
+	Failures int
+
+	// When the last run on the host started.
+	// Unlike the counts, it includes the runs before the period.
+	LastStarted time.Time
+}
+
+// HostStats summarizes the runs of jobs by the host they ran on.
+// The counts only include the runs that started at or after since.
+// A job that ran on a host before but not since has no runs.
+// The result is sorted by host, then by job name.
+func (c *AppDB) HostStats(since time.Time) ([]HostStats, error) {
+	rows, err := c.db.Query(`
+		SELECT job_name, ` + completedJobColumns + `
+		FROM completed_jobs
+		ORDER BY id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type key struct{ host, name string }
+	byKey := make(map[key]*HostStats)
+	for rows.Next() {
+		var name string
+		completed, err := scanCompletedJob(scanFunc(func(dest ...any) error {
+			return rows.Scan(append([]any{&name}, dest...)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		k := key{completed.Hostname, name}
+		stats, ok := byKey[k]
+		if !ok {
+			stats = &HostStats{Host: completed.Hostname, Name: name}
+			byKey[k] = stats
+		}
+
+		if completed.Started.After(stats.LastStarted) {
+			stats.LastStarted = completed.Started
+		}
+
+		if completed.Started.Before(since) {
+			continue
+		}
+
+		stats.Runs++
+		if !completed.IsSuccess() {
+			stats.Failures++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]HostStats, 0, len(byKey))
+	for _, stats := range byKey {
+		result = append(result, *stats)
+	}
+
+	slices.SortFunc(result, func(a, b HostStats) int {
+		if c := strings.Compare(a.Host, b.Host); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return result, nil
+}
+
+// StaleHost is a host that stopped running jobs before the others did.
+type StaleHost struct {
+	Host        string
+	LastStarted time.Time
+	// How long before the last run on any host the last run on this host started.
+	Behind time.Duration
+}
+
+// StaleHosts finds the hosts whose last run started more than a day before the last run on any host.
+// Runs without a host are ignored.
+// It usually means the daemon on the host isn't running.
+// The result is sorted by host.
+func StaleHosts(stats []HostStats) []StaleHost {
+	lastByHost := make(map[string]time.Time)
+	var latest time.Time
+
+	for _, s := range stats {
+		// The runs saved before Regular recorded the host.
+		if s.Host == "" {
+			continue
+		}
+
+		if s.LastStarted.After(lastByHost[s.Host]) {
+			lastByHost[s.Host] = s.LastStarted
+		}
+
+		if s.LastStarted.After(latest) {
+			latest = s.LastStarted
+		}
+	}
+
+	result := []StaleHost{}
+	for host, last := range lastByHost {
+		behind := latest.Sub(last)
+		if behind > staleHostWarning {
+			result = append(result, StaleHost{Host: host, LastStarted: last, Behind: behind})
+		}
+	}
+
+	slices.SortFunc(result, func(a, b StaleHost) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+
+	return result
+}
@@ -0,0 +1,270 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAppDBJobStats(t *testing.T) {
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := []struct {
+		job      string
+		offset   time.Duration
+		duration time.Duration
+		exit     int
+		skipped  bool
+	}{
+		{"old", -time.Hour, time.Minute, 0, false},
+		{"backup", 0, 10 * time.Minute, 0, false},
+		{"backup", time.Hour, 20 * time.Minute, 1, false},
+		{"backup", 2 * time.Hour, 30 * time.Minute, 0, false},
+		{"backup", 3 * time.Hour, 0, 0, true},
+		{"sync", 30 * time.Minute, time.Minute, 0, false},
+	}
+	for _, run := range runs {
+		started := start.Add(run.offset)
+		_, err := db.saveCompletedJob(run.job, CompletedJob{
+			ExitStatus: run.exit,
+			Started:    started,
+			Finished:   started.Add(run.duration),
+			Skipped:    run.skipped,
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to save completed job: %v", err)
+		}
+	}
+
+	stats, err := db.JobStats(start)
+	if err != nil {
+		t.Fatalf("Failed to get job stats: %v", err)
+	}
+
+	want := []JobStats{
+		{
+			Name:         "backup",
+			Runs:         3,
+			Failures:     1,
+			TotalRuntime: time.Hour,
+			MaxRuntime:   30 * time.Minute,
+			FirstStarted: start,
+			LastStarted:  start.Add(2 * time.Hour),
+		},
+		{
+			Name:         "sync",
+			Runs:         1,
+			TotalRuntime: time.Minute,
+			MaxRuntime:   time.Minute,
+			FirstStarted: start.Add(30 * time.Minute),
+			LastStarted:  start.Add(30 * time.Minute),
+		},
+	}
+	if diff := cmp.Diff(want, stats, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+		t.Errorf("Job stats mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := stats[0].MeanRuntime(); got != 20*time.Minute {
+		t.Errorf("Expected a mean run time of 20m, got %v", got)
+	}
+	if got := stats[0].MeanInterval(); got != time.Hour {
+		t.Errorf("Expected a mean interval of 1h, got %v", got)
+	}
+	if got := stats[1].MeanInterval(); got != 0 {
+		t.Errorf("Expected no interval for a single run, got %v", got)
+	}
+}
+
+func TestQueueLoads(t *testing.T) {
+	stats := []JobStats{
+		// 40m per run every hour.
+		{Name: "backup", Runs: 2, TotalRuntime: 80 * time.Minute, FirstStarted: time.Unix(0, 0), LastStarted: time.Unix(3600, 0)},
+		// 30m per run every 2 hours.
+		{Name: "photos", Runs: 2, TotalRuntime: 60 * time.Minute, FirstStarted: time.Unix(0, 0), LastStarted: time.Unix(7200, 0)},
+		// 1m per run every 10 minutes.
+		{Name: "ping", Runs: 2, TotalRuntime: 2 * time.Minute, FirstStarted: time.Unix(0, 0), LastStarted: time.Unix(600, 0)},
+		{Name: "deleted", Runs: 1, TotalRuntime: time.Minute},
+	}
+	queues := map[string]string{
+		"backup": "disk",
+		"photos": "disk",
+		"ping":   "ping",
+	}
+
+	loads := QueueLoads(stats, queues, 2*time.Hour)
+
+	want := []QueueLoad{
+		{Queue: "deleted", Jobs: []string{"deleted"}, Runtime: time.Minute, Busy: 1.0 / 120},
+		{Queue: "disk", Jobs: []string{"backup", "photos"}, Runtime: 70 * time.Minute, Interval: time.Hour, Busy: 140.0 / 120},
+		{Queue: "ping", Jobs: []string{"ping"}, Runtime: time.Minute, Interval: 10 * time.Minute, Busy: 2.0 / 120},
+	}
+	if diff := cmp.Diff(want, loads); diff != "" {
+		t.Errorf("Queue loads mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, load := range loads {
+		overloaded := load.Queue == "disk"
+
+		if load.Overlapping() != overloaded {
+			t.Errorf("Queue %q: expected overlapping %v", load.Queue, overloaded)
+		}
+		if load.Saturated() != overloaded {
+			t.Errorf("Queue %q: expected saturated %v", load.Queue, overloaded)
+		}
+	}
+}
+
+func TestAppDBHostStats(t *testing.T) {
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	runs := []struct {
+		host   string
+		job    string
+		offset time.Duration
+		exit   int
+	}{
+		{"", "backup", -30 * 24 * time.Hour, 0},
+		{"laptop", "backup", -3 * 24 * time.Hour, 0},
+		{"desktop", "backup", 0, 0},
+		{"desktop", "backup", time.Hour, 1},
+		{"laptop", "sync", -2 * 24 * time.Hour, 0},
+		{"laptop", "sync", -time.Hour, 0},
+	}
+	for _, run := range runs {
+		started := start.Add(run.offset)
+		_, err := db.saveCompletedJob(run.job, CompletedJob{
+			ExitStatus: run.exit,
+			Started:    started,
+			Finished:   started.Add(time.Minute),
+			Hostname:   run.host,
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to save completed job: %v", err)
+		}
+	}
+
+	stats, err := db.HostStats(start.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get host stats: %v", err)
+	}
+
+	want := []HostStats{
+		{Host: "", Name: "backup", LastStarted: start.Add(-30 * 24 * time.Hour)},
+		{Host: "desktop", Name: "backup", Runs: 2, Failures: 1, LastStarted: start.Add(time.Hour)},
+		{Host: "laptop", Name: "backup", LastStarted: start.Add(-3 * 24 * time.Hour)},
+		{Host: "laptop", Name: "sync", Runs: 1, LastStarted: start.Add(-time.Hour)},
+	}
+	if diff := cmp.Diff(want, stats, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+		t.Errorf("Host stats mismatch (-want +got):\n%s", diff)
+	}
+
+	if stale := StaleHosts(stats); len(stale) != 0 {
+		t.Errorf("Expected no stale hosts, got %v", stale)
+	}
+
+	// The laptop stops running jobs.
+	stats = append(stats, HostStats{Host: "desktop", Name: "sync", Runs: 1, LastStarted: start.Add(48 * time.Hour)})
+
+	wantStale := []StaleHost{{Host: "laptop", LastStarted: start.Add(-time.Hour), Behind: 49 * time.Hour}}
+	if diff := cmp.Diff(wantStale, StaleHosts(stats), cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+		t.Errorf("Stale hosts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAppDBRuntimes(t *testing.T) {
+	db, err := OpenAppDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := []CompletedJob{
+		{Started: start, Finished: start.Add(time.Minute)},
+		{ExitStatus: 1, Started: start, Finished: start.Add(time.Second)},
+		{Skipped: true, Started: start, Finished: start},
+		{Started: start, Finished: start.Add(2 * time.Minute)},
+	}
+	for _, run := range runs {
+		if _, err := db.saveCompletedJob("backup", run, nil); err != nil {
+			t.Fatalf("Failed to save completed job: %v", err)
+		}
+	}
+
+	runtimes, err := db.Runtimes("backup")
+	if err != nil {
+		t.Fatalf("Failed to get run times: %v", err)
+	}
+
+	want := []time.Duration{time.Minute, 2 * time.Minute}
+	if diff := cmp.Diff(want, runtimes); diff != "" {
+		t.Errorf("Run times mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	minutes := func(ns ...int) []time.Duration {
+		durations := []time.Duration{}
+		for _, n := range ns {
+			durations = append(durations, time.Duration(n)*time.Minute)
+		}
+
+		return durations
+	}
+
+	tests := []struct {
+		name      string
+		runtimes  []time.Duration
+		want      JobTrend
+		regressed bool
+	}{
+		{
+			name:      "steady",
+			runtimes:  minutes(10, 11, 9, 10, 12, 10, 11),
+			want:      JobTrend{Name: "job", BaselineRuns: 5, Baseline: 10 * time.Minute, RecentRuns: 2, Recent: 10*time.Minute + 30*time.Second},
+			regressed: false,
+		},
+		{
+			name:      "slowdown",
+			runtimes:  minutes(10, 11, 9, 10, 12, 20, 30),
+			want:      JobTrend{Name: "job", BaselineRuns: 5, Baseline: 10 * time.Minute, RecentRuns: 2, Recent: 25 * time.Minute},
+			regressed: true,
+		},
+		{
+			name:      "short baseline",
+			runtimes:  minutes(10, 10, 30, 30),
+			want:      JobTrend{Name: "job", BaselineRuns: 2, Baseline: 10 * time.Minute, RecentRuns: 2, Recent: 30 * time.Minute},
+			regressed: false,
+		},
+		{
+			name:      "few runs",
+			runtimes:  minutes(10),
+			want:      JobTrend{Name: "job", RecentRuns: 1, Recent: 10 * time.Minute},
+			regressed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Trend("job", tt.runtimes, 2)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Trend mismatch (-want +got):\n%s", diff)
+			}
+			if got.Regressed() != tt.regressed {
+				t.Errorf("Expected Regressed() = %v, got %v", tt.regressed, got.Regressed())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"bytes"
+	"log/syslog"
+)
+
+// lineWriter calls emit for every complete line written to it.
+// Close emits the last line if it has no trailing newline.
+type lineWriter struct {
+	buf  []byte
+	emit func(string) error
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf = append(lw.buf, p...)
+
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := string(lw.buf[:i])
+		lw.buf = lw.buf[i+1:]
+
+		if err := lw.emit(line); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (lw *lineWriter) Close() error {
+	if len(lw.buf) == 0 {
+		return nil
+	}
+
+	line := string(lw.buf)
+	lw.buf = nil
+
+	return lw.emit(line)
+}
+
+// jobSyslog forwards the output of a job to syslog line by line with the job name as the tag.
+// Stdout is logged with the priority "info" and stderr with "err".
+type jobSyslog struct {
+	writer *syslog.Writer
+	Stdout *lineWriter
+	Stderr *lineWriter
+}
+
+func newJobSyslog(jobName string) (*jobSyslog, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jobSyslog{
+		writer: writer,
+		Stdout: &lineWriter{emit: writer.Info},
+		Stderr: &lineWriter{emit: writer.Err},
+	}, nil
+}
+
+func (js *jobSyslog) Close() error {
+	stdoutErr := js.Stdout.Close()
+	stderrErr := js.Stderr.Close()
+	closeErr := js.writer.Close()
+
+	if stdoutErr != nil {
+		return stdoutErr
+	}
+	if stderrErr != nil {
+		return stderrErr
+	}
+
+	return closeErr
+}
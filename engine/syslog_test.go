@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	lines := []string{}
+	lw := &lineWriter{emit: func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}}
+
+	for _, s := range []string{"one\ntw", "o\n", "\nthree\nfo", "ur"} {
+		if n, err := lw.Write([]byte(s)); err != nil || n != len(s) {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+
+	if err := lw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	expected := []string{"one", "two", "", "three", "four"}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("lines = %q, want %q", lines, expected)
+	}
+}
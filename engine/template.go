@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.starlark.net/starlark"
+
+	"dbohdan.com/regular/starlarkutil"
+)
+
+// templateLoader returns the "load" function for a job file.
+// A job file can only load the template file in its config root.
+// The template file gets the same predeclared names as the job file.
+func templateLoader(configRoot string, predeclared starlark.StringDict) func(*starlark.Thread, string) (starlark.StringDict, error) {
+	var loaded starlark.StringDict
+
+	return func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+		if module != templateFileName {
+			return nil, fmt.Errorf("can only load %q, not %q", templateFileName, module)
+		}
+
+		if loaded != nil {
+			return loaded, nil
+		}
+
+		path := filepath.Join(configRoot, templateFileName)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+
+		thread, done := starlarkutil.NewThread("template", starlarkMaxSteps, starlarkTimeout)
+		defer done()
+
+		loaded, err = starlark.ExecFile(thread, path, src, predeclared)
+		if err != nil {
+			return nil, err
+		}
+
+		return loaded, nil
+	}
+}
+
+// readTemplate returns the contents of the template file in a config root or nil if there is none.
+func readTemplate(configRoot string) ([]byte, error) {
+	src, err := os.ReadFile(filepath.Join(configRoot, templateFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return src, err
+}
+
+// applyInstance sets the options in the "instance" dictionary that the job file doesn't set itself.
+// The dictionary usually comes from a function in the template file.
+func applyInstance(globals starlark.StringDict) (starlark.StringDict, error) {
+	value, exists := globals[instanceVar]
+	if !exists {
+		return globals, nil
+	}
+
+	dict, ok := value.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("%q isn't a dictionary", instanceVar)
+	}
+
+	merged := make(starlark.StringDict, len(globals)+dict.Len())
+	for _, item := range dict.Items() {
+		key, ok := item.Index(0).(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("%q key %q must be Starlark string", instanceVar, item.Index(0))
+		}
+
+		merged[key.GoString()] = item.Index(1)
+	}
+
+	for name, value := range globals {
+		if name != instanceVar {
+			merged[name] = value
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testTemplate = `
+def backup(target, time = "03:00"):
+    return {
+        "command": ["restic", "backup", target],
+        "schedule": daily_at(time),
+        "tags": ["backup"],
+    }
+
+def broken():
+    return {"jittr": 5}
+`
+
+func writeTestTemplate(t *testing.T, configRoot, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(configRoot, templateFileName), []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJobTemplate(t *testing.T) {
+	configRoot := t.TempDir()
+	writeTestTemplate(t, configRoot, testTemplate)
+
+	path := writeTestJob(t, configRoot, "docs", `
+load("template.star", "backup")
+instance = backup("/home/user/docs", time = "04:00")
+tags = ["docs"]
+`)
+
+	jsc := NewScheduler()
+	_, job, err := jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if !slices.Equal(job.Command, []string{"restic", "backup", "/home/user/docs"}) {
+		t.Errorf("Expected the command from the template, got %v", job.Command)
+	}
+	if !strings.HasPrefix(job.ScheduleSummary(), "daily at 04:00") {
+		t.Errorf("Expected the schedule from the template, got %q", job.ScheduleSummary())
+	}
+	if !slices.Equal(job.Tags, []string{"docs"}) {
+		t.Errorf("Expected the job file to override the tags, got %v", job.Tags)
+	}
+	if len(job.Warnings()) != 0 {
+		t.Errorf("Expected no warnings, got %v", job.Warnings())
+	}
+
+	// A change to the template updates the job.
+	writeTestTemplate(t, configRoot, strings.ReplaceAll(testTemplate, `"restic"`, `"borg"`))
+
+	res, job, err := jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsUpdated || job.Command[0] != "borg" {
+		t.Errorf("Expected the job to be updated with the new template, got %v, %v", res, job.Command)
+	}
+}
+
+func TestJobTemplateErrors(t *testing.T) {
+	configRoot := t.TempDir()
+	writeTestTemplate(t, configRoot, testTemplate)
+
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"other module", `load("other.star", "backup")`, `can only load "template.star"`},
+		{"missing function", `load("template.star", "restore")`, "restore"},
+		{"not a dictionary", `instance = ["restic"]`, `"instance" isn't a dictionary`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestJob(t, configRoot, "job", tt.src)
+
+			_, _, err := NewScheduler().Update(configRoot, path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Unknown options from a template are reported like ones in the job file.
+	path := writeTestJob(t, configRoot, "typo", "load(\"template.star\", \"broken\")\ninstance = broken()\n")
+	_, job, err := NewScheduler().Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(job.Warnings()) != 1 || !strings.Contains(job.Warnings()[0], "jittr") {
+		t.Errorf("Expected a warning about jittr, got %v", job.Warnings())
+	}
+}
+
+func TestJobTemplateFromConfigRoot(t *testing.T) {
+	configRoot := t.TempDir()
+	writeTestTemplate(t, configRoot, testTemplate)
+
+	// The job file is loaded for the config root it belongs to, not the directory it is in.
+	otherDir := t.TempDir()
+	writeTestTemplate(t, otherDir, `def backup(target): return {"command": ["false"]}`)
+	path := writeTestJob(t, otherDir, "docs", `
+load("template.star", "backup")
+instance = backup("/home/user/docs")
+`)
+
+	_, job, err := NewScheduler().Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !slices.Equal(job.Command, []string{"restic", "backup", "/home/user/docs"}) {
+		t.Errorf("Expected the command from the template in the config root, got %v", job.Command)
+	}
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// Flag a job when the median of its recent run times is this many times the median before them.
+	trendSlowdown = 1.5
+	// How many earlier runs a job needs for a baseline.
+	minBaselineRuns = 5
+)
+
+// JobTrend compares the recent run times of a job with the earlier ones.
+type JobTrend struct {
+	Name string
+
+	// The number of runs and the median run time of each part.
+	BaselineRuns int
+	Baseline     time.Duration
+	RecentRuns   int
+	Recent       time.Duration
+}
+
+// Change returns how much slower the recent runs are as a fraction of the baseline.
+// It is negative when they are faster and zero without a baseline.
+func (t JobTrend) Change() float64 {
+	if t.Baseline == 0 {
+		return 0
+	}
+
+	return float64(t.Recent)/float64(t.Baseline) - 1
+}
+
+// Regressed reports whether the recent runs are significantly slower than the baseline.
+func (t JobTrend) Regressed() bool {
+	return t.BaselineRuns >= minBaselineRuns && t.RecentRuns > 0 && t.Change() >= trendSlowdown-1
+}
+
+// Runtimes returns the run times of the successful runs of a job from the oldest.
+// Failed runs often stop early and skipped runs don't run, so they would skew the trend.
+func (c *AppDB) Runtimes(jobName string) ([]time.Duration, error) {
+	rows, err := c.db.Query(`
+		SELECT `+completedJobColumns+`
+		FROM completed_jobs
+		WHERE job_name = ?
+		ORDER BY id`,
+		jobName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runtimes := []time.Duration{}
+	for rows.Next() {
+		completed, err := scanCompletedJob(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if !completed.IsSuccess() || completed.Skipped {
+			continue
+		}
+
+		runtimes = append(runtimes, max(completed.Finished.Sub(completed.Started), 0))
+	}
+
+	return runtimes, rows.Err()
+}
+
+// Trend compares the last recent run times with the ones before them.
+func Trend(name string, runtimes []time.Duration, recent int) JobTrend {
+	split := max(len(runtimes)-recent, 0)
+	baseline, latest := runtimes[:split], runtimes[split:]
+
+	return JobTrend{
+		Name:         name,
+		BaselineRuns: len(baseline),
+		Baseline:     median(baseline),
+		RecentRuns:   len(latest),
+		Recent:       median(latest),
+	}
+}
+
+// median returns the median of durations or zero if there are none.
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return sorted[mid]
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+)
+
+// truncatingWriter passes through the first half of the limit and keeps the last half in memory.
+// Close writes a truncation marker and the kept tail if the output exceeded the limit.
+type truncatingWriter struct {
+	w       io.Writer
+	headMax int64
+	written int64
+
+	// A ring buffer with the tail of the output.
+	// It grows as the output does up to tailMax, so short output with a high limit uses little memory.
+	tail      []byte
+	tailMax   int
+	tailStart int
+	tailFull  bool
+}
+
+// The size the tail buffer starts at when it is first needed.
+const minTailBuffer = 4096
+
+func newTruncatingWriter(w io.Writer, limit int64) *truncatingWriter {
+	headMax := limit / 2
+
+	return &truncatingWriter{
+		w:       w,
+		headMax: headMax,
+		tailMax: int(limit - headMax),
+	}
+}
+
+func (tw *truncatingWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if tw.written < tw.headMax {
+		headLen := min(int64(len(p)), tw.headMax-tw.written)
+		if _, err := tw.w.Write(p[:headLen]); err != nil {
+			return 0, err
+		}
+
+		tw.written += headLen
+		p = p[headLen:]
+	}
+
+	for _, b := range p {
+		tw.written++
+
+		if tw.tailMax == 0 {
+			continue
+		}
+
+		if !tw.tailFull {
+			tw.growTail()
+			tw.tail = append(tw.tail, b)
+			tw.tailFull = len(tw.tail) == tw.tailMax
+
+			continue
+		}
+
+		tw.tail[tw.tailStart] = b
+		tw.tailStart = (tw.tailStart + 1) % len(tw.tail)
+	}
+
+	return n, nil
+}
+
+// growTail makes room for one more byte in the tail without going over tailMax.
+func (tw *truncatingWriter) growTail() {
+	if len(tw.tail) < cap(tw.tail) {
+		return
+	}
+
+	tail := make([]byte, len(tw.tail), min(max(2*cap(tw.tail), minTailBuffer), tw.tailMax))
+	copy(tail, tw.tail)
+	tw.tail = tail
+}
+
+// Close flushes the tail.
+// It doesn't close the underlying writer.
+func (tw *truncatingWriter) Close() error {
+	truncated := tw.written - tw.headMax - int64(len(tw.tail))
+	if truncated > 0 {
+		if _, err := fmt.Fprintf(tw.w, truncationMarker, truncated); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tw.w.Write(tw.tail[tw.tailStart:]); err != nil {
+		return err
+	}
+
+	_, err := tw.w.Write(tw.tail[:tw.tailStart])
+	return err
+}
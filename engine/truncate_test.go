@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTruncatingWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int64
+		writes []string
+		want   string
+	}{
+		{
+			name:   "under limit",
+			limit:  10,
+			writes: []string{"abc", "def"},
+			want:   "abcdef",
+		},
+		{
+			name:   "at limit",
+			limit:  10,
+			writes: []string{"0123456789"},
+			want:   "0123456789",
+		},
+		{
+			name:   "over limit in one write",
+			limit:  10,
+			writes: []string{"0123456789abcdef"},
+			want:   "01234" + fmt.Sprintf(truncationMarker, 6) + "bcdef",
+		},
+		{
+			name:   "over limit in many writes",
+			limit:  6,
+			writes: []string{"ab", "cd", "ef", "gh", "ij"},
+			want:   "abc" + fmt.Sprintf(truncationMarker, 4) + "hij",
+		},
+		{
+			name:   "limit of one",
+			limit:  1,
+			writes: []string{"abc"},
+			want:   fmt.Sprintf(truncationMarker, 2) + "c",
+		},
+		{
+			name:   "tail bigger than buffer start",
+			limit:  20000,
+			writes: []string{strings.Repeat("a", 10000), strings.Repeat("b", 15000), strings.Repeat("c", 5000)},
+			want:   strings.Repeat("a", 10000) + fmt.Sprintf(truncationMarker, 10000) + strings.Repeat("b", 5000) + strings.Repeat("c", 5000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := newTruncatingWriter(&buf, tt.limit)
+
+			for _, s := range tt.writes {
+				n, err := tw.Write([]byte(s))
+				if err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+
+			if err := tw.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncatingWriterGrowsTail(t *testing.T) {
+	tw := newTruncatingWriter(io.Discard, 1<<20)
+
+	if _, err := tw.Write([]byte(strings.Repeat("a", 1<<19+100))); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// Only the output past the head is kept, not the whole half of the limit.
+	if n := cap(tw.tail); n > minTailBuffer {
+		t.Errorf("Expected a tail buffer of at most %d bytes, got %d", minTailBuffer, n)
+	}
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// The thread-local key of the commands that "which" looks up while a job file loads.
+const whichLookupsKey = "regular.which_lookups"
+
+// whichLookup is a command looked up with "which" and the PATH it was looked up in.
+type whichLookup struct {
+	name     string
+	pathList string
+}
+
+// newWhich returns the "which" builtin for a job.
+// It finds a command in the PATH of the job's environment like the shell
+// and returns the path or None if the command is missing.
+// A command with a slash is checked relative to the job directory.
+// The lookups while loading a job are recorded, so the job is reloaded when their results change.
+func newWhich(jobDir string, envDict *starlark.Dict) *starlark.Builtin {
+	return starlark.NewBuiltin(whichVar, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name string
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &name); err != nil {
+			return nil, err
+		}
+
+		if name == "" {
+			return starlark.None, nil
+		}
+
+		lookup := whichLookup{name: name}
+		if strings.Contains(name, "/") {
+			lookup.name = expandHome(name)
+			if !filepath.IsAbs(lookup.name) {
+				lookup.name = filepath.Join(jobDir, lookup.name)
+			}
+		} else {
+			lookup.pathList = envPath(envDict)
+		}
+
+		if lookups, ok := thread.Local(whichLookupsKey).(*[]whichLookup); ok {
+			*lookups = append(*lookups, lookup)
+		}
+
+		path := lookup.find()
+		if path == "" {
+			return starlark.None, nil
+		}
+
+		return starlark.String(path), nil
+	})
+}
+
+// find returns the path of the command or an empty string if it is missing.
+func (l whichLookup) find() string {
+	if strings.Contains(l.name, "/") {
+		if isExecutable(l.name) {
+			return l.name
+		}
+
+		return ""
+	}
+
+	for _, dir := range filepath.SplitList(l.pathList) {
+		if dir == "" {
+			dir = "."
+		}
+
+		path := filepath.Join(dir, l.name)
+		if isExecutable(path) {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// hashWhichLookups hashes the results of lookups done now.
+func hashWhichLookups(lookups []whichLookup) [sha256.Size]byte {
+	h := sha256.New()
+
+	for _, lookup := range lookups {
+		h.Write([]byte(lookup.name))
+		h.Write([]byte{0})
+		h.Write([]byte(lookup.pathList))
+		h.Write([]byte{0})
+		h.Write([]byte(lookup.find()))
+		h.Write([]byte{0})
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+
+	return sum
+}
+
+// envPath returns "PATH" from the job's environment or else from the OS.
+func envPath(envDict *starlark.Dict) string {
+	value, found, err := envDict.Get(starlark.String("PATH"))
+	if err == nil && found {
+		if s, ok := starlark.AsString(value); ok {
+			return s
+		}
+	}
+
+	return os.Getenv("PATH")
+}
+
+// isExecutable reports whether a path is a file that someone can execute.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
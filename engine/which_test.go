@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestWhich(t *testing.T) {
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "bin")
+	if err := os.Mkdir(binDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]os.FileMode{
+		"bin/tool":      0o755,
+		"bin/data":      0o644,
+		"local-script":  0o700,
+		"not-a-command": 0o600,
+	}
+	for name, mode := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	envDict, err := envToDict(map[string]string{"PATH": "/nonexistent:" + binDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	which := newWhich(dir, envDict)
+
+	tests := []struct {
+		name string
+		want starlark.Value
+	}{
+		{"tool", starlark.String(filepath.Join(binDir, "tool"))},
+		{"data", starlark.None},
+		{"missing", starlark.None},
+		{"", starlark.None},
+		{"./local-script", starlark.String(filepath.Join(dir, "local-script"))},
+		{"./not-a-command", starlark.None},
+		{filepath.Join(binDir, "tool"), starlark.String(filepath.Join(binDir, "tool"))},
+	}
+
+	thread := &starlark.Thread{Name: "test"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := starlark.Call(thread, which, starlark.Tuple{starlark.String(tt.name)}, nil)
+			if err != nil {
+				t.Fatalf("which() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("which(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJobWhich(t *testing.T) {
+	jobDir := filepath.Join(t.TempDir(), "backup")
+	if err := os.Mkdir(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	content := `
+enable = which("regular-test-missing-command") != None
+command = [which("sh") or "false"]
+`
+	jobPath := filepath.Join(jobDir, jobConfigFileName)
+	if err := os.WriteFile(jobPath, []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := loadJob(map[string]string{"PATH": os.Getenv("PATH")}, jobPath)
+	if err != nil {
+		t.Fatalf("loadJob() error = %v", err)
+	}
+
+	if job.Enable {
+		t.Error("Expected the job to disable itself")
+	}
+	if len(job.Command) != 1 || filepath.Base(job.Command[0]) != "sh" {
+		t.Errorf("Expected the command to be the path of sh, got %v", job.Command)
+	}
+}
+
+func TestUpdateReloadsWhenWhichChanges(t *testing.T) {
+	configRoot := t.TempDir()
+	binDir := t.TempDir()
+
+	path := writeTestJob(t, configRoot, "backup", `enable = which("regular-test-restic") != None`)
+	if err := os.WriteFile(filepath.Join(configRoot, "backup", jobEnvFileName), []byte("PATH="+binDir+"\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	jsc := NewScheduler()
+	_, job, err := jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if job.Enable {
+		t.Error("Expected the job to disable itself")
+	}
+
+	res, _, err := jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsNoChanges {
+		t.Errorf(`Expected "jobsNoChanges", got %v`, res)
+	}
+
+	// Installing the command reloads the job file.
+	if err := os.WriteFile(filepath.Join(binDir, "regular-test-restic"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	res, job, err = jsc.Update(configRoot, path)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if res != jobsUpdated {
+		t.Errorf(`Expected "jobsUpdated", got %v`, res)
+	}
+	if !job.Enable {
+		t.Error("Expected the job to enable itself")
+	}
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// Decision explains why the scheduler queued a job or didn't at a time.
+type Decision struct {
+	Time time.Time `msgpack:"time"`
+
+	// The keyword arguments "should_run" got and what it returned.
+	// They are empty when the scheduler didn't call "should_run".
+	ShouldRunArgs   string `msgpack:"should_run_args"`
+	ShouldRunResult string `msgpack:"should_run_result"`
+
+	Queued bool   `msgpack:"queued"`
+	Reason string `msgpack:"reason"`
+
+	Queue       string    `msgpack:"queue"`
+	QueuePaused time.Time `msgpack:"queue_paused"`
+
+	// When a queued job should start.
+	start time.Time
+}
+
+// formatShouldRunArgs formats the keyword arguments of "should_run" like a call.
+// The history is summarized as the number of runs.
+func formatShouldRunArgs(kwargs []starlark.Tuple, runs int) string {
+	args := make([]string, 0, len(kwargs))
+	for _, kwarg := range kwargs {
+		name := string(kwarg[0].(starlark.String))
+
+		value := kwarg[1].String()
+		if name == "history" {
+			value = fmt.Sprintf("[%d runs]", runs)
+		}
+
+		args = append(args, name+"="+value)
+	}
+
+	return strings.Join(args, ", ")
+}
+
+// decide works out whether the scheduler should queue the job at t and why.
+// It checks the same things in the same order as the scheduler.
+func (j JobConfig) decide(runner Runner, t time.Time) (Decision, error) {
+	d := Decision{Time: t, Queue: j.QueueName()}
+
+	d.QueuePaused = runner.pausedSince(d.Queue)
+
+	snoozed, err := runner.snoozed(j.Name, t)
+	if err != nil {
+		return d, err
+	}
+
+	if snoozed {
+		d.Reason = "the job is disabled with \"regular disable\""
+	} else if err := j.decideDue(runner, t, &d); err != nil {
+		return d, err
+	}
+
+	if !d.QueuePaused.IsZero() {
+		d.Reason += fmt.Sprintf("; queue %q is paused", d.Queue)
+	}
+
+	return d, nil
+}
+
+// decideDue fills in the decision for a job that isn't snoozed.
+func (j JobConfig) decideDue(runner Runner, t time.Time, d *Decision) error {
+	history, err := runner.History(j.Name)
+	if err != nil {
+		return err
+	}
+
+	start, due, call, err := j.callShouldRun(t, history)
+	d.ShouldRunArgs = call.args
+	d.ShouldRunResult = call.result
+	if err != nil {
+		return err
+	}
+
+	switch {
+
+	case !j.Enable:
+		d.Reason = fmt.Sprintf("%q is false", enableVar)
+
+	case !j.RunsOnThisHost():
+		d.Reason = fmt.Sprintf("the job doesn't run on this host (%q)", hostsVar)
+
+	case !due:
+		d.Reason = fmt.Sprintf("%q returned %s", shouldRunVar, call.result)
+
+	case runner.tooSoon(j.Name, history, t):
+		d.Reason = fmt.Sprintf("the last run started less than the minimum interval of %v ago", FormatDuration(runner.MinInterval))
+
+	case !j.Duplicate && runner.inQueue(j.Name):
+		d.Reason = "the job was already in its queue, so it wasn't added again"
+
+	default:
+		d.Queued = true
+		d.start = start
+		d.Reason = fmt.Sprintf("%q returned %s", shouldRunVar, call.result)
+		if start.After(t) {
+			d.Reason += fmt.Sprintf("; the job waits in its queue until %s", start.Format(time.DateTime))
+		}
+	}
+
+	return nil
+}
+
+// recordDecision keeps the last scheduling decision for a job.
+// It also keeps the last decision that queued the job,
+// so "why" can show it after later checks that didn't queue the job.
+func (r Runner) recordDecision(jobName string, d Decision) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.decisions[jobName] = d
+	if d.Queued {
+		r.queuedDecisions[jobName] = d
+	}
+}
+
+// LastDecision returns the last scheduling decision for a job.
+func (r Runner) LastDecision(jobName string) (Decision, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.decisions[jobName]
+	return d, ok
+}
+
+// LastQueuedDecision returns the last scheduling decision that queued a job.
+func (r Runner) LastQueuedDecision(jobName string) (Decision, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.queuedDecisions[jobName]
+	return d, ok
+}
+
+// inQueue reports whether a job is running or waiting in its queue.
+func (r Runner) inQueue(jobName string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, queue := range r.queues {
+		for _, job := range queue.jobs {
+			if job.Name == jobName {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Decide works out whether the scheduler would queue a job at t and why without queueing it.
+func (r Runner) Decide(job JobConfig, t time.Time) (Decision, error) {
+	return job.decide(r, t)
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestDecide(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	tmpDir := t.TempDir()
+
+	db, err := OpenAppDB(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create app database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := NewRunner(db, nil, tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create job runner: %v", err)
+	}
+
+	path := writeTestJob(t, tmpDir, "why", `
+def should_run(hour, **_):
+    return hour == 3
+`)
+	job, err := loadJob(denv.Env{}, path)
+	if err != nil {
+		t.Fatalf("loadJob() error = %v", err)
+	}
+
+	notDue := time.Date(2025, 1, 6, 2, 0, 0, 0, time.Local)
+	due := time.Date(2025, 1, 6, 3, 0, 0, 0, time.Local)
+
+	// The scheduler records why it didn't queue the job.
+	if err := job.AddToQueueIfDue(runner, notDue); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	d, ok := runner.LastDecision(job.Name)
+	if !ok {
+		t.Fatal("Expected a decision")
+	}
+	if d.Queued || !strings.Contains(d.ShouldRunArgs, "hour=2") || d.ShouldRunResult != "False" {
+		t.Errorf("Expected should_run(hour=2) to return False, got %+v", d)
+	}
+	if !strings.Contains(d.ShouldRunArgs, "history=[0 runs]") {
+		t.Errorf("Expected the history to be summarized, got %q", d.ShouldRunArgs)
+	}
+
+	if err := job.AddToQueueIfDue(runner, due); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	d, _ = runner.LastDecision(job.Name)
+	if !d.Queued || d.ShouldRunResult != "True" {
+		t.Errorf("Expected the job to be queued, got %+v", d)
+	}
+
+	// The job is already waiting in its queue.
+	d, err = runner.Decide(job, due)
+	if err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if d.Queued || !strings.Contains(d.Reason, "already in its queue") {
+		t.Errorf("Expected the job to be deduplicated, got %+v", d)
+	}
+
+	// A later check that doesn't queue the job keeps the decision that did.
+	if err := job.AddToQueueIfDue(runner, due.Add(time.Hour)); err != nil {
+		t.Fatalf("AddToQueueIfDue() error = %v", err)
+	}
+	if d, _ = runner.LastDecision(job.Name); d.Queued {
+		t.Errorf("Expected the last decision not to queue the job, got %+v", d)
+	}
+	d, ok = runner.LastQueuedDecision(job.Name)
+	if !ok || !d.Time.Equal(due) {
+		t.Errorf("Expected the decision that queued the job at %v, got %+v", due, d)
+	}
+
+	// "regular pause" and "regular disable" tell the daemon.
+	runner.rememberPausedQueue(job.QueueName(), due)
+	runner.rememberSnooze(Snooze{JobName: job.Name, Since: due})
+
+	d, err = runner.Decide(job, due)
+	if err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if d.Queued || d.ShouldRunArgs != "" || !strings.Contains(d.Reason, "regular disable") {
+		t.Errorf("Expected the snoozed job not to be queued, got %+v", d)
+	}
+	if d.QueuePaused.IsZero() || !strings.Contains(d.Reason, "is paused") {
+		t.Errorf("Expected the paused queue in the decision, got %+v", d)
+	}
+}
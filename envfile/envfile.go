@@ -0,0 +1,124 @@
+// Package envfile loads the environment for Regular jobs from env files.
+package envfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"dbohdan.com/denv"
+)
+
+// File is an env file to load.
+// Name identifies the file in error messages.
+// If Decrypt isn't nil, the file is encrypted and Decrypt turns it into plaintext.
+type File struct {
+	Name    string
+	Path    string
+	Decrypt Decrypter
+}
+
+// Decrypter returns the decrypted contents of the file at path.
+type Decrypter func(path string) ([]byte, error)
+
+// Load starts with the OS environment and merges the files into it in order.
+// Each file can refer to the variables set before it.
+// Files that don't exist are skipped.
+func Load(files ...File) (denv.Env, error) {
+	return LoadOver(denv.OS(), files...)
+}
+
+// LoadOver is like Load but starts with the base environment.
+func LoadOver(base denv.Env, files ...File) (denv.Env, error) {
+	env := base
+
+	for _, file := range files {
+		newEnv, err := loadFile(file, env)
+		if err == nil {
+			env = denv.Merge(env, newEnv)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load %s env file: %v", file.Name, err)
+		}
+	}
+
+	return env, nil
+}
+
+func loadFile(file File, env denv.Env) (denv.Env, error) {
+	if file.Decrypt == nil {
+		return denv.Load(file.Path, true, env)
+	}
+
+	if _, err := os.Stat(file.Path); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := file.Decrypt(file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadBytes(plaintext, env)
+}
+
+// loadBytes parses env file contents without writing them to disk.
+func loadBytes(data []byte, env denv.Env) (denv.Env, error) {
+	return throughPipe(data, func(path string) (denv.Env, error) {
+		return denv.Load(path, true, env)
+	})
+}
+
+// throughPipe calls load with a path to a pipe that has the data.
+// The read end is closed when load returns, so the writer gets EPIPE instead of blocking
+// when load doesn't read everything.
+// The writer has finished when throughPipe returns.
+func throughPipe(data []byte, load func(path string) (denv.Env, error)) (denv.Env, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := w.Write(data)
+		w.Close()
+		written <- err
+	}()
+
+	env, err := load(fmt.Sprintf("/dev/fd/%d", r.Fd()))
+	r.Close()
+
+	writeErr := <-written
+	if err != nil {
+		return nil, err
+	}
+	if writeErr != nil && !errors.Is(writeErr, syscall.EPIPE) {
+		return nil, fmt.Errorf("failed to write to pipe: %w", writeErr)
+	}
+
+	return env, nil
+}
+
+// Age returns a Decrypter that runs age(1) with the identity file.
+func Age(identity string) Decrypter {
+	return func(path string) ([]byte, error) {
+		if identity == "" {
+			return nil, fmt.Errorf("no age identity configured to decrypt %q", path)
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("age", "--decrypt", "--identity", identity, path)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("failed to decrypt %q: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+		}
+
+		return stdout.Bytes(), nil
+	}
+}
@@ -0,0 +1,116 @@
+package envfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dbohdan.com/denv"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ENVFILE_TEST_OS", "os")
+
+	globalPath := filepath.Join(dir, "global.env")
+	if err := os.WriteFile(globalPath, []byte("FOO=global\nBAR=global\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	jobPath := filepath.Join(dir, "job.env")
+	if err := os.WriteFile(jobPath, []byte("BAR=job\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := Load(
+		File{Name: "global", Path: globalPath},
+		File{Name: "job", Path: jobPath},
+		File{Name: "missing", Path: filepath.Join(dir, "missing.env")},
+	)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"ENVFILE_TEST_OS", "os"},
+		{"FOO", "global"},
+		{"BAR", "job"},
+	}
+
+	for _, tt := range tests {
+		if got := env[tt.key]; got != tt.expected {
+			t.Errorf("env[%q] = %q, want %q", tt.key, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadDecrypt(t *testing.T) {
+	dir := t.TempDir()
+
+	globalPath := filepath.Join(dir, "global.env")
+	if err := os.WriteFile(globalPath, []byte("FOO=global\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedPath := filepath.Join(dir, "env.age")
+	if err := os.WriteFile(encryptedPath, []byte("ciphertext"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stand-in for real decryption.
+	decrypt := func(path string) ([]byte, error) {
+		if path != encryptedPath {
+			t.Errorf("Decrypt called with %q", path)
+		}
+
+		return []byte("SECRET=${FOO}-secret\n"), nil
+	}
+
+	env, err := Load(
+		File{Name: "global", Path: globalPath},
+		File{Name: "encrypted", Path: encryptedPath, Decrypt: decrypt},
+		File{Name: "missing encrypted", Path: filepath.Join(dir, "missing.age"), Decrypt: decrypt},
+	)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got, want := env["SECRET"], "global-secret"; got != want {
+		t.Errorf(`env["SECRET"] = %q, want %q`, got, want)
+	}
+}
+
+func TestThroughPipeUnread(t *testing.T) {
+	// More than a pipe buffer holds, so the writer blocks until the data is read or the pipe is closed.
+	data := []byte(strings.Repeat("FOO=bar\n", 1<<17))
+	errParse := errors.New("parse error")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := throughPipe(data, func(path string) (denv.Env, error) {
+			return nil, errParse
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errParse) {
+			t.Errorf("throughPipe() error = %v, want %v", err, errParse)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("throughPipe() blocked on the unread data")
+	}
+}
+
+func TestAgeNoIdentity(t *testing.T) {
+	if _, err := Age("")("env.age"); err == nil {
+		t.Error("expected error without an identity")
+	}
+}
@@ -0,0 +1,67 @@
+package envfile
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"dbohdan.com/denv"
+)
+
+// KeyringPrefix marks an env value as a reference to a secret in the system keyring.
+// The rest of the value is "service/account".
+const KeyringPrefix = "keyring:"
+
+// keyringLookup fetches a secret from the keyring.
+// Tests replace it.
+var keyringLookup = lookupKeyring
+
+// ResolveKeyring returns a copy of env where every "keyring:service/account" value is replaced with the secret from the system keyring.
+func ResolveKeyring(env denv.Env) (denv.Env, error) {
+	resolved := make(denv.Env, len(env))
+
+	for key, value := range env {
+		ref, ok := strings.CutPrefix(value, KeyringPrefix)
+		if !ok {
+			resolved[key] = value
+			continue
+		}
+
+		service, account, ok := strings.Cut(ref, "/")
+		if !ok || service == "" || account == "" {
+			return nil, fmt.Errorf("invalid keyring reference in %s: %q isn't \"service/account\"", key, ref)
+		}
+
+		secret, err := keyringLookup(service, account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s from keyring: %w", key, err)
+		}
+
+		resolved[key] = secret
+	}
+
+	return resolved, nil
+}
+
+// lookupKeyring uses the Keychain on macOS and the Secret Service through secret-tool(1) elsewhere.
+func lookupKeyring(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	// secret-tool prints the secret as is; security adds a newline.
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
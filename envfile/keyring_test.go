@@ -0,0 +1,41 @@
+package envfile
+
+import (
+	"errors"
+	"testing"
+
+	"dbohdan.com/denv"
+)
+
+func TestResolveKeyring(t *testing.T) {
+	lookup := keyringLookup
+	t.Cleanup(func() {
+		keyringLookup = lookup
+	})
+
+	keyringLookup = func(service, account string) (string, error) {
+		if service == "backup" && account == "alice" {
+			return "hunter2", nil
+		}
+
+		return "", errors.New("not found")
+	}
+
+	env, err := ResolveKeyring(denv.Env{
+		"PASSWORD": "keyring:backup/alice",
+		"PLAIN":    "value",
+	})
+	if err != nil {
+		t.Fatalf("ResolveKeyring() error = %v", err)
+	}
+
+	if env["PASSWORD"] != "hunter2" || env["PLAIN"] != "value" {
+		t.Errorf("unexpected env: %v", env)
+	}
+
+	for _, value := range []string{"keyring:backup", "keyring:/alice", "keyring:backup/bob"} {
+		if _, err := ResolveKeyring(denv.Env{"PASSWORD": value}); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (e *ExportCmd) Run(config engine.Config) error {
+	if e.Weeks < 1 {
+		return fmt.Errorf("--weeks must be at least 1")
+	}
+
+	jobs, err := e.loadJobs(config)
+	if err != nil {
+		return err
+	}
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	now := time.Now()
+	until := now.AddDate(0, 0, 7*e.Weeks)
+
+	runs := []engine.PredictedRun{}
+	for _, job := range jobs {
+		snooze, err := activeSnooze(db, job.Name)
+		if err != nil {
+			return err
+		}
+
+		// A snoozed job only runs after the snooze ends.
+		from := now
+		if snooze != nil {
+			if snooze.Until.IsZero() {
+				continue
+			}
+
+			from = snooze.Until
+		}
+
+		history, err := db.History(job.Name, engine.ShouldRunHistoryLength)
+		if err != nil {
+			return fmt.Errorf("error getting history of job %q: %w", job.Name, err)
+		}
+
+		predicted, err := job.PredictRuns(from, until, history)
+		if err != nil {
+			return fmt.Errorf("failed to predict runs of job %q: %w", job.Name, err)
+		}
+
+		runs = append(runs, predicted...)
+	}
+
+	slices.SortStableFunc(runs, func(a, b engine.PredictedRun) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return engine.WriteICS(os.Stdout, runs, now)
+}
+
+// loadJobs loads the named jobs or every job sorted by name.
+// The calendar goes to stdout, so jobs that fail to load are reported on stderr and left out.
+func (e *ExportCmd) loadJobs(config engine.Config) ([]engine.JobConfig, error) {
+	jsc := engine.NewScheduler()
+
+	if len(e.JobNames) > 0 {
+		jobs := []engine.JobConfig{}
+		for _, name := range e.JobNames {
+			job, err := jsc.LoadJob(config.ConfigRoots(), name)
+			if err != nil {
+				return nil, err
+			}
+
+			jobs = append(jobs, *job)
+		}
+
+		return jobs, nil
+	}
+
+	names, err := engine.ListJobNames(config.ConfigRoots()...)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []engine.JobConfig{}
+	for _, name := range names {
+		loaded, err := jsc.LoadJobs(config.ConfigRoots(), name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping job %q: %v\n", name, err)
+			continue
+		}
+
+		for _, job := range loaded {
+			if !slices.ContainsFunc(jobs, func(j engine.JobConfig) bool { return j.Name == job.Name }) {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+
+	slices.SortFunc(jobs, func(a, b engine.JobConfig) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return jobs, nil
+}
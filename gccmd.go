@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (g *GCCmd) Run(config engine.Config) error {
+	grace := g.Grace
+	if grace == 0 {
+		settings, err := engine.LoadSettings(config.ConfigRoot)
+		if err != nil {
+			return err
+		}
+
+		grace = settings.GCGracePeriod()
+	}
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stale, err := engine.FindStaleJobs(db, config, grace, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(stale) == 0 {
+		fmt.Println("No deleted jobs older than", engine.FormatDuration(grace))
+		return nil
+	}
+
+	for _, job := range stale {
+		lastActive := job.LastActive.Format(timestampFormat)
+
+		if g.DryRun {
+			fmt.Printf("Would remove %s (last active %s)\n", job.Name, lastActive)
+			continue
+		}
+
+		if err := engine.RemoveJobState(db, config.StateRoot, job.Name); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed %s (last active %s)\n", job.Name, lastActive)
+	}
+
+	return nil
+}
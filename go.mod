@@ -6,7 +6,6 @@ require (
 	dbohdan.com/denv v0.1.0
 	github.com/adrg/xdg v0.5.3
 	github.com/alecthomas/kong v1.6.0
-	github.com/alecthomas/repr v0.4.0
 	github.com/bep/debounce v1.2.1
 	github.com/fatih/color v1.18.0
 	github.com/gofrs/flock v0.12.1
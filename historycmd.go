@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (h *HistoryCmd) Run(config engine.Config) error {
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runs, err := db.Runs(h.JobName, h.Dead, h.Lines)
+	if err != nil {
+		return fmt.Errorf("error reading job history: %w", err)
+	}
+
+	if len(runs) == 0 {
+		if h.Dead {
+			fmt.Println("No dead runs recorded")
+		} else {
+			fmt.Println("No runs recorded")
+		}
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tJOB\tSTARTED\tDURATION\tATTEMPT\tARTIFACTS\tRESULT")
+
+	for _, run := range runs {
+		artifacts, err := engine.RunArtifacts(config.StateRoot, run.JobName, run.ID)
+		if err != nil {
+			return err
+		}
+
+		artifactCount := "-"
+		if len(artifacts) > 0 {
+			artifactCount = fmt.Sprint(len(artifacts))
+		}
+
+		fmt.Fprintf(
+			w,
+			"%d\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			run.ID,
+			run.JobName,
+			run.Started.Format(timestampFormat),
+			engine.FormatDuration(run.Finished.Sub(run.Started)),
+			run.Attempt,
+			artifactCount,
+			runResult(run.CompletedJob),
+		)
+	}
+
+	return w.Flush()
+}
+
+// runResult summarizes how a run ended.
+func runResult(completed engine.CompletedJob) string {
+	result := "ok"
+	switch {
+	case completed.Skipped:
+		result = "skipped"
+	case completed.Signal != 0:
+		result = "killed by " + completed.SignalName()
+	case completed.Error != "" && completed.ExitStatus == 0:
+		result = "error: " + completed.Error
+	case !completed.IsSuccess():
+		result = fmt.Sprintf("exit status %d", completed.ExitStatus)
+	}
+
+	if completed.ExitMeaning != "" {
+		result += ": " + completed.ExitMeaning
+	}
+
+	if completed.Dead {
+		result = "dead, " + result
+	}
+
+	return result
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (i *InitCmd) Run(config engine.Config) error {
+	path, err := engine.InitJob(config, i.JobName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s\n", path)
+	fmt.Printf("Try it with: regular run --force %s\n", i.JobName)
+
+	return nil
+}
@@ -1,297 +0,0 @@
-package main
-
-import (
-	"context"
-	"errors"
-	"fmt"
-	"io"
-	"math/rand"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
-	"sync"
-	"time"
-
-	"dbohdan.com/denv"
-)
-
-type jobRunner struct {
-	db        *appDB
-	notify    notifyWhenDone
-	queues    map[string]jobQueue
-	stateRoot string
-
-	mu *sync.Mutex
-}
-
-func newJobRunner(db *appDB, notify notifyWhenDone, stateRoot string) (jobRunner, error) {
-	return jobRunner{
-		db:        db,
-		notify:    notify,
-		queues:    make(map[string]jobQueue),
-		stateRoot: stateRoot,
-		mu:        &sync.Mutex{},
-	}, nil
-}
-
-func (r jobRunner) lastCompleted(jobName string) (*CompletedJob, error) {
-	completed, err := r.db.getLastCompleted(jobName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get last completed job for %q: %w", jobName, err)
-	}
-
-	return completed, nil
-}
-
-func (r jobRunner) addJob(job JobConfig) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	queueName := job.QueueName()
-
-	queue, ok := r.queues[queueName]
-	if !ok {
-		queue = newJobQueue()
-		r.queues[queueName] = queue
-	}
-
-	if !job.Duplicate {
-		for _, otherJob := range queue.jobs {
-			if otherJob.Name == job.Name {
-				return
-			}
-		}
-	}
-
-	queue.jobs = append(queue.jobs, job)
-	r.queues[queueName] = queue
-
-	// Report the queue length before the job was added.
-	if len(queue.jobs) == 1 {
-		logJobPrintf(
-			job.Name,
-			"Put job in empty runner queue: %v",
-			queueName,
-		)
-	} else {
-		logJobPrintf(
-			job.Name,
-			"Put job in runner queue of length %v: %v",
-			len(queue.jobs)-1,
-			queueName,
-		)
-	}
-}
-
-func (r jobRunner) activateQueueHead(queueName string) (*JobConfig, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	queue, ok := r.queues[queueName]
-
-	if !ok {
-		return nil, fmt.Errorf("requested to run head of nonexistent queue: %v", queueName)
-	}
-
-	if queue.activeJob || len(queue.jobs) == 0 {
-		return nil, nil
-	}
-
-	job := queue.jobs[0]
-
-	queue.activeJob = true
-	r.queues[queueName] = queue
-
-	return &job, nil
-}
-
-func (r jobRunner) runQueueHead(queueName string) error {
-	job, err := r.activateQueueHead(queueName)
-	if err != nil {
-		return err
-	}
-	if job == nil {
-		return nil
-	}
-
-	jobStateDir := filepath.Join(r.stateRoot, job.Name)
-
-	if job.Jitter > 0 {
-		sleepDuration := time.Duration(job.Jitter.Seconds()*rand.Float64()) * time.Second
-		logJobPrintf(job.Name, "Waiting %v before start", formatDuration(sleepDuration))
-
-		time.Sleep(sleepDuration)
-	}
-
-	cj := CompletedJob{}
-	cj.Started = time.Now()
-	logJobPrintf(job.Name, "Started")
-
-	stdoutFilePath := filepath.Join(jobStateDir, stdoutFileName)
-	stderrFilePath := filepath.Join(jobStateDir, stderrFileName)
-
-	runErr := func() error {
-		var stdoutFile, stderrFile io.Writer
-		if job.Log {
-			if err := os.MkdirAll(jobStateDir, dirPerms); err != nil {
-				return fmt.Errorf("failed to create job state directory: %w", err)
-			}
-
-			stdoutF, err := os.OpenFile(
-				stdoutFilePath,
-				os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
-				filePerms,
-			)
-			if err != nil {
-				return fmt.Errorf("failed to create stdout log file: %w", err)
-			}
-			defer stdoutF.Close()
-			stdoutFile = stdoutF
-
-			stderrF, err := os.OpenFile(
-				stderrFilePath,
-				os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
-				filePerms,
-			)
-			if err != nil {
-				return fmt.Errorf("failed to create stderr log file: %w", err)
-			}
-			defer stderrF.Close()
-			stderrFile = stderrF
-		}
-
-		// Tee output to optional extra writers (e.g., a socket client).
-		if job.Stdout != nil {
-			stdoutFile = teeOptional(stdoutFile, job.Stdout)
-		}
-		if job.Stderr != nil {
-			stderrFile = teeOptional(stderrFile, job.Stderr)
-		}
-
-		jobDir := job.Env[jobDirEnvVar]
-		return runCommand(job.Name, job.Env, jobDir, job.Command, job.Timeout, nil, stdoutFile, stderrFile)
-	}()
-
-	cj.Error = ""
-	if runErr != nil {
-		cj.Error = runErr.Error()
-	}
-	var exitErr *exec.ExitError
-	if errors.As(runErr, &exitErr) {
-		cj.ExitStatus = exitErr.ExitCode()
-	}
-
-	logJobPrintf(job.Name, "Finished")
-	cj.Finished = time.Now()
-
-	r.mu.Lock()
-	queue, ok := r.queues[queueName]
-	if ok {
-		queue.activeJob = false
-		queue.jobs = queue.jobs[1:]
-		r.queues[queueName] = queue
-	}
-	r.mu.Unlock()
-
-	saveErr := r.db.saveCompletedJob(job.Name, cj, []logFile{
-		{name: "stdout", path: stdoutFilePath},
-		{name: "stderr", path: stderrFilePath},
-	})
-	notifyErr := notifyIfNeeded(r.notify, job.Notify, job.Name, cj)
-
-	if job.OnComplete != nil {
-		job.OnComplete(cj)
-	}
-
-	if notifyErr != nil {
-		return newJobError(job.Name, fmt.Errorf("failed to notify about completed job: %w", notifyErr))
-	}
-
-	if saveErr != nil {
-		return newJobError(job.Name, fmt.Errorf("failed to save completed job: %w", saveErr))
-	}
-
-	if runErr != nil {
-		return newJobError(job.Name, fmt.Errorf("command failed: %w", runErr))
-	}
-
-	return nil
-}
-
-// teeOptional returns extra alone if base is nil, otherwise an io.MultiWriter
-// of both. Avoids creating a MultiWriter wrapping a nil base, which exec.Cmd
-// would treat as a non-nil writer and fail to send to /dev/null.
-func teeOptional(base, extra io.Writer) io.Writer {
-	if base == nil {
-		return extra
-	}
-	return io.MultiWriter(base, extra)
-}
-
-func (r jobRunner) run() {
-	ticker := time.NewTicker(runInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		names := []string{}
-
-		r.mu.Lock()
-		for queueName, _ := range r.queues {
-			names = append(names, queueName)
-		}
-		r.mu.Unlock()
-
-		for _, queueName := range names {
-			go withLog(func() error {
-				return r.runQueueHead(queueName)
-			})
-		}
-	}
-}
-
-// This function doesn't lock the runner or the queues.
-// It is left to the caller.
-func (r jobRunner) summarize() string {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	var sb strings.Builder
-
-	for queueName, queue := range r.queues {
-		sb.WriteString(queueName + ": ")
-
-		for i, job := range queue.jobs {
-			sb.WriteString(job.Name)
-
-			if i != len(queue.jobs)-1 {
-				sb.WriteString(", ")
-			}
-		}
-
-		sb.WriteString("\n")
-	}
-
-	return sb.String()
-}
-
-func runCommand(jobName string, env denv.Env, dir string, cmd []string, timeout time.Duration, stdin io.Reader, stdout, stderr io.Writer) error {
-	if len(cmd) == 0 {
-		return fmt.Errorf("empty command")
-	}
-
-	ctx := context.Background()
-	if timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, timeout)
-		defer cancel()
-	}
-
-	c := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
-	c.Dir = dir
-	c.Env = env.Strings()
-	c.Stdin = stdin
-	c.Stdout = stdout
-	c.Stderr = stderr
-
-	return c.Run()
-}
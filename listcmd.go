@@ -3,24 +3,85 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"dbohdan.com/regular/engine"
 )
 
-func (l *ListCmd) Run(config Config) error {
-	entries, err := os.ReadDir(config.ConfigRoot)
+func (l *ListCmd) Run(config engine.Config) error {
+	if l.Names {
+		return l.printNames(config)
+	}
+
+	jobs, _, err := engine.LoadJobInfo(config)
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(jobs, func(a, b engine.JobInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	db, err := engine.OpenAppDB(config.StateRoot)
 	if err != nil {
-		return fmt.Errorf("failed to read config directory: %w", err)
+		return err
 	}
+	defer db.Close()
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tENABLED\tQUEUE\tNOTIFY\tSCHEDULE\tLAST EXIT")
+
+	for _, job := range jobs {
+		completed, err := db.LastCompleted(job.Name)
+		if err != nil {
+			return fmt.Errorf("error getting last completed job %q: %w", job.Name, err)
+		}
+
+		lastExit := "-"
+		if completed != nil {
+			lastExit = strconv.Itoa(completed.ExitStatus)
+			if completed.Error != "" && completed.ExitStatus == 0 {
+				lastExit = "error"
+			}
 		}
 
-		jobFile := filepath.Join(config.ConfigRoot, entry.Name(), jobConfigFileName)
-		if _, err := os.Stat(jobFile); err == nil {
-			fmt.Println(entry.Name())
+		notify := string(job.Notify)
+		if notify == "" {
+			notify = string(engine.NotifyOnFailure)
 		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", job.Name, boolYesNo(job.Enable), job.Queue, notify, job.Schedule, lastExit)
+	}
+
+	return w.Flush()
+}
+
+// printNames prints the job names loading only the job files that may define several jobs.
+// It is fast and works when a job fails to load, so shell completion uses it.
+func (l *ListCmd) printNames(config engine.Config) error {
+	daemonJobs, fromDaemon, err := engine.QueryDaemonJobs()
+	if err != nil {
+		return err
+	}
+
+	if fromDaemon {
+		for _, job := range daemonJobs {
+			fmt.Println(job.Name)
+		}
+
+		return nil
+	}
+
+	names, err := engine.ListJobNames(config.ConfigRoots()...)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
 	}
 
 	return nil
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"dbohdan.com/regular/engine"
 	"github.com/nxadm/tail"
 )
 
-func (l *LogCmd) Run(config Config) error {
+func (l *LogCmd) Run(config engine.Config) error {
 	logPath := filepath.Join(config.StateRoot, appLogFileName)
+
+	if l.Job != "" {
+		return l.printJobLog(config, logPath)
+	}
+
 	lines, err := tailFile(logPath, l.LogLines)
 
 	if err != nil {
@@ -64,3 +74,127 @@ func tailFile(path string, maxLines int) ([]string, error) {
 
 	return lines, nil
 }
+
+// printJobLog prints the app log lines about a job.
+// The output of each run follows the line the run finished at, since the database doesn't store when each line was written.
+func (l *LogCmd) printJobLog(config engine.Config, logPath string) error {
+	lines, err := jobLogLines(logPath, l.Job, l.LogLines)
+	if err != nil {
+		return fmt.Errorf("error reading log file: %w", err)
+	}
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runs, err := db.Runs(l.Job, false, l.LogLines)
+	if err != nil {
+		return fmt.Errorf("error getting runs of job %q: %w", l.Job, err)
+	}
+	// Oldest first.
+	slices.Reverse(runs)
+
+	// Skipped runs have no output.
+	runs = slices.DeleteFunc(runs, func(run engine.JobRun) bool {
+		return run.Skipped
+	})
+
+	// Show the runs from the time of the first line or, without lines, the latest run.
+	if len(lines) > 0 {
+		runs = slices.DeleteFunc(runs, func(run engine.JobRun) bool {
+			return run.Finished.Truncate(time.Second).Before(lines[0].time)
+		})
+	} else if len(runs) > 0 {
+		runs = runs[len(runs)-1:]
+	}
+
+	if len(lines) == 0 && len(runs) == 0 {
+		fmt.Printf("No log lines or runs for job %q\n", l.Job)
+		return nil
+	}
+
+	for _, line := range lines {
+		for len(runs) > 0 && runs[0].Finished.Truncate(time.Second).Before(line.time) {
+			if err := printRunOutput(db, runs[0]); err != nil {
+				return err
+			}
+			runs = runs[1:]
+		}
+
+		fmt.Println(line.text)
+	}
+
+	for _, run := range runs {
+		if err := printRunOutput(db, run); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printRunOutput prints the stdout and the stderr of a run stored in the database.
+func printRunOutput(db *engine.AppDB, run engine.JobRun) error {
+	for _, logName := range []string{"stdout", "stderr"} {
+		output, err := db.RunLog(run.ID, logName)
+		if err != nil {
+			return fmt.Errorf("error loading %s for job %q: %w", logName, run.JobName, err)
+		}
+
+		if len(output) == 0 {
+			continue
+		}
+
+		fmt.Printf("[%s] [%s] %s of run %d:\n", run.Finished.Format(timestampFormat), run.JobName, logName, run.ID)
+		for _, text := range output {
+			fmt.Println("    " + text)
+		}
+	}
+
+	return nil
+}
+
+type logLine struct {
+	time time.Time
+	text string
+}
+
+// jobLogLines returns the last maxLines lines of the app log about a job.
+func jobLogLines(path, jobName string, maxLines int) ([]logLine, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Lines look like "[<timestamp>] [<job>] <message>".
+	tag := "] [" + jobName + "] "
+
+	lines := []logLine{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		stamp, rest, ok := strings.Cut(strings.TrimPrefix(text, "["), "] ")
+		if !ok || !strings.HasPrefix("] "+rest, tag) {
+			continue
+		}
+
+		t, err := time.Parse(timestampFormat, stamp)
+		if err != nil {
+			continue
+		}
+
+		lines = append(lines, logLine{time: t, text: text})
+		if len(lines) > maxLines {
+			lines = lines[1:]
+		}
+	}
+
+	return lines, scanner.Err()
+}
@@ -1,6 +0,0 @@
-package main
-
-type logFile struct {
-	name string
-	path string
-}
@@ -6,43 +6,174 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
-	"unicode"
-	"unicode/utf8"
 
+	"dbohdan.com/regular/engine"
 	"github.com/alecthomas/kong"
 )
 
-type ListCmd struct{}
+type AddCmd struct {
+	Every    time.Duration `help:"Run the job at this interval" xor:"schedule"`
+	DailyAt  string        `help:"Run the job once a day at this time (HH:MM)" xor:"schedule"`
+	Timezone string        `help:"Time zone for --daily-at (the local time zone by default)"`
+	Cron     string        `help:"Run the job on this crontab schedule" xor:"schedule"`
+	JobName  string        `arg:"" help:"Name of the job to create"`
+	Command  []string      `arg:"" passthrough:"" help:"Command to run (put \"--\" before it)"`
+}
+
+type AuditCmd struct {
+	Lines   int    `help:"Number of entries to show" short:"n" default:"20"`
+	JobName string `arg:"" optional:"" help:"Job to show changes for (shows all changes if none specified)"`
+}
+
+type BackupCmd struct {
+	Path string `arg:"" help:"Path to the backup file to create" type:"path"`
+}
+
+type CatLogCmd struct {
+	RunID   int64  `name:"run" help:"ID of the run to show (the latest run that wasn't skipped if 0)" default:"0"`
+	Stderr  bool   `help:"Show stderr instead of stdout"`
+	JobName string `arg:"" help:"Job to show output for"`
+}
+
+type DisableCmd struct {
+	Until    string        `help:"Enable the jobs again at this time (YYYY-MM-DD, YYYY-MM-DD HH:MM, or RFC 3339)" xor:"end"`
+	For      time.Duration `help:"Enable the jobs again after this long" xor:"end"`
+	JobNames []string      `arg:"" help:"Jobs to disable"`
+}
+
+type EnableCmd struct {
+	JobNames []string `arg:"" help:"Jobs to enable"`
+}
+
+type ExportCmd struct {
+	Format   string   `help:"Format of the export (${enum})" enum:"ics" default:"ics"`
+	Weeks    int      `help:"How many weeks of runs to predict" short:"w" default:"4"`
+	JobNames []string `arg:"" optional:"" help:"Jobs to export (exports all jobs if none specified)"`
+}
+
+type GCCmd struct {
+	DryRun bool          `short:"n" help:"Only print what would be removed"`
+	Grace  time.Duration `help:"Keep the state of jobs active within this time (overrides gc_grace in the settings)"`
+}
+
+type HistoryCmd struct {
+	Dead    bool   `help:"Only show runs that failed on their last retry"`
+	Lines   int    `help:"Number of runs to show" short:"n" default:"20"`
+	JobName string `arg:"" optional:"" help:"Job to show runs for (shows all jobs if none specified)"`
+}
+
+type ValidateCmd struct {
+	JobNames []string `arg:"" optional:"" help:"Jobs to validate (validates all jobs if none specified)"`
+}
+
+type WhyCmd struct {
+	JobName string `arg:"" help:"Job to explain"`
+}
+
+type InitCmd struct {
+	JobName string `arg:"" optional:"" help:"Name of the job to create" default:"example"`
+}
+
+type ListCmd struct {
+	Names bool `help:"Only print job names"`
+}
 
 type LogCmd struct {
-	LogLines int `help:"Number of log lines to show" short:"l" default:"${defaultLogLines}"`
+	Job      string `help:"Show the log lines about a job interleaved with the output of its runs"`
+	LogLines int    `help:"Number of log lines to show" short:"l" default:"${defaultLogLines}"`
+}
+
+type NotifyTestCmd struct {
+	JobName string `arg:"" optional:"" help:"Job to send a test notification for (uses a placeholder job name if none specified)"`
+}
+
+type PauseCmd struct {
+	Queues []string `arg:"" help:"Queues to pause"`
+}
+
+type ResumeCmd struct {
+	Queues []string `arg:"" help:"Queues to resume"`
+}
+
+type RestoreCmd struct {
+	Path string `arg:"" help:"Path to the backup file" type:"path"`
 }
 
 type RunCmd struct {
+	All      bool     `short:"a" help:"Run all jobs"`
+	Env      []string `short:"e" help:"Set an environment variable for the run as KEY=VALUE (repeatable)" placeholder:"KEY=VALUE"`
+	EnvFile  string   `help:"Load environment variables for the run from an env file" type:"path"`
 	Force    bool     `short:"f" help:"Run jobs regardless of schedule"`
+	Tags     []string `name:"tag" short:"t" help:"Run jobs with this tag (repeatable)"`
+	NoJitter bool     `help:"Start jobs immediately without the random delay"`
+	Parallel int      `short:"p" help:"Number of queues to run at the same time" default:"1"`
 	JobNames []string `arg:"" optional:"" help:"Job names to run"`
 }
 
-type StartCmd struct{}
+type StartCmd struct {
+	ForegroundLogs bool          `help:"Log to stderr without timestamps for a service manager like systemd (the default when stderr is connected to the journal); the app log file is still written unless --output is \"-\""`
+	Listen         string        `help:"Address for the HTTP API to listen on (for example, \"127.0.0.1:8700\"; disabled if empty)"`
+	DebugListen    string        `help:"Address for the expvar and pprof debug endpoint to listen on (disabled if empty)"`
+	ClockOffset    time.Duration `help:"Shift the time the scheduler sees by this much to try out schedules (for example, \"5h\" or \"-30m\"; requires a non-default --state-dir)"`
+}
+
+type RenameCmd struct {
+	OldName string `arg:"" help:"Current name of the job"`
+	NewName string `arg:"" help:"New name of the job"`
+}
+
+type StatsCmd struct {
+	Period   time.Duration `help:"How far back to look" default:"168h"`
+	Recent   int           `help:"How many of the latest runs to compare with the earlier ones" default:"5"`
+	Trend    bool          `help:"Compare the latest run times with the earlier ones and flag slowdowns" xor:"mode"`
+	ByHost   bool          `help:"Show which hosts ran which jobs and when" xor:"mode"`
+	JobNames []string      `arg:"" optional:"" help:"Jobs to show stats for (shows all jobs if none specified)"`
+}
 
 type StatusCmd struct {
+	Disabled bool     `help:"Show disabled jobs"`
+	Failed   bool     `help:"Show jobs whose last run failed"`
+	Running  bool     `help:"Show running jobs (requires a running scheduler)"`
 	LogLines int      `help:"Number of log lines to show" short:"l" default:"${defaultLogLines}"`
+	Events   int      `help:"Number of scheduler events to show" default:"5"`
 	JobNames []string `arg:"" optional:"" help:"Jobs to show status for (shows all jobs if none specified)"`
 }
 
 type CLI struct {
-	List   ListCmd   `cmd:"" help:"List available jobs"`
-	Log    LogCmd    `cmd:"" help:"Show application log"`
-	Run    RunCmd    `cmd:"" help:"Run jobs once"`
-	Start  StartCmd  `cmd:"" help:"Start scheduler"`
-	Status StatusCmd `cmd:"" help:"Show job status"`
-
-	Version    VersionFlag `short:"V" help:"Print version number and exit"`
-	ConfigRoot string      `name:"config-dir" short:"c" help:"Path to config directory" default:"${defaultConfigRoot}" type:"path"`
-	Output     string      `short:"o" help:"Path to text file where to write the log in addition to stdout (\"-\" for only stdout)" default:"${defaultLogPath}" type:"path"`
-	StateRoot  string      `name:"state-dir" short:"s" help:"Path to state directory" default:"${defaultStateRoot}" type:"path"`
+	Add        AddCmd        `cmd:"" help:"Create a job from a command line"`
+	Audit      AuditCmd      `cmd:"" help:"Show configuration changes"`
+	Backup     BackupCmd     `cmd:"" help:"Back up the state database"`
+	CatLog     CatLogCmd     `cmd:"" help:"Show the complete output of a job run"`
+	Disable    DisableCmd    `cmd:"" help:"Stop scheduling jobs until a time or until enabled"`
+	Enable     EnableCmd     `cmd:"" help:"Resume scheduling disabled jobs"`
+	Export     ExportCmd     `cmd:"" help:"Export the predicted runs of jobs as a calendar"`
+	GC         GCCmd         `cmd:"" name:"gc" help:"Remove the state of deleted jobs"`
+	History    HistoryCmd    `cmd:"" help:"Show recent runs"`
+	Init       InitCmd       `cmd:"" help:"Create an example job"`
+	List       ListCmd       `cmd:"" help:"List available jobs"`
+	Log        LogCmd        `cmd:"" help:"Show application log"`
+	NotifyTest NotifyTestCmd `cmd:"" help:"Send a test notification"`
+	Pause      PauseCmd      `cmd:"" help:"Stop starting the jobs waiting in queues"`
+	Rename     RenameCmd     `cmd:"" help:"Rename a job and keep its history"`
+	Restore    RestoreCmd    `cmd:"" help:"Restore the state database from a backup"`
+	Resume     ResumeCmd     `cmd:"" help:"Start the jobs in paused queues again"`
+	Run        RunCmd        `cmd:"" help:"Run jobs once"`
+	Start      StartCmd      `cmd:"" help:"Start scheduler"`
+	Stats      StatsCmd      `cmd:"" help:"Show run statistics and queue load"`
+	Status     StatusCmd     `cmd:"" help:"Show job status"`
+	Validate   ValidateCmd   `cmd:"" help:"Check job files for errors and unknown variables"`
+	Why        WhyCmd        `cmd:"" help:"Explain why the scheduler queued a job or didn't"`
+
+	Version     VersionFlag `short:"V" help:"Print version number and exit"`
+	Color       string      `help:"When to color the output (${enum})" enum:"auto,always,never" default:"auto"`
+	ConfigRoots []string    `name:"config-dir" short:"c" help:"Path to config directory (repeat or separate with \":\" for more; later directories override earlier ones)" default:"${defaultConfigRoot}" sep:":" type:"path"`
+	Output      string      `short:"o" help:"Path to text file where to write the log in addition to stdout (\"-\" for only stdout and no log file; the default is app.log in the state directory)" type:"path"`
+	StateRoot   string      `name:"state-dir" short:"s" help:"Path to state directory" default:"${defaultStateRoot}" type:"path"`
 }
 
 type VersionFlag string
@@ -62,33 +193,12 @@ func (v VersionFlag) BeforeApply(app *kong.Kong, vars kong.Vars) error {
 	return nil
 }
 
-func capitalizeFirst(s string) string {
-	if s == "" {
-		return s
-	}
-
-	r, size := utf8.DecodeRuneInString(s)
-	if r == utf8.RuneError {
-		return s
-	}
-
-	return string(unicode.ToUpper(r)) + s[size:]
-}
-
-func withLog(f func() error) {
-	if err := f(); err != nil {
-		msg := capitalizeFirst(err.Error())
-
-		if je, ok := err.(*JobError); ok {
-			logJobPrintf(je.JobName, "%v", msg)
-		} else {
-			log.Printf("%v", msg)
-		}
-	}
-}
-
 type logWriter struct {
 	tee io.StringWriter
+
+	// Write messages to stderr without timestamps instead of to stdout.
+	// The service manager adds its own timestamps.
+	foreground bool
 }
 
 func (writer *logWriter) Write(bytes []byte) (int, error) {
@@ -101,12 +211,32 @@ func (writer *logWriter) Write(bytes []byte) (int, error) {
 		}
 	}
 
+	if writer.foreground {
+		return os.Stderr.Write(bytes)
+	}
+
 	return fmt.Print(formattedMsg)
 }
 
-func logJobPrintf(job, format string, v ...any) {
-	values := append([]any{job}, v...)
-	log.Printf("[%s] "+format, values...)
+// underJournal reports whether stderr is connected to the systemd journal.
+// systemd sets JOURNAL_STREAM to the device and inode of the stream.
+func underJournal() bool {
+	journalStream := os.Getenv("JOURNAL_STREAM")
+	if journalStream == "" {
+		return false
+	}
+
+	info, err := os.Stderr.Stat()
+	if err != nil {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+
+	return journalStream == fmt.Sprintf("%d:%d", stat.Dev, stat.Ino)
 }
 
 func main() {
@@ -117,8 +247,6 @@ func run() int {
 	log.SetFlags(0)
 	log.SetOutput(&logWriter{tee: nil})
 
-	defaultLogPath := filepath.Join(defaultStateRoot, appLogFileName)
-
 	cli := CLI{}
 	ctx := kong.Parse(&cli,
 		kong.Name("regular"),
@@ -136,42 +264,54 @@ func run() int {
 		kong.Vars{
 			"defaultConfigRoot": defaultConfigRoot,
 			"defaultLogLines":   strconv.Itoa(defaultLogLines),
-			"defaultLogPath":    defaultLogPath,
 			"defaultStateRoot":  defaultStateRoot,
 		},
 	)
 
-	config := Config{
-		ConfigRoot: cli.ConfigRoot,
-		StateRoot:  cli.StateRoot,
+	setColorMode(cli.Color)
+
+	config := engine.Config{
+		ConfigRoot:       cli.ConfigRoots[0],
+		ExtraConfigRoots: cli.ConfigRoots[1:],
+		StateRoot:        cli.StateRoot,
 	}
 
 	command := ctx.Command()
-	if command == "run" || command == "start" {
-		if err := createDirectories(config); err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			return exitError
-		}
+
+	foreground := command == "start" && (cli.Start.ForegroundLogs || underJournal())
+	log.SetOutput(&logWriter{tee: nil, foreground: foreground})
+
+	// Set up on the first run, so every command works.
+	created, err := engine.CreateDirectories(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	for _, dir := range created {
+		fmt.Fprintf(os.Stderr, "Created %s\n", dir)
+	}
+	if slices.Contains(created, config.ConfigRoot) && !strings.HasPrefix(command, "add") && !strings.HasPrefix(command, "init") {
+		fmt.Fprintln(os.Stderr, "Run \"regular init example\" to create an example job")
+	}
+
+	// The default log file is in the state directory from the flags, which exists by now.
+	logPath := cli.Output
+	if logPath == "" {
+		logPath = filepath.Join(config.StateRoot, appLogFileName)
 	}
 
-	if cli.Output != "-" {
-		logFile, err := os.OpenFile(cli.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerms)
+	if logPath != "-" {
+		logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerms)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to open app log file: %v\n", err)
 			return exitError
 		}
 		defer logFile.Close()
 
-		log.SetOutput(&logWriter{tee: logFile})
+		log.SetOutput(&logWriter{tee: logFile, foreground: foreground})
 	}
 
-	db, err := openAppDB(cli.StateRoot)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open app database: %v\n", err)
-		return exitError
-	}
-	defer db.close()
-
+	// Each command opens the app database in config.StateRoot itself if it needs it.
 	if err := ctx.Run(config); err != nil {
 		log.Print(err)
 		return exitError
@@ -179,3 +319,59 @@ func run() int {
 
 	return exitOK
 }
+
+// configureRunner applies the global settings and the completion hook to a runner.
+func configureRunner(runner *engine.Runner, config engine.Config) error {
+	settings, err := engine.LoadSettings(config.ConfigRoot)
+	if err != nil {
+		return err
+	}
+
+	runner.MinFreeSpace = settings.MinFreeSpace
+	runner.LowSpace, err = engine.ParseLowSpacePolicy(settings.LowSpace)
+	if err != nil {
+		return err
+	}
+
+	runner.MinInterval = time.Duration(settings.MinInterval) * time.Second
+	runner.OnComplete = engine.PublishMQTT(config.ConfigRoot)
+
+	return nil
+}
+
+// namedNotifier is a notification channel with a stable name for the retry queue and notify-test.
+type namedNotifier struct {
+	name   string
+	notify engine.NotifyWhenDone
+}
+
+// notificationChannels returns email, the backends in the settings, and the user's notifier plugins.
+func notificationChannels(db *engine.AppDB, config engine.Config) []namedNotifier {
+	return []namedNotifier{
+		{"email", engine.NotifyUserByEmail(db, config.ConfigRoot)},
+		{"backends", engine.NotifyBackends(db, config.ConfigRoot)},
+		{"plugins", engine.NotifyPlugins(db, config.ConfigRoot)},
+	}
+}
+
+// notifiers combines the notification channels and queues their failed notifications for retry.
+// Failure emails go through the batcher, which the caller must flush before exiting.
+func notifiers(db *engine.AppDB, config engine.Config) (engine.NotifyWhenDone, *engine.RetryQueue, *engine.EmailBatcher) {
+	retries := engine.NewRetryQueue(db)
+	var batcher *engine.EmailBatcher
+
+	wrapped := []engine.NotifyWhenDone{}
+	for _, channel := range notificationChannels(db, config) {
+		notify := retries.Wrap(channel.name, channel.notify)
+
+		// Retried emails are sent one by one.
+		if channel.name == "email" {
+			batcher = engine.NewEmailBatcher(db, config.ConfigRoot, notify)
+			notify = batcher.Notify
+		}
+
+		wrapped = append(wrapped, notify)
+	}
+
+	return engine.NotifyAll(wrapped...), retries, batcher
+}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 var (
@@ -119,6 +123,208 @@ func TestLogCommand(t *testing.T) {
 	}
 }
 
+func TestLogJob(t *testing.T) {
+	tempDir := createTempDir(t)
+	jobDir := filepath.Join(tempDir, "config", "echo")
+	if err := os.Mkdir(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "command = [\"echo\", \"job-output\"]\nnotify = \"never\"\n"
+	if err := os.WriteFile(filepath.Join(jobDir, "config.star"), []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, stderr, err := commandWithDirs(tempDir, "run", "--force", "--no-jitter", "echo"); err != nil {
+		t.Fatalf("run failed: %v: %s", err, stderr)
+	}
+
+	now := time.Now()
+	appLog := strings.Join([]string{
+		"[" + now.Add(-time.Minute).Format(timestampFormat) + "] [echo] Before",
+		"[" + now.Format(timestampFormat) + "] [other] Unrelated",
+		"[" + now.Add(time.Minute).Format(timestampFormat) + "] [echo] After",
+	}, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "state", appLogFileName), []byte(appLog), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := commandWithDirs(tempDir, "log", "--job", "echo")
+	if err != nil {
+		t.Fatalf("log --job failed: %v: %s", err, stderr)
+	}
+
+	before := strings.Index(stdout, "Before")
+	output := strings.Index(stdout, "job-output")
+	after := strings.Index(stdout, "After")
+	if before == -1 || output == -1 || after == -1 || !(before < output && output < after) {
+		t.Errorf("Expected the run output between the log lines, got %q", stdout)
+	}
+	if strings.Contains(stdout, "Unrelated") {
+		t.Errorf("Expected only lines about the job, got %q", stdout)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	jobs := map[string]string{
+		"good":   `command = ["true"]`,
+		"typo":   "command = [\"true\"]\njittr = 5",
+		"broken": `command = [`,
+	}
+	for name, content := range jobs {
+		if err := os.Mkdir(filepath.Join(configDir, name), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(configDir, name, "config.star"), []byte(content), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "validate")
+	if err == nil {
+		t.Error("Expected validate to fail with a broken job")
+	}
+
+	for _, want := range []string{"good: ok", "typo: warning: unknown variable \"jittr\"", "broken: error:"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in output, got %q", want, stdout)
+		}
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "validate", "good", "typo"); err != nil {
+		t.Errorf("Expected validate to pass with warnings only, got %v", err)
+	}
+}
+
+func TestDisableEnable(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	if err := os.Mkdir(filepath.Join(configDir, "backup"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "backup", "config.star"), []byte(`command = ["true"]`), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "disable", "--until", "2999-01-05", "backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout, "Disabled backup until 2999-01-05 00:00:00") {
+		t.Errorf("Expected the end of the snooze in output, got %q", stdout)
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "status", "backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout, "snoozed: until 2999-01-05") {
+		t.Errorf("Expected the snooze in status, got %q", stdout)
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "disable", "--until", "2000-01-01", "backup"); err == nil {
+		t.Error("Expected an error for a time in the past")
+	}
+	if _, _, err := commandWithDirs(tempDir, "disable", "--for", "1h", "missing"); err == nil {
+		t.Error("Expected an error for an unknown job")
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "enable", "backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout, "Enabled backup") {
+		t.Errorf("Expected the job to be enabled, got %q", stdout)
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "status", "backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(stdout, "snoozed:") {
+		t.Errorf("Expected no snooze in status, got %q", stdout)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	if err := os.Mkdir(filepath.Join(configDir, "backup"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "backup", "config.star"), []byte("command = [\"true\"]\nqueue = \"disks\""), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "pause", "missing"); err == nil {
+		t.Error("Expected an error for a queue no job uses")
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "pause", "disks")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout, "Paused queue disks") {
+		t.Errorf("Expected the queue to be paused, got %q", stdout)
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "status", "backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout, "queue: disks (paused since") {
+		t.Errorf("Expected the paused queue in status, got %q", stdout)
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "resume", "disks")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout, "Resumed queue disks") {
+		t.Errorf("Expected the queue to be resumed, got %q", stdout)
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "status", "backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(stdout, "paused") {
+		t.Errorf("Expected no paused queue in status, got %q", stdout)
+	}
+}
+
+func TestWhy(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	if err := os.Mkdir(filepath.Join(configDir, "never"), dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "never", "config.star"), []byte("command = [\"true\"]\ndef should_run(**_):\n    return False"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "why", "never")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{"Decision for now", "should_run(minute=", "returned: False", "queued: no"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in the output, got %q", want, stdout)
+		}
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "why", "missing"); err == nil {
+		t.Error("Expected an error for a missing job")
+	}
+}
+
 func TestRunCommandHelp(t *testing.T) {
 	stdout, _, err := command("run", "--help")
 
@@ -183,13 +389,635 @@ func TestStatusLogLines(t *testing.T) {
 }
 
 func TestStatusInvalidConfigDir(t *testing.T) {
-	stdout, _, err := command("status", "--config-dir", "/nonexistent/path")
+	tempDir := createTempDir(t)
+
+	// The config directory can't be created under a file.
+	file := filepath.Join(tempDir, "file")
+	if err := os.WriteFile(file, nil, filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, err := commandWithDirs(tempDir, "status", "--config-dir", filepath.Join(file, "config"))
 
 	if _, ok := err.(*exec.ExitError); !ok {
 		t.Error("Expected error for invalid config directory")
 	}
 
-	if !strings.Contains(stdout, "error looking for jobs in config dir") {
-		t.Error("Expected 'error looking for jobs in config dir' in stdout")
+	if !strings.Contains(stderr, "failed to create config directory") {
+		t.Errorf("Expected 'failed to create config directory' in stderr, got %q", stderr)
+	}
+}
+
+func TestFirstRun(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "new-config")
+	stateDir := filepath.Join(tempDir, "new-state")
+
+	_, stderr, err := command("--config-dir", configDir, "--state-dir", stateDir, "list")
+	if err != nil {
+		t.Fatalf("list failed: %v: %s", err, stderr)
+	}
+
+	for _, want := range []string{"Created " + configDir, "Created " + stateDir, "regular init example"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Expected %q in stderr, got %q", want, stderr)
+		}
+	}
+
+	stdout, stderr, err := command("--config-dir", configDir, "--state-dir", stateDir, "init", "example")
+	if err != nil {
+		t.Fatalf("init failed: %v: %s", err, stderr)
+	}
+	if stderr != "" {
+		t.Errorf("Expected no setup messages the second time, got %q", stderr)
+	}
+	if !strings.Contains(stdout, filepath.Join(configDir, "example", "config.star")) {
+		t.Errorf("Expected the path of the job file in stdout, got %q", stdout)
+	}
+
+	if _, _, err := command("--config-dir", configDir, "--state-dir", stateDir, "init", "example"); err == nil {
+		t.Error("Expected init to refuse to overwrite the job")
+	}
+
+	stdout, stderr, err = command("--config-dir", configDir, "--state-dir", stateDir, "run", "--force", "--no-jitter", "example")
+	if err != nil {
+		t.Fatalf("Failed to run the example job: %v: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Hello from Regular") {
+		t.Errorf("Expected the output of the example job, got %q", stdout)
+	}
+}
+
+func TestBackupRestoreCommands(t *testing.T) {
+	tempDir := createTempDir(t)
+	backupPath := filepath.Join(tempDir, "backup.sqlite3")
+
+	if _, stderr, err := commandWithDirs(tempDir, "backup", backupPath); err != nil {
+		t.Fatalf("Expected no error for 'backup', got %v: %s", err, stderr)
+	}
+
+	if _, err := os.Stat(backupPath); err != nil {
+		t.Fatalf("Expected backup file to exist: %v", err)
+	}
+
+	if _, stderr, err := commandWithDirs(tempDir, "restore", backupPath); err != nil {
+		t.Errorf("Expected no error for 'restore', got %v: %s", err, stderr)
+	}
+}
+
+func TestCatLogNoRuns(t *testing.T) {
+	tempDir := createTempDir(t)
+	stdout, _, err := commandWithDirs(tempDir, "cat-log", "nonexistent")
+
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Error("Expected error for job with no runs")
+	}
+
+	if !strings.Contains(stdout, "has no recorded runs") {
+		t.Errorf("Expected 'has no recorded runs' in stdout, got %q", stdout)
+	}
+}
+
+func TestNotifyTestCommand(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	settings := "email_transport = \"sendmail\"\nsendmail_path = \"true\"\n"
+	if err := os.WriteFile(filepath.Join(configDir, "settings.star"), []byte(settings), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	pluginsDir := filepath.Join(configDir, "notifiers")
+	if err := os.Mkdir(pluginsDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	reportPath := filepath.Join(tempDir, "report.json")
+	plugin := "#! /bin/sh\ncat > " + reportPath + "\n"
+	if err := os.WriteFile(filepath.Join(pluginsDir, "save"), []byte(plugin), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "notify-test")
+	if err != nil {
+		t.Fatalf("notify-test failed: %v\n%s", err, stdout)
+	}
+
+	for _, want := range []string{"email: ok", "plugins: ok"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected %q in stdout, got %q", want, stdout)
+		}
+	}
+
+	report, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Plugin didn't receive a report: %v", err)
+	}
+	if !strings.Contains(string(report), "test notification") {
+		t.Errorf("Unexpected report: %s", report)
+	}
+}
+
+func TestNotifyTestUnknownJob(t *testing.T) {
+	tempDir := createTempDir(t)
+	stdout, _, err := commandWithDirs(tempDir, "notify-test", "nonexistent")
+
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Error("Expected error for unknown job")
+	}
+
+	if !strings.Contains(stdout, "failed to load job") {
+		t.Errorf("Expected 'failed to load job' in stdout, got %q", stdout)
+	}
+}
+
+func TestStatusFilters(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	jobs := map[string]string{
+		"enabled-job":  `command = ["true"]`,
+		"disabled-job": "command = [\"true\"]\nenable = False",
+	}
+	for name, content := range jobs {
+		if err := os.Mkdir(filepath.Join(configDir, name), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(configDir, name, "config.star"), []byte(content), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "status", "--disabled")
+	if err != nil {
+		t.Fatalf("status --disabled failed: %v", err)
+	}
+
+	if !strings.Contains(stdout, "disabled-job") || strings.Contains(stdout, "enabled-job") {
+		t.Errorf("Expected only the disabled job, got %q", stdout)
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "status", "--failed")
+	if err != nil {
+		t.Fatalf("status --failed failed: %v", err)
+	}
+
+	if stdout != "" {
+		t.Errorf("Expected no failed jobs, got %q", stdout)
+	}
+}
+
+func TestMultipleConfigDirs(t *testing.T) {
+	tempDir := createTempDir(t)
+	personalDir := filepath.Join(tempDir, "config")
+	sharedDir := filepath.Join(tempDir, "shared")
+
+	jobs := map[string]string{
+		filepath.Join(personalDir, "personal-job"): `command = ["true"]`,
+		filepath.Join(sharedDir, "shared-job"):     `command = ["true"]`,
+	}
+	for dir, content := range jobs {
+		if err := os.MkdirAll(dir, dirPerms); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "config.star"), []byte(content), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, _, err := command(
+		"--config-dir", personalDir+":"+sharedDir,
+		"--state-dir", filepath.Join(tempDir, "state"),
+		"list",
+	)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+
+	if !strings.Contains(stdout, "personal-job") || !strings.Contains(stdout, "shared-job") {
+		t.Errorf("Expected jobs from both directories, got %q", stdout)
+	}
+}
+
+func TestListColumns(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	jobDir := filepath.Join(configDir, "documented")
+	if err := os.Mkdir(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "notify = \"always\"\ndef should_run(**_):\n    \"\"\"Every minute.\"\"\"\n    return True\n"
+	if err := os.WriteFile(filepath.Join(jobDir, "config.star"), []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "list")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+
+	fields := strings.Fields(strings.Split(stdout, "\n")[1])
+	expected := []string{"documented", "yes", "documented", "always", "Every", "minute.", "-"}
+	if !slices.Equal(fields, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, fields)
+	}
+
+	stdout, _, err = commandWithDirs(tempDir, "list", "--names")
+	if err != nil {
+		t.Fatalf("list --names failed: %v", err)
+	}
+
+	if stdout != "documented\n" {
+		t.Errorf("Expected only the job name, got %q", stdout)
+	}
+}
+
+func TestRunStreamsOutput(t *testing.T) {
+	tempDir := createTempDir(t)
+	jobDir := filepath.Join(tempDir, "config", "echo")
+	if err := os.Mkdir(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "command = [\"sh\", \"-c\", \"echo to-stdout; echo to-stderr >&2\"]\nnotify = \"never\"\n"
+	if err := os.WriteFile(filepath.Join(jobDir, "config.star"), []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := commandWithDirs(tempDir, "run", "--force", "echo")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if !strings.Contains(stdout, "to-stdout") {
+		t.Errorf("Expected job stdout in stdout, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "to-stderr") {
+		t.Errorf("Expected job stderr in stderr, got %q", stderr)
+	}
+
+	logged, err := os.ReadFile(filepath.Join(tempDir, "state", "echo", "stdout.log"))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if string(logged) != "to-stdout\n" {
+		t.Errorf("Expected the output in the log file, got %q", logged)
+	}
+}
+
+func TestRunAllAndTags(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+	stateDir := filepath.Join(tempDir, "state")
+
+	jobs := map[string]string{
+		"tagged-a": `tags = ["nightly"]`,
+		"tagged-b": `tags = ["nightly", "backup"]`,
+		"untagged": ``,
+	}
+	for name, extra := range jobs {
+		if err := os.Mkdir(filepath.Join(configDir, name), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+
+		content := "command = [\"true\"]\nnotify = \"never\"\n" + extra + "\n"
+		if err := os.WriteFile(filepath.Join(configDir, name, "config.star"), []byte(content), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ran := func(name string) bool {
+		_, err := os.Stat(filepath.Join(stateDir, name, "stdout.log"))
+		return err == nil
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "run", "--force", "--tag", "nightly"); err != nil {
+		t.Fatalf("run --tag failed: %v", err)
+	}
+
+	if !ran("tagged-a") || !ran("tagged-b") || ran("untagged") {
+		t.Errorf("Expected only the tagged jobs to run")
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "run", "--force", "--all"); err != nil {
+		t.Fatalf("run --all failed: %v", err)
+	}
+
+	if !ran("untagged") {
+		t.Errorf("Expected --all to run the untagged job")
+	}
+}
+
+func TestRunEnvOverrides(t *testing.T) {
+	tempDir := createTempDir(t)
+	jobDir := filepath.Join(tempDir, "config", "env")
+	if err := os.Mkdir(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "command = [\"sh\", \"-c\", \"echo $FROM_FILE $FROM_FLAG\"]\nnotify = \"never\"\nenv[\"FROM_FLAG\"] = \"config\"\n"
+	if err := os.WriteFile(filepath.Join(jobDir, "config.star"), []byte(content), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	envFile := filepath.Join(tempDir, "test.env")
+	if err := os.WriteFile(envFile, []byte("FROM_FILE=file\nFROM_FLAG=file\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "run", "--force", "--env-file", envFile, "-e", "FROM_FLAG=flag", "env")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if !strings.Contains(stdout, "file flag\n") {
+		t.Errorf("Expected overridden variables in stdout, got %q", stdout)
+	}
+
+	_, _, err = commandWithDirs(tempDir, "run", "-e", "NO_EQUALS_SIGN", "env")
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Error("Expected error for a malformed variable")
+	}
+}
+
+func TestUnderJournal(t *testing.T) {
+	t.Setenv("JOURNAL_STREAM", "")
+	if underJournal() {
+		t.Error("Expected no journal without JOURNAL_STREAM")
+	}
+
+	t.Setenv("JOURNAL_STREAM", "0:0")
+	if underJournal() {
+		t.Error("Expected no journal when stderr is a different file")
+	}
+
+	info, err := os.Stderr.Stat()
+	if err != nil {
+		t.Skip("can't stat stderr")
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+
+	t.Setenv("JOURNAL_STREAM", fmt.Sprintf("%d:%d", stat.Dev, stat.Ino))
+	if !underJournal() {
+		t.Error("Expected the journal when JOURNAL_STREAM matches stderr")
+	}
+}
+
+func TestGCCommand(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+	stateDir := filepath.Join(tempDir, "state")
+
+	for _, name := range []string{"kept", "deleted"} {
+		if err := os.Mkdir(filepath.Join(configDir, name), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+
+		content := "command = [\"true\"]\nnotify = \"never\"\n"
+		if err := os.WriteFile(filepath.Join(configDir, name, "config.star"), []byte(content), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, stderr, err := commandWithDirs(tempDir, "run", "--force", "--all"); err != nil {
+		t.Fatalf("run --all failed: %v: %s", err, stderr)
+	}
+
+	if err := os.RemoveAll(filepath.Join(configDir, "deleted")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deleted job is still in the grace period.
+	stdout, stderr, err := commandWithDirs(tempDir, "gc")
+	if err != nil {
+		t.Fatalf("gc failed: %v: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "No deleted jobs") {
+		t.Errorf("Expected no jobs to remove, got %q", stdout)
+	}
+
+	stdout, stderr, err = commandWithDirs(tempDir, "gc", "--grace", "1ns", "--dry-run")
+	if err != nil {
+		t.Fatalf("gc --dry-run failed: %v: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Would remove deleted") {
+		t.Errorf("Expected dry run to list the deleted job, got %q", stdout)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "deleted")); err != nil {
+		t.Errorf("Expected dry run to keep the state directory: %v", err)
+	}
+
+	stdout, stderr, err = commandWithDirs(tempDir, "gc", "--grace", "1ns")
+	if err != nil {
+		t.Fatalf("gc failed: %v: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "Removed deleted") || strings.Contains(stdout, "kept") {
+		t.Errorf("Expected only the deleted job to be removed, got %q", stdout)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "deleted")); !os.IsNotExist(err) {
+		t.Errorf("Expected the state directory to be removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "kept")); err != nil {
+		t.Errorf("Expected the state directory of the kept job: %v", err)
+	}
+}
+
+func TestHistoryDead(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+
+	jobs := map[string]string{
+		"broken": "command = [\"false\"]\nretries = 1\nnotify = \"never\"\n",
+		"fine":   "command = [\"true\"]\nnotify = \"never\"\n",
+	}
+	for name, content := range jobs {
+		if err := os.Mkdir(filepath.Join(configDir, name), dirPerms); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(configDir, name, "config.star"), []byte(content), filePerms); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "run", "--force", "--all", "--parallel", "2"); err == nil {
+		t.Fatal("Expected the broken job to fail")
+	}
+
+	stdout, stderr, err := commandWithDirs(tempDir, "history")
+	if err != nil {
+		t.Fatalf("history failed: %v: %s", err, stderr)
+	}
+	if strings.Count(stdout, "broken") != 2 || !strings.Contains(stdout, "fine") {
+		t.Errorf("Expected two runs of the broken job and one of the fine job, got %q", stdout)
+	}
+
+	stdout, stderr, err = commandWithDirs(tempDir, "history", "--dead")
+	if err != nil {
+		t.Fatalf("history --dead failed: %v: %s", err, stderr)
+	}
+	if strings.Count(stdout, "broken") != 1 || !strings.Contains(stdout, "dead, exit status 1") || strings.Contains(stdout, "fine") {
+		t.Errorf("Expected only the dead run, got %q", stdout)
+	}
+}
+
+func TestCustomStateDirOnly(t *testing.T) {
+	tempDir := createTempDir(t)
+	configDir := filepath.Join(tempDir, "config")
+	defaultStateDir := filepath.Join(tempDir, "default-state")
+
+	jobDir := filepath.Join(configDir, "fail")
+	if err := os.Mkdir(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(jobDir, "config.star"), []byte("command = [\"false\"]\nnotify_via = [\"plugins\"]\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	pluginsDir := filepath.Join(configDir, "notifiers")
+	if err := os.Mkdir(pluginsDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+
+	reportPath := filepath.Join(tempDir, "report.json")
+	plugin := "#! /bin/sh\ncat > " + reportPath + "\n"
+	if err := os.WriteFile(filepath.Join(pluginsDir, "save"), []byte(plugin), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(
+		commandRegular,
+		"--output", "-",
+		"--config-dir", configDir,
+		"--state-dir", filepath.Join(tempDir, "state"),
+		"run", "--force", "fail",
+	)
+	cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+defaultStateDir)
+	_ = cmd.Run()
+
+	if _, err := os.Stat(reportPath); err != nil {
+		t.Fatalf("Expected a notification about the failed run: %v", err)
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "history", "fail")
+	if err != nil {
+		t.Fatalf("history failed: %v", err)
+	}
+	if !strings.Contains(stdout, "exit status 1") {
+		t.Errorf("Expected the run in the custom state directory, got %q", stdout)
+	}
+
+	// Nothing goes to the default state directory.
+	if _, err := os.Stat(defaultStateDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no default state directory, got %v", err)
+	}
+}
+
+func TestAppLogInStateDir(t *testing.T) {
+	tempDir := createTempDir(t)
+	stateDir := filepath.Join(tempDir, "state")
+	defaultStateDir := filepath.Join(tempDir, "missing", "state")
+
+	jobDir := filepath.Join(tempDir, "config", "hello")
+	if err := os.Mkdir(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(jobDir, "config.star"), []byte("command = [\"true\"]\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without --output, the log goes to the state directory from the flags.
+	cmd := exec.Command(
+		commandRegular,
+		"--config-dir", filepath.Join(tempDir, "config"),
+		"--state-dir", stateDir,
+		"run", "--force", "hello",
+	)
+	cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+defaultStateDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("run failed: %v\n%s", err, output)
+	}
+
+	logged, err := os.ReadFile(filepath.Join(stateDir, "app.log"))
+	if err != nil {
+		t.Fatalf("Expected the app log in the state directory: %v", err)
+	}
+	if !strings.Contains(string(logged), "[hello] Finished") {
+		t.Errorf("Expected the run in the app log, got %q", logged)
+	}
+
+	if _, err := os.Stat(defaultStateDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no default state directory, got %v", err)
+	}
+
+	stdout, _, err := commandWithDirs(tempDir, "log")
+	if err != nil {
+		t.Fatalf("log failed: %v", err)
+	}
+	if !strings.Contains(stdout, "Finished") {
+		t.Errorf("Expected log to read the same file, got %q", stdout)
+	}
+}
+
+func TestNoAppLogWithOutputDash(t *testing.T) {
+	tempDir := createTempDir(t)
+
+	jobDir := filepath.Join(tempDir, "config", "hello")
+	if err := os.MkdirAll(jobDir, dirPerms); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(jobDir, "config.star"), []byte("command = [\"true\"]\n"), filePerms); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := commandWithDirs(tempDir, "--output", "-", "run", "--force", "hello"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "state", "app.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected no app log with --output -, got %v", err)
+	}
+}
+
+func TestClockOffsetNeedsStateDir(t *testing.T) {
+	tempDir := createTempDir(t)
+	defaultStateDir := filepath.Join(tempDir, "default-state")
+
+	cmd := exec.Command(
+		commandRegular,
+		"--output", "-",
+		"--config-dir", filepath.Join(tempDir, "config"),
+		"start", "--clock-offset", "5h",
+	)
+	cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+defaultStateDir)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Expected start to fail without --state-dir")
+		}
+	case <-time.After(10 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("Expected start to refuse to run without --state-dir")
+	}
+
+	if !strings.Contains(output.String(), "--state-dir") {
+		t.Errorf("Expected an error about --state-dir, got %q", output.String())
 	}
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"dbohdan.com/regular/engine"
+)
+
+const (
+	notifyTestJobName = "regular-test"
+	notifyTestMessage = "This is a test notification from Regular"
+)
+
+func (n *NotifyTestCmd) Run(config engine.Config) error {
+	now := time.Now()
+	completed := engine.CompletedJob{
+		Error:    notifyTestMessage,
+		Started:  now,
+		Finished: now,
+	}
+
+	jobName := n.JobName
+	if jobName == "" {
+		jobName = notifyTestJobName
+	} else {
+		job, err := engine.NewScheduler().LoadJob(config.ConfigRoots(), jobName)
+		if err != nil {
+			return fmt.Errorf("failed to load job %q: %w", jobName, err)
+		}
+
+		completed.NotifyLines = job.NotifyLines
+		completed.NotifyStdout = job.NotifyStdout
+		completed.NotifyTo = job.NotifyTo
+		completed.NotifyVia = job.NotifyVia
+	}
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	channels := append(
+		notificationChannels(db, config),
+		namedNotifier{"mqtt", engine.PublishMQTT(config.ConfigRoot)},
+	)
+
+	failed := 0
+	for _, channel := range channels {
+		if err := channel.notify(jobName, completed); err != nil {
+			fmt.Printf("%s: failed: %v\n", channel.name, err)
+			failed++
+
+			continue
+		}
+
+		fmt.Printf("%s: ok\n", channel.name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d notification channels failed", failed, len(channels))
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+// checkQueueNames returns an error if no job uses a queue.
+func checkQueueNames(config engine.Config, queues []string) error {
+	jobs, _, err := engine.LoadJobInfo(config)
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]struct{})
+	for _, job := range jobs {
+		used[job.Queue] = struct{}{}
+	}
+
+	for _, queue := range queues {
+		if _, ok := used[queue]; !ok {
+			return fmt.Errorf("no job uses queue %q", queue)
+		}
+	}
+
+	return nil
+}
+
+func (p *PauseCmd) Run(config engine.Config) error {
+	if err := checkQueueNames(config, p.Queues); err != nil {
+		return err
+	}
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, queue := range p.Queues {
+		if err := db.PauseQueue(queue); err != nil {
+			return fmt.Errorf("failed to pause queue %q: %w", queue, err)
+		}
+
+		if err := engine.TellDaemonPaused(queue); err != nil {
+			return fmt.Errorf("failed to tell the scheduler to pause queue %q: %w", queue, err)
+		}
+
+		fmt.Printf("Paused queue %s\n", queue)
+	}
+
+	return nil
+}
+
+func (r *ResumeCmd) Run(config engine.Config) error {
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, queue := range r.Queues {
+		resumed, err := db.ResumeQueue(queue)
+		if err != nil {
+			return fmt.Errorf("failed to resume queue %q: %w", queue, err)
+		}
+
+		if err := engine.TellDaemonResumed(queue); err != nil {
+			return fmt.Errorf("failed to tell the scheduler to resume queue %q: %w", queue, err)
+		}
+
+		if resumed {
+			fmt.Printf("Resumed queue %s\n", queue)
+		} else {
+			fmt.Printf("Queue %s wasn't paused\n", queue)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (r *RenameCmd) Run(config engine.Config) error {
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := engine.RenameJob(db, config, r.OldName, r.NewName); err != nil {
+		return err
+	}
+
+	fmt.Printf("Renamed %s to %s\n", r.OldName, r.NewName)
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (r *RestoreCmd) Run(config engine.Config) error {
+	// Don't replace the database under a running scheduler.
+	unlock, locked, err := engine.LockStateDir(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return fmt.Errorf("another regular instance is using %s; stop it before restoring", config.StateRoot)
+	}
+	defer unlock()
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Restore(r.Path); err != nil {
+		return err
+	}
+
+	fmt.Println("Restored state database from", r.Path)
+
+	return nil
+}
@@ -7,15 +7,29 @@ import (
 	"log"
 	"net"
 	"os"
-	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/gofrs/flock"
+	"dbohdan.com/denv"
+	"dbohdan.com/regular/engine"
 	"github.com/vmihailenco/msgpack/v5"
 )
 
-func (r *RunCmd) Run(config Config) error {
-	socketPath, err := defaultSocketPath()
+func (r *RunCmd) Run(config engine.Config) error {
+	env, err := r.envOverrides()
+	if err != nil {
+		return err
+	}
+
+	if r.All || len(r.Tags) > 0 {
+		if err := r.selectJobs(config); err != nil {
+			return err
+		}
+	}
+
+	socketPath, err := engine.DefaultSocketPath()
 	if err != nil {
 		return fmt.Errorf("failed to resolve socket path: %w", err)
 	}
@@ -23,10 +37,10 @@ func (r *RunCmd) Run(config Config) error {
 	if _, statErr := os.Stat(socketPath); statErr == nil {
 		// A socket file exists. Verify it's safe before talking to it; on
 		// security failure, error out rather than fall back silently.
-		if err := checkSocketSecurity(socketPath); err != nil {
+		if err := engine.CheckSocketSecurity(socketPath); err != nil {
 			return fmt.Errorf("refusing to use socket %s: %w", socketPath, err)
 		}
-		failed, err := r.runOverSocket(socketPath)
+		failed, err := r.runOverSocket(socketPath, env)
 		if err == nil {
 			if failed {
 				return errors.New("one or more jobs failed")
@@ -38,25 +52,113 @@ func (r *RunCmd) Run(config Config) error {
 		log.Printf("Falling back to standalone run after socket error: %v", err)
 	}
 
-	return r.runStandalone(config)
+	return r.runStandalone(config, env)
+}
+
+// envOverrides loads the variables from --env-file and -e.
+// Variables from -e take precedence.
+func (r *RunCmd) envOverrides() (denv.Env, error) {
+	env := denv.Env{}
+
+	if r.EnvFile != "" {
+		fileEnv, err := denv.Load(r.EnvFile, true, denv.OS())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load env file: %w", err)
+		}
+
+		env = denv.Merge(env, fileEnv)
+	}
+
+	for _, pair := range r.Env {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid environment variable %q: expected KEY=VALUE", pair)
+		}
+
+		env[key] = value
+	}
+
+	return env, nil
+}
+
+// selectJobs adds the jobs that match --all and --tag to the job names.
+func (r *RunCmd) selectJobs(config engine.Config) error {
+	jobs, _, err := engine.LoadJobInfo(config)
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(jobs, func(a, b engine.JobInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, job := range jobs {
+		if len(r.Tags) > 0 && !job.HasTag(r.Tags...) {
+			continue
+		}
+
+		if !slices.Contains(r.JobNames, job.Name) {
+			r.JobNames = append(r.JobNames, job.Name)
+		}
+	}
+
+	return nil
 }
 
 // runOverSocket dials the daemon for each requested job, streams output
 // frames back to stdout/stderr, and reports whether any job failed.
-func (r *RunCmd) runOverSocket(socketPath string) (failed bool, err error) {
+// Up to r.Parallel jobs run at the same time; the daemon still serializes
+// jobs that share a queue.
+func (r *RunCmd) runOverSocket(socketPath string, env denv.Env) (failed bool, err error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(r.Parallel, 1))
+
 	for _, jobName := range r.JobNames {
-		jobFailed, jobErr := runOneOverSocket(socketPath, jobName, r.Force)
-		if jobErr != nil {
-			return failed, jobErr
+		slots <- struct{}{}
+
+		// Don't start more jobs after a connection error.
+		mu.Lock()
+		stop := err != nil
+		mu.Unlock()
+		if stop {
+			<-slots
+			break
 		}
-		if jobFailed {
-			failed = true
+
+		req := engine.Request{
+			Verb:     engine.VerbRun,
+			Job:      jobName,
+			Force:    r.Force,
+			NoJitter: r.NoJitter,
+			Env:      env,
 		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			jobFailed, jobErr := runOneOverSocket(socketPath, req)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if jobErr != nil && err == nil {
+				err = jobErr
+			}
+			if jobFailed {
+				failed = true
+			}
+		}()
 	}
-	return failed, nil
+
+	wg.Wait()
+	return failed, err
 }
 
-func runOneOverSocket(socketPath, jobName string, force bool) (failed bool, err error) {
+func runOneOverSocket(socketPath string, req engine.Request) (failed bool, err error) {
+	jobName := req.Job
+
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
 		return false, fmt.Errorf("failed to connect to %s: %w", socketPath, err)
@@ -64,13 +166,13 @@ func runOneOverSocket(socketPath, jobName string, force bool) (failed bool, err
 	defer conn.Close()
 
 	enc := msgpack.NewEncoder(conn)
-	if err := enc.Encode(Request{Verb: verbRun, Job: jobName, Force: force}); err != nil {
+	if err := enc.Encode(req); err != nil {
 		return false, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	dec := msgpack.NewDecoder(conn)
 	for {
-		var f Frame
+		var f engine.Frame
 		if err := dec.Decode(&f); err != nil {
 			if errors.Is(err, io.EOF) {
 				return false, fmt.Errorf("connection closed before exit frame")
@@ -79,80 +181,85 @@ func runOneOverSocket(socketPath, jobName string, force bool) (failed bool, err
 		}
 
 		switch f.Type {
-		case frameStdout:
+		case engine.FrameStdout:
 			os.Stdout.Write(f.Data)
-		case frameStderr:
+		case engine.FrameStderr:
 			os.Stderr.Write(f.Data)
-		case frameLog:
-			logJobPrintf(jobName, "%s", f.Msg)
-		case frameExit:
+		case engine.FrameLog:
+			engine.LogJobPrintf(jobName, "%s", f.Msg)
+		case engine.FrameExit:
 			if f.Error != "" {
-				logJobPrintf(jobName, "Error: %s", f.Error)
+				engine.LogJobPrintf(jobName, "Error: %s", f.Error)
 			}
 			return f.Code != 0 || f.Error != "", nil
 		default:
-			logJobPrintf(jobName, "Unknown frame type %q", f.Type)
+			engine.LogJobPrintf(jobName, "Unknown frame type %q", f.Type)
 		}
 	}
 }
 
 // runStandalone is the no-daemon path. It locks the state directory so two
 // concurrent invocations cannot race on the DB or log files.
-func (r *RunCmd) runStandalone(config Config) error {
-	lockPath := filepath.Join(config.StateRoot, appLockFileName)
-	fileLock := flock.New(lockPath)
-	locked, err := fileLock.TryLock()
+func (r *RunCmd) runStandalone(config engine.Config, env denv.Env) error {
+	unlock, locked, err := engine.LockStateDir(config.StateRoot)
 	if err != nil {
-		return fmt.Errorf("error checking lock file: %w", err)
+		return err
 	}
 	if !locked {
 		return fmt.Errorf("another regular instance is using %s", config.StateRoot)
 	}
-	defer func() {
-		_ = fileLock.Unlock()
-	}()
+	defer unlock()
 
-	db, err := openAppDB(config.StateRoot)
+	db, err := engine.OpenAppDB(config.StateRoot)
 	if err != nil {
 		return err
 	}
-	defer db.close()
+	defer db.Close()
 
-	runner, err := newJobRunner(db, notifyUserByEmail(db), config.StateRoot)
+	// Failed notifications are retried by the service.
+	notify, _, batcher := notifiers(db, config)
+	defer batcher.Flush()
+	runner, err := engine.NewRunner(db, notify, config.StateRoot)
 	if err != nil {
 		return err
 	}
+	if err := configureRunner(&runner, config); err != nil {
+		return err
+	}
 
-	jobs := newJobScheduler()
+	jobs := engine.NewScheduler()
 	now := time.Now()
 
 	for _, jobName := range r.JobNames {
-		path := filepath.Join(config.ConfigRoot, jobName, jobConfigFileName)
-
-		_, job, err := jobs.update(config.ConfigRoot, path)
+		job, err := jobs.LoadJob(config.ConfigRoots(), jobName)
 		if err != nil {
-			logJobPrintf(jobNameFromPath(path), "Error loading job: %v", err)
+			engine.LogJobPrintf(jobName, "Error loading job: %v", err)
 			return nil
 		}
 
+		if r.NoJitter {
+			job.Jitter = 0
+		}
+
+		job.Env = denv.Merge(job.Env, env)
+
+		// Mirror the output to the terminal like a run through the daemon does.
+		job.Stdout = os.Stdout
+		job.Stderr = os.Stderr
+
 		// Either force-run or check should_run.
 		if r.Force {
-			runner.addJob(*job)
+			runner.AddJob(*job)
 		} else {
-			if err := job.addToQueueIfDue(runner, now); err != nil {
+			if err := job.AddToQueueIfDue(runner, now); err != nil {
 				return fmt.Errorf("failed to schedule job %q: %w", job.Name, err)
 			}
 		}
 	}
 
-	// Drain each queue sequentially.
-	for queueName := range runner.queues {
-		for len(runner.queues[queueName].jobs) > 0 {
-			if err := runner.runQueueHead(queueName); err != nil {
-				return err
-			}
-		}
+	if r.Parallel > 1 {
+		return runner.RunQueuedParallel(r.Parallel)
 	}
 
-	return nil
+	return runner.RunQueued()
 }
@@ -1,13 +1,48 @@
 package starlarkutil
 
 import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
 	"go.starlark.net/starlark"
 
 	"dbohdan.com/regular/shellquote"
 )
 
 func AddPredeclared(d starlark.StringDict) {
+	d["check_type"] = starlark.NewBuiltin("check_type", CheckType)
+	d["fail_unless"] = starlark.NewBuiltin("fail_unless", FailUnless)
+	d["hash"] = starlark.NewBuiltin("hash", Hash)
 	d["quote"] = starlark.NewBuiltin("quote", Quote)
+	d["random"] = starlark.NewBuiltin("random", Random)
+}
+
+// NewThread returns a thread that is cancelled after maxSteps execution steps or once timeout elapses.
+// Zero disables the respective limit.
+// Call the returned function when done with the thread to release the timer.
+func NewThread(name string, maxSteps uint64, timeout time.Duration) (*starlark.Thread, func()) {
+	thread := &starlark.Thread{Name: name}
+
+	if maxSteps > 0 {
+		thread.SetMaxExecutionSteps(maxSteps)
+	}
+
+	if timeout <= 0 {
+		return thread, func() {}
+	}
+
+	timer := time.AfterFunc(timeout, func() {
+		thread.Cancel(fmt.Sprintf("timed out after %v", timeout))
+	})
+
+	return thread, func() {
+		timer.Stop()
+	}
 }
 
 func Quote(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
@@ -25,3 +60,109 @@ func Quote(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kw
 
 	return starlark.String(quoted), nil
 }
+
+// FailUnless stops the evaluation with the message unless the condition is true.
+func FailUnless(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cond starlark.Value
+	var msg string = "assertion failed"
+
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &cond, &msg); err != nil {
+		return starlark.None, err
+	}
+
+	if !cond.Truth() {
+		return starlark.None, errors.New(msg)
+	}
+
+	return starlark.None, nil
+}
+
+// CheckType stops the evaluation unless the value has one of the types as named by "type()".
+// It returns the value, so a check can wrap an assignment.
+func CheckType(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(kwargs) > 0 {
+		return starlark.None, fmt.Errorf("%s: unexpected keyword arguments", b.Name())
+	}
+	if len(args) < 3 {
+		return starlark.None, fmt.Errorf("%s: expected a name, a value, and at least one type", b.Name())
+	}
+
+	name, ok := starlark.AsString(args[0])
+	if !ok {
+		return starlark.None, fmt.Errorf("%s: name must be string, got %s", b.Name(), args[0].Type())
+	}
+
+	types := []string{}
+	for _, arg := range args[2:] {
+		typ, ok := starlark.AsString(arg)
+		if !ok {
+			return starlark.None, fmt.Errorf("%s: type must be string, got %s", b.Name(), arg.Type())
+		}
+
+		types = append(types, typ)
+	}
+
+	value := args[1]
+	if !slices.Contains(types, value.Type()) {
+		return starlark.None, fmt.Errorf("%q must be %s, got %s", name, strings.Join(types, " or "), value.Type())
+	}
+
+	return value, nil
+}
+
+// hashString returns the 64-bit FNV-1a hash of a string.
+// Unlike the hash of Go maps, it is the same on every machine and in every process.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	return h.Sum64()
+}
+
+// Hash returns a non-negative integer hash of a string that doesn't change between runs.
+// With n, the hash is in the range [0, n), which spreads jobs over slots like the minutes of an hour.
+// It replaces the Starlark builtin, which returns negative numbers.
+func Hash(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	var n int
+
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s, &n); err != nil {
+		return starlark.None, err
+	}
+
+	if n < 0 {
+		return starlark.None, fmt.Errorf("%s: n must not be negative, got %d", b.Name(), n)
+	}
+
+	h := hashString(s)
+	if n > 0 {
+		return starlark.MakeUint64(h % uint64(n)), nil
+	}
+
+	return starlark.MakeUint64(h >> 1), nil
+}
+
+// Random returns a float in the range [0, 1).
+// With a seed, it returns the same number for the same seed.
+// A seed that isn't a string is converted to one like with "str()".
+func Random(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var seed starlark.Value = starlark.None
+
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0, &seed); err != nil {
+		return starlark.None, err
+	}
+
+	if seed == starlark.None {
+		return starlark.Float(rand.Float64()), nil
+	}
+
+	s, ok := starlark.AsString(seed)
+	if !ok {
+		s = seed.String()
+	}
+
+	h := hashString(s)
+	r := rand.New(rand.NewPCG(h, h>>32|h<<32))
+
+	return starlark.Float(r.Float64()), nil
+}
@@ -1,7 +1,9 @@
 package starlarkutil
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"go.starlark.net/starlark"
 )
@@ -10,8 +12,10 @@ func TestAddPredeclared(t *testing.T) {
 	d := starlark.StringDict{}
 	AddPredeclared(d)
 
-	if _, ok := d["quote"]; !ok {
-		t.Error("quote function not added to predeclared dict")
+	for _, name := range []string{"check_type", "fail_unless", "hash", "quote", "random"} {
+		if _, ok := d[name]; !ok {
+			t.Errorf("%s function not added to predeclared dict", name)
+		}
 	}
 }
 
@@ -91,3 +95,121 @@ func TestQuote(t *testing.T) {
 		})
 	}
 }
+
+func TestNewThread(t *testing.T) {
+	const loop = `
+def f():
+    for i in range(1000000000):
+        pass
+
+f()
+`
+
+	t.Run("max steps", func(t *testing.T) {
+		thread, done := NewThread("test", 1000, 0)
+		defer done()
+
+		_, err := starlark.ExecFile(thread, "test.star", loop, nil)
+		if err == nil || !strings.Contains(err.Error(), "too many steps") {
+			t.Errorf("expected step limit error, got %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		thread, done := NewThread("test", 0, 10*time.Millisecond)
+		defer done()
+
+		_, err := starlark.ExecFile(thread, "test.star", loop, nil)
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("expected timeout error, got %v", err)
+		}
+	})
+
+	t.Run("no limits", func(t *testing.T) {
+		thread, done := NewThread("test", 0, 0)
+		defer done()
+
+		if _, err := starlark.ExecFile(thread, "test.star", "x = 1", nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestFailUnlessAndCheckType(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"true condition", `fail_unless(1 < 2, "math is broken")`, ""},
+		{"false condition", `fail_unless(1 > 2, "math is broken")`, "math is broken"},
+		{"default message", `fail_unless([])`, "assertion failed"},
+		{"matching type", `x = check_type("port", 22, "int")`, ""},
+		{"one of types", `x = check_type("ratio", 0.5, "int", "float")`, ""},
+		{"wrong type", `x = check_type("port", "22", "int")`, `"port" must be int, got string`},
+		{"no types", `x = check_type("port", 22)`, "expected a name, a value, and at least one type"},
+	}
+
+	predeclared := starlark.StringDict{}
+	AddPredeclared(predeclared)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := &starlark.Thread{Name: "test"}
+			_, err := starlark.ExecFile(thread, "test.star", tt.src, predeclared)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHashAndRandom(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"stable hash", `fail_unless(hash("backup") == hash("backup"))`, ""},
+		{"different strings", `fail_unless(hash("backup") != hash("sync"))`, ""},
+		{"non-negative hash", `fail_unless(hash("backup") >= 0)`, ""},
+		{"hash in range", `fail_unless(all([hash(str(i), 60) < 60 for i in range(100)]))`, ""},
+		{"known hash", `fail_unless(hash("a", 1000) == 12638187200555641996 % 1000)`, ""},
+		{"negative range", `hash("backup", -1)`, "n must not be negative"},
+		{"hash of int", `hash(1)`, "got int, want string"},
+		{"seeded random", `fail_unless(random("backup") == random("backup"))`, ""},
+		{"different seeds", `fail_unless(random("backup") != random("sync"))`, ""},
+		{"int seed", `fail_unless(random(1) == random("1"))`, ""},
+		{"random in range", `fail_unless(all([random(i) >= 0 and random(i) < 1 for i in range(100)]))`, ""},
+		{"unseeded random", `fail_unless(random() >= 0 and random() < 1)`, ""},
+	}
+
+	predeclared := starlark.StringDict{}
+	AddPredeclared(predeclared)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := &starlark.Thread{Name: "test"}
+			_, err := starlark.ExecFile(thread, "test.star", tt.src, predeclared)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -3,70 +3,123 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
-	"github.com/gofrs/flock"
+	"dbohdan.com/regular/engine"
 	"github.com/syncthing/notify"
 )
 
-func (r *StartCmd) Run(config Config) error {
-	withLog(func() error {
-		return runService(config)
+func (r *StartCmd) Run(config engine.Config) error {
+	// The shifted times stay in the history, so keep them out of the state of the real daemon.
+	if r.ClockOffset != 0 && filepath.Clean(config.StateRoot) == filepath.Clean(defaultStateRoot) {
+		return fmt.Errorf("--clock-offset requires a --state-dir other than the default")
+	}
+
+	engine.WithLog(func() error {
+		return r.runService(config)
 	})
 
 	return nil
 }
 
-func runService(config Config) error {
-	lockPath := filepath.Join(config.StateRoot, appLockFileName)
-	fileLock := flock.New(lockPath)
-
+func (r *StartCmd) runService(config engine.Config) error {
 	log.Print("Starting")
 
-	locked, err := fileLock.TryLock()
+	unlock, locked, err := engine.LockStateDir(config.StateRoot)
 	if err != nil {
-		return fmt.Errorf("error checking lock file: %w", err)
+		return err
 	}
 	if !locked {
 		return fmt.Errorf("another instance is already running")
 	}
-	defer func() {
-		_ = fileLock.Unlock()
-	}()
+	defer unlock()
+
+	// The lock is free, so a recorded daemon is gone.
+	stale, err := engine.ReadDaemonInfo(config.StateRoot)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if stale != nil {
+		log.Printf(
+			"Warning: the previous daemon (PID %d, started %s) didn't shut down cleanly",
+			stale.PID,
+			stale.Started.Format(timestampFormat),
+		)
+	}
 
-	jsc := newJobScheduler()
+	removeDaemonInfo, err := engine.WriteDaemonInfo(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer removeDaemonInfo()
+
+	jsc := engine.NewScheduler()
+	if r.ClockOffset != 0 {
+		jsc.SetClock(engine.OffsetClock{Offset: r.ClockOffset})
+		log.Printf("Warning: the scheduler clock is offset by %v", engine.FormatDuration(r.ClockOffset))
+	}
 
 	eventChan := make(chan notify.EventInfo, 1)
 
-	// "..." indicates recursive watching.
-	watchPath := filepath.Join(config.ConfigRoot, "...")
-	if err := notify.Watch(watchPath, eventChan, notify.Create, notify.Rename, notify.Remove, notify.Write); err != nil {
-		return fmt.Errorf("failed to create watcher: %w", err)
+	for _, configRoot := range config.ConfigRoots() {
+		// "..." indicates recursive watching.
+		watchPath := filepath.Join(configRoot, "...")
+		if err := notify.Watch(watchPath, eventChan, notify.Create, notify.Rename, notify.Remove, notify.Write); err != nil {
+			return fmt.Errorf("failed to create watcher: %w", err)
+		}
 	}
 	defer notify.Stop(eventChan)
 
-	loadedJobs, err := jsc.loadAll(config.ConfigRoot)
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Record job events from the start, so "status" shows why a job failed to load.
+	engine.RecordJobEvents(db)
+	defer engine.RecordJobEvents(nil)
+
+	loadedJobs, err := jsc.LoadAll(config.ConfigRoots()...)
 	if err != nil {
 		return fmt.Errorf("error looking for jobs in config dir: %w", err)
 	}
 	log.Print("Loaded jobs: " + strings.Join(loadedJobs, ", "))
 
-	db, err := openAppDB(config.StateRoot)
+	jsc.SetAuditLog(db)
+	notify, retries, batcher := notifiers(db, config)
+	defer batcher.Flush()
+	runner, err := engine.NewRunner(db, notify, config.StateRoot)
 	if err != nil {
 		return err
 	}
-	defer db.close()
-	runner, _ := newJobRunner(db, notifyUserByEmail(db), config.StateRoot)
+	if err := configureRunner(&runner, config); err != nil {
+		return err
+	}
+	if r.ClockOffset != 0 {
+		runner.Clock = engine.OffsetClock{Offset: r.ClockOffset}
+	}
+
+	restored, err := runner.RestoreQueues(jsc.Job)
+	if err != nil {
+		return err
+	}
+	if len(restored) > 0 {
+		log.Print("Restored queued jobs: " + strings.Join(restored, ", "))
+	}
+	jsc.TriggerRunNowFiles(runner, config.ConfigRoots())
 
-	socketPath, err := defaultSocketPath()
+	socketPath, err := engine.DefaultSocketPath()
 	if err != nil {
 		return fmt.Errorf("failed to resolve socket path: %w", err)
 	}
-	listener, err := listenSocket(socketPath)
+	listener, err := engine.ListenSocket(socketPath)
 	if err != nil {
 		return fmt.Errorf("failed to set up socket: %w", err)
 	}
@@ -76,19 +129,55 @@ func runService(config Config) error {
 	}()
 	log.Print("Listening on " + socketPath)
 
-	go withLog(func() error {
-		return jsc.schedule(runner)
+	if r.Listen != "" {
+		httpListener, err := net.Listen("tcp", r.Listen)
+		if err != nil {
+			return fmt.Errorf("failed to set up HTTP API: %w", err)
+		}
+
+		server := engine.ServeHTTPAPI(httpListener, engine.NewHTTPHandler(jsc, runner))
+		defer server.Close()
+		log.Print("HTTP API listening on " + httpListener.Addr().String())
+	}
+
+	if r.DebugListen != "" {
+		debugListener, err := net.Listen("tcp", r.DebugListen)
+		if err != nil {
+			return fmt.Errorf("failed to set up debug endpoint: %w", err)
+		}
+
+		server := engine.ServeHTTPAPI(debugListener, engine.NewDebugHandler(jsc, runner))
+		defer server.Close()
+		log.Print("Debug endpoint listening on " + debugListener.Addr().String())
+	}
+
+	started := time.Now()
+	go engine.WithLog(func() error {
+		return jsc.Schedule(runner)
 	})
-	go withLog(func() error {
-		return jsc.watchChanges(config.ConfigRoot, eventChan)
+	go engine.WithLog(func() error {
+		return jsc.WatchChanges(config.ConfigRoots(), runner, eventChan)
 	})
-	go runner.run()
-	go serveSocket(listener, jsc, runner)
+	go runner.Run()
+	go retries.Run()
+	go engine.RunGarbageCollector(db, config)
+	go engine.ServeSocket(listener, jsc, runner)
+	go jsc.RunWatchdog(started)
+
+	if err := engine.SdNotify("READY=1"); err != nil {
+		log.Printf("Warning: %v", err)
+	}
 
 	// Wait for SIGINT/SIGTERM; the deferred cleanups remove the socket.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	sig := <-sigCh
 	log.Printf("Received %v; shutting down", sig)
-	return nil
+
+	if err := engine.SdNotify("STOPPING=1"); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Jobs still in the queues run after the next start.
+	return runner.SaveQueues()
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (s *StatsCmd) Run(config engine.Config) error {
+	jobs, _, err := engine.LoadJobInfo(config)
+	if err != nil {
+		return err
+	}
+
+	queues := make(map[string]string, len(jobs))
+	for _, job := range jobs {
+		queues[job.Name] = job.Queue
+	}
+
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if s.Trend {
+		return s.printTrends(db, jobs)
+	}
+
+	if s.ByHost {
+		return s.printByHost(db)
+	}
+
+	stats, err := db.JobStats(time.Now().Add(-s.Period))
+	if err != nil {
+		return fmt.Errorf("error reading job history: %w", err)
+	}
+
+	selected := func(name string) bool {
+		return len(s.JobNames) == 0 || slices.Contains(s.JobNames, name)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tQUEUE\tRUNS\tFAILED\tMEAN\tMAX\tINTERVAL")
+
+	for _, job := range stats {
+		if !selected(job.Name) {
+			continue
+		}
+
+		queue, ok := queues[job.Name]
+		if !ok {
+			queue = "-"
+		}
+
+		interval := "-"
+		if job.Runs > 1 {
+			interval = engine.FormatDuration(job.MeanInterval())
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+			job.Name,
+			queue,
+			job.Runs,
+			job.Failures,
+			engine.FormatDuration(job.MeanRuntime()),
+			engine.FormatDuration(job.MaxRuntime),
+			interval,
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, load := range engine.QueueLoads(stats, queues, s.Period) {
+		if !slices.ContainsFunc(load.Jobs, selected) {
+			continue
+		}
+
+		if load.Overlapping() {
+			fmt.Printf(
+				"\nWarning: the jobs in queue %q take %s to run but are due every %s; the queue will fall further behind (jobs: %s)\n",
+				load.Queue,
+				engine.FormatDuration(load.Runtime),
+				engine.FormatDuration(load.Interval),
+				strings.Join(load.Jobs, ", "),
+			)
+		}
+
+		if load.Saturated() {
+			fmt.Printf(
+				"\nWarning: queue %q was busy %.0f%% of the last %s\n",
+				load.Queue,
+				load.Busy*100,
+				engine.FormatDuration(s.Period),
+			)
+		}
+	}
+
+	return nil
+}
+
+// printTrends compares the latest run times of the jobs with the earlier ones.
+// Unlike the other stats, the comparison uses the whole history.
+func (s *StatsCmd) printTrends(db *engine.AppDB, jobs []engine.JobInfo) error {
+	if s.Recent < 1 {
+		return fmt.Errorf("--recent must be at least 1")
+	}
+
+	names := s.JobNames
+	if len(names) == 0 {
+		for _, job := range jobs {
+			names = append(names, job.Name)
+		}
+
+		slices.Sort(names)
+	}
+
+	trends := []engine.JobTrend{}
+	for _, name := range names {
+		runtimes, err := db.Runtimes(name)
+		if err != nil {
+			return fmt.Errorf("error reading history of job %q: %w", name, err)
+		}
+
+		trends = append(trends, engine.Trend(name, runtimes, s.Recent))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tBASELINE\tRUNS\tRECENT\tRUNS\tCHANGE")
+
+	for _, trend := range trends {
+		baseline, change := "-", "-"
+		if trend.BaselineRuns > 0 {
+			baseline = engine.FormatDuration(trend.Baseline)
+		}
+		if trend.BaselineRuns > 0 && trend.RecentRuns > 0 {
+			change = fmt.Sprintf("%+.0f%%", trend.Change()*100)
+		}
+
+		recent := "-"
+		if trend.RecentRuns > 0 {
+			recent = engine.FormatDuration(trend.Recent)
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%d\t%s\t%d\t%s\n",
+			trend.Name,
+			baseline,
+			trend.BaselineRuns,
+			recent,
+			trend.RecentRuns,
+			change,
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, trend := range trends {
+		if trend.Regressed() {
+			fmt.Printf(
+				"\nWarning: the last %d successful runs of job %q took a median %s, up from %s before\n",
+				trend.RecentRuns,
+				trend.Name,
+				engine.FormatDuration(trend.Recent),
+				engine.FormatDuration(trend.Baseline),
+			)
+		}
+	}
+
+	return nil
+}
+
+// printByHost shows which hosts ran which jobs.
+// It is for a state directory shared between machines.
+func (s *StatsCmd) printByHost(db *engine.AppDB) error {
+	stats, err := db.HostStats(time.Now().Add(-s.Period))
+	if err != nil {
+		return fmt.Errorf("error reading job history: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tNAME\tRUNS\tFAILED\tLAST RUN")
+
+	for _, job := range stats {
+		if len(s.JobNames) > 0 && !slices.Contains(s.JobNames, job.Name) {
+			continue
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%d\t%d\t%s\n",
+			hostOrDash(job.Host),
+			job.Name,
+			job.Runs,
+			job.Failures,
+			job.LastStarted.Format(timestampFormat),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, host := range engine.StaleHosts(stats) {
+		fmt.Printf(
+			"\nWarning: host %s last ran a job at %s, %s before the last run on another host; is its daemon running?\n",
+			hostOrDash(host.Host),
+			host.LastStarted.Format(timestampFormat),
+			engine.FormatDuration(host.Behind),
+		)
+	}
+
+	return nil
+}
+
+// hostOrDash returns "-" for runs saved before Regular recorded the host.
+func hostOrDash(host string) string {
+	if host == "" {
+		return "-"
+	}
+
+	return host
+}
@@ -3,63 +3,97 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"golang.org/x/term"
 
-	"dbohdan.com/denv"
+	"dbohdan.com/regular/engine"
 )
 
-func (s *StatusCmd) Run(config Config) error {
+func (s *StatusCmd) Run(config engine.Config) error {
 	width := getTermWidth()
 	separator := strings.Repeat("-", width)
 
-	jobs := newJobScheduler()
-
-	err := filepath.Walk(config.ConfigRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	jobs, fromDaemon, err := engine.LoadJobInfo(config)
+	if err != nil {
+		return err
+	}
 
-		if !info.IsDir() && filepath.Base(path) == jobConfigFileName {
-			_, _, err := jobs.update(config.ConfigRoot, path)
-			if err != nil {
-				return err
-			}
-		}
+	byName := make(map[string]engine.JobInfo, len(jobs))
+	for _, job := range jobs {
+		byName[job.Name] = job
+	}
 
-		return nil
-	})
+	db, err := engine.OpenAppDB(config.StateRoot)
 	if err != nil {
-		return fmt.Errorf("error looking for jobs in config dir: %v", err)
+		return err
 	}
+	defer db.Close()
 
-	db, err := openAppDB(config.StateRoot)
+	pausedQueues, err := db.PausedQueues()
 	if err != nil {
-		return err
+		return fmt.Errorf("error getting paused queues: %w", err)
 	}
-	defer db.close()
 
-	secret := regexp.MustCompile(secretRegexp)
+	filtering := s.Disabled || s.Failed || s.Running
+	if len(s.JobNames) == 0 && !filtering {
+		if err := printDaemonState(config, fromDaemon); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	if fromDaemon && len(s.JobNames) == 0 {
+		stats, err := engine.QueryDaemonStats()
+		if err != nil {
+			return err
+		}
+
+		if stats != nil && stats.Ticks > 0 {
+			printSchedulerStats(*stats)
+			fmt.Println()
+		}
+	}
 
 	seenNames := make(map[string]struct{})
 
-	// We iterate over a copy of selectedNames instead of the keys of jobs.byName to preserve order.
+	// We iterate over a copy of selectedNames instead of the keys of byName to preserve order.
 	selectedNames := s.JobNames[:]
 	if len(selectedNames) == 0 {
-		for name := range jobs.byName {
+		for name := range byName {
 			selectedNames = append(selectedNames, name)
 		}
 
 		slices.Sort(selectedNames)
 	}
 
+	if filtering {
+		filtered := []string{}
+
+		for _, name := range selectedNames {
+			job, ok := byName[name]
+			if !ok {
+				continue
+			}
+
+			match, err := s.matches(db, job)
+			if err != nil {
+				return err
+			}
+
+			if match {
+				filtered = append(filtered, name)
+			}
+		}
+
+		selectedNames = filtered
+	}
+
 	for i, name := range selectedNames {
-		job, ok := jobs.byName[name]
+		job, ok := byName[name]
 		if !ok {
 			continue
 		}
@@ -70,18 +104,6 @@ func (s *StatusCmd) Run(config Config) error {
 		}
 		seenNames[name] = struct{}{}
 
-		osEnv := denv.OS()
-		for _, key := range job.Env.Keys() {
-			if osValue, ok := osEnv[key]; ok && osValue == job.Env[key] {
-				delete(job.Env, key)
-				continue
-			}
-
-			if secret.MatchString(key) {
-				job.Env[key] = redactedValue
-			}
-		}
-
 		color.Set(color.Bold)
 		fmt.Println(name)
 		color.Unset()
@@ -89,6 +111,14 @@ func (s *StatusCmd) Run(config Config) error {
 		fmt.Println("    duplicate:", boolYesNo(job.Duplicate))
 		fmt.Println("    enable:", boolYesNo(job.Enable))
 
+		snooze, err := activeSnooze(db, name)
+		if err != nil {
+			return err
+		}
+		if snooze != nil {
+			fmt.Println("    snoozed:", snoozeEnd(*snooze))
+		}
+
 		if len(job.Env) == 0 {
 			fmt.Println("    env: none")
 		} else {
@@ -98,29 +128,104 @@ func (s *StatusCmd) Run(config Config) error {
 			}
 		}
 
-		fmt.Println("    jitter:", formatDuration(job.Jitter))
+		executor := job.Executor
+		if executor == "" {
+			executor = "local"
+		}
+		fmt.Println("    executor:", executor)
+
+		if len(job.Hosts) > 0 {
+			hosts := strings.Join(job.Hosts, ", ")
+			if !job.RunsOnThisHost() {
+				hosts += " (not this host)"
+			}
+
+			fmt.Println("    hosts:", hosts)
+		}
+
+		fmt.Println("    jitter:", engine.FormatDuration(job.Jitter))
 		fmt.Println("    log:", boolYesNo(job.Log))
-		fmt.Println("    queue:", job.QueueName())
-		fmt.Println()
+		if since, ok := pausedQueues[job.Queue]; ok {
+			fmt.Printf("    queue: %s (paused since %s)\n", job.Queue, since.Format(timestampFormat))
+		} else {
+			fmt.Println("    queue:", job.Queue)
+		}
+
+		if len(job.Tags) > 0 {
+			fmt.Println("    tags:", strings.Join(job.Tags, ", "))
+		}
+
+		if fromDaemon {
+			fmt.Println("    running:", boolYesNo(job.Running))
+			fmt.Println("    pending:", job.Pending)
+
+			if job.Pending > 0 {
+				fmt.Println("    jobs ahead in queue:", job.Position)
+				fmt.Println("    waiting:", engine.FormatDuration(job.Waiting))
+			}
+		}
 
-		completed, err := db.getLastCompleted(job.Name)
+		completed, err := db.LastCompleted(name)
 		if err != nil {
 			return fmt.Errorf("error getting last completed job %q: %w", name, err)
 		}
 
+		history, err := db.History(name, engine.ShouldRunHistoryLength)
+		if err != nil {
+			return fmt.Errorf("error getting history of job %q: %w", name, err)
+		}
+
+		fmt.Println("    next due:", nextDue(config, name, history, snooze, time.Now()))
+
+		fmt.Println()
+
 		if completed == nil {
+			fmt.Println("    last run ID: unknown")
 			fmt.Println("    last started:  unknown")
 			fmt.Println("    last finished: unknown")
 			fmt.Println("    exit status: unknown")
 		} else {
+			fmt.Println("    last run ID:", completed.ID)
 			fmt.Println("    last started: ", completed.Started.Format(timestampFormat))
 			fmt.Println("    last finished:", completed.Finished.Format(timestampFormat))
-			fmt.Println("    exit status:", completed.ExitStatus)
+			fmt.Println("    exit status:", completed.ExitStatusText())
+
+			if completed.Signal != 0 {
+				fmt.Println("    killed by:", completed.SignalName())
+			}
+			if completed.Skipped {
+				fmt.Println("    skipped: unchanged since the last successful run")
+			}
+			if completed.Dead {
+				fmt.Printf("    dead: gave up after %d attempts\n", completed.Attempt)
+			}
+
+			if completed.Hostname != "" || completed.Username != "" {
+				fmt.Println("    last ran on:", completed.Username+"@"+completed.Hostname)
+			}
+			if len(completed.Command) > 0 {
+				fmt.Println("    last command:", completed.CommandLine())
+			}
+
+			artifacts, err := engine.RunArtifacts(config.StateRoot, name, completed.ID)
+			if err != nil {
+				return err
+			}
+			if len(artifacts) > 0 {
+				fmt.Println("    artifacts:", engine.ArtifactsDir(config.StateRoot, name, completed.ID))
+				for _, artifact := range artifacts {
+					fmt.Println("        " + artifact)
+				}
+			}
 		}
 
-		fmt.Println("    logs:")
+		if completed != nil && completed.Skipped {
+			fmt.Println("    logs of the last run that wasn't skipped:")
+		} else {
+			fmt.Println("    logs:")
+		}
 
-		stdoutLines, err := db.getJobLogs(name, "stdout", s.LogLines)
+		stdoutLines, err := db.JobLogs(name, "stdout", s.LogLines)
 		if err != nil {
 			return fmt.Errorf("error loading stdout for job %q: %w", name, err)
 		}
@@ -135,7 +240,7 @@ func (s *StatusCmd) Run(config Config) error {
 			fmt.Println(separator)
 		}
 
-		stderrLines, err := db.getJobLogs(name, "stderr", s.LogLines)
+		stderrLines, err := db.JobLogs(name, "stderr", s.LogLines)
 		if err != nil {
 			return fmt.Errorf("error loading stderr for job %q: %w", name, err)
 		}
@@ -150,6 +255,19 @@ func (s *StatusCmd) Run(config Config) error {
 			fmt.Println(separator)
 		}
 
+		events, err := db.JobEvents(name, s.Events)
+		if err != nil {
+			return fmt.Errorf("error loading events for job %q: %w", name, err)
+		}
+		if len(events) == 0 {
+			fmt.Println("    events: none")
+		} else {
+			fmt.Println("    events:")
+			for _, event := range events {
+				fmt.Printf("        %s %s\n", event.Time.Format(timestampFormat), event.Message)
+			}
+		}
+
 		if i != len(selectedNames)-1 {
 			fmt.Println()
 		}
@@ -158,6 +276,134 @@ func (s *StatusCmd) Run(config Config) error {
 	return nil
 }
 
+// printDaemonState prints whether a daemon is running for the state directory.
+// A daemon that answers on the socket is running.
+// Otherwise, the daemon info tells a hung daemon from a crashed one.
+func printDaemonState(config engine.Config, responding bool) error {
+	info, err := engine.ReadDaemonInfo(config.StateRoot)
+	if err != nil {
+		return err
+	}
+
+	state := "not running"
+	switch {
+	case responding:
+		state = "running"
+	case info != nil && info.Alive():
+		state = "not responding on the socket"
+	case info != nil:
+		state = "not running (crashed; stale daemon info)"
+	}
+
+	color.Set(color.Bold)
+	fmt.Println("daemon")
+	color.Unset()
+
+	fmt.Println("    state:", state)
+	if info != nil {
+		fmt.Println("    pid:", info.PID)
+		fmt.Println("    started:", info.Started.Format(timestampFormat))
+	}
+
+	return nil
+}
+
+// printSchedulerStats prints how far behind the clock the scheduler is.
+func printSchedulerStats(stats engine.SchedulerStats) {
+	color.Set(color.Bold)
+	fmt.Println("scheduler")
+	color.Unset()
+
+	fmt.Println("    last tick:", stats.LastTick.Format(timestampFormat))
+	fmt.Printf("    lag: %v (max %v)\n", engine.FormatDuration(stats.Lag), engine.FormatDuration(stats.MaxLag))
+	fmt.Printf("    check time: %v (max %v)\n", engine.FormatDuration(stats.CheckTime), engine.FormatDuration(stats.MaxCheckTime))
+}
+
+// matches reports whether a job passes any of the state filters.
+func (s *StatusCmd) matches(db *engine.AppDB, job engine.JobInfo) (bool, error) {
+	if s.Disabled {
+		if !job.Enable {
+			return true, nil
+		}
+
+		snooze, err := activeSnooze(db, job.Name)
+		if err != nil {
+			return false, err
+		}
+		if snooze != nil {
+			return true, nil
+		}
+	}
+
+	if s.Running && job.Running {
+		return true, nil
+	}
+
+	if s.Failed {
+		completed, err := db.LastCompleted(job.Name)
+		if err != nil {
+			return false, fmt.Errorf("error getting last completed job %q: %w", job.Name, err)
+		}
+
+		if completed != nil && !completed.IsSuccess() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// activeSnooze returns the snooze of a job if it hasn't ended.
+func activeSnooze(db *engine.AppDB, name string) (*engine.Snooze, error) {
+	snooze, err := db.JobSnooze(name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting snooze of job %q: %w", name, err)
+	}
+
+	if snooze == nil || !snooze.ActiveAt(time.Now()) {
+		return nil, nil
+	}
+
+	return snooze, nil
+}
+
+// nextDue describes when the scheduler will next queue a job according to its "should_run".
+// A job that is snoozed is only due after the snooze ends.
+func nextDue(config engine.Config, name string, history []engine.CompletedJob, snooze *engine.Snooze, now time.Time) string {
+	job, err := engine.NewScheduler().LoadJob(config.ConfigRoots(), name)
+	if err != nil {
+		return "unknown"
+	}
+
+	if !job.Enable {
+		return "never (disabled)"
+	}
+
+	from := now
+	if snooze != nil {
+		if snooze.Until.IsZero() {
+			return "never (snoozed)"
+		}
+
+		from = snooze.Until
+	}
+
+	next, due, err := job.NextDue(from, history, nextDueHorizon)
+	if err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+
+	if !due {
+		return "not in the next " + engine.FormatDuration(nextDueHorizon)
+	}
+
+	if !next.After(now) {
+		return "now"
+	}
+
+	return next.Format(timestampFormat)
+}
+
 func getTermWidth() int {
 	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
 		return w
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (v *ValidateCmd) Run(config engine.Config) error {
+	names := v.JobNames
+	if len(names) == 0 {
+		var err error
+		names, err = engine.ListJobNames(config.ConfigRoots()...)
+		if err != nil {
+			return err
+		}
+	}
+
+	jsc := engine.NewScheduler()
+	checked := make(map[string]struct{})
+	failed := 0
+	for _, name := range names {
+		// A job file that calls "job" loads every job it defines at once.
+		if _, ok := checked[name]; ok {
+			continue
+		}
+
+		jobs, err := jsc.LoadJobs(config.ConfigRoots(), name)
+		if err != nil {
+			failed++
+			fmt.Printf("%s: error: %v\n", name, err)
+
+			continue
+		}
+
+		for _, job := range jobs {
+			checked[job.Name] = struct{}{}
+
+			warnings := job.Warnings()
+			for _, warning := range warnings {
+				fmt.Printf("%s: warning: %s\n", job.Name, warning)
+			}
+
+			if len(warnings) == 0 {
+				fmt.Printf("%s: ok\n", job.Name)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs failed to load", failed, failed+len(checked))
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"dbohdan.com/regular/engine"
+)
+
+func (w *WhyCmd) Run(config engine.Config) error {
+	job, err := engine.NewScheduler().LoadJob(config.ConfigRoots(), w.JobName)
+	if err != nil {
+		return err
+	}
+
+	d, queued, fromDaemon, err := engine.QueryDaemonDecision(job.Name)
+	if err != nil {
+		return err
+	}
+
+	if d != nil {
+		fmt.Printf("Last decision of the scheduler at %s:\n", d.Time.Format(timestampFormat))
+		printDecision(*d)
+
+		if queued != nil {
+			fmt.Printf("Last time the scheduler queued the job at %s:\n", queued.Time.Format(timestampFormat))
+			printDecision(*queued)
+		}
+
+		return nil
+	}
+
+	// Without a decision from the daemon, work out what it would decide now.
+	db, err := engine.OpenAppDB(config.StateRoot)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runner, err := engine.NewRunner(db, nil, config.StateRoot)
+	if err != nil {
+		return err
+	}
+	if err := configureRunner(&runner, config); err != nil {
+		return err
+	}
+
+	decision, err := runner.Decide(*job, time.Now())
+	if err != nil {
+		decision.Reason = "error: " + err.Error()
+	}
+
+	if fromDaemon {
+		fmt.Println("The scheduler hasn't decided about the job yet.")
+	}
+	fmt.Printf("Decision for now (%s):\n", decision.Time.Format(timestampFormat))
+	printDecision(decision)
+
+	return nil
+}
+
+func printDecision(d engine.Decision) {
+	if d.ShouldRunArgs != "" {
+		fmt.Printf("    should_run(%s)\n", d.ShouldRunArgs)
+	}
+	if d.ShouldRunResult != "" {
+		fmt.Printf("    returned: %s\n", d.ShouldRunResult)
+	}
+
+	queued := "no"
+	if d.Queued {
+		queued = "yes"
+	}
+	fmt.Printf("    queued: %s\n", queued)
+	fmt.Printf("    reason: %s\n", d.Reason)
+
+	if d.QueuePaused.IsZero() {
+		fmt.Printf("    queue: %s\n", d.Queue)
+	} else {
+		fmt.Printf("    queue: %s (paused since %s)\n", d.Queue, d.QueuePaused.Format(timestampFormat))
+	}
+}